@@ -18,6 +18,7 @@ import (
 	"flag"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -57,11 +58,25 @@ func serverCmd() *cobra.Command {
 			// Retrieve Viper values for each Cobra Val Flag
 			viper.SetTypeByDefaultValue(true)
 			cmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
-				if reflect.TypeOf(viper.Get(f.Name)).Kind() == reflect.Slice {
+				switch reflect.TypeOf(viper.Get(f.Name)).Kind() {
+				case reflect.Slice:
 					// Viper cannot convert slices to strings, so this is our workaround.
 					_ = f.Value.Set(strings.Join(viper.GetStringSlice(f.Name), ","))
-				} else {
-					_ = f.Value.Set(viper.GetString(f.Name))
+				case reflect.Map:
+					// Nor maps, which are flattened to key=value pairs.
+					var pairs []string
+					for k, v := range viper.GetStringMapString(f.Name) {
+						pairs = append(pairs, k+"="+v)
+					}
+					sort.Strings(pairs)
+					_ = f.Value.Set(strings.Join(pairs, ","))
+				default:
+					v := viper.GetString(f.Name)
+					if f.Value.Type() == "stringToString" {
+						// The values of map flags are formatted in brackets.
+						v = strings.Trim(v, "[]")
+					}
+					_ = f.Value.Set(v)
 				}
 			})
 
@@ -130,6 +145,14 @@ func serverCmd() *cobra.Command {
 		"The access list yaml file that contains the allowed mTLS peer ids.")
 	svr.PersistentFlags().StringVar(&serverArgs.ConfigPath, "configPath", serverArgs.ConfigPath,
 		"Istio config file path")
+	svr.PersistentFlags().StringVar(&serverArgs.GitOptions.Repository, "gitRepository", serverArgs.GitOptions.Repository,
+		"Path of a local clone or bare Git repository to read the Istio config committed at --gitRef from, instead of --configPath or Kubernetes")
+	svr.PersistentFlags().StringVar(&serverArgs.GitOptions.Ref, "gitRef", serverArgs.GitOptions.Ref,
+		"Ref of --gitRepository tracked for new commits, e.g. refs/heads/master. HEAD if empty")
+	svr.PersistentFlags().StringVar(&serverArgs.GitOptions.Path, "gitPath", serverArgs.GitOptions.Path,
+		"Directory of the config files in --gitRepository. The whole repository if empty")
+	svr.PersistentFlags().DurationVar(&serverArgs.GitOptions.PollInterval, "gitPollInterval", serverArgs.GitOptions.PollInterval,
+		"Interval between the checks of --gitRef for new commits. 10s if 0")
 	svr.PersistentFlags().StringVar(&serverArgs.MeshConfigFile, "meshConfigFile", serverArgs.MeshConfigFile,
 		"Path to the mesh config file")
 	svr.PersistentFlags().StringVar(&serverArgs.DomainSuffix, "domain", serverArgs.DomainSuffix,
@@ -155,6 +178,12 @@ func serverCmd() *cobra.Command {
 		"Enable the Fsnotify for watching config source files on the disk and implicit signaling on a config change. Explicit signaling will still be enabled")
 	svr.PersistentFlags().BoolVar(&serverArgs.EnableConfigAnalysis, "enableAnalysis", serverArgs.EnableConfigAnalysis,
 		"Enable config analysis service")
+	svr.PersistentFlags().StringToStringVar(&serverArgs.CollectionStrategies, "collectionStrategies", serverArgs.CollectionStrategies,
+		"Comma-separated list of collection=strategy publishing the changes of the collections with their own strategy, "+
+			"e.g. 'istio/networking/v1alpha3/virtualservices=debounce:1s'. The strategies are immediate, "+
+			"debounce[:<quiesce>[:<max wait>]] and ratelimit:<interval>")
+	svr.PersistentFlags().DurationVar(&serverArgs.SnapshotMaxStaleness, "snapshotMaxStaleness", serverArgs.SnapshotMaxStaleness,
+		"Maximum duration a config change may stay unpublished whatever the strategies, or 0 if unbounded")
 
 	// validation webhook server config
 	svr.PersistentFlags().UintVar(&serverArgs.ValidationWebhookServerArgs.Port, "validation-port",
@@ -216,6 +245,8 @@ func setupAliases() {
 	viper.RegisterAlias("processing.analysis.enable", "enableAnalysis")
 	viper.RegisterAlias("processing.discovery.enable", "enableServiceDiscovery")
 	viper.RegisterAlias("processing.domainSuffix", "domain")
+	viper.RegisterAlias("processing.snapshot.collectionStrategies", "collectionStrategies")
+	viper.RegisterAlias("processing.snapshot.maxStaleness", "snapshotMaxStaleness")
 	viper.RegisterAlias("processing.oldprocessor", "useOldProcessor")
 	viper.RegisterAlias("processing.server.enable", "enable-server")
 	viper.RegisterAlias("processing.server.address", "server-address")
@@ -229,6 +260,10 @@ func setupAliases() {
 	viper.RegisterAlias("processing.server.auth.insecure", "insecure")
 	viper.RegisterAlias("processing.source.kubernetes.resyncPeriod", "resyncPeriod")
 	viper.RegisterAlias("processing.source.filesystem.path", "configPath")
+	viper.RegisterAlias("processing.source.git.repository", "gitRepository")
+	viper.RegisterAlias("processing.source.git.ref", "gitRef")
+	viper.RegisterAlias("processing.source.git.path", "gitPath")
+	viper.RegisterAlias("processing.source.git.pollInterval", "gitPollInterval")
 	viper.RegisterAlias("validation.enable", "enable-validation")
 	viper.RegisterAlias("validation.webhookConfigFile", "validation-webhook-config-file")
 	viper.RegisterAlias("validation.webhookPort", "validation-port")
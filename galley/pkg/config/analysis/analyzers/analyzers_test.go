@@ -447,6 +447,30 @@ func TestAnalyzers(t *testing.T) {
 	})
 }
 
+// Verify that the messages point to the fields of the resources in their files
+func TestAnalyzerMessageLocations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tc := testCase{
+		name:       "virtualServiceDestinationHostLocations",
+		inputFiles: []string{"testdata/virtualservice_destinationhosts.yaml"},
+		analyzer:   &virtualservice.DestinationHostAnalyzer{},
+	}
+	sa, err := setupAnalyzerForCase(tc, nil)
+	g.Expect(err).To(BeNil())
+	result, err := runAnalyzer(sa)
+	g.Expect(err).To(BeNil())
+
+	locations := make(map[string]string)
+	for _, m := range result.Messages {
+		locations[m.Resource.Origin.FriendlyName()] = m.Location()
+	}
+	g.Expect(locations).To(HaveKeyWithValue("VirtualService reviews-bogushost.default",
+		"testdata/virtualservice_destinationhosts.yaml:87 spec.http[0].route[0].destination.host"))
+	g.Expect(locations).To(HaveKeyWithValue("VirtualService reviews-mirror-bogushost.default",
+		"testdata/virtualservice_destinationhosts.yaml:174 spec.http[0].mirror.host"))
+}
+
 // Verify that all of the analyzers tested here are also registered in All()
 func TestAnalyzersInAll(t *testing.T) {
 	g := NewGomegaWithT(t)
@@ -59,6 +59,7 @@ func (s *ServiceRoleBindingAnalyzer) analyzeRoleBinding(r *resource.Instance, ct
 	}
 
 	if !ctx.Exists(collections.IstioRbacV1Alpha1Serviceroles.Name(), resource.NewFullName(ns, resource.LocalName(srb.RoleRef.Name))) {
-		ctx.Report(collections.IstioRbacV1Alpha1Servicerolebindings.Name(), msg.NewReferencedResourceNotFound(r, "service role", srb.RoleRef.Name))
+		ctx.Report(collections.IstioRbacV1Alpha1Servicerolebindings.Name(), msg.NewReferencedResourceNotFound(r, "service role", srb.RoleRef.Name).
+			WithField("spec.roleRef.name"))
 	}
 }
@@ -15,6 +15,8 @@
 package gateway
 
 import (
+	"fmt"
+
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 
@@ -55,11 +57,11 @@ func (a *SecretAnalyzer) Analyze(ctx analysis.Context) {
 		// If we can't find a namespace for the gateway, it's because there's no matching selector. Exit early with a different message.
 		if gwNs == "" {
 			ctx.Report(collections.IstioNetworkingV1Alpha3Gateways.Name(),
-				msg.NewReferencedResourceNotFound(r, "selector", labels.SelectorFromSet(gw.Selector).String()))
+				msg.NewReferencedResourceNotFound(r, "selector", labels.SelectorFromSet(gw.Selector).String()).WithField("spec.selector"))
 			return true
 		}
 
-		for _, srv := range gw.GetServers() {
+		for i, srv := range gw.GetServers() {
 			tls := srv.GetTls()
 			if tls == nil {
 				continue
@@ -71,7 +73,8 @@ func (a *SecretAnalyzer) Analyze(ctx analysis.Context) {
 			}
 
 			if !ctx.Exists(collections.K8SCoreV1Secrets.Name(), resource.NewShortOrFullName(gwNs, cn)) {
-				ctx.Report(collections.IstioNetworkingV1Alpha3Gateways.Name(), msg.NewReferencedResourceNotFound(r, "credentialName", cn))
+				ctx.Report(collections.IstioNetworkingV1Alpha3Gateways.Name(), msg.NewReferencedResourceNotFound(r, "credentialName", cn).
+					WithField(fmt.Sprintf("spec.servers[%d].tls.credentialName", i)))
 			}
 		}
 		return true
@@ -0,0 +1,267 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rules implements analyzers declared in files as CEL conditions over the resources of a collection,
+// so that organization-specific checks can run alongside the built-in analyzers without rebuilding.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/galley/pkg/config/schema/collection"
+	"istio.io/istio/galley/pkg/config/scope"
+)
+
+// resourceVar is the name of the variable holding the resource evaluated by a condition.
+const resourceVar = "resource"
+
+var codeRegexp = regexp.MustCompile(`^[A-Z]+[0-9]+$`)
+
+// File is a file of rules.
+type File struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule declares an analyzer reporting a message for each resource of a collection matching a condition, e.g.
+//
+//	name: require-app-label
+//	collection: k8s/apps/v1/deployments
+//	condition: "!('app' in resource.metadata.labels)"
+//	code: ORG0001
+//	level: Warning
+//	message: Deployments must have an app label
+//
+// The condition is a CEL expression over the resource variable, a map with the metadata (name, namespace, labels
+// and annotations) and the spec of the resource as in its JSON representation.
+type Rule struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Collection  string `json:"collection"`
+	Condition   string `json:"condition"`
+	Code        string `json:"code"`
+	Level       string `json:"level,omitempty"`
+	Message     string `json:"message"`
+}
+
+// Analyzer reports the resources matching the condition of a rule.
+type Analyzer struct {
+	rule       Rule
+	collection collection.Name
+	msgType    *diag.MessageType
+	program    cel.Program
+}
+
+var _ analysis.Analyzer = &Analyzer{}
+
+// Load reads the rules of the files and returns their analyzers.
+func Load(schemas collection.Schemas, filenames ...string) ([]analysis.Analyzer, error) {
+	var analyzers []analysis.Analyzer
+	for _, filename := range filenames {
+		in, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		a, err := Parse(schemas, in)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rules in %s: %v", filename, err)
+		}
+		analyzers = append(analyzers, a...)
+	}
+	return analyzers, nil
+}
+
+// Parse parses a file of rules and returns their analyzers.
+func Parse(schemas collection.Schemas, in []byte) ([]analysis.Analyzer, error) {
+	var f File
+	if err := yaml.Unmarshal(in, &f); err != nil {
+		return nil, err
+	}
+
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewIdent(resourceVar, decls.NewMapType(decls.String, decls.Dyn), nil)))
+	if err != nil {
+		return nil, err
+	}
+
+	builtinCodes := map[string]bool{}
+	for _, t := range msg.All() {
+		builtinCodes[t.Code()] = true
+	}
+
+	names := map[string]bool{}
+	analyzers := make([]analysis.Analyzer, 0, len(f.Rules))
+	for _, r := range f.Rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule without a name")
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate rule %q", r.Name)
+		}
+		names[r.Name] = true
+		a, err := newAnalyzer(env, schemas, builtinCodes, r)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %v", r.Name, err)
+		}
+		analyzers = append(analyzers, a)
+	}
+	return analyzers, nil
+}
+
+func newAnalyzer(env cel.Env, schemas collection.Schemas, builtinCodes map[string]bool, r Rule) (*Analyzer, error) {
+	s, ok := schemas.Find(r.Collection)
+	if !ok {
+		return nil, fmt.Errorf("unknown collection %q", r.Collection)
+	}
+	if !codeRegexp.MatchString(r.Code) {
+		return nil, fmt.Errorf("invalid code %q, codes are an uppercase prefix followed by digits, e.g. ORG0001", r.Code)
+	}
+	if builtinCodes[r.Code] {
+		return nil, fmt.Errorf("code %s is already used by a built-in analyzer", r.Code)
+	}
+	level := diag.Warning
+	if r.Level != "" {
+		l, ok := diag.GetUppercaseStringToLevelMap()[strings.ToUpper(r.Level)]
+		if !ok {
+			return nil, fmt.Errorf("invalid level %q, valid levels are %v", r.Level, diag.GetAllLevelStrings())
+		}
+		level = l
+	}
+	if r.Message == "" {
+		return nil, fmt.Errorf("rule without a message")
+	}
+
+	ast, iss := env.Parse(r.Condition)
+	if iss != nil && iss.Err() != nil {
+		return nil, fmt.Errorf("invalid condition: %v", iss.Err())
+	}
+	checked, iss := env.Check(ast)
+	if iss != nil && iss.Err() != nil {
+		return nil, fmt.Errorf("invalid condition: %v", iss.Err())
+	}
+	if checked.ResultType().GetPrimitive() != decls.Bool.GetPrimitive() {
+		return nil, fmt.Errorf("condition does not evaluate to a bool")
+	}
+	program, err := env.Program(checked)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition: %v", err)
+	}
+
+	return &Analyzer{
+		rule:       r,
+		collection: s.Name(),
+		msgType:    diag.NewMessageType(level, r.Code, "%s"),
+		program:    program,
+	}, nil
+}
+
+// Metadata implements Analyzer
+func (a *Analyzer) Metadata() analysis.Metadata {
+	description := a.rule.Description
+	if description == "" {
+		description = a.rule.Message
+	}
+	return analysis.Metadata{
+		Name:        "rules." + a.rule.Name,
+		Description: description,
+		Inputs:      collection.Names{a.collection},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *Analyzer) Analyze(c analysis.Context) {
+	c.ForEach(a.collection, func(r *resource.Instance) bool {
+		matched, err := a.matches(r)
+		if err != nil {
+			// A condition may fail on some resources, e.g. when accessing a field they don't set
+			scope.Analysis.Debugf("Rule %q could not be evaluated on %s: %v", a.rule.Name, r.Metadata.FullName, err)
+			return true
+		}
+		if matched {
+			c.Report(a.collection, diag.NewMessage(a.msgType, r, a.rule.Message))
+		}
+		return true
+	})
+}
+
+func (a *Analyzer) matches(r *resource.Instance) (bool, error) {
+	v, err := toValue(r)
+	if err != nil {
+		return false, err
+	}
+	out, _, err := a.program.Eval(map[string]interface{}{resourceVar: v})
+	if err != nil {
+		return false, err
+	}
+	return out == types.True, nil
+}
+
+// toValue returns the map of the resource evaluated by the conditions.
+func toValue(r *resource.Instance) (map[string]interface{}, error) {
+	spec := map[string]interface{}{}
+	if r.Message != nil {
+		var js []byte
+		var err error
+		if isKubeResource(r) {
+			// Kubernetes types are not protos generated by gogo, they have JSON tags instead
+			js, err = json.Marshal(r.Message)
+		} else {
+			var s string
+			s, err = (&jsonpb.Marshaler{}).MarshalToString(r.Message)
+			js = []byte(s)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(js, &spec); err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":        string(r.Metadata.FullName.Name),
+			"namespace":   string(r.Metadata.FullName.Namespace),
+			"labels":      toMap(r.Metadata.Labels),
+			"annotations": toMap(r.Metadata.Annotations),
+		},
+		"spec": spec,
+	}, nil
+}
+
+// toMap converts labels or annotations to a generic map, since the CEL maps of strings don't support the in operator.
+func toMap(m resource.StringMap) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func isKubeResource(r *resource.Instance) bool {
+	return r.Metadata.Schema != nil && strings.HasPrefix(r.Metadata.Schema.ProtoPackage(), "k8s.io/")
+}
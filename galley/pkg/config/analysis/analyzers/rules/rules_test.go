@@ -0,0 +1,173 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rules
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/testing/fixtures"
+	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/galley/pkg/config/schema"
+	"istio.io/istio/galley/pkg/config/schema/collections"
+)
+
+const houseRules = `
+rules:
+- name: no-wildcard-hosts
+  description: Virtual services must not match all hosts
+  collection: istio/networking/v1alpha3/virtualservices
+  condition: "resource.spec.hosts.exists(h, h == '*')"
+  code: ORG0001
+  level: Error
+  message: Wildcard hosts are forbidden
+- name: require-app-label
+  collection: k8s/apps/v1/deployments
+  condition: "!('app' in resource.metadata.labels)"
+  code: ORG0002
+  message: Deployments must have an app label
+`
+
+func TestParse(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	analyzers, err := Parse(schema.MustGet().AllCollections(), []byte(houseRules))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(analyzers).To(HaveLen(2))
+
+	g.Expect(analyzers[0].Metadata().Name).To(Equal("rules.no-wildcard-hosts"))
+	g.Expect(analyzers[0].Metadata().Description).To(Equal("Virtual services must not match all hosts"))
+	g.Expect(analyzers[0].Metadata().Inputs).To(ConsistOf(collections.IstioNetworkingV1Alpha3Virtualservices.Name()))
+	g.Expect(analyzers[1].(*Analyzer).msgType.Level()).To(Equal(diag.Warning))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	cases := []struct {
+		name    string
+		rules   string
+		wantErr string
+	}{
+		{
+			name:    "unknown collection",
+			rules:   "rules:\n- {name: r, collection: foo/bar, condition: 'true', code: ORG0001, message: m}",
+			wantErr: `unknown collection "foo/bar"`,
+		},
+		{
+			name:    "builtin code",
+			rules:   "rules:\n- {name: r, collection: k8s/core/v1/pods, condition: 'true', code: IST0101, message: m}",
+			wantErr: "already used by a built-in analyzer",
+		},
+		{
+			name:    "invalid code",
+			rules:   "rules:\n- {name: r, collection: k8s/core/v1/pods, condition: 'true', code: org-1, message: m}",
+			wantErr: "invalid code",
+		},
+		{
+			name:    "invalid level",
+			rules:   "rules:\n- {name: r, collection: k8s/core/v1/pods, condition: 'true', code: ORG0001, level: Fatal, message: m}",
+			wantErr: "invalid level",
+		},
+		{
+			name:    "invalid condition",
+			rules:   "rules:\n- {name: r, collection: k8s/core/v1/pods, condition: 'resource.', code: ORG0001, message: m}",
+			wantErr: "invalid condition",
+		},
+		{
+			name:    "condition not a bool",
+			rules:   "rules:\n- {name: r, collection: k8s/core/v1/pods, condition: '1 + 1', code: ORG0001, message: m}",
+			wantErr: "does not evaluate to a bool",
+		},
+		{
+			name: "duplicate rule",
+			rules: "rules:\n- {name: r, collection: k8s/core/v1/pods, condition: 'true', code: ORG0001, message: m}" +
+				"\n- {name: r, collection: k8s/core/v1/pods, condition: 'true', code: ORG0002, message: m}",
+			wantErr: `duplicate rule "r"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			_, err := Parse(schema.MustGet().AllCollections(), []byte(c.rules))
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(ContainSubstring(c.wantErr))
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	analyzers, err := Parse(schema.MustGet().AllCollections(), []byte(houseRules))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	vsCtx := &fixtures.Context{Resources: []*resource.Instance{
+		{
+			Metadata: resource.Metadata{
+				Schema:   collections.IstioNetworkingV1Alpha3Virtualservices.Resource(),
+				FullName: resource.NewFullName("default", "wildcard"),
+			},
+			Message: &v1alpha3.VirtualService{Hosts: []string{"reviews", "*"}},
+		},
+		{
+			Metadata: resource.Metadata{
+				Schema:   collections.IstioNetworkingV1Alpha3Virtualservices.Resource(),
+				FullName: resource.NewFullName("default", "reviews"),
+			},
+			Message: &v1alpha3.VirtualService{Hosts: []string{"reviews"}},
+		},
+		{
+			// The condition can't be evaluated without hosts
+			Metadata: resource.Metadata{
+				Schema:   collections.IstioNetworkingV1Alpha3Virtualservices.Resource(),
+				FullName: resource.NewFullName("default", "empty"),
+			},
+			Message: &v1alpha3.VirtualService{},
+		},
+	}}
+	analyzers[0].Analyze(vsCtx)
+	g.Expect(vsCtx.Reports).To(HaveLen(1))
+	g.Expect(vsCtx.Reports[0].Resource.Metadata.FullName.String()).To(Equal("default/wildcard"))
+	g.Expect(vsCtx.Reports[0].Type.Code()).To(Equal("ORG0001"))
+	g.Expect(vsCtx.Reports[0].Type.Level()).To(Equal(diag.Error))
+	g.Expect(vsCtx.Reports[0].Parameters).To(Equal([]interface{}{"Wildcard hosts are forbidden"}))
+
+	deploymentCtx := &fixtures.Context{Resources: []*resource.Instance{
+		{
+			Metadata: resource.Metadata{
+				Schema:   collections.K8SAppsV1Deployments.Resource(),
+				FullName: resource.NewFullName("default", "labeled"),
+				Labels:   map[string]string{"app": "reviews"},
+			},
+			Message: &appsv1.Deployment{},
+		},
+		{
+			Metadata: resource.Metadata{
+				Schema:   collections.K8SAppsV1Deployments.Resource(),
+				FullName: resource.NewFullName("default", "unlabeled"),
+			},
+			Message: &appsv1.Deployment{},
+		},
+	}}
+	analyzers[1].Analyze(deploymentCtx)
+	g.Expect(deploymentCtx.Reports).To(HaveLen(1))
+	g.Expect(deploymentCtx.Reports[0].Resource.Metadata.FullName.String()).To(Equal("default/unlabeled"))
+}
@@ -0,0 +1,239 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upgrade
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"istio.io/api/mesh/v1alpha1"
+	"istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/galley/pkg/config/schema/collection"
+	"istio.io/istio/galley/pkg/config/schema/collections"
+)
+
+// Analyzer checks for the Istio configuration and proxies which are incompatible with the Istio version
+// targeted by an upgrade. Unlike the other analyzers it depends on the target version, so it is not
+// part of analyzers.All().
+type Analyzer struct {
+	// TargetVersion is the Istio version targeted by the upgrade, e.g. 1.5 or 1.5.0.
+	TargetVersion string
+}
+
+var _ analysis.Analyzer = &Analyzer{}
+
+const istioProxyName = "istio-proxy"
+
+// change describes a feature which is deprecated and removed in some Istio versions.
+type change struct {
+	feature     string
+	deprecated  minorVersion
+	removed     minorVersion
+	remediation string
+}
+
+var (
+	authnChange = change{
+		feature:     "authentication.istio.io/v1alpha1",
+		deprecated:  minorVersion{1, 5},
+		removed:     minorVersion{1, 6},
+		remediation: "use PeerAuthentication and RequestAuthentication of security.istio.io/v1beta1",
+	}
+	rbacChange = change{
+		feature:     "rbac.istio.io/v1alpha1",
+		deprecated:  minorVersion{1, 4},
+		removed:     minorVersion{1, 6},
+		remediation: "use AuthorizationPolicy of security.istio.io/v1beta1",
+	}
+	mixerChange = change{
+		feature:     "Mixer configuration",
+		deprecated:  minorVersion{1, 5},
+		removed:     minorVersion{1, 8},
+		remediation: "use the in-proxy telemetry and Envoy extensions",
+	}
+	mixerMeshConfigChange = change{
+		feature:     "Mixer policy checks and reports in the mesh configuration",
+		deprecated:  minorVersion{1, 5},
+		removed:     minorVersion{1, 8},
+		remediation: "use the in-proxy telemetry and Envoy extensions",
+	}
+	envoyFilterV1Change = change{
+		feature:     "EnvoyFilter.filters",
+		deprecated:  minorVersion{1, 3},
+		removed:     minorVersion{1, 5},
+		remediation: "use EnvoyFilter.configPatches",
+	}
+	envoyFilterWorkloadLabelsChange = change{
+		feature:     "EnvoyFilter.workloadLabels",
+		deprecated:  minorVersion{1, 4},
+		removed:     minorVersion{1, 6},
+		remediation: "use EnvoyFilter.workloadSelector",
+	}
+
+	// typeChanges are the Istio types which are deprecated or removed.
+	typeChanges = map[collection.Name]change{
+		collections.IstioAuthenticationV1Alpha1Policies.Name():       authnChange,
+		collections.IstioAuthenticationV1Alpha1Meshpolicies.Name():   authnChange,
+		collections.IstioRbacV1Alpha1Serviceroles.Name():             rbacChange,
+		collections.IstioRbacV1Alpha1Servicerolebindings.Name():      rbacChange,
+		collections.IstioRbacV1Alpha1Rbacconfigs.Name():              rbacChange,
+		collections.IstioRbacV1Alpha1Clusterrbacconfigs.Name():       rbacChange,
+		collections.IstioPolicyV1Beta1Rules.Name():                   mixerChange,
+		collections.IstioPolicyV1Beta1Handlers.Name():                mixerChange,
+		collections.IstioPolicyV1Beta1Instances.Name():               mixerChange,
+		collections.IstioPolicyV1Beta1Attributemanifests.Name():      mixerChange,
+		collections.IstioConfigV1Alpha2Adapters.Name():               mixerChange,
+		collections.IstioConfigV1Alpha2Templates.Name():              mixerChange,
+		collections.IstioConfigV1Alpha2Httpapispecs.Name():           mixerChange,
+		collections.IstioConfigV1Alpha2Httpapispecbindings.Name():    mixerChange,
+		collections.IstioMixerV1ConfigClientQuotaspecs.Name():        mixerChange,
+		collections.IstioMixerV1ConfigClientQuotaspecbindings.Name(): mixerChange,
+	}
+)
+
+// Metadata implements analysis.Analyzer
+func (a *Analyzer) Metadata() analysis.Metadata {
+	inputs := collection.Names{
+		collections.IstioMeshV1Alpha1MeshConfig.Name(),
+		collections.IstioNetworkingV1Alpha3Envoyfilters.Name(),
+		collections.K8SCoreV1Pods.Name(),
+	}
+	for c := range typeChanges {
+		inputs = append(inputs, c)
+	}
+	return analysis.Metadata{
+		Name:        "upgrade.Analyzer",
+		Description: "Checks for Istio configuration and proxies incompatible with the target version of an upgrade",
+		Inputs:      inputs,
+	}
+}
+
+// Analyze implements analysis.Analyzer
+func (a *Analyzer) Analyze(ctx analysis.Context) {
+	target, err := parseMinorVersion(a.TargetVersion)
+	if err != nil {
+		// The target version is validated by the callers, there is nothing to compare with
+		return
+	}
+
+	for c, ch := range typeChanges {
+		c, ch := c, ch
+		ctx.ForEach(c, func(r *resource.Instance) bool {
+			a.report(ctx, c, r, target, ch)
+			return true
+		})
+	}
+
+	ctx.ForEach(collections.IstioMeshV1Alpha1MeshConfig.Name(), func(r *resource.Instance) bool {
+		mc := r.Message.(*v1alpha1.MeshConfig)
+		if mc.MixerCheckServer != "" || mc.MixerReportServer != "" {
+			a.report(ctx, collections.IstioMeshV1Alpha1MeshConfig.Name(), r, target, mixerMeshConfigChange)
+		}
+		return true
+	})
+
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Envoyfilters.Name(), func(r *resource.Instance) bool {
+		ef := r.Message.(*v1alpha3.EnvoyFilter)
+		if len(ef.Filters) > 0 {
+			a.report(ctx, collections.IstioNetworkingV1Alpha3Envoyfilters.Name(), r, target, envoyFilterV1Change)
+		}
+		if len(ef.WorkloadLabels) > 0 {
+			a.report(ctx, collections.IstioNetworkingV1Alpha3Envoyfilters.Name(), r, target, envoyFilterWorkloadLabelsChange)
+		}
+		return true
+	})
+
+	ctx.ForEach(collections.K8SCoreV1Pods.Name(), func(r *resource.Instance) bool {
+		pod := r.Message.(*v1.Pod)
+		for _, container := range pod.Spec.Containers {
+			if container.Name != istioProxyName {
+				continue
+			}
+			v, err := parseMinorVersion(imageTag(container.Image))
+			if err != nil {
+				// We can't check anything without a version
+				continue
+			}
+			if !target.supportsProxy(v) {
+				ctx.Report(collections.K8SCoreV1Pods.Name(),
+					msg.NewProxyVersionOutsideSupportedSkew(r, imageTag(container.Image), a.TargetVersion))
+			}
+		}
+		return true
+	})
+}
+
+func (a *Analyzer) report(ctx analysis.Context, c collection.Name, r *resource.Instance, target minorVersion, ch change) {
+	switch {
+	case !target.less(ch.removed):
+		ctx.Report(c, msg.NewRemovedInTargetVersion(r, ch.feature, a.TargetVersion, ch.remediation))
+	case !target.less(ch.deprecated):
+		ctx.Report(c, msg.NewDeprecatedInTargetVersion(r, ch.feature, a.TargetVersion, ch.remediation))
+	}
+}
+
+// minorVersion is the major and minor parts of an Istio version.
+type minorVersion struct {
+	major, minor int
+}
+
+var versionRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)`)
+
+// parseMinorVersion parses versions like 1.5, 1.5.0, v1.5.0-beta.1 or release-1.5-20200101.
+func parseMinorVersion(s string) (minorVersion, error) {
+	parts := versionRegexp.FindStringSubmatch(strings.TrimPrefix(s, "release-"))
+	if parts == nil {
+		return minorVersion{}, fmt.Errorf("could not parse %q as version", s)
+	}
+	major, _ := strconv.Atoi(parts[1])
+	minor, _ := strconv.Atoi(parts[2])
+	return minorVersion{major, minor}, nil
+}
+
+// ValidateVersion returns an error if the target version of an upgrade can't be parsed.
+func ValidateVersion(s string) error {
+	_, err := parseMinorVersion(s)
+	return err
+}
+
+func (v minorVersion) less(o minorVersion) bool {
+	if v.major != o.major {
+		return v.major < o.major
+	}
+	return v.minor < o.minor
+}
+
+// supportsProxy returns true if a control plane of this version supports a proxy of the given version,
+// which must be the same or one minor version older.
+func (v minorVersion) supportsProxy(p minorVersion) bool {
+	return p.major == v.major && p.minor <= v.minor && p.minor >= v.minor-1
+}
+
+// imageTag returns the tag of a container image, or the empty string if there is none.
+func imageTag(image string) string {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+	return image[i+1:]
+}
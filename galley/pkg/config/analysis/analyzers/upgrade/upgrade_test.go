@@ -0,0 +1,110 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upgrade
+
+import (
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/local"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/galley/pkg/config/schema"
+)
+
+type message struct {
+	messageType *diag.MessageType
+	origin      string
+}
+
+func TestAnalyzer(t *testing.T) {
+	cases := []struct {
+		targetVersion string
+		expected      []message
+	}{
+		{
+			targetVersion: "1.4.2",
+			expected: []message{
+				{msg.DeprecatedInTargetVersion, "ServiceRole service-viewer.default"},
+				{msg.DeprecatedInTargetVersion, "EnvoyFilter lua-filter.default"},
+			},
+		},
+		{
+			targetVersion: "1.5",
+			expected: []message{
+				{msg.DeprecatedInTargetVersion, "Policy default.default"},
+				{msg.DeprecatedInTargetVersion, "ServiceRole service-viewer.default"},
+				{msg.DeprecatedInTargetVersion, "rule deny-reviews.default"},
+				{msg.RemovedInTargetVersion, "EnvoyFilter lua-filter.default"},
+				{msg.ProxyVersionOutsideSupportedSkew, "Pod reviews-v1.default"},
+			},
+		},
+		{
+			targetVersion: "release-1.6-20200301",
+			expected: []message{
+				{msg.RemovedInTargetVersion, "Policy default.default"},
+				{msg.RemovedInTargetVersion, "ServiceRole service-viewer.default"},
+				{msg.DeprecatedInTargetVersion, "rule deny-reviews.default"},
+				{msg.RemovedInTargetVersion, "EnvoyFilter lua-filter.default"},
+				{msg.ProxyVersionOutsideSupportedSkew, "Pod details-v1.default"},
+				{msg.ProxyVersionOutsideSupportedSkew, "Pod reviews-v1.default"},
+			},
+		},
+		{
+			// Nothing can be compared with an invalid version
+			targetVersion: "latest",
+			expected:      []message{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.targetVersion, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			sa := local.NewSourceAnalyzer(schema.MustGet(), analysis.Combine("upgrade", &Analyzer{TargetVersion: c.targetVersion}),
+				"", "istio-system", nil, true, 10*time.Second)
+			g.Expect(sa.AddDefaultResources()).To(Succeed())
+			f, err := os.Open("testdata/upgrade.yaml")
+			g.Expect(err).To(BeNil())
+			defer f.Close() // nolint: errcheck
+			g.Expect(sa.AddReaderKubeSource([]io.Reader{f})).To(Succeed())
+
+			result, err := sa.Analyze(make(chan struct{}))
+			g.Expect(err).To(BeNil())
+
+			got := make([]message, 0)
+			for _, m := range result.Messages {
+				got = append(got, message{m.Type, m.Resource.Origin.FriendlyName()})
+			}
+			g.Expect(got).To(ConsistOf(c.expected))
+		})
+	}
+}
+
+func TestValidateVersion(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	for _, v := range []string{"1.5", "1.5.0", "v1.5.0-beta.1", "release-1.5-20200101"} {
+		g.Expect(ValidateVersion(v)).To(Succeed(), v)
+	}
+	for _, v := range []string{"", "latest", "1"} {
+		g.Expect(ValidateVersion(v)).NotTo(Succeed(), v)
+	}
+}
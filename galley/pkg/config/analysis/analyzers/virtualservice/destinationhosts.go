@@ -71,7 +71,7 @@ func (a *DestinationHostAnalyzer) analyzeVirtualService(r *resource.Instance, ct
 		s := getDestinationHost(r.Metadata.FullName.Namespace, d.GetHost(), serviceEntryHosts)
 		if s == nil {
 			ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
-				msg.NewReferencedResourceNotFound(r, "host", d.GetHost()))
+				msg.NewReferencedResourceNotFound(r, "host", d.GetHost()).WithField(d.field+".host"))
 			continue
 		}
 		checkServiceEntryPorts(ctx, r, d, s)
@@ -141,7 +141,7 @@ func initServiceEntryHostMap(ctx analysis.Context) map[util.ScopedFqdn]*v1alpha3
 	return result
 }
 
-func checkServiceEntryPorts(ctx analysis.Context, r *resource.Instance, d *v1alpha3.Destination, s *v1alpha3.ServiceEntry) {
+func checkServiceEntryPorts(ctx analysis.Context, r *resource.Instance, d routeDestination, s *v1alpha3.ServiceEntry) {
 	if d.GetPort() == nil {
 		// If destination port isn't specified, it's only a problem if the service being referenced exposes multiple ports.
 		if len(s.GetPorts()) > 1 {
@@ -150,7 +150,7 @@ func checkServiceEntryPorts(ctx analysis.Context, r *resource.Instance, d *v1alp
 				portNumbers = append(portNumbers, int(p.GetNumber()))
 			}
 			ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
-				msg.NewVirtualServiceDestinationPortSelectorRequired(r, d.GetHost(), portNumbers))
+				msg.NewVirtualServiceDestinationPortSelectorRequired(r, d.GetHost(), portNumbers).WithField(d.field))
 			return
 		}
 
@@ -167,6 +167,7 @@ func checkServiceEntryPorts(ctx analysis.Context, r *resource.Instance, d *v1alp
 	}
 	if !foundPort {
 		ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
-			msg.NewReferencedResourceNotFound(r, "host:port", fmt.Sprintf("%s:%d", d.GetHost(), d.GetPort().GetNumber())))
+			msg.NewReferencedResourceNotFound(r, "host:port", fmt.Sprintf("%s:%d", d.GetHost(), d.GetPort().GetNumber())).
+				WithField(d.field+".port"))
 	}
 }
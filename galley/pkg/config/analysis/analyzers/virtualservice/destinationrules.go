@@ -64,9 +64,10 @@ func (d *DestinationRuleAnalyzer) analyzeVirtualService(r *resource.Instance, ct
 	destinations := getRouteDestinations(vs)
 
 	for _, destination := range destinations {
-		if !d.checkDestinationSubset(ns, destination, destHostsAndSubsets) {
+		if !d.checkDestinationSubset(ns, destination.Destination, destHostsAndSubsets) {
 			ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
-				msg.NewReferencedResourceNotFound(r, "host+subset in destinationrule", fmt.Sprintf("%s+%s", destination.GetHost(), destination.GetSubset())))
+				msg.NewReferencedResourceNotFound(r, "host+subset in destinationrule", fmt.Sprintf("%s+%s", destination.GetHost(), destination.GetSubset())).
+					WithField(destination.field+".subset"))
 		}
 	}
 }
@@ -15,6 +15,8 @@
 package virtualservice
 
 import (
+	"fmt"
+
 	"istio.io/api/networking/v1alpha3"
 
 	"istio.io/istio/galley/pkg/config/analysis"
@@ -54,14 +56,15 @@ func (s *GatewayAnalyzer) analyzeVirtualService(r *resource.Instance, c analysis
 	vs := r.Message.(*v1alpha3.VirtualService)
 
 	vsNs := r.Metadata.FullName.Namespace
-	for _, gwName := range vs.Gateways {
+	for i, gwName := range vs.Gateways {
 		// This is a special-case accepted value
 		if gwName == util.MeshGateway {
 			continue
 		}
 
 		if !c.Exists(collections.IstioNetworkingV1Alpha3Gateways.Name(), resource.NewShortOrFullName(vsNs, gwName)) {
-			c.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), msg.NewReferencedResourceNotFound(r, "gateway", gwName))
+			c.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), msg.NewReferencedResourceNotFound(r, "gateway", gwName).
+				WithField(fmt.Sprintf("spec.gateways[%d]", i)))
 		}
 	}
 }
@@ -29,3 +29,18 @@ func (o testOrigin) FriendlyName() string {
 func (o testOrigin) Namespace() resource.Namespace {
 	return ""
 }
+
+var _ resource.FieldPositioner = testPositionedOrigin{}
+
+type testPositionedOrigin struct {
+	testOrigin
+	positions map[string]resource.Position
+}
+
+func (o testPositionedOrigin) FieldPosition(path string) (resource.Position, bool) {
+	p, ok := o.positions[path]
+	if !ok {
+		p, ok = o.positions[""]
+	}
+	return p, ok
+}
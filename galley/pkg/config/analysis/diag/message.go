@@ -17,6 +17,7 @@ package diag
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"istio.io/istio/galley/pkg/config/resource"
 )
@@ -58,6 +59,10 @@ type Message struct {
 
 	// DocRef is an optional reference tracker for the documentation URL
 	DocRef string
+
+	// Field is the path of the field of the resource the message is about, e.g.
+	// spec.http[1].route[0].destination.host, or empty if the message is about the whole resource.
+	Field string
 }
 
 // Unstructured returns this message as a JSON-style unstructured map
@@ -68,6 +73,12 @@ func (m *Message) Unstructured(includeOrigin bool) map[string]interface{} {
 	result["level"] = m.Type.Level().String()
 	if includeOrigin && m.Resource != nil {
 		result["origin"] = m.Resource.Origin.FriendlyName()
+		if p, ok := m.Position(); ok {
+			result["position"] = p.Location()
+		}
+		if m.Field != "" {
+			result["field"] = m.Field
+		}
 	}
 	result["message"] = fmt.Sprintf(m.Type.Template(), m.Parameters...)
 
@@ -84,12 +95,50 @@ func (m *Message) Unstructured(includeOrigin bool) map[string]interface{} {
 func (m *Message) String() string {
 	origin := ""
 	if m.Resource != nil {
-		origin = "(" + m.Resource.Origin.FriendlyName() + ")"
+		origin = m.Resource.Origin.FriendlyName()
+		if l := m.Location(); l != "" {
+			origin += " " + l
+		}
+		origin = "(" + origin + ")"
 	}
 	return fmt.Sprintf(
 		"%v [%v]%s %s", m.Type.Level(), m.Type.Code(), origin, fmt.Sprintf(m.Type.Template(), m.Parameters...))
 }
 
+// Position returns the position of the field of the message in the source of its resource, or of the resource if
+// the message has no field. It returns false if the position is not known.
+func (m *Message) Position() (resource.Position, bool) {
+	if m.Resource == nil {
+		return resource.Position{}, false
+	}
+	fp, ok := m.Resource.Origin.(resource.FieldPositioner)
+	if !ok {
+		return resource.Position{}, false
+	}
+	return fp.FieldPosition(m.Field)
+}
+
+// Location returns where the message points to in the source of its resource, e.g.
+// file.yaml:42 spec.http[1].route[0].destination.host, with the revision of the file if known, e.g.
+// 3f2a1b0c:file.yaml:42, or an empty string if unknown.
+func (m *Message) Location() string {
+	var parts []string
+	if p, ok := m.Position(); ok {
+		parts = append(parts, p.Location())
+	}
+	if m.Field != "" {
+		parts = append(parts, m.Field)
+	}
+	return strings.Join(parts, " ")
+}
+
+// WithField returns a copy of the message about the field at the path of its resource, e.g.
+// spec.http[1].route[0].destination.host.
+func (m Message) WithField(path string) Message {
+	m.Field = path
+	return m
+}
+
 // MarshalJSON satisfies the Marshaler interface
 func (m *Message) MarshalJSON() ([]byte, error) {
 	return json.Marshal(m.Unstructured(true))
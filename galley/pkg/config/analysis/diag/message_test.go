@@ -39,6 +39,48 @@ func TestMessageWithResource_String(t *testing.T) {
 	g.Expect(m.String()).To(Equal(`Error [IST-0042](toppings/cheese) Cheese type not found: "Feta"`))
 }
 
+func TestMessageWithField_String(t *testing.T) {
+	g := NewGomegaWithT(t)
+	mt := NewMessageType(Error, "IST-0042", "Cheese type not found: %q")
+	r := &resource.Instance{Origin: testPositionedOrigin{
+		testOrigin: "toppings/cheese",
+		positions: map[string]resource.Position{
+			"":             {Filename: "pizza.yaml", Line: 3, Column: 1},
+			"spec.cheeses": {Filename: "pizza.yaml", Line: 42, Column: 3},
+		},
+	}}
+
+	m := NewMessage(mt, r, "Feta")
+	g.Expect(m.String()).To(Equal(`Error [IST-0042](toppings/cheese pizza.yaml:3) Cheese type not found: "Feta"`))
+
+	m = NewMessage(mt, r, "Feta").WithField("spec.cheeses")
+	g.Expect(m.String()).To(Equal(`Error [IST-0042](toppings/cheese pizza.yaml:42 spec.cheeses) Cheese type not found: "Feta"`))
+	g.Expect(m.Unstructured(true)).To(HaveKeyWithValue("position", "pizza.yaml:42"))
+	g.Expect(m.Unstructured(true)).To(HaveKeyWithValue("field", "spec.cheeses"))
+	g.Expect(m.Unstructured(false)).To(Not(HaveKey("position")))
+
+	// Fields without a known position are at their resource.
+	m = NewMessage(mt, &resource.Instance{Origin: testOrigin("toppings/cheese")}, "Feta").WithField("spec.cheeses")
+	g.Expect(m.String()).To(Equal(`Error [IST-0042](toppings/cheese spec.cheeses) Cheese type not found: "Feta"`))
+	_, ok := m.Position()
+	g.Expect(ok).To(BeFalse())
+}
+
+func TestMessageWithRevision_String(t *testing.T) {
+	g := NewGomegaWithT(t)
+	mt := NewMessageType(Error, "IST-0042", "Cheese type not found: %q")
+	r := &resource.Instance{Origin: testPositionedOrigin{
+		testOrigin: "toppings/cheese",
+		positions: map[string]resource.Position{
+			"": {Filename: "pizza.yaml", Line: 3, Column: 1, Revision: "3f2a1b0c"},
+		},
+	}}
+
+	m := NewMessage(mt, r, "Feta")
+	g.Expect(m.String()).To(Equal(`Error [IST-0042](toppings/cheese 3f2a1b0c:pizza.yaml:3) Cheese type not found: "Feta"`))
+	g.Expect(m.Unstructured(true)).To(HaveKeyWithValue("position", "3f2a1b0c:pizza.yaml:3"))
+}
+
 func TestMessage_Unstructured(t *testing.T) {
 	g := NewGomegaWithT(t)
 	mt := NewMessageType(Error, "IST-0042", "Cheese type not found: %q")
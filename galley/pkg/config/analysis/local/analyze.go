@@ -232,9 +232,13 @@ func (sa *SourceAnalyzer) AddReaderKubeSource(readers []io.Reader) error {
 			continue
 		}
 
-		if err = src.ApplyContent(string(i), string(by)); err != nil {
+		// The content of files is named after them, for the positions of the resources in the messages.
+		name := fmt.Sprintf("reader-%d", i)
+		if n, ok := r.(interface{ Name() string }); ok {
+			name = n.Name()
+		}
+		if err = src.ApplyContent(name, string(by)); err != nil {
 			errs = multierror.Append(errs, err)
-			src.ApplyContent(string(i), string(by))
 		}
 	}
 
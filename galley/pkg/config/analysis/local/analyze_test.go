@@ -194,6 +194,13 @@ func TestAddReaderKubeSource(t *testing.T) {
 	g.Expect(sa.sources).To(HaveLen(1))
 	g.Expect(sa.sources[0].src).To(BeAssignableToTypeOf(&inmemory.KubeSource{})) // Resources via files
 
+	// The resources are positioned in their file
+	rs := sa.sources[0].src.(*inmemory.KubeSource).Get(basicmeta.K8SCollection1.Name()).AllSorted()
+	g.Expect(rs).To(HaveLen(1))
+	p, ok := rs[0].Origin.(resource.FieldPositioner).FieldPosition("spec")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(p).To(Equal(resource.Position{Filename: tmpfile.Name(), Line: 7, Column: 1}))
+
 	// Note that a blank file for mesh cfg is equivalent to specifying all the defaults
 	testRootNamespace := "testNamespace"
 	tmpMeshFile := tempFileFromString(t, fmt.Sprintf("rootNamespace: %s", testRootNamespace))
@@ -88,6 +88,18 @@ var (
 	// PortNameIsNotUnderNamingConvention defines a diag.MessageType for message "PortNameIsNotUnderNamingConvention".
 	// Description: Port name is not under naming convention. Protocol detection is applied to the port.
 	PortNameIsNotUnderNamingConvention = diag.NewMessageType(diag.Info, "IST0118", "Port name %s (port: %d, targetPort: %s) doesn't follow the naming convention of Istio port.")
+
+	// RemovedInTargetVersion defines a diag.MessageType for message "RemovedInTargetVersion".
+	// Description: A resource uses a type or field which is removed in the Istio version targeted by the upgrade.
+	RemovedInTargetVersion = diag.NewMessageType(diag.Error, "IST0119", "%s is removed in Istio %s; %s")
+
+	// DeprecatedInTargetVersion defines a diag.MessageType for message "DeprecatedInTargetVersion".
+	// Description: A resource uses a type or field which is deprecated in the Istio version targeted by the upgrade.
+	DeprecatedInTargetVersion = diag.NewMessageType(diag.Warning, "IST0120", "%s is deprecated in Istio %s; %s")
+
+	// ProxyVersionOutsideSupportedSkew defines a diag.MessageType for message "ProxyVersionOutsideSupportedSkew".
+	// Description: The version of the proxy of a pod is not supported by the Istio version targeted by the upgrade.
+	ProxyVersionOutsideSupportedSkew = diag.NewMessageType(diag.Error, "IST0121", "The proxy version %s is not supported by Istio %s. Proxies must be at most one minor version older than the control plane.")
 )
 
 // All returns a list of all known message types.
@@ -113,6 +125,9 @@ func All() []*diag.MessageType {
 		DeploymentAssociatedToMultipleServices,
 		DeploymentRequiresServiceAssociated,
 		PortNameIsNotUnderNamingConvention,
+		RemovedInTargetVersion,
+		DeprecatedInTargetVersion,
+		ProxyVersionOutsideSupportedSkew,
 	}
 }
 
@@ -314,3 +329,35 @@ func NewPortNameIsNotUnderNamingConvention(r *resource.Instance, portName string
 		targetPort,
 	)
 }
+
+// NewRemovedInTargetVersion returns a new diag.Message based on RemovedInTargetVersion.
+func NewRemovedInTargetVersion(r *resource.Instance, feature string, targetVersion string, remediation string) diag.Message {
+	return diag.NewMessage(
+		RemovedInTargetVersion,
+		r,
+		feature,
+		targetVersion,
+		remediation,
+	)
+}
+
+// NewDeprecatedInTargetVersion returns a new diag.Message based on DeprecatedInTargetVersion.
+func NewDeprecatedInTargetVersion(r *resource.Instance, feature string, targetVersion string, remediation string) diag.Message {
+	return diag.NewMessage(
+		DeprecatedInTargetVersion,
+		r,
+		feature,
+		targetVersion,
+		remediation,
+	)
+}
+
+// NewProxyVersionOutsideSupportedSkew returns a new diag.Message based on ProxyVersionOutsideSupportedSkew.
+func NewProxyVersionOutsideSupportedSkew(r *resource.Instance, proxyVersion string, targetVersion string) diag.Message {
+	return diag.NewMessage(
+		ProxyVersionOutsideSupportedSkew,
+		r,
+		proxyVersion,
+		targetVersion,
+	)
+}
@@ -85,6 +85,10 @@ var (
 		"galley/runtime/processor/snapshot_lifetime_duration_milliseconds",
 		"The duration of each snapshot",
 		stats.UnitMilliseconds)
+	snapshotPublishDelaysMs = stats.Int64(
+		"galley/runtime/processor/snapshot_publish_delay_milliseconds",
+		"The duration between the first change of a collection and the publishing of a snapshot with it",
+		stats.UnitMilliseconds)
 	stateTypeInstancesTotal = stats.Int64(
 		"galley/runtime/state/type_instances_total",
 		"The number of type instances per type URL",
@@ -128,6 +132,16 @@ func RecordProcessorSnapshotPublished(events int64, snapshotSpan time.Duration)
 		processorSnapshotLifetimesMs.M(snapshotSpan.Nanoseconds()/1e6))
 }
 
+// RecordSnapshotPublishDelay event
+func RecordSnapshotPublishDelay(collection string, delay time.Duration) {
+	ctx, err := tag.New(context.Background(), tag.Insert(CollectionTag, collection))
+	if err != nil {
+		scope.Processing.Errorf("Error creating monitoring context for recording the publish delay: %v", err)
+		return
+	}
+	stats.Record(ctx, snapshotPublishDelaysMs.M(delay.Nanoseconds()/1e6))
+}
+
 // RecordStateTypeCount event
 func RecordStateTypeCount(collection string, count int) {
 	ctx, err := tag.New(context.Background(), tag.Insert(CollectionTag, collection))
@@ -238,6 +252,7 @@ func init() {
 		newView(processorSnapshotsPublished, noKeys, view.Count()),
 		newView(processorEventsPerSnapshot, noKeys, view.Distribution(0, 1, 2, 4, 8, 16, 32, 64, 128, 256)),
 		newView(processorSnapshotLifetimesMs, noKeys, durationDistributionMs),
+		newView(snapshotPublishDelaysMs, collectionKeys, durationDistributionMs),
 		newView(stateTypeInstancesTotal, collectionKeys, view.LastValue()),
 	)
 
@@ -15,6 +15,8 @@
 package snapshotter
 
 import (
+	"time"
+
 	"istio.io/istio/galley/pkg/config/processing/snapshotter/strategy"
 	"istio.io/istio/galley/pkg/config/schema/collection"
 )
@@ -31,4 +33,12 @@ type SnapshotOptions struct {
 
 	// The set of collections to Snapshot.
 	Collections []collection.Name
+
+	// The publishing strategies of the collections which are not published with Strategy, e.g. to publish the
+	// changes of endpoints immediately while debouncing the other collections. A change of such a collection is
+	// published with the last published state of the others: their pending changes wait for their own strategy.
+	CollectionStrategies map[collection.Name]strategy.Instance
+
+	// The maximum duration a change may stay unpublished, whatever the strategies, or 0 if unbounded.
+	MaxStaleness time.Duration
 }
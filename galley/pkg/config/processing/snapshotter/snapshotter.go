@@ -16,6 +16,7 @@ package snapshotter
 
 import (
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -71,6 +72,23 @@ type snapshotGroup struct {
 	synced atomic.Value
 	// Strategy to execute on handled events only after all collections in the group have been synced.
 	strategy strategy.Instance
+	// Strategies to execute instead of strategy on the handled events of their collections.
+	collectionStrategies map[collection.Name]strategy.Instance
+	// Maximum duration a change may stay unpublished, or 0 if unbounded.
+	maxStaleness time.Duration
+	// Collections of the group, in the order of the SnapshotOptions.
+	collections []*coll.Instance
+	// Publishes the pending changes of the group when maxStaleness is reached. Set on Start.
+	flush func()
+
+	// mu protects the fields below, and serializes the publishing of the snapshots of the group.
+	mu sync.Mutex
+	// Clones of the collections in the last published snapshot, or to be published with the next.
+	staged map[collection.Name]*coll.Instance
+	// Time of the first unpublished change of each collection.
+	pendingSince map[collection.Name]time.Time
+	// Timer flushing the pending changes when the oldest one reaches maxStaleness.
+	stalenessTimer *time.Timer
 }
 
 // Handle implements event.Handler
@@ -153,6 +171,8 @@ func NewSnapshotter(xforms []event.Transformer, settings []SnapshotOptions) (*Sn
 
 	for _, o := range settings {
 		sg := newSnapshotGroup(len(o.Collections), o.Strategy)
+		sg.collectionStrategies = o.CollectionStrategies
+		sg.maxStaleness = o.MaxStaleness
 		s.snapshotGroups = append(s.snapshotGroups, sg)
 		for _, c := range o.Collections {
 			a := s.accumulators[c]
@@ -161,6 +181,12 @@ func NewSnapshotter(xforms []event.Transformer, settings []SnapshotOptions) (*Sn
 			}
 
 			a.snapshotGroups = append(a.snapshotGroups, sg)
+			sg.collections = append(sg.collections, a.collection)
+		}
+		for c := range o.CollectionStrategies {
+			if s.accumulators[c] == nil || !containsCollection(o.Collections, c) {
+				return nil, fmt.Errorf("strategy of a collection not in SnapshotOptions: %v (Group: %s)", c, o.Group)
+			}
 		}
 	}
 
@@ -185,14 +211,110 @@ func (sg *snapshotGroup) onSync(c *coll.Instance) {
 
 	// proceed with triggering the strategy OnChange only after we've full synced every collection in a group.
 	if atomic.LoadInt32(&sg.remaining) <= 0 {
+		sg.markPending(c.Name())
+		if cs, ok := sg.collectionStrategies[c.Name()]; ok {
+			scope.Processing.Debugf("sg.onSync: all collections synced, proceeding with %v strategy.OnChange()", c.Name())
+			cs.OnChange()
+			return
+		}
 		scope.Processing.Debugf("sg.onSync: all collections synced, proceeding with strategy.OnChange()")
 		sg.strategy.OnChange()
 	}
 }
 
+// markPending records the first unpublished change of the collection, and schedules the flush of the pending
+// changes if their staleness is bounded.
+func (sg *snapshotGroup) markPending(n collection.Name) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	if _, ok := sg.pendingSince[n]; ok {
+		return
+	}
+	sg.pendingSince[n] = time.Now()
+	if sg.maxStaleness > 0 && sg.stalenessTimer == nil && sg.flush != nil {
+		sg.stalenessTimer = time.AfterFunc(sg.maxStaleness, sg.flush)
+	}
+}
+
+// stage clones the named collections for publishing, and returns the set of the staged collections of the group.
+// All the collections are staged for the first snapshot of the group. If names is nil, the collections with
+// pending changes are staged.
+func (sg *snapshotGroup) stage(names []collection.Name) *coll.Set {
+	now := time.Now()
+	if names == nil {
+		for n := range sg.pendingSince {
+			names = append(names, n)
+		}
+	}
+	toStage := make(map[collection.Name]bool, len(names))
+	for _, n := range names {
+		toStage[n] = true
+	}
+
+	collections := make([]*coll.Instance, 0, len(sg.collections))
+	for _, c := range sg.collections {
+		n := c.Name()
+		if toStage[n] || sg.staged[n] == nil {
+			sg.staged[n] = c.Clone()
+			if since, ok := sg.pendingSince[n]; ok {
+				monitoring.RecordSnapshotPublishDelay(n.String(), now.Sub(since))
+				delete(sg.pendingSince, n)
+			}
+		}
+		collections = append(collections, sg.staged[n])
+	}
+
+	// Reschedule the flush for the oldest change still pending, e.g. of a collection with its own strategy.
+	if sg.stalenessTimer != nil {
+		sg.stalenessTimer.Stop()
+		sg.stalenessTimer = nil
+	}
+	if sg.maxStaleness > 0 && len(sg.pendingSince) > 0 && sg.flush != nil {
+		oldest := now
+		for _, since := range sg.pendingSince {
+			if since.Before(oldest) {
+				oldest = since
+			}
+		}
+		sg.stalenessTimer = time.AfterFunc(sg.maxStaleness-now.Sub(oldest), sg.flush)
+	}
+
+	return coll.NewSetFromCollections(collections)
+}
+
+// groupStrategyCollections returns the collections of the group published with the group strategy.
+func (sg *snapshotGroup) groupStrategyCollections() []collection.Name {
+	names := make([]collection.Name, 0, len(sg.collections))
+	for _, c := range sg.collections {
+		if _, ok := sg.collectionStrategies[c.Name()]; !ok {
+			names = append(names, c.Name())
+		}
+	}
+	return names
+}
+
 func (sg *snapshotGroup) reset(size int) {
 	atomic.StoreInt32(&sg.remaining, int32(size))
 	sg.synced.Store(make(map[*coll.Instance]bool))
+
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	if sg.stalenessTimer != nil {
+		sg.stalenessTimer.Stop()
+		sg.stalenessTimer = nil
+	}
+	sg.staged = make(map[collection.Name]*coll.Instance)
+	sg.pendingSince = make(map[collection.Name]time.Time)
+}
+
+func containsCollection(names []collection.Name, n collection.Name) bool {
+	for _, c := range names {
+		if c == n {
+			return true
+		}
+	}
+	return false
 }
 
 // Start implements Processor
@@ -201,24 +323,39 @@ func (s *Snapshotter) Start() {
 		x.Start()
 	}
 
-	for _, o := range s.settings {
-		// Capture the iteration variable in a local
+	for i, o := range s.settings {
+		// Capture the iteration variables in locals
 		opt := o
+		sg := s.snapshotGroups[i]
+		sg.mu.Lock()
+		sg.flush = func() {
+			s.publish(opt, sg, nil)
+		}
+		sg.mu.Unlock()
+		groupCollections := sg.groupStrategyCollections()
 		o.Strategy.Start(func() {
-			s.publish(opt)
+			s.publish(opt, sg, groupCollections)
 		})
+		for n, cs := range o.CollectionStrategies {
+			names := []collection.Name{n}
+			cs.Start(func() {
+				s.publish(opt, sg, names)
+			})
+		}
 	}
 }
 
-func (s *Snapshotter) publish(o SnapshotOptions) {
-	var collections []*coll.Instance
+// publish distributes a snapshot of the group with the current state of the named collections, and the last
+// published state of the others.
+func (s *Snapshotter) publish(o SnapshotOptions, sg *snapshotGroup, names []collection.Name) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
 
-	for _, n := range o.Collections {
-		col := s.accumulators[n].collection.Clone()
-		collections = append(collections, col)
+	if names == nil && len(sg.pendingSince) == 0 {
+		// The pending changes were published since the flush was scheduled.
+		return
 	}
-
-	set := coll.NewSetFromCollections(collections)
+	set := sg.stage(names)
 	sn := &Snapshot{set: set}
 
 	s.markSnapshotTime()
@@ -232,6 +369,9 @@ func (s *Snapshotter) publish(o SnapshotOptions) {
 func (s *Snapshotter) Stop() {
 	for _, o := range s.settings {
 		o.Strategy.Stop()
+		for _, cs := range o.CollectionStrategies {
+			cs.Stop()
+		}
 	}
 
 	for _, x := range s.xforms {
@@ -16,6 +16,7 @@ package snapshotter
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 
@@ -153,3 +154,119 @@ func TestSnapshotterWaitForAllSync(t *testing.T) {
 	sn = d.GetSnapshot("default")
 	g.Expect(sn).NotTo(BeNil())
 }
+
+func TestSnapshotter_CollectionStrategies(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tr := fixtures.NewTransformer(
+		collection.NewSchemasBuilder().MustAdd(basicmeta.K8SCollection1).MustAdd(basicmeta.Collection2).Build(),
+		collection.NewSchemasBuilder().MustAdd(basicmeta.K8SCollection1).MustAdd(basicmeta.Collection2).Build(),
+		func(tr *fixtures.Transformer, e event.Event) {
+			tr.Publish(e.Source.Name(), e)
+		})
+
+	d := NewInMemoryDistributor()
+
+	options := []SnapshotOptions{
+		{
+			Collections: []collection.Name{basicmeta.K8SCollection1.Name(), basicmeta.Collection2.Name()},
+			Strategy:    strategy.NewDebounce(time.Hour, time.Hour),
+			CollectionStrategies: map[collection.Name]strategy.Instance{
+				basicmeta.Collection2.Name(): strategy.NewImmediate(),
+			},
+			Group:       "default",
+			Distributor: d,
+		},
+	}
+
+	s, err := NewSnapshotter([]event.Transformer{tr}, options)
+	g.Expect(err).To(BeNil())
+	s.Start()
+	defer s.Stop()
+
+	s.Handle(data.Event1Col1Synced)
+	s.Handle(data.Event1Col2Synced)
+
+	// The first snapshot has all the collections, whatever the strategy triggering it.
+	sn := d.GetSnapshot("default")
+	g.Expect(sn).NotTo(BeNil())
+	g.Expect(sn.Resources(basicmeta.K8SCollection1.Name().String())).To(HaveLen(0))
+	g.Expect(sn.Resources(basicmeta.Collection2.Name().String())).To(HaveLen(0))
+
+	// The change of the debounced collection is not published yet.
+	s.Handle(data.Event1Col1AddItem1)
+	g.Expect(d.GetSnapshot("default")).To(Equal(sn))
+
+	// The change of the immediate collection is published, with the published state of the debounced one.
+	s.Handle(data.Event3Col2AddItem1)
+	sn = d.GetSnapshot("default")
+	g.Expect(sn.Resources(basicmeta.K8SCollection1.Name().String())).To(HaveLen(0))
+	g.Expect(sn.Resources(basicmeta.Collection2.Name().String())).To(HaveLen(1))
+}
+
+func TestSnapshotter_CollectionStrategyNotInSnapshot(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tr := fixtures.NewTransformer(
+		collection.NewSchemasBuilder().MustAdd(basicmeta.K8SCollection1).MustAdd(basicmeta.Collection2).Build(),
+		collection.NewSchemasBuilder().MustAdd(basicmeta.K8SCollection1).MustAdd(basicmeta.Collection2).Build(),
+		func(tr *fixtures.Transformer, e event.Event) {
+			tr.Publish(e.Source.Name(), e)
+		})
+
+	options := []SnapshotOptions{
+		{
+			Collections: []collection.Name{basicmeta.K8SCollection1.Name()},
+			Strategy:    strategy.NewImmediate(),
+			CollectionStrategies: map[collection.Name]strategy.Instance{
+				basicmeta.Collection2.Name(): strategy.NewImmediate(),
+			},
+			Group:       "default",
+			Distributor: NewInMemoryDistributor(),
+		},
+	}
+
+	_, err := NewSnapshotter([]event.Transformer{tr}, options)
+	g.Expect(err).NotTo(BeNil())
+}
+
+func TestSnapshotter_MaxStaleness(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tr := fixtures.NewTransformer(
+		collection.NewSchemasBuilder().MustAdd(basicmeta.K8SCollection1).MustAdd(basicmeta.Collection2).Build(),
+		collection.NewSchemasBuilder().MustAdd(basicmeta.K8SCollection1).MustAdd(basicmeta.Collection2).Build(),
+		func(tr *fixtures.Transformer, e event.Event) {
+			tr.Publish(e.Source.Name(), e)
+		})
+
+	d := NewInMemoryDistributor()
+
+	options := []SnapshotOptions{
+		{
+			Collections: []collection.Name{basicmeta.K8SCollection1.Name(), basicmeta.Collection2.Name()},
+			Strategy:    strategy.NewDebounce(time.Hour, time.Hour),
+			CollectionStrategies: map[collection.Name]strategy.Instance{
+				basicmeta.Collection2.Name(): strategy.NewImmediate(),
+			},
+			MaxStaleness: time.Millisecond * 100,
+			Group:        "default",
+			Distributor:  d,
+		},
+	}
+
+	s, err := NewSnapshotter([]event.Transformer{tr}, options)
+	g.Expect(err).To(BeNil())
+	s.Start()
+	defer s.Stop()
+
+	s.Handle(data.Event1Col1Synced)
+	s.Handle(data.Event1Col2Synced)
+	g.Expect(d.GetSnapshot("default")).NotTo(BeNil())
+
+	// The debounced change is flushed when it reaches the max staleness.
+	s.Handle(data.Event1Col1AddItem1)
+	g.Eventually(func() int {
+		return len(d.GetSnapshot("default").Resources(basicmeta.K8SCollection1.Name().String()))
+	}).Should(Equal(1))
+}
@@ -14,21 +14,56 @@
 
 package strategy
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 const (
 	debounce  = "debounce"
 	immediate = "immediate"
+	rateLimit = "ratelimit"
 )
 
-// Create a strategy with the given name.
-func Create(name string) (Instance, error) {
-	switch name {
-	case debounce:
+// Create returns a new strategy instance for the given spec. A spec is a strategy name, optionally followed by
+// colon separated durations:
+//
+//	immediate
+//	debounce[:<quiesce duration>[:<max wait duration>]]
+//	ratelimit:<minimum interval>
+func Create(spec string) (Instance, error) {
+	parts := strings.Split(spec, ":")
+	durations := make([]time.Duration, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		d, err := time.ParseDuration(p)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid duration %q in strategy: %q", p, spec)
+		}
+		durations = append(durations, d)
+	}
+
+	switch name := parts[0]; {
+	case name == debounce && len(durations) == 0:
 		return NewDebounceWithDefaults(), nil
-	case immediate:
+	case name == debounce && len(durations) == 1:
+		maxWait := defaultMaxWaitDuration
+		if maxWait < 2*durations[0] {
+			maxWait = 2 * durations[0]
+		}
+		return NewDebounce(maxWait, durations[0]), nil
+	case name == debounce && len(durations) == 2:
+		if durations[1] < durations[0] {
+			return nil, fmt.Errorf("max wait duration is shorter than the quiesce duration in strategy: %q", spec)
+		}
+		return NewDebounce(durations[1], durations[0]), nil
+	case name == immediate && len(durations) == 0:
 		return NewImmediate(), nil
+	case name == rateLimit && len(durations) == 1:
+		return NewRateLimit(durations[0]), nil
+	case name == debounce || name == immediate || name == rateLimit:
+		return nil, fmt.Errorf("invalid number of durations in strategy: %q", spec)
 	default:
-		return nil, fmt.Errorf("unknown strategy: %q", name)
+		return nil, fmt.Errorf("unknown strategy: %q", spec)
 	}
 }
@@ -17,6 +17,7 @@ package strategy
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 )
@@ -39,6 +40,48 @@ func TestCreate_Debounce(t *testing.T) {
 	g.Expect(reflect.TypeOf(s)).To(Equal(reflect.TypeOf(&Debounce{})))
 }
 
+func TestCreate_DebounceDurations(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, err := Create("debounce:2s")
+	g.Expect(err).To(BeNil())
+	d := s.(*Debounce)
+	g.Expect(d.quiesceDuration).To(Equal(2 * time.Second))
+	g.Expect(d.maxWaitDuration).To(Equal(4 * time.Second))
+
+	s, err = Create("debounce:100ms:3s")
+	g.Expect(err).To(BeNil())
+	d = s.(*Debounce)
+	g.Expect(d.quiesceDuration).To(Equal(100 * time.Millisecond))
+	g.Expect(d.maxWaitDuration).To(Equal(3 * time.Second))
+}
+
+func TestCreate_RateLimit(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, err := Create("ratelimit:10s")
+	g.Expect(err).To(BeNil())
+	g.Expect(s.(*RateLimit).interval).To(Equal(10 * time.Second))
+}
+
+func TestCreate_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"immediate:1s",
+		"debounce:1s:2s:3s",
+		"debounce:2s:1s",
+		"debounce:foo",
+		"debounce:-1s",
+		"ratelimit",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			_, err := Create(spec)
+			g.Expect(err).NotTo(BeNil())
+		})
+	}
+}
+
 func TestCreate_Unknown(t *testing.T) {
 	g := NewGomegaWithT(t)
 
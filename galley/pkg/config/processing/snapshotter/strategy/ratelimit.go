@@ -0,0 +1,123 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package strategy
+
+import (
+	"sync"
+	"time"
+
+	"istio.io/istio/galley/pkg/config/monitoring"
+)
+
+// RateLimit is a strategy publishing snapshots at most once per interval. A change after a quiet interval is
+// published immediately, and the changes within an interval are published together at its end.
+type RateLimit struct {
+	mu sync.Mutex
+
+	interval time.Duration
+
+	changeCh chan struct{}
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+var _ Instance = &RateLimit{}
+
+// NewRateLimit returns a new RateLimit strategy publishing at most once per interval.
+func NewRateLimit(interval time.Duration) *RateLimit {
+	return &RateLimit{
+		interval: interval,
+		changeCh: make(chan struct{}, 1),
+	}
+}
+
+// Start implements Instance
+func (r *RateLimit) Start(fn OnSnapshotFn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stopCh != nil {
+		scope.Debug("RateLimit.Start: already started")
+		return
+	}
+	r.stopCh = make(chan struct{})
+	r.doneCh = make(chan struct{})
+
+	// Drain the changeCh, to avoid events from a previous incarnation.
+	drainCh(r.changeCh)
+
+	go r.run(r.stopCh, r.doneCh, fn)
+}
+
+// Stop implements Instance
+func (r *RateLimit) Stop() {
+	r.mu.Lock()
+
+	if r.stopCh != nil {
+		scope.Debug("RateLimit.Stop: stopping")
+		close(r.stopCh)
+		r.stopCh = nil
+	} else {
+		scope.Debug("RateLimit.Stop: already stopped")
+	}
+	r.mu.Unlock()
+
+	<-r.doneCh
+}
+
+func (r *RateLimit) run(stopCh, doneCh chan struct{}, fn OnSnapshotFn) {
+	var last time.Time
+
+mainloop:
+	for {
+		select {
+		case <-stopCh:
+			scope.Debug("RateLimit.run: stopping")
+			break mainloop
+
+		case <-r.changeCh:
+			scope.Debug("RateLimit.run: change")
+			monitoring.RecordStrategyOnChange()
+		}
+
+		if wait := r.interval - time.Since(last); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-stopCh:
+				timer.Stop()
+				scope.Debug("RateLimit.run: stopping")
+				break mainloop
+			case <-timer.C:
+				monitoring.RecordOnTimer(true, false, false)
+			}
+		}
+
+		// The changes received while waiting are published with this snapshot.
+		drainCh(r.changeCh)
+		last = time.Now()
+		scope.Debug("RateLimit.run: calling callback...")
+		fn()
+	}
+
+	close(doneCh)
+}
+
+// OnChange implements Instance
+func (r *RateLimit) OnChange() {
+	select {
+	case r.changeCh <- struct{}{}:
+	default:
+	}
+}
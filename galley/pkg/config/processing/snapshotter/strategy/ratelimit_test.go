@@ -0,0 +1,98 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package strategy
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRateLimit_StartStop(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := NewRateLimit(time.Minute)
+
+	var called int32
+	s.Start(func() {
+		atomic.StoreInt32(&called, 1)
+	})
+	s.Stop()
+	s.Stop()
+
+	s.Start(func() {
+		atomic.StoreInt32(&called, 1)
+	})
+	s.Stop()
+
+	g.Expect(atomic.LoadInt32(&called)).To(Equal(int32(0)))
+}
+
+func TestRateLimit_FirstChangeIsImmediate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := NewRateLimit(time.Hour)
+
+	var called int32
+	s.Start(func() {
+		atomic.AddInt32(&called, 1)
+	})
+	defer s.Stop()
+
+	s.OnChange()
+	g.Eventually(func() int32 { return atomic.LoadInt32(&called) }).Should(Equal(int32(1)))
+}
+
+func TestRateLimit_ChangesWithinIntervalAreCoalesced(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := NewRateLimit(time.Millisecond * 200)
+
+	var called int32
+	s.Start(func() {
+		atomic.AddInt32(&called, 1)
+	})
+	defer s.Stop()
+
+	s.OnChange()
+	g.Eventually(func() int32 { return atomic.LoadInt32(&called) }).Should(Equal(int32(1)))
+
+	for i := 0; i < 10; i++ {
+		s.OnChange()
+	}
+	g.Consistently(func() int32 { return atomic.LoadInt32(&called) }, time.Millisecond*100).Should(Equal(int32(1)))
+	g.Eventually(func() int32 { return atomic.LoadInt32(&called) }).Should(Equal(int32(2)))
+	g.Consistently(func() int32 { return atomic.LoadInt32(&called) }, time.Millisecond*300).Should(Equal(int32(2)))
+}
+
+func TestRateLimit_StopWhileWaiting(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := NewRateLimit(time.Hour)
+
+	var called int32
+	s.Start(func() {
+		atomic.AddInt32(&called, 1)
+	})
+
+	s.OnChange()
+	g.Eventually(func() int32 { return atomic.LoadInt32(&called) }).Should(Equal(int32(1)))
+	s.OnChange()
+	s.Stop()
+
+	g.Expect(atomic.LoadInt32(&called)).To(Equal(int32(1)))
+}
@@ -15,12 +15,17 @@
 package processor
 
 import (
+	"fmt"
+	"sort"
+	"time"
+
 	"istio.io/istio/galley/pkg/config/event"
 	"istio.io/istio/galley/pkg/config/processing"
 	"istio.io/istio/galley/pkg/config/processing/snapshotter"
 	"istio.io/istio/galley/pkg/config/processing/snapshotter/strategy"
 	"istio.io/istio/galley/pkg/config/processing/transformer"
 	"istio.io/istio/galley/pkg/config/schema"
+	"istio.io/istio/galley/pkg/config/schema/collection"
 )
 
 // Settings is the settings that are needed for creating a config processing pipeline that can read
@@ -32,11 +37,17 @@ type Settings struct {
 	TransformProviders transformer.Providers
 	Distributor        snapshotter.Distributor
 	EnabledSnapshots   []string
+	// CollectionStrategies are the publishing strategies of the collections published with a strategy other than
+	// the one of their snapshot, by collection name. See strategy.Create for the format of the strategies.
+	CollectionStrategies map[collection.Name]string
+	// SnapshotMaxStaleness is the maximum duration a change may stay unpublished, or 0 if unbounded.
+	SnapshotMaxStaleness time.Duration
 }
 
 // Initialize a processing runtime for Galley.
 func Initialize(settings Settings) (*processing.Runtime, error) {
 	var options []snapshotter.SnapshotOptions
+	configured := make(map[collection.Name]bool, len(settings.CollectionStrategies))
 	for _, s := range settings.Metadata.AllSnapshots() {
 		if !isEnabled(s.Name, settings.EnabledSnapshots) {
 			continue
@@ -48,14 +59,43 @@ func Initialize(settings Settings) (*processing.Runtime, error) {
 		}
 
 		opt := snapshotter.SnapshotOptions{
-			Group:       s.Name,
-			Distributor: settings.Distributor,
-			Collections: s.Collections,
-			Strategy:    str,
+			Group:        s.Name,
+			Distributor:  settings.Distributor,
+			Collections:  s.Collections,
+			Strategy:     str,
+			MaxStaleness: settings.SnapshotMaxStaleness,
+		}
+
+		// Each snapshot gets its own instances, as they are started with the publishing of the snapshot.
+		for _, c := range s.Collections {
+			spec, ok := settings.CollectionStrategies[c]
+			if !ok {
+				continue
+			}
+			cs, err := strategy.Create(spec)
+			if err != nil {
+				return nil, fmt.Errorf("invalid strategy of collection %v: %v", c, err)
+			}
+			if opt.CollectionStrategies == nil {
+				opt.CollectionStrategies = make(map[collection.Name]strategy.Instance)
+			}
+			opt.CollectionStrategies[c] = cs
+			configured[c] = true
 		}
 		options = append(options, opt)
 	}
 
+	var unknown []string
+	for c := range settings.CollectionStrategies {
+		if !configured[c] {
+			unknown = append(unknown, c.String())
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("strategies of collections not in any enabled snapshot: %v", unknown)
+	}
+
 	// TODO: Add a precondition test here to ensure the panic below will not fire during runtime.
 
 	// This is passed as a provider so it can be evaluated once ProcessorOptions become available
@@ -25,6 +25,8 @@ import (
 	"istio.io/istio/galley/pkg/config/processing/snapshotter"
 	"istio.io/istio/galley/pkg/config/processor/transforms"
 	"istio.io/istio/galley/pkg/config/schema"
+	"istio.io/istio/galley/pkg/config/schema/collection"
+	"istio.io/istio/galley/pkg/config/schema/collections"
 	"istio.io/istio/galley/pkg/config/schema/snapshots"
 	"istio.io/istio/galley/pkg/config/source/kube/inmemory"
 )
@@ -81,3 +83,35 @@ func TestProcessor(t *testing.T) {
 	time.Sleep(time.Second)
 	_ = distributor.GetSnapshot("default")
 }
+
+func TestProcessor_CollectionStrategies(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	processorSettings := Settings{
+		Metadata:           schema.MustGet(),
+		DomainSuffix:       "svc.local",
+		Source:             inmemory.NewKubeSource(schema.MustGet().KubeCollections()),
+		TransformProviders: transforms.Providers(schema.MustGet()),
+		Distributor:        snapshotter.NewInMemoryDistributor(),
+		EnabledSnapshots:   []string{snapshots.Default},
+		CollectionStrategies: map[collection.Name]string{
+			collections.IstioNetworkingV1Alpha3Virtualservices.Name(): "debounce:1s",
+		},
+		SnapshotMaxStaleness: time.Second * 5,
+	}
+
+	_, err := Initialize(processorSettings)
+	g.Expect(err).To(BeNil())
+
+	processorSettings.CollectionStrategies = map[collection.Name]string{
+		collections.IstioNetworkingV1Alpha3Virtualservices.Name(): "foo",
+	}
+	_, err = Initialize(processorSettings)
+	g.Expect(err).NotTo(BeNil())
+
+	processorSettings.CollectionStrategies = map[collection.Name]string{
+		collection.NewName("istio/unknown"): "immediate",
+	}
+	_, err = Initialize(processorSettings)
+	g.Expect(err).NotTo(BeNil())
+}
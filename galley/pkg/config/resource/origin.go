@@ -14,9 +14,49 @@
 
 package resource
 
+import (
+	"fmt"
+)
+
 // Origin of a resource. This is source-implementation dependent.
 type Origin interface {
 	FriendlyName() string
 
 	Namespace() Namespace
 }
+
+// Position is a position in the source of a resource, e.g. a file.
+type Position struct {
+	Filename string
+	// Line and Column start at 1. Column is 0 if unknown.
+	Line   int
+	Column int
+	// Revision is the revision of the file, e.g. the commit it was read from, or empty if unknown.
+	Revision string
+}
+
+// String implements fmt.Stringer, in the [revision:]file:line[:column] format.
+func (p Position) String() string {
+	if p.Column == 0 {
+		return p.Location()
+	}
+	return fmt.Sprintf("%s:%d", p.Location(), p.Column)
+}
+
+// Location returns the file and line of the position in the [revision:]file:line format, the revision prefix
+// following the notation of git, e.g. 3f2a1b0c:samples/gateway.yaml:12.
+func (p Position) Location() string {
+	if p.Revision == "" {
+		return fmt.Sprintf("%s:%d", p.Filename, p.Line)
+	}
+	return fmt.Sprintf("%s:%s:%d", p.Revision, p.Filename, p.Line)
+}
+
+// FieldPositioner is implemented by the Origins knowing the positions of the fields of their resources, e.g. of the
+// resources read from YAML files.
+type FieldPositioner interface {
+	// FieldPosition returns the position of the field at the path, e.g. spec.http[1].route[0].destination.host, or
+	// of the resource if the path is empty. If the field is not known, e.g. it is not set, the position of its
+	// closest known parent is returned. It returns false if the position of the resource is not known.
+	FieldPosition(path string) (Position, bool)
+}
@@ -375,6 +375,18 @@ snapshots:
     collections:
       - "istio/authentication/v1alpha1/meshpolicies"
       - "istio/authentication/v1alpha1/policies"
+      - "istio/config/v1alpha2/adapters"
+      - "istio/config/v1alpha2/httpapispecs"
+      - "istio/config/v1alpha2/httpapispecbindings"
+      - "istio/config/v1alpha2/templates"
+      - "istio/mixer/v1/config/client/quotaspecbindings"
+      - "istio/mixer/v1/config/client/quotaspecs"
+      - "istio/policy/v1beta1/attributemanifests"
+      - "istio/policy/v1beta1/handlers"
+      - "istio/policy/v1beta1/instances"
+      - "istio/policy/v1beta1/rules"
+      - "istio/rbac/v1alpha1/clusterrbacconfigs"
+      - "istio/rbac/v1alpha1/rbacconfigs"
       - "istio/rbac/v1alpha1/servicerolebindings"
       - "istio/rbac/v1alpha1/serviceroles"
       - "istio/mesh/v1alpha1/MeshConfig"
@@ -150,6 +150,14 @@ func (c *Collection) Remove(n resource.FullName) {
 	}
 }
 
+// Get returns the entry with the given name, or nil if there is none.
+func (c *Collection) Get(n resource.FullName) *resource.Instance {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.resources[n]
+}
+
 // AllSorted returns all entries in this collection, in sort order.
 // Warning: This is not performant!
 func (c *Collection) AllSorted() []*resource.Instance {
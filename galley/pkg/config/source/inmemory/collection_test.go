@@ -85,6 +85,16 @@ func TestCollection_Update(t *testing.T) {
 	g.Expect(actual).To(Equal(expected))
 }
 
+func TestCollection_Get(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	col := NewCollection(basicmeta.K8SCollection1)
+	col.Set(data.Event1Col1AddItem1.Resource)
+
+	g.Expect(col.Get(data.Event1Col1AddItem1.Resource.Metadata.FullName)).To(Equal(data.Event1Col1AddItem1.Resource))
+	g.Expect(col.Get(resource.NewFullName("unknown", "name"))).To(BeNil())
+}
+
 func TestCollection_Delete(t *testing.T) {
 	g := NewGomegaWithT(t)
 
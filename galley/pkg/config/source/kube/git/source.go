@@ -0,0 +1,277 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package git provides a source reading Kubernetes style config from the commits of a tracked ref of a local Git
+// repository, e.g. to run Galley in GitOps mode.
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/galley/pkg/config/event"
+	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/galley/pkg/config/schema/collection"
+	"istio.io/istio/galley/pkg/config/scope"
+	"istio.io/istio/galley/pkg/config/source/kube/inmemory"
+)
+
+const (
+	defaultRef          = "HEAD"
+	defaultPollInterval = 10 * time.Second
+)
+
+var (
+	supportedExtensions = map[string]bool{
+		".yaml": true,
+		".yml":  true,
+	}
+)
+
+var nameDiscriminator int64
+
+// Options for the Git source.
+type Options struct {
+	// Repository is the path of a local clone or bare repository.
+	Repository string
+
+	// Ref is the tracked ref, e.g. refs/heads/master. HEAD if empty.
+	Ref string
+
+	// Path is the directory of the config files in the repository. The whole repository if empty.
+	Path string
+
+	// PollInterval is the interval between the checks of the ref for new commits. 10s if 0.
+	PollInterval time.Duration
+}
+
+type source struct {
+	mu      sync.Mutex
+	name    string
+	options Options
+	s       *inmemory.KubeSource
+	done    chan struct{}
+
+	// commit is the last synced commit of the ref.
+	commit string
+}
+
+var _ event.Source = &source{}
+
+// New returns a new source reading the config files committed at the tracked ref of a local Git repository. The
+// files changed by new commits are applied when the ref is polled, and the changed resources get the commit as
+// their version. Uncommitted changes of a working tree are ignored.
+func New(o Options, schemas collection.Schemas) (event.Source, error) {
+	if o.Repository == "" {
+		return nil, fmt.Errorf("git repository not specified")
+	}
+	if o.Ref == "" {
+		o.Ref = defaultRef
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = defaultPollInterval
+	}
+	if _, err := runGit(o.Repository, "rev-parse", "--git-dir"); err != nil {
+		return nil, fmt.Errorf("invalid git repository %q: %v", o.Repository, err)
+	}
+
+	name := fmt.Sprintf("git-%d", nameDiscriminator)
+	nameDiscriminator++
+
+	return &source{
+		name:    name,
+		options: o,
+		s:       inmemory.NewKubeSource(schemas),
+	}, nil
+}
+
+// Start implements processor.Source
+func (s *source) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done != nil {
+		return
+	}
+	done := make(chan struct{})
+	s.done = done
+
+	go func() {
+		s.sync(done)
+
+		t := time.NewTicker(s.options.PollInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				s.sync(done)
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop implements processor.Source.
+func (s *source) Stop() {
+	scope.Source.Debugf("git.Source.Stop >>>")
+	defer scope.Source.Debugf("git.Source.Stop <<<")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done == nil {
+		return
+	}
+	close(s.done)
+	s.s.Stop()
+	s.s.Clear()
+	s.done = nil
+	s.commit = ""
+}
+
+// Dispatch implements event.Source
+func (s *source) Dispatch(h event.Handler) {
+	s.s.Dispatch(h)
+}
+
+// sync applies the files changed between the last synced commit and the current commit of the ref.
+func (s *source) sync(done chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done != done {
+		// Stopped since the sync was triggered.
+		return
+	}
+
+	out, err := s.git("rev-parse", "--verify", "--quiet", s.options.Ref+"^{commit}")
+	if err != nil {
+		scope.Source.Errorf("[%s] Error resolving ref %q: %v", s.name, s.options.Ref, err)
+		return
+	}
+	commit := strings.TrimSpace(out)
+	if commit == s.commit {
+		return
+	}
+
+	var changed, removed []string
+	if s.commit != "" {
+		changed, removed, err = s.diff(s.commit, commit)
+		if err != nil {
+			// e.g. the last synced commit was garbage collected after a force push.
+			scope.Source.Warnf("[%s] Error diffing %s..%s, reloading all files: %v", s.name, s.commit, commit, err)
+		}
+	}
+	if s.commit == "" || err != nil {
+		if changed, removed, err = s.list(commit); err != nil {
+			scope.Source.Errorf("[%s] Error listing the files of %s: %v", s.name, commit, err)
+			return
+		}
+	}
+
+	scope.Source.Infof("[%s] Syncing %s: %d changed and %d removed files", s.name, commit, len(changed), len(removed))
+	for _, p := range changed {
+		content, err := s.git("cat-file", "blob", commit+":"+p)
+		if err != nil {
+			scope.Source.Errorf("[%s] Error reading file %q of %s: %v", s.name, p, commit, err)
+			continue
+		}
+		if err := s.s.ApplyContentWithVersion(p, content, resource.Version(commit)); err != nil {
+			scope.Source.Errorf("[%s] Error applying file contents(%q): %v", s.name, p, err)
+		}
+	}
+	for _, p := range removed {
+		scope.Source.Infof("[%s] Removing the contents of the file %q", s.name, p)
+		s.s.RemoveContent(p)
+	}
+	s.commit = commit
+
+	// Start once the first commit is loaded, not to publish an empty snapshot, e.g. if the ref doesn't exist yet.
+	// Starting again is a no-op.
+	s.s.Start()
+}
+
+// diff returns the config files changed and removed between the commits.
+func (s *source) diff(from, to string) (changed, removed []string, err error) {
+	args := []string{"diff", "--name-status", "--no-renames", "-z", from, to}
+	out, err := s.git(append(args, s.pathspec()...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The output is a sequence of NUL terminated status and path pairs.
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, p := fields[i], fields[i+1]
+		if !supportedExtensions[path.Ext(p)] {
+			continue
+		}
+		if status == "D" {
+			removed = append(removed, p)
+		} else {
+			changed = append(changed, p)
+		}
+	}
+	return changed, removed, nil
+}
+
+// list returns all the config files of the commit as changed, and the known files which are not in the commit as
+// removed.
+func (s *source) list(commit string) (changed, removed []string, err error) {
+	args := []string{"ls-tree", "-r", "-z", "--name-only", commit}
+	out, err := s.git(append(args, s.pathspec()...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := s.s.ContentNames()
+	for _, p := range strings.Split(out, "\x00") {
+		if !supportedExtensions[path.Ext(p)] {
+			continue
+		}
+		changed = append(changed, p)
+		delete(names, p)
+	}
+	for n := range names {
+		removed = append(removed, n)
+	}
+	return changed, removed, nil
+}
+
+func (s *source) pathspec() []string {
+	if s.options.Path == "" {
+		return nil
+	}
+	return []string{"--", s.options.Path}
+}
+
+func (s *source) git(args ...string) (string, error) {
+	return runGit(s.options.Repository, args...)
+}
+
+// runGit runs the git command in the repository and returns its output.
+func runGit(repository string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", repository}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
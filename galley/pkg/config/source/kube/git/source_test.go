@@ -0,0 +1,278 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/event"
+	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/galley/pkg/config/source/kube/git"
+	"istio.io/istio/galley/pkg/config/testing/basicmeta"
+	"istio.io/istio/galley/pkg/config/testing/data"
+	"istio.io/istio/galley/pkg/config/testing/fixtures"
+)
+
+func TestNew_InvalidRepository(t *testing.T) {
+	dir := createTempDir(t)
+	defer deleteTempDir(t, dir)
+
+	if _, err := git.New(git.Options{Repository: dir}, basicmeta.MustGet().KubeCollections()); err == nil {
+		t.Fatal("expected error for a directory which is not a repository")
+	}
+	if _, err := git.New(git.Options{}, basicmeta.MustGet().KubeCollections()); err == nil {
+		t.Fatal("expected error for an empty repository path")
+	}
+}
+
+func TestInitialCommit(t *testing.T) {
+	dir := createRepo(t)
+	defer deleteTempDir(t, dir)
+
+	writeFile(t, dir, "foo.yaml", data.YamlN1I1V1)
+	writeFile(t, dir, "README.md", "not config")
+	c1 := commit(t, dir)
+
+	s := newOrFail(t, git.Options{Repository: dir})
+	acc := startOrFail(t, s)
+	defer s.Stop()
+
+	fixtures.ExpectEventsWithoutOriginsEventually(t, acc,
+		event.AddFor(basicmeta.K8SCollection1, withVersion(data.EntryN1I1V1, c1)),
+		event.FullSyncFor(basicmeta.K8SCollection1))
+}
+
+func TestStartsOnceFirstCommitIsLoaded(t *testing.T) {
+	dir := createRepo(t)
+	defer deleteTempDir(t, dir)
+
+	// The ref can't be resolved before the first commit.
+	s := newOrFail(t, git.Options{Repository: dir, PollInterval: 10 * time.Millisecond})
+	acc := startOrFail(t, s)
+	defer s.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	fixtures.ExpectEventsWithoutOriginsEventually(t, acc)
+
+	writeFile(t, dir, "foo.yaml", data.YamlN1I1V1)
+	c1 := commit(t, dir)
+
+	fixtures.ExpectEventsWithoutOriginsEventually(t, acc,
+		event.AddFor(basicmeta.K8SCollection1, withVersion(data.EntryN1I1V1, c1)),
+		event.FullSyncFor(basicmeta.K8SCollection1))
+}
+
+func TestOriginRevision(t *testing.T) {
+	dir := createRepo(t)
+	defer deleteTempDir(t, dir)
+
+	writeFile(t, dir, "foo.yaml", data.YamlN1I1V1)
+	c1 := commit(t, dir)
+
+	s := newOrFail(t, git.Options{Repository: dir})
+	acc := startOrFail(t, s)
+	defer s.Stop()
+
+	// Not expecting the events without origins, which strips them.
+	var r *resource.Instance
+	for deadline := time.Now().Add(5 * time.Second); r == nil && time.Now().Before(deadline); {
+		for _, e := range acc.Events() {
+			if e.Kind == event.Added {
+				r = e.Resource
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if r == nil {
+		t.Fatal("timed out waiting for the resource of the commit")
+	}
+	m := diag.NewMessage(diag.NewMessageType(diag.Error, "IST-0-0", "Template: %q"), r, "")
+	if want := c1 + ":foo.yaml:"; !strings.Contains(m.String(), want) {
+		t.Errorf("got message %q, want it to point to %s", m.String(), want)
+	}
+}
+
+func TestChangedFilesOnly(t *testing.T) {
+	dir := createRepo(t)
+	defer deleteTempDir(t, dir)
+
+	writeFile(t, dir, "foo.yaml", data.YamlN1I1V1)
+	writeFile(t, dir, "bar.yaml", data.YamlN2I2V1)
+	c1 := commit(t, dir)
+
+	s := newOrFail(t, git.Options{Repository: dir, PollInterval: 10 * time.Millisecond})
+	acc := startOrFail(t, s)
+	defer s.Stop()
+
+	fixtures.ExpectEventsWithoutOriginsEventually(t, acc,
+		event.AddFor(basicmeta.K8SCollection1, withVersion(data.EntryN2I2V1, c1)),
+		event.AddFor(basicmeta.K8SCollection1, withVersion(data.EntryN1I1V1, c1)),
+		event.FullSyncFor(basicmeta.K8SCollection1))
+	acc.Clear()
+
+	writeFile(t, dir, "foo.yaml", data.YamlN1I1V2)
+	c2 := commit(t, dir)
+
+	fixtures.ExpectEventsWithoutOriginsEventually(t, acc,
+		event.UpdateFor(basicmeta.K8SCollection1, withVersion(data.EntryN1I1V2, c2)))
+	acc.Clear()
+
+	deleteFiles(t, dir, "bar.yaml")
+	commit(t, dir)
+
+	fixtures.ExpectEventsWithoutOriginsEventually(t, acc,
+		event.DeleteForResource(basicmeta.K8SCollection1, withVersion(data.EntryN2I2V1, c1)))
+}
+
+func TestUncommittedChangesAreIgnored(t *testing.T) {
+	dir := createRepo(t)
+	defer deleteTempDir(t, dir)
+
+	writeFile(t, dir, "foo.yaml", data.YamlN1I1V1)
+	c1 := commit(t, dir)
+
+	s := newOrFail(t, git.Options{Repository: dir, PollInterval: 10 * time.Millisecond})
+	acc := startOrFail(t, s)
+	defer s.Stop()
+
+	fixtures.ExpectEventsWithoutOriginsEventually(t, acc,
+		event.AddFor(basicmeta.K8SCollection1, withVersion(data.EntryN1I1V1, c1)),
+		event.FullSyncFor(basicmeta.K8SCollection1))
+	acc.Clear()
+
+	writeFile(t, dir, "foo.yaml", data.YamlN1I1V2)
+	time.Sleep(100 * time.Millisecond)
+	fixtures.ExpectEventsWithoutOriginsEventually(t, acc)
+}
+
+func TestBareRepositoryPath(t *testing.T) {
+	dir := createRepo(t)
+	defer deleteTempDir(t, dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "config"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "config/foo.yaml", data.YamlN1I1V1)
+	writeFile(t, dir, "bar.yaml", data.YamlN2I2V1)
+	c1 := commit(t, dir)
+
+	bare := createTempDir(t)
+	defer deleteTempDir(t, bare)
+	runGit(t, bare, "clone", "--bare", "--quiet", dir, ".")
+
+	s := newOrFail(t, git.Options{Repository: bare, Ref: "refs/heads/master", Path: "config"})
+	acc := startOrFail(t, s)
+	defer s.Stop()
+
+	fixtures.ExpectEventsWithoutOriginsEventually(t, acc,
+		event.AddFor(basicmeta.K8SCollection1, withVersion(data.EntryN1I1V1, c1)),
+		event.FullSyncFor(basicmeta.K8SCollection1))
+}
+
+func createTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "gitsource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func deleteTempDir(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir := createTempDir(t)
+	runGit(t, dir, "init", "--quiet")
+	runGit(t, dir, "checkout", "--quiet", "-b", "master")
+	return dir
+}
+
+func writeFile(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func deleteFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, name := range files {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// commit commits all the changes of the working tree and returns the commit.
+func commit(t *testing.T, dir string) string {
+	t.Helper()
+	runGit(t, dir, "add", "--all")
+	runGit(t, dir, "commit", "--quiet", "--message", "change")
+	return strings.TrimSpace(runGit(t, dir, "rev-parse", "HEAD"))
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@istio.io",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@istio.io")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return string(out)
+}
+
+func newOrFail(t *testing.T, o git.Options) event.Source {
+	t.Helper()
+	s, err := git.New(o, basicmeta.MustGet().KubeCollections())
+	if err != nil {
+		t.Fatalf("Unexpected error found: %v", err)
+	}
+	return s
+}
+
+func startOrFail(t *testing.T, s event.Source) *fixtures.Accumulator {
+	t.Helper()
+
+	acc := &fixtures.Accumulator{}
+	s.Dispatch(acc)
+	s.Start()
+
+	return acc
+}
+
+func withVersion(r *resource.Instance, v string) *resource.Instance {
+	r = r.Clone()
+	r.Metadata.Version = resource.Version(v)
+	return r
+}
@@ -20,6 +20,7 @@ import (
 	"crypto/sha1"
 	"fmt"
 	"io"
+	"reflect"
 	"strings"
 	"sync"
 
@@ -35,6 +36,7 @@ import (
 	"istio.io/istio/galley/pkg/config/scope"
 	"istio.io/istio/galley/pkg/config/source/inmemory"
 	"istio.io/istio/galley/pkg/config/source/kube/rt"
+	"istio.io/istio/galley/pkg/config/util/kubeyaml"
 )
 
 var inMemoryKubeNameDiscriminator int64
@@ -137,11 +139,19 @@ func (s *KubeSource) ContentNames() map[string]struct{} {
 	return result
 }
 
-// ApplyContent applies the given yamltext to this source. The content is tracked with the given name. If ApplyContent
+// ApplyContent applies the given yamltext to this source. The content is tracked with the given name, which is also
+// the filename of the positions of the fields of the resources. If ApplyContent
 // gets called multiple times with the same name, the contents applied by the previous incarnation will be overwritten
 // or removed, depending on the new content.
 // Returns an error if any were encountered, but that still may represent a partial success
 func (s *KubeSource) ApplyContent(name, yamlText string) error {
+	return s.ApplyContentWithVersion(name, yamlText, "")
+}
+
+// ApplyContentWithVersion is like ApplyContent, but the resources changed by the content get the given version,
+// e.g. the commit of the content, instead of a generated one. The version is also recorded in their origin, as the
+// version of the resource and the revision of the file.
+func (s *KubeSource) ApplyContentWithVersion(name, yamlText string, version resource.Version) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -153,11 +163,23 @@ func (s *KubeSource) ApplyContent(name, yamlText string) error {
 
 	for _, r := range resources {
 		key := r.newKey()
+		if o, ok := r.resource.Origin.(*rt.Origin); ok && version != "" {
+			o.Revision = string(version)
+		}
 
 		oldSha, found := s.shas[key]
-		if !found || oldSha != r.sha {
-			s.versionCtr++
-			r.resource.Metadata.Version = resource.Version(fmt.Sprintf("v%d", s.versionCtr))
+		if found && oldSha == r.sha {
+			s.updateOrigin(r)
+		} else {
+			if version != "" {
+				r.resource.Metadata.Version = version
+				if o, ok := r.resource.Origin.(*rt.Origin); ok {
+					o.Version = version
+				}
+			} else {
+				s.versionCtr++
+				r.resource.Metadata.Version = resource.Version(fmt.Sprintf("v%d", s.versionCtr))
+			}
 			scope.Source.Debuga("KubeSource.ApplyContent: Set: ", r.schema.Name(), r.resource.Metadata.FullName)
 			s.source.Get(r.schema.Name()).Set(r.resource)
 			s.shas[key] = r.sha
@@ -180,6 +202,27 @@ func (s *KubeSource) ApplyContent(name, yamlText string) error {
 	return nil
 }
 
+// updateOrigin replaces the origin of an unchanged resource if it moved, e.g. in its file after a document was
+// prepended, keeping its version.
+func (s *KubeSource) updateOrigin(r kubeResource) {
+	c := s.source.Get(r.schema.Name())
+	old := c.Get(r.resource.Metadata.FullName)
+	if old == nil {
+		return
+	}
+	oldOrigin, ok := old.Origin.(*rt.Origin)
+	newOrigin, ok2 := r.resource.Origin.(*rt.Origin)
+	// The revision of an unmoved resource is left as is, its positions are still right in the previous revision.
+	if !ok || !ok2 || (oldOrigin.Filename == newOrigin.Filename &&
+		reflect.DeepEqual(oldOrigin.FieldPositions, newOrigin.FieldPositions)) {
+		return
+	}
+	scope.Source.Debuga("KubeSource.ApplyContent: Set origin: ", r.schema.Name(), r.resource.Metadata.FullName)
+	r.resource.Metadata.Version = old.Metadata.Version
+	newOrigin.Version = oldOrigin.Version
+	c.Set(r.resource)
+}
+
 // RemoveContent removes the content for the given name
 func (s *KubeSource) RemoveContent(name string) {
 	s.mu.Lock()
@@ -203,6 +246,8 @@ func (s *KubeSource) parseContent(r *collection.Schemas, name, yamlText string)
 	reader := bufio.NewReader(strings.NewReader(yamlText))
 	decoder := yaml.NewYAMLReader(reader)
 	chunkCount := -1
+	// The offset in yamlText after the last read document, to find the lines of the documents.
+	offset := 0
 
 	for {
 		chunkCount++
@@ -218,6 +263,12 @@ func (s *KubeSource) parseContent(r *collection.Schemas, name, yamlText string)
 			break
 		}
 
+		line := 0
+		if i := strings.Index(yamlText[offset:], string(doc)); i >= 0 {
+			line = strings.Count(yamlText[:offset+i], "\n")
+			offset += i + len(doc)
+		}
+
 		chunk := bytes.TrimSpace(doc)
 		r, err := s.parseChunk(r, chunk)
 		if err != nil {
@@ -227,6 +278,14 @@ func (s *KubeSource) parseContent(r *collection.Schemas, name, yamlText string)
 			errs = multierror.Append(errs, e)
 			continue
 		}
+		if o, ok := r.resource.Origin.(*rt.Origin); ok {
+			o.Filename = name
+			o.FieldPositions = kubeyaml.FieldPositions(string(doc))
+			for p, pos := range o.FieldPositions {
+				pos.Line += line
+				o.FieldPositions[p] = pos
+			}
+		}
 		resources = append(resources, r)
 	}
 
@@ -21,6 +21,7 @@ import (
 
 	"istio.io/istio/galley/pkg/config/event"
 	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/galley/pkg/config/source/kube/rt"
 	"istio.io/istio/galley/pkg/config/testing/basicmeta"
 	"istio.io/istio/galley/pkg/config/testing/data"
 	"istio.io/istio/galley/pkg/config/testing/fixtures"
@@ -51,6 +52,93 @@ func TestKubeSource_ApplyContent(t *testing.T) {
 	g.Expect(acc.Events()[1].Resource.Metadata.FullName).To(Equal(data.EntryN1I1V1.Metadata.FullName))
 }
 
+func TestKubeSource_ApplyContentWithVersion(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, acc := setupKubeSource()
+	s.Start()
+	defer s.Stop()
+
+	err := s.ApplyContentWithVersion("foo", kubeyaml.JoinString(data.YamlN1I1V1, data.YamlN2I2V1), "c1")
+	g.Expect(err).To(BeNil())
+
+	// Only the changed resources get the new version.
+	err = s.ApplyContentWithVersion("foo", kubeyaml.JoinString(data.YamlN1I1V1, data.YamlN2I2V2), "c2")
+	g.Expect(err).To(BeNil())
+
+	actual := s.Get(basicmeta.K8SCollection1.Name()).AllSorted()
+	g.Expect(actual).To(HaveLen(2))
+	g.Expect(actual[0].Metadata.Version).To(Equal(resource.Version("c1")))
+	g.Expect(actual[0].Origin.(*rt.Origin).Version).To(Equal(resource.Version("c1")))
+	g.Expect(actual[1].Metadata.Version).To(Equal(resource.Version("c2")))
+	g.Expect(actual[1].Origin.(*rt.Origin).Version).To(Equal(resource.Version("c2")))
+
+	events := acc.EventsWithoutOrigins()
+	g.Expect(events).To(HaveLen(4))
+	fixtures.ExpectEqual(t, events[1].Resource, withVersion(data.EntryN1I1V1, "c1"))
+	fixtures.ExpectEqual(t, events[2].Resource, withVersion(data.EntryN2I2V1, "c1"))
+	g.Expect(events[3].Kind).To(Equal(event.Updated))
+	fixtures.ExpectEqual(t, events[3].Resource, withVersion(data.EntryN2I2V2, "c2"))
+}
+
+func TestKubeSource_ApplyContent_FieldPositions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, _ := setupKubeSource()
+	s.Start()
+	defer s.Stop()
+
+	err := s.ApplyContent("foo.yaml", kubeyaml.JoinString(data.YamlN1I1V1, data.YamlN2I2V1))
+	g.Expect(err).To(BeNil())
+
+	actual := s.Get(basicmeta.K8SCollection1.Name()).AllSorted()
+	g.Expect(actual).To(HaveLen(2))
+
+	o := actual[0].Origin.(*rt.Origin)
+	p, ok := o.FieldPosition("")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(p).To(Equal(resource.Position{Filename: "foo.yaml", Line: 2, Column: 1}))
+	p, _ = o.FieldPosition("spec.n1_i1")
+	g.Expect(p).To(Equal(resource.Position{Filename: "foo.yaml", Line: 8, Column: 3}))
+
+	// Unknown fields are at their closest known parent.
+	o = actual[1].Origin.(*rt.Origin)
+	p, _ = o.FieldPosition("spec.unknown[0].field")
+	g.Expect(p).To(Equal(resource.Position{Filename: "foo.yaml", Line: 16, Column: 1}))
+}
+
+func TestKubeSource_ApplyContent_MovedFieldPositions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, acc := setupKubeSource()
+	s.Start()
+	defer s.Stop()
+
+	err := s.ApplyContentWithVersion("foo.yaml", data.YamlN1I1V1, "c1")
+	g.Expect(err).To(BeNil())
+
+	// Prepending a document moves the unchanged resource down in the file.
+	err = s.ApplyContentWithVersion("foo.yaml", kubeyaml.JoinString(data.YamlN2I2V1, data.YamlN1I1V1), "c2")
+	g.Expect(err).To(BeNil())
+
+	actual := s.Get(basicmeta.K8SCollection1.Name()).AllSorted()
+	g.Expect(actual).To(HaveLen(2))
+	g.Expect(actual[0].Metadata.Version).To(Equal(resource.Version("c1")))
+	o := actual[0].Origin.(*rt.Origin)
+	g.Expect(o.Version).To(Equal(resource.Version("c1")))
+	p, ok := o.FieldPosition("")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(p).To(Equal(resource.Position{Filename: "foo.yaml", Line: 11, Column: 1, Revision: "c2"}))
+
+	// Unmoved resources are not updated again.
+	err = s.ApplyContentWithVersion("foo.yaml", kubeyaml.JoinString(data.YamlN2I2V1, data.YamlN1I1V1), "c3")
+	g.Expect(err).To(BeNil())
+	events := acc.EventsWithoutOrigins()
+	g.Expect(events).To(HaveLen(4))
+	g.Expect(events[3].Kind).To(Equal(event.Updated))
+	fixtures.ExpectEqual(t, events[3].Resource, withVersion(data.EntryN1I1V1, "c1"))
+}
+
 func TestKubeSource_ApplyContent_BeforeStart(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -21,6 +21,7 @@ import (
 	"istio.io/istio/galley/pkg/config/resource"
 	"istio.io/istio/galley/pkg/config/schema/collection"
 	"istio.io/istio/galley/pkg/config/schema/collections"
+	"istio.io/istio/galley/pkg/config/util/kubeyaml"
 )
 
 // Origin is a K8s specific implementation of resource.Origin
@@ -29,9 +30,18 @@ type Origin struct {
 	Kind       string
 	FullName   resource.FullName
 	Version    resource.Version
+
+	// Filename is the name of the file the resource was read from, if any.
+	Filename string
+	// Revision is the revision of the file, e.g. the commit it was read from, if any.
+	Revision string
+	// FieldPositions are the positions of the fields of the resource in the file, by field path. The resource is at
+	// the empty path.
+	FieldPositions map[string]kubeyaml.Position
 }
 
 var _ resource.Origin = &Origin{}
+var _ resource.FieldPositioner = &Origin{}
 
 // FriendlyName implements resource.Origin
 func (o *Origin) FriendlyName() string {
@@ -53,3 +63,18 @@ func (o *Origin) Namespace() resource.Namespace {
 
 	return o.FullName.Namespace
 }
+
+// FieldPosition implements resource.FieldPositioner
+func (o *Origin) FieldPosition(path string) (resource.Position, bool) {
+	for {
+		if p, ok := o.FieldPositions[path]; ok {
+			return resource.Position{Filename: o.Filename, Line: p.Line, Column: p.Column, Revision: o.Revision}, true
+		}
+		if path == "" {
+			return resource.Position{}, false
+		}
+		// Fall back to the parent field, e.g. spec.http[1] for spec.http[1].route.
+		path = path[:strings.LastIndexAny(path, ".[")+1]
+		path = strings.TrimRight(path, ".[")
+	}
+}
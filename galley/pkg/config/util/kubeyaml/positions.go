@@ -0,0 +1,163 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeyaml
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Position is the line and column of a YAML node, starting at 1.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// frame is a mapping or a sequence being scanned.
+type frame struct {
+	indent int
+	path   string
+	seq    bool
+	next   int
+}
+
+// FieldPositions returns the positions of the mapping keys and sequence items of a block style YAML document, by
+// field path, e.g. spec.http[1].route[0].destination.host. The document itself is at the empty path. The contents of
+// flow style collections and of multi-line scalars are not scanned: they are only known by the position of their
+// field. Lines are counted from the start of the document.
+func FieldPositions(doc string) map[string]Position {
+	positions := make(map[string]Position)
+	stack := []*frame{{indent: 0}}
+
+	// The path and indentation of the last key or item without a value on its line, whose value may be a collection
+	// on the next lines.
+	pendingPath := ""
+	pendingIndent := -1
+	// The lines indented more than skipIndent continue the value of the last key or item.
+	skipIndent := -1
+
+	for i, line := range strings.Split(doc, "\n") {
+		content := strings.TrimLeft(line, " ")
+		indent := len(line) - len(content)
+		content = strings.TrimRight(content, " \t\r")
+		if content == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+		if indent == 0 && (strings.HasPrefix(content, "---") || strings.HasPrefix(content, "...")) {
+			continue
+		}
+		if _, ok := positions[""]; !ok {
+			positions[""] = Position{Line: i + 1, Column: indent + 1}
+		}
+		if skipIndent >= 0 && indent > skipIndent {
+			continue
+		}
+		skipIndent = -1
+
+		if pendingIndent >= 0 && (indent > pendingIndent || (indent == pendingIndent && isItem(content))) {
+			stack = append(stack, &frame{indent: indent, path: pendingPath, seq: isItem(content)})
+		}
+		pendingIndent = -1
+
+		for len(stack) > 1 && stack[len(stack)-1].indent > indent {
+			stack = stack[:len(stack)-1]
+		}
+		// A sequence may be at the indentation of the key holding it.
+		if t := stack[len(stack)-1]; len(stack) > 1 && t.seq && t.indent == indent && !isItem(content) {
+			stack = stack[:len(stack)-1]
+		}
+		top := stack[len(stack)-1]
+		if top.indent != indent {
+			// Unexpected indentation, e.g. of a document which isn't block style.
+			continue
+		}
+
+		// A line may hold nested collections, e.g. "- - name: foo".
+		column := indent
+		for content != "" {
+			if top.seq {
+				if !isItem(content) {
+					break
+				}
+				p := top.path + "[" + strconv.Itoa(top.next) + "]"
+				top.next++
+				positions[p] = Position{Line: i + 1, Column: column + 1}
+
+				rest := strings.TrimLeft(content[1:], " ")
+				column += len(content) - len(rest)
+				content = rest
+				if content == "" {
+					pendingPath, pendingIndent = p, indent
+					break
+				}
+				if !isItem(content) && keyEnd(content) < 0 {
+					skipIndent = indent
+					break
+				}
+				top = &frame{indent: column, path: p, seq: isItem(content)}
+				stack = append(stack, top)
+				continue
+			}
+
+			end := keyEnd(content)
+			if end < 0 {
+				break
+			}
+			key := strings.Trim(strings.TrimSpace(content[:end]), `"'`)
+			p := key
+			if top.path != "" {
+				p = top.path + "." + key
+			}
+			positions[p] = Position{Line: i + 1, Column: column + 1}
+
+			value := strings.TrimSpace(content[end+1:])
+			if value == "" || strings.HasPrefix(value, "#") || strings.HasPrefix(value, "&") {
+				pendingPath, pendingIndent = p, indent
+			} else {
+				skipIndent = indent
+			}
+			break
+		}
+	}
+
+	return positions
+}
+
+func isItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// keyEnd returns the index of the colon ending the mapping key of the line content, or -1 if it has none.
+func keyEnd(content string) int {
+	start := 0
+	if q := content[0]; q == '"' || q == '\'' {
+		end := strings.IndexByte(content[1:], q)
+		if end < 0 {
+			return -1
+		}
+		start = end + 2
+	} else if q == '{' || q == '[' || q == '|' || q == '>' {
+		return -1
+	}
+	for i := start; i < len(content); i++ {
+		if content[i] != ':' {
+			continue
+		}
+		if i+1 == len(content) || content[i+1] == ' ' || content[i+1] == '\t' {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeyaml
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestFieldPositions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	doc := `
+# A virtual service
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  labels: {app: reviews}
+spec:
+  hosts:
+  - reviews
+  http:
+  - match:
+    - uri:
+        prefix: /v1
+    route:
+    - destination:
+        host: reviews
+        subset: v1
+  - route:
+      - destination:
+          host: "reviews.default"
+  description: |
+    key: not a field
+  "quoted.key": value
+  tcp:
+  - - nested: item
+`
+	positions := FieldPositions(doc)
+
+	expected := map[string]Position{
+		"":                                       {Line: 3, Column: 1},
+		"apiVersion":                             {Line: 3, Column: 1},
+		"kind":                                   {Line: 4, Column: 1},
+		"metadata":                               {Line: 5, Column: 1},
+		"metadata.name":                          {Line: 6, Column: 3},
+		"metadata.labels":                        {Line: 7, Column: 3},
+		"spec":                                   {Line: 8, Column: 1},
+		"spec.hosts":                             {Line: 9, Column: 3},
+		"spec.hosts[0]":                          {Line: 10, Column: 3},
+		"spec.http":                              {Line: 11, Column: 3},
+		"spec.http[0]":                           {Line: 12, Column: 3},
+		"spec.http[0].match":                     {Line: 12, Column: 5},
+		"spec.http[0].match[0]":                  {Line: 13, Column: 5},
+		"spec.http[0].match[0].uri":              {Line: 13, Column: 7},
+		"spec.http[0].match[0].uri.prefix":       {Line: 14, Column: 9},
+		"spec.http[0].route":                     {Line: 15, Column: 5},
+		"spec.http[0].route[0]":                  {Line: 16, Column: 5},
+		"spec.http[0].route[0].destination":      {Line: 16, Column: 7},
+		"spec.http[0].route[0].destination.host": {Line: 17, Column: 9},
+		"spec.http[0].route[0].destination.subset": {Line: 18, Column: 9},
+		"spec.http[1]":                           {Line: 19, Column: 3},
+		"spec.http[1].route":                     {Line: 19, Column: 5},
+		"spec.http[1].route[0]":                  {Line: 20, Column: 7},
+		"spec.http[1].route[0].destination":      {Line: 20, Column: 9},
+		"spec.http[1].route[0].destination.host": {Line: 21, Column: 11},
+		"spec.description":                       {Line: 22, Column: 3},
+		"spec.quoted.key":                        {Line: 24, Column: 3},
+		"spec.tcp":                               {Line: 25, Column: 3},
+		"spec.tcp[0]":                            {Line: 26, Column: 3},
+		"spec.tcp[0][0]":                         {Line: 26, Column: 5},
+		"spec.tcp[0][0].nested":                  {Line: 26, Column: 7},
+	}
+	g.Expect(positions).To(Equal(expected))
+}
+
+func TestFieldPositions_Empty(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(FieldPositions("")).To(BeEmpty())
+	g.Expect(FieldPositions("# comment\n---\n")).To(BeEmpty())
+}
@@ -43,6 +43,13 @@ var (
 
 	// MCPSourceReqFreq is the frequency that is used by the rate limiter in MCP Sources
 	MCPSourceReqFreq = env.RegisterDurationVar("MCP_SOURCE_REQ_FREQ", time.Second, "")
+
+	// MCPSourceIncremental allows the MCP sources to send only the changed resources to the sinks requesting it,
+	// instead of the full collections.
+	MCPSourceIncremental = env.RegisterBoolVar("MCP_SOURCE_INCREMENTAL", true,
+		"If enabled, the MCP sources send only the added, updated and removed resources to the sinks requesting it, "+
+			"e.g. Pilot. The first response of each connection and the response following a NACK hold the full "+
+			"collection. Set to false to always send the full collections.")
 )
 
 // RegisteredEnvVarNames returns the names of registered environment variables.
@@ -57,5 +64,7 @@ func RegisteredEnvVarNames() []string {
 		// MCP Source RateLimiter
 		MCPSourceReqBurstSize.Name,
 		MCPSourceReqFreq.Name,
+		// MCP Source incremental updates
+		MCPSourceIncremental.Name,
 	}
 }
@@ -23,6 +23,7 @@ import (
 	"istio.io/istio/galley/pkg/config/processor"
 	"istio.io/istio/galley/pkg/config/source/kube"
 	"istio.io/istio/galley/pkg/config/source/kube/fs"
+	"istio.io/istio/galley/pkg/config/source/kube/git"
 	"istio.io/istio/pkg/mcp/monitoring"
 	"istio.io/pkg/filewatcher"
 )
@@ -38,6 +39,7 @@ var (
 	meshcfgNewFS        = func(path string) (event.Source, error) { return meshcfg.NewFS(path) }
 	processorInitialize = processor.Initialize
 	fsNew               = fs.New
+	gitNew              = git.New
 )
 
 func resetPatchTable() {
@@ -50,4 +52,5 @@ func resetPatchTable() {
 	meshcfgNewFS = func(path string) (event.Source, error) { return meshcfg.NewFS(path) }
 	processorInitialize = processor.Initialize
 	fsNew = fs.New
+	gitNew = git.New
 }
@@ -134,13 +134,20 @@ func (p *Processing) Start() (err error) {
 		})
 	}
 
+	collectionStrategies := make(map[collection.Name]string, len(p.args.CollectionStrategies))
+	for c, s := range p.args.CollectionStrategies {
+		collectionStrategies[collection.NewName(c)] = s
+	}
+
 	processorSettings := processor.Settings{
-		Metadata:           m,
-		DomainSuffix:       p.args.DomainSuffix,
-		Source:             event.CombineSources(mesh, src),
-		TransformProviders: transformProviders,
-		Distributor:        distributor,
-		EnabledSnapshots:   p.args.Snapshots,
+		Metadata:             m,
+		DomainSuffix:         p.args.DomainSuffix,
+		Source:               event.CombineSources(mesh, src),
+		TransformProviders:   transformProviders,
+		Distributor:          distributor,
+		EnabledSnapshots:     p.args.Snapshots,
+		CollectionStrategies: collectionStrategies,
+		SnapshotMaxStaleness: p.args.SnapshotMaxStaleness,
 	}
 	if p.runtime, err = processorInitialize(processorSettings); err != nil {
 		return
@@ -169,10 +176,14 @@ func (p *Processing) Start() (err error) {
 	p.reporter = mcpMetricReporter("galley")
 
 	mcpSourceRateLimiter := mcprate.NewRateLimiter(envvar.MCPSourceReqFreq.Get(), envvar.MCPSourceReqBurstSize.Get())
+	collectionsOptions := source.CollectionOptionsFromSlice(m.AllCollectionsInSnapshots(snapshots.SnapshotNames()))
+	for i := range collectionsOptions {
+		collectionsOptions[i].Incremental = envvar.MCPSourceIncremental.Get()
+	}
 	options := &source.Options{
 		Watcher:            p.mcpCache,
 		Reporter:           p.reporter,
-		CollectionsOptions: source.CollectionOptionsFromSlice(m.AllCollectionsInSnapshots(snapshots.SnapshotNames())),
+		CollectionsOptions: collectionsOptions,
 		ConnRateLimiter:    mcpSourceRateLimiter,
 	}
 
@@ -294,7 +305,12 @@ func (p *Processing) getKubeInterfaces() (k kube.Interfaces, err error) {
 func (p *Processing) createSourceAndStatusUpdater(schemas collection.Schemas) (
 	src event.Source, updater snapshotter.StatusUpdater, err error) {
 
-	if p.args.ConfigPath != "" {
+	if p.args.GitOptions.Repository != "" {
+		if src, err = gitNew(p.args.GitOptions, schemas); err != nil {
+			return
+		}
+		updater = &snapshotter.InMemoryStatusUpdater{}
+	} else if p.args.ConfigPath != "" {
 		if src, err = fsNew(p.args.ConfigPath, schemas, p.args.WatchConfigFiles); err != nil {
 			return
 		}
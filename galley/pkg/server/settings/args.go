@@ -28,6 +28,7 @@ import (
 	"istio.io/pkg/probe"
 
 	"istio.io/istio/galley/pkg/config/schema/snapshots"
+	"istio.io/istio/galley/pkg/config/source/kube/git"
 	"istio.io/istio/galley/pkg/config/util/kuberesource"
 	"istio.io/istio/pkg/keepalive"
 	"istio.io/istio/pkg/mcp/creds"
@@ -94,6 +95,9 @@ type Args struct { // nolint:maligned
 	// ConfigPath is the path for Galley specific config files
 	ConfigPath string
 
+	// GitOptions are the options of the Git repository config source, which is used if a repository is set.
+	GitOptions git.Options
+
 	// ExcludedResourceKinds is a list of resource kinds for which no source events will be triggered.
 	// DEPRECATED
 	ExcludedResourceKinds []string
@@ -162,6 +166,12 @@ type Args struct { // nolint:maligned
 
 	Snapshots       []string
 	TriggerSnapshot string
+
+	// CollectionStrategies are the publishing strategies of the collections published with a strategy other than
+	// the one of their snapshot, by collection name, e.g. to publish the changes of endpoints immediately.
+	CollectionStrategies map[string]string
+	// SnapshotMaxStaleness is the maximum duration a change may stay unpublished, or 0 if unbounded.
+	SnapshotMaxStaleness time.Duration
 }
 
 // DefaultArgs allocates an Args struct initialized with Galley's default configuration.
@@ -228,6 +238,7 @@ func (a *Args) String() string {
 	_, _ = fmt.Fprintf(buf, "CertificateFile: %s\n", a.CredentialOptions.CertificateFile)
 	_, _ = fmt.Fprintf(buf, "CACertificateFile: %s\n", a.CredentialOptions.CACertificateFile)
 	_, _ = fmt.Fprintf(buf, "ConfigFilePath: %s\n", a.ConfigPath)
+	_, _ = fmt.Fprintf(buf, "GitOptions: %+v\n", a.GitOptions)
 	_, _ = fmt.Fprintf(buf, "MeshConfigFile: %s\n", a.MeshConfigFile)
 	_, _ = fmt.Fprintf(buf, "DomainSuffix: %s\n", a.DomainSuffix)
 	_, _ = fmt.Fprintf(buf, "DisableResourceReadyCheck: %v\n", a.DisableResourceReadyCheck)
@@ -239,6 +250,8 @@ func (a *Args) String() string {
 	_, _ = fmt.Fprintf(buf, "KeepAlive.MaxServerConnectionAgeGrace: %v\n", a.KeepAlive.MaxServerConnectionAgeGrace)
 	_, _ = fmt.Fprintf(buf, "KeepAlive.Time: %v\n", a.KeepAlive.Time)
 	_, _ = fmt.Fprintf(buf, "KeepAlive.Timeout: %v\n", a.KeepAlive.Timeout)
+	_, _ = fmt.Fprintf(buf, "CollectionStrategies: %v\n", a.CollectionStrategies)
+	_, _ = fmt.Fprintf(buf, "SnapshotMaxStaleness: %v\n", a.SnapshotMaxStaleness)
 
 	return buf.String()
 }
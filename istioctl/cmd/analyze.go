@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -35,12 +36,14 @@ import (
 
 	"istio.io/istio/galley/pkg/config/analysis"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/rules"
 	"istio.io/istio/galley/pkg/config/analysis/diag"
 	"istio.io/istio/galley/pkg/config/analysis/local"
 	"istio.io/istio/galley/pkg/config/resource"
 	"istio.io/istio/galley/pkg/config/schema"
 	cfgKube "istio.io/istio/galley/pkg/config/source/kube"
 	"istio.io/istio/istioctl/pkg/util/handlers"
+	analyzewriter "istio.io/istio/istioctl/pkg/writer/analyze"
 	"istio.io/istio/pkg/kube"
 )
 
@@ -54,6 +57,8 @@ const (
 	LogOutput        = "log"
 	JSONOutput       = "json"
 	YamlOutput       = "yaml"
+	SARIFOutput      = "sarif"
+	JUnitOutput      = "junit"
 )
 
 func (f AnalyzerFoundIssuesError) Error() string {
@@ -75,6 +80,9 @@ var (
 	allNamespaces   bool
 	suppress        []string
 	analysisTimeout time.Duration
+	baselineFile    string
+	ruleFiles       []string
+	updateBaseline  bool
 
 	termEnvVar = env.RegisterStringVar("TERM", "", "Specifies terminal type.  Use 'dumb' to suppress color output")
 
@@ -88,7 +96,7 @@ var (
 // Analyze command
 func Analyze() *cobra.Command {
 	// Validate the output format before doing potentially expensive work to fail earlier
-	msgOutputFormats := map[string]bool{LogOutput: true, JSONOutput: true, YamlOutput: true}
+	msgOutputFormats := map[string]bool{LogOutput: true, JSONOutput: true, YamlOutput: true, SARIFOutput: true, JUnitOutput: true}
 	var msgOutputFormatKeys []string
 
 	for k := range msgOutputFormats {
@@ -115,6 +123,16 @@ istioctl analyze -S "IST0103=Pod mypod.testing"
 # and suppress MisplacedAnnotation on deployment foobar in namespace default.
 istioctl analyze -S "IST0103=Pod *.testing" -S "IST0107=Deployment foobar.default"
 
+# Analyze the current live cluster and print the messages as a JUnit XML report for CI
+istioctl analyze -o junit
+
+# Record the current messages in a baseline file, then only report the messages which are not in it
+istioctl analyze --baseline analyze-baseline.yaml --update-baseline
+istioctl analyze --baseline analyze-baseline.yaml
+
+# Analyze the current live cluster with custom rules in addition to the built-in analyzers
+istioctl analyze --rules house-rules.yaml
+
 # List available analyzers
 istioctl analyze -L
 `,
@@ -127,11 +145,29 @@ istioctl analyze -L
 				}
 			}
 
+			customAnalyzers, err := rules.Load(schema.MustGet().AllCollections(), ruleFiles...)
+			if err != nil {
+				return err
+			}
+			allAnalyzers := append(analyzers.All(), customAnalyzers...)
+
 			if listAnalyzers {
-				fmt.Print(AnalyzersAsString(analyzers.All()))
+				fmt.Print(AnalyzersAsString(allAnalyzers))
 				return nil
 			}
 
+			if updateBaseline && baselineFile == "" {
+				return CommandParseError{errors.New("--update-baseline requires --baseline")}
+			}
+			var baseline *analyzewriter.Baseline
+			if baselineFile != "" && !updateBaseline {
+				b, err := analyzewriter.ReadBaseline(baselineFile)
+				if err != nil {
+					return err
+				}
+				baseline = b
+			}
+
 			readers, err := gatherFiles(args)
 			if err != nil {
 				return err
@@ -147,7 +183,7 @@ istioctl analyze -L
 				selectedNamespace = ""
 			}
 
-			sa := local.NewSourceAnalyzer(schema.MustGet(), analyzers.AllCombined(),
+			sa := local.NewSourceAnalyzer(schema.MustGet(), analysis.Combine("all", allAnalyzers...),
 				resource.Namespace(selectedNamespace), resource.Namespace(istioNamespace), nil, true, analysisTimeout)
 
 			// Check for suppressions and add them to our SourceAnalyzer
@@ -242,9 +278,25 @@ istioctl analyze -L
 				fmt.Fprintln(cmd.ErrOrStderr())
 			}
 
+			// Record all the messages in the baseline, or only keep the messages which aren't in it
+			messages := result.Messages
+			if updateBaseline {
+				if err := analyzewriter.NewBaseline(messages).Write(baselineFile); err != nil {
+					return fmt.Errorf("could not write baseline: %v", err)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "Recorded %d messages in baseline %s\n", len(messages), baselineFile)
+				messages = nil
+			} else if baseline != nil {
+				var suppressed int
+				messages, suppressed = baseline.Filter(messages)
+				if verbose {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Suppressed %d messages found in baseline %s\n", suppressed, baselineFile)
+				}
+			}
+
 			// Filter outputMessages by specified level, and append a ref arg to the doc URL
 			var outputMessages diag.Messages
-			for _, m := range result.Messages {
+			for _, m := range messages {
 				if m.Type.Level().IsWorseThanOrEqualTo(outputLevel.Level) {
 					m.DocRef = "istioctl-analyze"
 					outputMessages = append(outputMessages, m)
@@ -285,13 +337,21 @@ istioctl analyze -L
 					return err
 				}
 				fmt.Fprintln(cmd.OutOrStdout(), string(yamlOutput))
+			case SARIFOutput:
+				if err := analyzewriter.PrintSARIF(cmd.OutOrStdout(), outputMessages); err != nil {
+					return err
+				}
+			case JUnitOutput:
+				if err := analyzewriter.PrintJUnit(cmd.OutOrStdout(), outputMessages, failureLevel.Level); err != nil {
+					return err
+				}
 			default: // This should never happen since we validate this already
 				panic(fmt.Sprintf("%q not found in output format switch statement post validate?", msgOutputFormat))
 			}
 
 			// Return code is based on the unfiltered validation message list/parse errors
 			// We're intentionally keeping failure threshold and output threshold decoupled for now
-			returnError := errorIfMessagesExceedThreshold(result.Messages)
+			returnError := errorIfMessagesExceedThreshold(messages)
 			if returnError == nil && parseErrors > 0 {
 				returnError = FileParseError{}
 			}
@@ -323,6 +383,13 @@ istioctl analyze -L
 			`You can include the wildcard character '*' to support a partial match (e.g. '--suppress "IST0102=DestinationRule *.default" ).`)
 	analysisCmd.PersistentFlags().DurationVar(&analysisTimeout, "timeout", 30*time.Second,
 		"the duration to wait before failing")
+	analysisCmd.PersistentFlags().StringArrayVar(&ruleFiles, "rules", []string{},
+		"Files of custom rules to run alongside the built-in analyzers. Each rule reports a message with its code, "+
+			"level and text for the resources of a collection matching a CEL condition")
+	analysisCmd.PersistentFlags().StringVar(&baselineFile, "baseline", "",
+		"Baseline file listing the known messages, by code and resource, which are not reported")
+	analysisCmd.PersistentFlags().BoolVar(&updateBaseline, "update-baseline", false,
+		"Record all the current messages in the --baseline file instead of reporting them")
 	return analysisCmd
 }
 
@@ -372,7 +439,11 @@ func colorSuffix() string {
 func renderMessage(m diag.Message) string {
 	origin := ""
 	if m.Resource != nil {
-		origin = " (" + m.Resource.Origin.FriendlyName() + ")"
+		origin = m.Resource.Origin.FriendlyName()
+		if l := m.Location(); l != "" {
+			origin += " " + l
+		}
+		origin = " (" + origin + ")"
 	}
 	return fmt.Sprintf(
 		"%s%v%s [%v]%s %s", colorPrefix(m), m.Type.Level(), colorSuffix(), m.Type.Code(), origin, fmt.Sprintf(m.Type.Template(), m.Parameters...))
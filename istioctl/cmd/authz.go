@@ -21,6 +21,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -50,6 +51,10 @@ var (
 	serviceFiles             []string
 	rootNamespace            string
 	allowNoClusterRbacConfig bool
+
+	evalRequest authz.Request
+	evalHeaders []string
+	evalClaims  []string
 )
 
 var (
@@ -63,13 +68,21 @@ with authorization and the rules used in the authorization.
 The Envoy config dump could be provided either by pod name or from a config dump file
 (the whole output of http://localhost:15000/config_dump of an Envoy instance).
 
+When --port is set, check instead evaluates a request sent to that port against the RBAC filters
+of the inbound listener, including the ALLOW and DENY semantics and the shadow rules, and reports
+whether the request is allowed along with the policy which matched it.
+
 THIS COMMAND IS STILL UNDER ACTIVE DEVELOPMENT AND NOT READY FOR PRODUCTION USE.
 `,
 		Example: `  # Check Envoy authorization configuration for pod httpbin-88ddbcfdd-nt5jb:
   istioctl x authz check httpbin-88ddbcfdd-nt5jb
 
   # Check Envoy authorization configuration from a config dump file:
-  istioctl x authz check -f httpbin_config_dump.json`,
+  istioctl x authz check -f httpbin_config_dump.json
+
+  # Evaluate a request from the sleep service account with a JWT groups claim:
+  istioctl x authz check httpbin-88ddbcfdd-nt5jb --port 8000 --method POST --path /post \
+    --source-principal cluster.local/ns/default/sa/sleep --claim groups=admin`,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 1 {
 				cmd.Println(cmd.UsageString())
@@ -99,6 +112,9 @@ THIS COMMAND IS STILL UNDER ACTIVE DEVELOPMENT AND NOT READY FOR PRODUCTION USE.
 			if err != nil {
 				return err
 			}
+			if evalRequest.Port != 0 {
+				return evaluateRequest(cmd, analyzer)
+			}
 			analyzer.Print(cmd.OutOrStdout(), printAll)
 			return nil
 		},
@@ -184,6 +200,33 @@ PLEASE ALWAYS REVIEW THE CONVERTED POLICIES BEFORE APPLYING.
 	}
 )
 
+func evaluateRequest(cmd *cobra.Command, analyzer *authz.Analyzer) error {
+	req := evalRequest
+	req.Headers = map[string]string{}
+	for _, h := range evalHeaders {
+		kv := strings.SplitN(h, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid header %q, expecting key=value", h)
+		}
+		req.Headers[kv[0]] = kv[1]
+	}
+	req.Claims = map[string][]string{}
+	for _, c := range evalClaims {
+		kv := strings.SplitN(c, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid claim %q, expecting key=value", c)
+		}
+		req.Claims[kv[0]] = append(req.Claims[kv[0]], kv[1])
+	}
+
+	evaluation, err := analyzer.Evaluate(&req)
+	if err != nil {
+		return err
+	}
+	evaluation.Print(cmd.OutOrStdout())
+	return nil
+}
+
 func getConfigDumpFromFile(filename string) (*configdump.Wrapper, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -376,6 +419,28 @@ func init() {
 		"Show additional information (e.g. SNI and ALPN)")
 	checkCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"The json file with Envoy config dump to be checked")
+	checkCmd.PersistentFlags().Uint32Var(&evalRequest.Port, "port", 0,
+		"Evaluate a request sent to this port instead of listing the authorization configuration")
+	checkCmd.PersistentFlags().StringVar(&evalRequest.SourcePrincipal, "source-principal", "",
+		"Peer identity of the evaluated request, e.g. cluster.local/ns/default/sa/sleep, used with --port")
+	checkCmd.PersistentFlags().StringVar(&evalRequest.SourceNamespace, "source-namespace", "",
+		"Namespace of the peer of the evaluated request when --source-principal is not set, used with --port")
+	checkCmd.PersistentFlags().StringVar(&evalRequest.SourceIP, "source-ip", "",
+		"Source IP of the evaluated request, used with --port")
+	checkCmd.PersistentFlags().StringVar(&evalRequest.SNI, "sni", "",
+		"Requested server name of the evaluated request, used with --port")
+	checkCmd.PersistentFlags().StringVar(&evalRequest.Host, "host", "",
+		"Host of the evaluated request, used with --port")
+	checkCmd.PersistentFlags().StringVar(&evalRequest.Method, "method", "GET",
+		"Method of the evaluated request, used with --port")
+	checkCmd.PersistentFlags().StringVar(&evalRequest.Path, "path", "/",
+		"Path of the evaluated request, used with --port")
+	checkCmd.PersistentFlags().StringArrayVarP(&evalHeaders, "header", "H", nil,
+		"Header of the evaluated request as key=value, can be repeated, used with --port")
+	checkCmd.PersistentFlags().StringVar(&evalRequest.RequestPrincipal, "request-principal", "",
+		"Principal of the request credential as issuer/subject, defaults to the iss and sub claims, used with --port")
+	checkCmd.PersistentFlags().StringArrayVar(&evalClaims, "claim", nil,
+		"JWT claim of the evaluated request as key=value, can be repeated, used with --port")
 	convertCmd.PersistentFlags().StringSliceVarP(&v1Files, "file", "f", []string{},
 		"The yaml file with v1alpha1 RBAC policies to be converted")
 	convertCmd.PersistentFlags().StringSliceVarP(&serviceFiles, "service", "s", []string{},
@@ -22,6 +22,7 @@ import (
 	"strings"
 	"testing"
 
+	"istio.io/istio/istioctl/pkg/authz"
 	"istio.io/istio/pilot/pkg/config/kube/crd"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/security/authz/policy"
@@ -239,3 +240,36 @@ func TestAuthZConvert(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthZCheckEvaluate(t *testing.T) {
+	defer func() {
+		evalRequest = authz.Request{}
+		evalHeaders, evalClaims = nil, nil
+	}()
+	testCases := []struct {
+		name   string
+		args   string
+		golden string
+	}{
+		{
+			name:   "allowed",
+			args:   "--port 9080 --source-principal cluster.local/ns/default/sa/sleep",
+			golden: "testdata/authz/productpage_evaluate_allow.golden",
+		},
+		{
+			name:   "denied",
+			args:   "--port 9080 --source-principal cluster.local/ns/default/sa/sleep --method POST",
+			golden: "testdata/authz/productpage_evaluate_deny.golden",
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			command := fmt.Sprintf("experimental authz check -f testdata/authz/productpage_config_dump.json %s", c.args)
+			runCommandWantOutput(command, c.golden, t)
+		})
+	}
+
+	runCommandWantError("experimental authz check -f testdata/authz/productpage_config_dump.json --port 1234 --method GET",
+		"no listener found for port 1234 with node IP 10.52.2.21", t)
+}
@@ -0,0 +1,174 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/galley/pkg/config/analysis/analyzers"
+	"istio.io/istio/galley/pkg/config/analysis/local"
+	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/galley/pkg/config/schema"
+	"istio.io/istio/galley/pkg/config/schema/collections"
+	cfgKube "istio.io/istio/galley/pkg/config/source/kube"
+	"istio.io/istio/istioctl/pkg/bugreport"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pkg/kube"
+)
+
+func bugReportCmd() *cobra.Command {
+	var (
+		output        string
+		maxBytes      int64
+		logLimitBytes int64
+		logSince      time.Duration
+		proxies       []string
+		proxySelector string
+		skipAnalyze   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bug-report",
+		Short: "Collect the state of the Istio installation into an archive for bug reports",
+		Long: `Bug-report collects the diagnostic data of the Istio installation into a single tarball,
+to be attached to bug reports and support requests. It includes:
+
+  - the Kubernetes and Istio versions
+  - the pods and the logs of the control plane
+  - the output of the Pilot debug endpoints (syncz, configz, push_status, registryz, ...)
+  - the config dump, stats, clusters and logs of the selected proxies
+  - the mesh and sidecar injector configuration
+  - the Istio resources
+  - the output of istioctl analyze
+
+Private keys, credentials and the values of sensitive keys (e.g. password or token) are redacted, and
+Kubernetes secrets are never collected. Files which would make the archive exceed --max-size are
+skipped and listed in the archive. Failures to collect some of the data are listed as well.
+`,
+		Example: `  # Collect a bug report with the productpage proxy and all the ingress gateways:
+  istioctl x bug-report --proxy productpage-v1-7bbd79f8fd-k6j79.default --proxy-selector istio=ingressgateway
+
+  # Collect the last hour of logs, limited to 10MB per container:
+  istioctl x bug-report --since 1h --log-limit-bytes 10000000 -o report.tar.gz`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := interfaceFactory(kubeconfig)
+			if err != nil {
+				return err
+			}
+			execClient, err := clientExecFactory(kubeconfig, configContext)
+			if err != nil {
+				return err
+			}
+			dynamicClient, err := clientGetter(kubeconfig, configContext)
+			if err != nil {
+				return err
+			}
+
+			qualified := make([]string, 0, len(proxies))
+			for _, p := range proxies {
+				podName, podNamespace := handlers.InferPodInfo(p, handlers.HandleNamespace(namespace, defaultNamespace))
+				qualified = append(qualified, podName+"."+podNamespace)
+			}
+			collector := &bugreport.Collector{
+				Kube:           kubeClient,
+				Exec:           execClient,
+				Dynamic:        dynamicClient,
+				Schemas:        collections.Istio,
+				IstioNamespace: istioNamespace,
+				Proxies:        qualified,
+				ProxySelector:  proxySelector,
+				LogLimitBytes:  logLimitBytes,
+				LogSince:       logSince,
+			}
+			if !skipAnalyze {
+				collector.Analyze = analyzeLiveCluster
+			}
+
+			if output == "" {
+				output = bugreport.DefaultArchiveName(time.Now())
+			}
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer f.Close() // nolint: errcheck
+
+			archive := bugreport.NewArchive(f, strings.TrimSuffix(output, ".tar.gz"), maxBytes)
+			if err := collector.Collect(archive); err != nil {
+				return fmt.Errorf("failed to write %s: %v", output, err)
+			}
+			if err := archive.Close(); err != nil {
+				return fmt.Errorf("failed to write %s: %v", output, err)
+			}
+
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Wrote bug report to %s\n", output)
+			if n := len(archive.Errors()); n > 0 {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Failed to collect %d items, see errors.txt in the archive\n", n)
+			}
+			if n := len(archive.Skipped()); n > 0 {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Skipped %d files because of --max-size, see skipped.txt in the archive\n", n)
+			}
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", "",
+		"Path of the archive, defaults to bug-report-<timestamp>.tar.gz")
+	cmd.PersistentFlags().Int64Var(&maxBytes, "max-size", 500*1000*1000,
+		"Maximum uncompressed size of the archive in bytes, 0 for no limit")
+	cmd.PersistentFlags().Int64Var(&logLimitBytes, "log-limit-bytes", 50*1000*1000,
+		"Maximum size of the log of each container in bytes, 0 for no limit")
+	cmd.PersistentFlags().DurationVar(&logSince, "since", 0,
+		"Only collect the logs newer than this duration, e.g. 1h, 0 for all the logs")
+	cmd.PersistentFlags().StringSliceVar(&proxies, "proxy", nil,
+		"Proxy to collect as <pod-name>[.<namespace>], can be repeated")
+	cmd.PersistentFlags().StringVar(&proxySelector, "proxy-selector", "",
+		"Label selector of the proxies to collect in all namespaces, e.g. istio=ingressgateway")
+	cmd.PersistentFlags().BoolVar(&skipAnalyze, "skip-analyze", false,
+		"Do not include the output of istioctl analyze")
+
+	return cmd
+}
+
+// analyzeLiveCluster analyzes the Istio configuration of all the namespaces of the cluster.
+func analyzeLiveCluster() (string, error) {
+	sa := local.NewSourceAnalyzer(schema.MustGet(), analyzers.AllCombined(),
+		resource.Namespace(""), resource.Namespace(istioNamespace), nil, true, analysisTimeout)
+	restConfig, err := kube.BuildClientCmd(kubeconfig, configContext).ClientConfig()
+	if err != nil {
+		return "", err
+	}
+	sa.AddRunningKubeSource(cfgKube.NewInterfaces(restConfig))
+
+	result, err := sa.Analyze(make(chan struct{}))
+	if err != nil {
+		return "", err
+	}
+	if len(result.Messages) == 0 {
+		return NoIssuesString + "\n", nil
+	}
+	var out strings.Builder
+	for _, m := range result.Messages {
+		out.WriteString(m.String())
+		out.WriteString("\n")
+	}
+	return out.String(), nil
+}
@@ -16,9 +16,13 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -33,23 +37,32 @@ import (
 	"istio.io/pkg/log"
 )
 
-var (
-	metricsCmd = &cobra.Command{
+func metricsCmd() *cobra.Command {
+	opts := &metricsOptions{}
+	var end string
+
+	cmd := &cobra.Command{
 		Use:   "metrics <workload name>...",
 		Short: "Prints the metrics for the specified workload(s) when running in Kubernetes.",
 		Long: `
 Prints the metrics for the specified service(s) when running in Kubernetes.
 
-This command finds a Prometheus pod running in the specified istio system 
+This command finds a Prometheus pod running in the specified istio system
 namespace. It then executes a series of queries per requested workload to
 find the following top-level workload metrics: total requests per second,
-error rate, and request latency at p50, p90, and p99 percentiles. The 
+error rate, and request latency at p50, p90, and p99 percentiles. The
 query results are printed to the console, organized by workload name.
 
 All metrics returned are from server-side reports. This means that latencies
 and error rates are from the perspective of the service itself and not of an
 individual client (or aggregate set of clients). Rates and latencies are
-calculated over a time interval of 1 minute.
+calculated over a time interval of 1 minute by default.
+
+The metrics can be broken down by the workloads sending requests to the
+workload (--by source), or by the workloads it sends requests to
+(--by destination), in which case they are from client-side reports.
+A workload can be restricted to a version as <workload>@<version>, and
+two workloads or versions can be compared with --compare.
 `,
 		Example: `
 # Retrieve workload metrics for productpage-v1 workload
@@ -57,6 +70,15 @@ istioctl experimental metrics productpage-v1
 
 # Retrieve workload metrics for various services in the different namespaces
 istioctl experimental metrics productpage-v1.foo reviews-v1.bar ratings-v1.baz
+
+# Retrieve the metrics of the requests sent to reviews by each workload over the last 10 minutes
+istioctl experimental metrics reviews.default --by source --duration 10m
+
+# Compare the canary version of reviews with the stable one, with their latency histograms
+istioctl experimental metrics reviews.default@v1 reviews.default@v2 --compare --histogram
+
+# Retrieve workload metrics as JSON from a Prometheus server running outside of the cluster
+istioctl experimental metrics productpage-v1 -o json --prometheus-address http://prometheus.example.com:9090
 `,
 		// nolint: goimports
 		Aliases: []string{"m"},
@@ -65,29 +87,107 @@ istioctl experimental metrics productpage-v1.foo reviews-v1.bar ratings-v1.baz
 				cmd.Println(cmd.UsageString())
 				return fmt.Errorf("metrics requires workload name")
 			}
+			if opts.compare && len(args) != 2 {
+				return fmt.Errorf("--compare requires exactly two workloads")
+			}
+			if opts.compare && opts.by != "" {
+				return fmt.Errorf("--compare can't be used with --by")
+			}
+			if opts.by != "" && opts.by != bySource && opts.by != byDestination {
+				return fmt.Errorf("--by must be one of %s|%s", bySource, byDestination)
+			}
+			if opts.output != tableOutput && opts.output != jsonOutput {
+				return fmt.Errorf("output format %q not supported", opts.output)
+			}
+			if opts.duration <= 0 {
+				return fmt.Errorf("--duration must be positive")
+			}
 			return nil
 		},
-		RunE:                  run,
+		RunE: func(c *cobra.Command, args []string) error {
+			opts.end = time.Now()
+			if end != "" {
+				t, err := time.Parse(time.RFC3339, end)
+				if err != nil {
+					return fmt.Errorf("invalid --end %q: %v", end, err)
+				}
+				opts.end = t
+			}
+			return run(c, opts, args)
+		},
 		DisableFlagsInUseLine: true,
 	}
-)
+
+	cmd.PersistentFlags().DurationVarP(&opts.duration, "duration", "d", time.Minute,
+		"Time interval over which the rates and latencies are calculated")
+	cmd.PersistentFlags().StringVar(&end, "end", "",
+		"End of the time interval in RFC3339 format, e.g. 2020-01-02T15:04:05Z, defaults to now")
+	cmd.PersistentFlags().StringVar(&opts.by, "by", "",
+		"Break down the metrics by the source or the destination workloads: one of source|destination")
+	cmd.PersistentFlags().BoolVar(&opts.compare, "compare", false,
+		"Compare the metrics of two workloads or versions")
+	cmd.PersistentFlags().BoolVar(&opts.histogram, "histogram", false,
+		"Print the latency histogram of the workloads")
+	cmd.PersistentFlags().StringVarP(&opts.output, "output", "o", tableOutput,
+		"Output format: one of table|json")
+	cmd.PersistentFlags().StringVar(&opts.prometheusAddress, "prometheus-address", "",
+		"Address of the Prometheus server, instead of port forwarding to the Prometheus pod in the istio system namespace")
+
+	return cmd
+}
 
 const (
-	wlabel   = "destination_workload"
-	wnslabel = "destination_workload_namespace"
-	reqTot   = "istio_requests_total"
-	reqDur   = "istio_request_duration_seconds"
+	wlabel    = "destination_workload"
+	wnslabel  = "destination_workload_namespace"
+	wvlabel   = "destination_version"
+	swlabel   = "source_workload"
+	swnslabel = "source_workload_namespace"
+	swvlabel  = "source_version"
+	reqTot    = "istio_requests_total"
+	reqDur    = "istio_request_duration_seconds"
+
+	bySource      = "source"
+	byDestination = "destination"
+	tableOutput   = "table"
 )
 
+// metricsOptions are the queries and the output of the metrics command.
+type metricsOptions struct {
+	// duration is the time interval of the rates and latencies, ending at end.
+	duration time.Duration
+	end      time.Time
+	// by breaks down the metrics by the source or destination workloads, if set.
+	by                string
+	compare           bool
+	histogram         bool
+	output            string
+	prometheusAddress string
+}
+
 type workloadMetrics struct {
 	workload                           string
 	totalRPS, errorRPS                 float64
 	p50Latency, p90Latency, p99Latency time.Duration
+	// histogram is the count of requests per latency bucket, sorted by upper bound.
+	histogram []latencyBucket
+}
+
+type latencyBucket struct {
+	le    float64
+	count float64
 }
 
-func run(c *cobra.Command, args []string) error {
+func run(c *cobra.Command, opts *metricsOptions, args []string) error {
 	log.Debugf("metrics command invoked for workload(s): %v", args)
 
+	if opts.prometheusAddress != "" {
+		promAPI, err := newPrometheusAPI(opts.prometheusAddress)
+		if err != nil {
+			return err
+		}
+		return printWorkloadsMetrics(c.OutOrStdout(), promAPI, opts, args)
+	}
+
 	client, err := clientExecFactory(kubeconfig, configContext)
 	if err != nil {
 		return fmt.Errorf("failed to create k8s client: %v", err)
@@ -116,36 +216,62 @@ func run(c *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
-
-		printHeader(c.OutOrStdout())
-
-		workloads := args
-		for _, workload := range workloads {
-			sm, err := metrics(promAPI, workload)
-			if err != nil {
-				return fmt.Errorf("could not build metrics for workload '%s': %v", workload, err)
-			}
-
-			printMetrics(c.OutOrStdout(), sm)
-		}
+		err = printWorkloadsMetrics(c.OutOrStdout(), promAPI, opts, args)
 		close(fw.StopChannel)
-		return nil
+		return err
 	}); err != nil {
 		return fmt.Errorf("failure running port forward process: %v", err)
 	}
 	return nil
 }
 
+func printWorkloadsMetrics(writer io.Writer, promAPI promv1.API, opts *metricsOptions, workloads []string) error {
+	var all []workloadMetrics
+	for _, workload := range workloads {
+		wm, err := metrics(promAPI, opts, workload)
+		if err != nil {
+			return fmt.Errorf("could not build metrics for workload '%s': %v", workload, err)
+		}
+		all = append(all, wm...)
+	}
+
+	if opts.output == jsonOutput {
+		return printMetricsJSON(writer, all, opts.compare)
+	}
+	printHeader(writer)
+	for _, wm := range all {
+		printMetrics(writer, wm)
+	}
+	if opts.compare {
+		printComparison(writer, all[0], all[1])
+	}
+	if opts.histogram {
+		for _, wm := range all {
+			printHistogram(writer, wm)
+		}
+	}
+	return nil
+}
+
 func prometheusAPI(port int) (promv1.API, error) {
-	promClient, err := api.NewClient(api.Config{Address: fmt.Sprintf("http://localhost:%d", port)})
+	return newPrometheusAPI(fmt.Sprintf("http://localhost:%d", port))
+}
+
+func newPrometheusAPI(address string) (promv1.API, error) {
+	promClient, err := api.NewClient(api.Config{Address: address})
 	if err != nil {
 		return nil, fmt.Errorf("could not build prometheus client: %v", err)
 	}
 	return promv1.NewAPI(promClient), nil
 }
 
-func metrics(promAPI promv1.API, workload string) (workloadMetrics, error) {
-
+// selector returns the label matchers of the requests of the workload, given as
+// <name>[.<namespace>][@<version>], and the labels identifying the peers of the breakdown.
+func (o *metricsOptions) selector(workload string) (string, []string) {
+	version := ""
+	if i := strings.LastIndex(workload, "@"); i >= 0 {
+		workload, version = workload[:i], workload[i+1:]
+	}
 	parts := strings.Split(workload, ".")
 	wname := parts[0]
 	wns := ""
@@ -153,69 +279,182 @@ func metrics(promAPI promv1.API, workload string) (workloadMetrics, error) {
 		wns = parts[1]
 	}
 
-	rpsQuery := fmt.Sprintf(`sum(rate(%s{%s=~"%s.*", %s=~"%s.*",reporter="destination"}[1m]))`, reqTot, wlabel, wname, wnslabel, wns)
-	errRPSQuery := fmt.Sprintf(`sum(rate(%s{%s=~"%s.*", %s=~"%s.*",reporter="destination",response_code!="200"}[1m]))`, reqTot, wlabel, wname, wnslabel, wns)
-	p50LatencyQuery := fmt.Sprintf(`histogram_quantile(%f, sum(rate(%s_bucket{%s=~"%s.*", %s=~"%s.*",reporter="destination"}[1m])) by (le))`,
-		0.5, reqDur, wlabel, wname, wnslabel, wns)
-	p90LatencyQuery := fmt.Sprintf(`histogram_quantile(%f, sum(rate(%s_bucket{%s=~"%s.*", %s=~"%s.*",reporter="destination"}[1m])) by (le))`,
-		0.9, reqDur, wlabel, wname, wnslabel, wns)
-	p99LatencyQuery := fmt.Sprintf(`histogram_quantile(%f, sum(rate(%s_bucket{%s=~"%s.*", %s=~"%s.*",reporter="destination"}[1m])) by (le))`,
-		0.99, reqDur, wlabel, wname, wnslabel, wns)
+	name, ns, v, reporter := wlabel, wnslabel, wvlabel, "destination"
+	var peers []string
+	switch o.by {
+	case bySource:
+		peers = []string{swlabel, swnslabel}
+	case byDestination:
+		name, ns, v, reporter = swlabel, swnslabel, swvlabel, "source"
+		peers = []string{wlabel, wnslabel}
+	}
+	selector := fmt.Sprintf(`%s=~"%s.*", %s=~"%s.*",reporter="%s"`, name, wname, ns, wns, reporter)
+	if version != "" {
+		selector += fmt.Sprintf(`,%s="%s"`, v, version)
+	}
+	return selector, peers
+}
+
+// by returns the grouping clause of a query over the given labels.
+func by(labels ...string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	return " by (" + strings.Join(labels, ", ") + ")"
+}
+
+func metrics(promAPI promv1.API, opts *metricsOptions, workload string) ([]workloadMetrics, error) {
+	selector, peers := opts.selector(workload)
+	interval := model.Duration(opts.duration).String()
+
+	rpsQuery := fmt.Sprintf(`sum(rate(%s{%s}[%s]))%s`, reqTot, selector, interval, by(peers...))
+	errRPSQuery := fmt.Sprintf(`sum(rate(%s{%s,response_code!="200"}[%s]))%s`, reqTot, selector, interval, by(peers...))
+	latencyQuery := func(quantile float64) string {
+		return fmt.Sprintf(`histogram_quantile(%f, sum(rate(%s_bucket{%s}[%s]))%s)`,
+			quantile, reqDur, selector, interval, by(append([]string{"le"}, peers...)...))
+	}
+	histogramQuery := fmt.Sprintf(`sum(increase(%s_bucket{%s}[%s]))%s`,
+		reqDur, selector, interval, by(append([]string{"le"}, peers...)...))
 
 	var me *multierror.Error
-	var err error
-	sm := workloadMetrics{workload: workload}
-	sm.totalRPS, err = vectorValue(promAPI, rpsQuery)
+	totalRPS, err := vectorValues(promAPI, rpsQuery, opts.end, peers)
 	if err != nil {
 		me = multierror.Append(me, err)
 	}
-
-	sm.errorRPS, err = vectorValue(promAPI, errRPSQuery)
+	errorRPS, err := vectorValues(promAPI, errRPSQuery, opts.end, peers)
 	if err != nil {
 		me = multierror.Append(me, err)
 	}
-
-	p50Latency, err := vectorValue(promAPI, p50LatencyQuery)
+	p50Latency, err := vectorValues(promAPI, latencyQuery(0.5), opts.end, peers)
 	if err != nil {
 		me = multierror.Append(me, err)
 	}
-	sm.p50Latency = time.Duration(p50Latency*1000) * time.Millisecond
-
-	p90Latency, err := vectorValue(promAPI, p90LatencyQuery)
+	p90Latency, err := vectorValues(promAPI, latencyQuery(0.9), opts.end, peers)
 	if err != nil {
 		me = multierror.Append(me, err)
 	}
-	sm.p90Latency = time.Duration(p90Latency*1000) * time.Millisecond
-
-	p99Latency, err := vectorValue(promAPI, p99LatencyQuery)
+	p99Latency, err := vectorValues(promAPI, latencyQuery(0.99), opts.end, peers)
 	if err != nil {
 		me = multierror.Append(me, err)
 	}
-	sm.p99Latency = time.Duration(p99Latency*1000) * time.Millisecond
+	var histograms map[string][]latencyBucket
+	if opts.histogram {
+		histograms, err = histogramValues(promAPI, histogramQuery, opts.end, peers)
+		if err != nil {
+			me = multierror.Append(me, err)
+		}
+	}
+
+	// Without a breakdown there is a single row, even if there are no requests
+	keys := []string{""}
+	if len(peers) > 0 {
+		keys = make([]string, 0, len(totalRPS))
+		for k := range totalRPS {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+
+	sms := make([]workloadMetrics, 0, len(keys))
+	for _, k := range keys {
+		sm := workloadMetrics{
+			workload:   workload,
+			totalRPS:   totalRPS[k],
+			errorRPS:   errorRPS[k],
+			p50Latency: time.Duration(p50Latency[k]*1000) * time.Millisecond,
+			p90Latency: time.Duration(p90Latency[k]*1000) * time.Millisecond,
+			p99Latency: time.Duration(p99Latency[k]*1000) * time.Millisecond,
+			histogram:  histograms[k],
+		}
+		switch opts.by {
+		case bySource:
+			sm.workload = k + " -> " + workload
+		case byDestination:
+			sm.workload = workload + " -> " + k
+		}
+		sms = append(sms, sm)
+	}
 
 	if me.ErrorOrNil() != nil {
-		return sm, fmt.Errorf("error retrieving some metrics: %v", me.Error())
+		return sms, fmt.Errorf("error retrieving some metrics: %v", me.Error())
 	}
 
-	return sm, nil
+	return sms, nil
+}
+
+// vectorValues returns the values of the query, keyed by the <workload>.<namespace> of the given
+// peer labels, or by the empty string without peer labels.
+func vectorValues(promAPI promv1.API, query string, ts time.Time, peers []string) (map[string]float64, error) {
+	v, err := vector(promAPI, query, ts)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]float64, len(v))
+	for _, s := range v {
+		if math.IsNaN(float64(s.Value)) {
+			continue
+		}
+		values[peerKey(s.Metric, peers)] = float64(s.Value)
+	}
+	return values, nil
 }
 
-func vectorValue(promAPI promv1.API, query string) (float64, error) {
+// histogramValues returns the count of requests per latency bucket, keyed like vectorValues.
+func histogramValues(promAPI promv1.API, query string, ts time.Time, peers []string) (map[string][]latencyBucket, error) {
+	v, err := vector(promAPI, query, ts)
+	if err != nil {
+		return nil, err
+	}
+	cumulative := make(map[string][]latencyBucket)
+	for _, s := range v {
+		le, err := strconv.ParseFloat(string(s.Metric["le"]), 64)
+		if err != nil {
+			continue
+		}
+		k := peerKey(s.Metric, peers)
+		cumulative[k] = append(cumulative[k], latencyBucket{le: le, count: float64(s.Value)})
+	}
+	// Prometheus buckets are cumulative, only keep the requests of each bucket
+	histograms := make(map[string][]latencyBucket, len(cumulative))
+	for k, buckets := range cumulative {
+		sort.Slice(buckets, func(i, j int) bool { return buckets[i].le < buckets[j].le })
+		histogram := make([]latencyBucket, len(buckets))
+		previous := 0.0
+		for i, b := range buckets {
+			histogram[i] = latencyBucket{le: b.le, count: b.count - previous}
+			previous = b.count
+		}
+		histograms[k] = histogram
+	}
+	return histograms, nil
+}
+
+func peerKey(m model.Metric, peers []string) string {
+	if len(peers) == 0 {
+		return ""
+	}
+	values := make([]string, 0, len(peers))
+	for _, p := range peers {
+		values = append(values, string(m[model.LabelName(p)]))
+	}
+	return strings.Join(values, ".")
+}
+
+func vector(promAPI promv1.API, query string, ts time.Time) (model.Vector, error) {
 	log.Debugf("executing query: %s", query)
-	val, _, err := promAPI.Query(context.Background(), query, time.Now())
+	val, _, err := promAPI.Query(context.Background(), query, ts)
 	if err != nil {
-		return 0, fmt.Errorf("query() failure for '%s': %v", query, err)
+		return nil, fmt.Errorf("query() failure for '%s': %v", query, err)
 	}
 
 	switch v := val.(type) {
 	case model.Vector:
 		if v.Len() < 1 {
 			log.Debugf("no values for query: %s", query)
-			return 0, nil
 		}
-		return float64(v[0].Value), nil
+		return v, nil
 	default:
-		return 0, errors.New("bad metric value type returned for query")
+		return nil, errors.New("bad metric value type returned for query")
 	}
 }
 
@@ -235,3 +474,126 @@ func printMetrics(writer io.Writer, wm workloadMetrics) {
 	fmt.Fprintf(w, "%s\t\n", wm.p99Latency)
 	_ = w.Flush()
 }
+
+// printComparison prints the change of the metrics of b relative to a.
+func printComparison(writer io.Writer, a, b workloadMetrics) {
+	w := tabwriter.NewWriter(writer, 13, 1, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "%40s\t", b.workload+" vs "+a.workload)
+	fmt.Fprintf(w, "%s\t", relativeChange(a.totalRPS, b.totalRPS))
+	fmt.Fprintf(w, "%s\t", relativeChange(a.errorRPS, b.errorRPS))
+	fmt.Fprintf(w, "%s\t", durationChange(a.p50Latency, b.p50Latency))
+	fmt.Fprintf(w, "%s\t", durationChange(a.p90Latency, b.p90Latency))
+	fmt.Fprintf(w, "%s\t\n", durationChange(a.p99Latency, b.p99Latency))
+	_ = w.Flush()
+}
+
+func relativeChange(a, b float64) string {
+	if a == 0 {
+		if b == 0 {
+			return "+0.0%"
+		}
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", (b-a)/a*100)
+}
+
+func durationChange(a, b time.Duration) string {
+	if b < a {
+		return "-" + (a - b).String()
+	}
+	return "+" + (b - a).String()
+}
+
+func printHistogram(writer io.Writer, wm workloadMetrics) {
+	fmt.Fprintf(writer, "\nLATENCY HISTOGRAM OF %s\n", wm.workload)
+	total := 0.0
+	for _, b := range wm.histogram {
+		total += b.count
+	}
+	w := tabwriter.NewWriter(writer, 13, 1, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "LATENCY\tREQUESTS\tPERCENT\t\n")
+	for _, b := range wm.histogram {
+		percent := 0.0
+		if total > 0 {
+			percent = b.count / total * 100
+		}
+		fmt.Fprintf(w, "%s\t%.0f\t%.1f%%\t\n", bucketName(b.le), b.count, percent)
+	}
+	_ = w.Flush()
+}
+
+func bucketName(le float64) string {
+	if math.IsInf(le, 1) {
+		return "+Inf"
+	}
+	return "<= " + time.Duration(le*float64(time.Second)).String()
+}
+
+type metricsJSON struct {
+	Workload          string       `json:"workload"`
+	TotalRPS          float64      `json:"totalRPS"`
+	ErrorRPS          float64      `json:"errorRPS"`
+	P50LatencySeconds float64      `json:"p50LatencySeconds"`
+	P90LatencySeconds float64      `json:"p90LatencySeconds"`
+	P99LatencySeconds float64      `json:"p99LatencySeconds"`
+	Histogram         []bucketJSON `json:"histogram,omitempty"`
+}
+
+type bucketJSON struct {
+	// LE is the upper bound of the bucket in seconds, as a string to represent +Inf.
+	LE       string  `json:"le"`
+	Requests float64 `json:"requests"`
+}
+
+// comparisonJSON is the change of the metrics of the compared workload relative to the base workload.
+type comparisonJSON struct {
+	BaseWorkload     string `json:"base"`
+	ComparedWorkload string `json:"compared"`
+	TotalRPSChange   string `json:"totalRPSChange"`
+	ErrorRPSChange   string `json:"errorRPSChange"`
+	P50LatencyChange string `json:"p50LatencyChange"`
+	P90LatencyChange string `json:"p90LatencyChange"`
+	P99LatencyChange string `json:"p99LatencyChange"`
+}
+
+func printMetricsJSON(writer io.Writer, all []workloadMetrics, compare bool) error {
+	out := struct {
+		Workloads  []metricsJSON   `json:"workloads"`
+		Comparison *comparisonJSON `json:"comparison,omitempty"`
+	}{Workloads: make([]metricsJSON, 0, len(all))}
+	for _, wm := range all {
+		mj := metricsJSON{
+			Workload:          wm.workload,
+			TotalRPS:          wm.totalRPS,
+			ErrorRPS:          wm.errorRPS,
+			P50LatencySeconds: wm.p50Latency.Seconds(),
+			P90LatencySeconds: wm.p90Latency.Seconds(),
+			P99LatencySeconds: wm.p99Latency.Seconds(),
+		}
+		for _, b := range wm.histogram {
+			mj.Histogram = append(mj.Histogram, bucketJSON{
+				LE:       strconv.FormatFloat(b.le, 'g', -1, 64),
+				Requests: b.count,
+			})
+		}
+		out.Workloads = append(out.Workloads, mj)
+	}
+	if compare {
+		a, b := all[0], all[1]
+		out.Comparison = &comparisonJSON{
+			BaseWorkload:     a.workload,
+			ComparedWorkload: b.workload,
+			TotalRPSChange:   relativeChange(a.totalRPS, b.totalRPS),
+			ErrorRPSChange:   relativeChange(a.errorRPS, b.errorRPS),
+			P50LatencyChange: durationChange(a.p50Latency, b.p50Latency),
+			P90LatencyChange: durationChange(a.p90Latency, b.p90Latency),
+			P99LatencyChange: durationChange(a.p99Latency, b.p99Latency),
+		}
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(writer, string(b))
+	return nil
+}
@@ -17,7 +17,10 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
 	"strings"
 	"testing"
@@ -168,14 +171,14 @@ func TestPrintMetrics(t *testing.T) {
 	}
 	workload := "details"
 
-	sm, err := metrics(mockProm, workload)
+	sm, err := metrics(mockProm, &metricsOptions{duration: time.Minute}, workload)
 	if err != nil {
 		t.Fatalf("Unwanted exception %v", err)
 	}
 
 	var out bytes.Buffer
 	printHeader(&out)
-	printMetrics(&out, sm)
+	printMetrics(&out, sm[0])
 	output := out.String()
 
 	expectedOutput := `                                  WORKLOAD    TOTAL RPS    ERROR RPS  P50 LATENCY  P90 LATENCY  P99 LATENCY
@@ -186,6 +189,148 @@ func TestPrintMetrics(t *testing.T) {
 	}
 }
 
+// fakePrometheus serves the canned responses of the Prometheus HTTP query API, and empty vectors for other queries.
+func fakePrometheus(t *testing.T, cannedResponse map[string]prometheus_model.Vector) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			http.NotFound(w, r)
+			return
+		}
+		result, ok := cannedResponse[r.FormValue("query")]
+		if !ok {
+			result = prometheus_model.Vector{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data": map[string]interface{}{
+				"resultType": "vector",
+				"result":     result,
+			},
+		}); err != nil {
+			t.Errorf("could not encode the response to %q: %v", r.FormValue("query"), err)
+		}
+	}))
+}
+
+func sample(value float64, labels ...string) *prometheus_model.Sample {
+	m := prometheus_model.Metric{}
+	for i := 0; i+1 < len(labels); i += 2 {
+		m[prometheus_model.LabelName(labels[i])] = prometheus_model.LabelValue(labels[i+1])
+	}
+	return &prometheus_model.Sample{Metric: m, Value: prometheus_model.SampleValue(value)}
+}
+
+func TestMetricsPrometheusServer(t *testing.T) {
+	const (
+		reviews   = `destination_workload=~"reviews.*", destination_workload_namespace=~"default.*",reporter="destination"`
+		reviewsV1 = `destination_workload=~"reviews.*", destination_workload_namespace=~".*",reporter="destination",destination_version="v1"`
+		reviewsV2 = `destination_workload=~"reviews.*", destination_workload_namespace=~".*",reporter="destination",destination_version="v2"`
+		fromPage  = `source_workload=~"productpage.*", source_workload_namespace=~".*",reporter="source"`
+		bySrc     = " by (source_workload, source_workload_namespace)"
+		byDst     = " by (destination_workload, destination_workload_namespace)"
+	)
+	server := fakePrometheus(t, map[string]prometheus_model.Vector{
+		`sum(rate(istio_requests_total{` + reviews + `}[5m]))`:                                                        {sample(2)},
+		`sum(rate(istio_requests_total{` + reviews + `,response_code!="200"}[5m]))`:                                   {sample(0.5)},
+		`histogram_quantile(0.500000, sum(rate(istio_request_duration_seconds_bucket{` + reviews + `}[5m])) by (le))`: {sample(0.01)},
+		`sum(increase(istio_request_duration_seconds_bucket{` + reviews + `}[5m])) by (le)`: {
+			sample(10, "le", "0.01"), sample(40, "le", "0.1"), sample(30, "le", "0.05"), sample(40, "le", "+Inf"),
+		},
+
+		`sum(rate(istio_requests_total{` + reviews + `}[1m]))` + bySrc: {
+			sample(3, "source_workload", "productpage-v1", "source_workload_namespace", "default"),
+			sample(1, "source_workload", "unknown", "source_workload_namespace", "unknown"),
+		},
+		`sum(rate(istio_requests_total{` + reviews + `,response_code!="200"}[1m]))` + bySrc: {
+			sample(1, "source_workload", "unknown", "source_workload_namespace", "unknown"),
+		},
+		`histogram_quantile(0.990000, sum(rate(istio_request_duration_seconds_bucket{` + reviews + `}[1m])) by (le, source_workload, source_workload_namespace))`: {
+			sample(0.2, "source_workload", "productpage-v1", "source_workload_namespace", "default"),
+		},
+
+		`sum(rate(istio_requests_total{` + fromPage + `}[1m]))` + byDst: {
+			sample(1.5, "destination_workload", "details-v1", "destination_workload_namespace", "default"),
+		},
+
+		`sum(rate(istio_requests_total{` + reviewsV1 + `}[1m]))`:                                                        {sample(4)},
+		`histogram_quantile(0.500000, sum(rate(istio_request_duration_seconds_bucket{` + reviewsV1 + `}[1m])) by (le))`: {sample(0.02)},
+		`sum(rate(istio_requests_total{` + reviewsV2 + `}[1m]))`:                                                        {sample(1)},
+		`histogram_quantile(0.500000, sum(rate(istio_request_duration_seconds_bucket{` + reviewsV2 + `}[1m])) by (le))`: {sample(0.05)},
+	})
+	defer server.Close()
+	address := " --prometheus-address " + server.URL
+
+	cases := []testCase{
+		{ // case 0
+			args: strings.Split("experimental metrics reviews.default -d 5m"+address, " "),
+			expectedOutput: `                                  WORKLOAD    TOTAL RPS    ERROR RPS  P50 LATENCY  P90 LATENCY  P99 LATENCY
+                           reviews.default        2.000        0.500         10ms           0s           0s
+`,
+		},
+		{ // case 1
+			args: strings.Split("experimental metrics reviews.default --by source"+address, " "),
+			expectedOutput: `                                  WORKLOAD    TOTAL RPS    ERROR RPS  P50 LATENCY  P90 LATENCY  P99 LATENCY
+  productpage-v1.default -> reviews.default        3.000        0.000           0s           0s        200ms
+        unknown.unknown -> reviews.default        1.000        1.000           0s           0s           0s
+`,
+		},
+		{ // case 2
+			args: strings.Split("experimental metrics productpage --by destination"+address, " "),
+			expectedOutput: `                                  WORKLOAD    TOTAL RPS    ERROR RPS  P50 LATENCY  P90 LATENCY  P99 LATENCY
+         productpage -> details-v1.default        1.500        0.000           0s           0s           0s
+`,
+		},
+		{ // case 3
+			args: strings.Split("experimental metrics reviews@v1 reviews@v2 --compare"+address, " "),
+			expectedOutput: `                                  WORKLOAD    TOTAL RPS    ERROR RPS  P50 LATENCY  P90 LATENCY  P99 LATENCY
+                                reviews@v1        4.000        0.000         20ms           0s           0s
+                                reviews@v2        1.000        0.000         50ms           0s           0s
+                  reviews@v2 vs reviews@v1       -75.0%        +0.0%        +30ms          +0s          +0s
+`,
+		},
+		{ // case 4
+			args: strings.Split("experimental metrics reviews.default -d 5m --histogram"+address, " "),
+			expectedOutput: `                                  WORKLOAD    TOTAL RPS    ERROR RPS  P50 LATENCY  P90 LATENCY  P99 LATENCY
+                           reviews.default        2.000        0.500         10ms           0s           0s
+
+LATENCY HISTOGRAM OF reviews.default
+      LATENCY     REQUESTS      PERCENT
+      <= 10ms           10        25.0%
+      <= 50ms           20        50.0%
+     <= 100ms           10        25.0%
+         +Inf            0         0.0%
+`,
+		},
+		{ // case 5
+			args: strings.Split("experimental metrics reviews@v1 reviews@v2 --compare -o json"+address, " "),
+			expectedRegexp: regexp.MustCompile(`(?s)"workload": "reviews@v1",\s+"totalRPS": 4,.*"p50LatencySeconds": 0.05,.*` +
+				`"comparison": {\s+"base": "reviews@v1",\s+"compared": "reviews@v2",\s+"totalRPSChange": "-75.0%"`),
+		},
+		{ // case 6
+			args:           strings.Split("experimental metrics reviews --compare"+address, " "),
+			expectedRegexp: regexp.MustCompile("Error: --compare requires exactly two workloads"),
+			wantException:  true,
+		},
+		{ // case 7
+			args:           strings.Split("experimental metrics reviews --by peer"+address, " "),
+			expectedRegexp: regexp.MustCompile("Error: --by must be one of source|destination"),
+			wantException:  true,
+		},
+		{ // case 8
+			args:           strings.Split("experimental metrics reviews -o yaml"+address, " "),
+			expectedRegexp: regexp.MustCompile(`Error: output format "yaml" not supported`),
+			wantException:  true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyOutput(t, c)
+		})
+	}
+}
+
 func (client mockPromAPI) Alerts(ctx context.Context) (prometheus_v1.AlertsResult, error) {
 	return prometheus_v1.AlertsResult{}, fmt.Errorf("TODO mockPromAPI doesn't mock Alerts")
 }
@@ -36,6 +36,7 @@ import (
 
 const (
 	jsonOutput    = "json"
+	yamlOutput    = "yaml"
 	summaryOutput = "short"
 )
 
@@ -271,7 +272,7 @@ func proxyConfig() *cobra.Command {
 		Aliases: []string{"pc"},
 	}
 
-	configCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|short")
+	configCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
 
 	clusterConfigCmd := &cobra.Command{
 		Use:   "cluster [<pod-name[.namespace]>]",
@@ -319,8 +320,8 @@ func proxyConfig() *cobra.Command {
 			switch outputFormat {
 			case summaryOutput:
 				return configWriter.PrintClusterSummary(filter)
-			case jsonOutput:
-				return configWriter.PrintClusterDump(filter)
+			case jsonOutput, yamlOutput:
+				return configWriter.PrintClusterDump(filter, outputFormat)
 			default:
 				return fmt.Errorf("output format %q not supported", outputFormat)
 			}
@@ -380,8 +381,8 @@ func proxyConfig() *cobra.Command {
 			switch outputFormat {
 			case summaryOutput:
 				return configWriter.PrintListenerSummary(filter)
-			case jsonOutput:
-				return configWriter.PrintListenerDump(filter)
+			case jsonOutput, yamlOutput:
+				return configWriter.PrintListenerDump(filter, outputFormat)
 			default:
 				return fmt.Errorf("output format %q not supported", outputFormat)
 			}
@@ -516,6 +517,9 @@ func proxyConfig() *cobra.Command {
   # Retrieve full route dump for route 9080
   istioctl proxy-config route <pod-name[.namespace]> --name 9080 -o json
 
+  # Retrieve the virtual hosts of the reviews service in route 9080 as YAML
+  istioctl proxy-config route <pod-name[.namespace]> --name 9080 --fqdn reviews.default.svc.cluster.local -o yaml
+
   # Retrieve route summary without using Kubernetes API
   ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
   istioctl proxy-config routes --file envoy-config.json
@@ -542,12 +546,13 @@ func proxyConfig() *cobra.Command {
 			}
 			filter := configdump.RouteFilter{
 				Name: routeName,
+				FQDN: fqdn,
 			}
 			switch outputFormat {
 			case summaryOutput:
 				return configWriter.PrintRouteSummary(filter)
-			case jsonOutput:
-				return configWriter.PrintRouteDump(filter)
+			case jsonOutput, yamlOutput:
+				return configWriter.PrintRouteDump(filter, outputFormat)
 			default:
 				return fmt.Errorf("output format %q not supported", outputFormat)
 			}
@@ -555,6 +560,7 @@ func proxyConfig() *cobra.Command {
 	}
 
 	routeConfigCmd.PersistentFlags().StringVar(&routeName, "name", "", "Filter listeners by route name field")
+	routeConfigCmd.PersistentFlags().StringVar(&fqdn, "fqdn", "", "Filter routes by the domains of their virtual hosts")
 	routeConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
 
@@ -576,6 +582,9 @@ func proxyConfig() *cobra.Command {
   # Retrieve full endpoint with the status (healthy).
   istioctl proxy-config endpoint <pod-name[.namespace]> --status healthy -ojson
 
+  # Retrieve the endpoints of subset v1 of the outbound reviews clusters as YAML.
+  istioctl proxy-config endpoint <pod-name[.namespace]> --fqdn reviews.default.svc.cluster.local --direction outbound --subset v1 -o yaml
+
   # Retrieve endpoint summary without using Kubernetes API
   ssh <user@hostname> 'curl localhost:15000/clusters?format=json' > envoy-clusters.json
   istioctl proxy-config endpoints --file envoy-clusters.json
@@ -606,13 +615,17 @@ func proxyConfig() *cobra.Command {
 				Port:    uint32(port),
 				Cluster: clusterName,
 				Status:  status,
+
+				FQDN:      host.Name(fqdn),
+				Subset:    subset,
+				Direction: model.TrafficDirection(direction),
 			}
 
 			switch outputFormat {
 			case summaryOutput:
 				return configWriter.PrintEndpointsSummary(filter)
-			case jsonOutput:
-				return configWriter.PrintEndpoints(filter)
+			case jsonOutput, yamlOutput:
+				return configWriter.PrintEndpoints(filter, outputFormat)
 			default:
 				return fmt.Errorf("output format %q not supported", outputFormat)
 			}
@@ -623,8 +636,11 @@ func proxyConfig() *cobra.Command {
 	endpointConfigCmd.PersistentFlags().IntVar(&port, "port", 0, "Filter endpoints by Port field")
 	endpointConfigCmd.PersistentFlags().StringVar(&clusterName, "cluster", "", "Filter endpoints by cluster name field")
 	endpointConfigCmd.PersistentFlags().StringVar(&status, "status", "", "Filter endpoints by status field")
+	endpointConfigCmd.PersistentFlags().StringVar(&fqdn, "fqdn", "", "Filter endpoints by substring of the Service FQDN of their cluster")
+	endpointConfigCmd.PersistentFlags().StringVar(&direction, "direction", "", "Filter endpoints by the Direction of their cluster")
+	endpointConfigCmd.PersistentFlags().StringVar(&subset, "subset", "", "Filter endpoints by substring of the Subset of their cluster")
 	endpointConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
-		"Envoy config dump JSON file")
+		"Envoy clusters JSON file (the output of /clusters?format=json)")
 
 	bootstrapConfigCmd := &cobra.Command{
 		Use:   "bootstrap [<pod-name[.namespace]>]",
@@ -636,6 +652,9 @@ func proxyConfig() *cobra.Command {
   # Retrieve full bootstrap without using Kubernetes API
   ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
   istioctl proxy-config bootstrap --file envoy-config.json
+
+  # Retrieve full bootstrap as YAML
+  istioctl proxy-config bootstrap <pod-name[.namespace]> -o yaml
 `,
 		Aliases: []string{"b"},
 		Args: func(cmd *cobra.Command, args []string) error {
@@ -657,7 +676,14 @@ func proxyConfig() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			return configWriter.PrintBootstrapDump()
+			switch outputFormat {
+			case summaryOutput, jsonOutput:
+				return configWriter.PrintBootstrapDump(jsonOutput)
+			case yamlOutput:
+				return configWriter.PrintBootstrapDump(yamlOutput)
+			default:
+				return fmt.Errorf("output format %q not supported", outputFormat)
+			}
 		},
 	}
 
@@ -700,8 +726,8 @@ THIS COMMAND IS STILL UNDER ACTIVE DEVELOPMENT AND NOT READY FOR PRODUCTION USE.
 			switch outputFormat {
 			case summaryOutput:
 				return configWriter.PrintSecretSummary()
-			case jsonOutput:
-				return configWriter.PrintSecretDump()
+			case jsonOutput, yamlOutput:
+				return configWriter.PrintSecretDump(outputFormat)
 			default:
 				return fmt.Errorf("output format %q not supported", outputFormat)
 			}
@@ -248,6 +248,32 @@ default           Cert Chain     ACTIVE      true           17232678821166591831
 172.17.0.14:15014     UNHEALTHY     OK                outbound|15014||istio-policy.istio-system.svc.cluster.local
 `,
 		},
+		{ // endpoint using --file filtered by the fields of the cluster name
+			args: strings.Split("proxy-config endpoint --file ../pkg/writer/envoy/clusters/testdata/clusters.json "+
+				"--fqdn istio-policy.istio-system --direction outbound", " "),
+			expectedOutput: `ENDPOINT              STATUS        OUTLIER CHECK     CLUSTER
+172.17.0.14:15014     UNHEALTHY     OK                outbound|15014||istio-policy.istio-system.svc.cluster.local
+`,
+		},
+		{ // routes using --file filtered by fqdn
+			args: strings.Split("proxy-config routes --file ../pkg/writer/compare/testdata/envoyconfigdump.json "+
+				"--fqdn istio-policy.istio-system", " "),
+			expectedOutput: `NOTE: This output only contains routes loaded via RDS.
+NAME      VIRTUAL HOSTS
+15004     1
+`,
+		},
+		{ // clusters using --file in yaml
+			args: strings.Split("proxy-config clusters --file ../pkg/writer/compare/testdata/envoyconfigdump.json "+
+				"--fqdn xds-grpc -o yaml", " "),
+			expectedString: "  name: xds-grpc\n  type: STRICT_DNS\n",
+		},
+		{ // bootstrap using --file with an unsupported output
+			args: strings.Split("proxy-config bootstrap --file ../pkg/writer/compare/testdata/envoyconfigdump.json "+
+				"-o xml", " "),
+			expectedString: `output format "xml" not supported`,
+			wantException:  true,
+		},
 	}
 
 	for i, c := range cases {
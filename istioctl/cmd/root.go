@@ -144,12 +144,16 @@ debug and diagnose their Istio mesh.
 	experimentalCmd.AddCommand(graduatedCmd("convert-ingress"))
 	experimentalCmd.AddCommand(graduatedCmd("dashboard"))
 	experimentalCmd.AddCommand(uninjectCommand())
-	experimentalCmd.AddCommand(metricsCmd)
+	experimentalCmd.AddCommand(metricsCmd())
 	experimentalCmd.AddCommand(describe())
 	experimentalCmd.AddCommand(addToMeshCmd())
 	experimentalCmd.AddCommand(removeFromMeshCmd())
 	experimentalCmd.AddCommand(softGraduatedCmd(Analyze()))
 	experimentalCmd.AddCommand(waitCmd())
+	experimentalCmd.AddCommand(traceRequestCmd())
+	experimentalCmd.AddCommand(bugReportCmd())
+	experimentalCmd.AddCommand(install.NewUpgradePreCheckCommand())
+	experimentalCmd.AddCommand(install.NewRevisionCommand())
 
 	postInstallCmd.AddCommand(Webhook())
 	experimentalCmd.AddCommand(postInstallCmd)
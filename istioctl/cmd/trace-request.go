@@ -0,0 +1,154 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/kubernetes"
+	"istio.io/istio/istioctl/pkg/tracerequest"
+	"istio.io/istio/istioctl/pkg/util/clusters"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+)
+
+func traceRequestCmd() *cobra.Command {
+	var (
+		endpointsFile string
+		headers       []string
+	)
+	req := &tracerequest.Request{}
+
+	cmd := &cobra.Command{
+		Use:   "trace-request [<pod-name>[.<pod-namespace>]]",
+		Short: "Simulate the routing of a request through the Envoy configuration of a pod",
+		Long: `Trace-request simulates how the Envoy sidecar or gateway of a pod routes a request, walking
+the listeners, filter chains, route configuration, virtual hosts, routes, clusters and endpoints of its
+config dump. It prints each decision along with the Istio configuration which produced it.
+
+The Envoy config dump could be provided either by pod name or from a config dump file
+(the whole output of http://localhost:15000/config_dump of an Envoy instance). In the latter case the
+endpoints can be provided with the output of http://localhost:15000/clusters?format=json.
+
+THIS COMMAND IS STILL UNDER ACTIVE DEVELOPMENT AND NOT READY FOR PRODUCTION USE.
+`,
+		Example: `  # Trace a request sent from pod productpage-v1-7bbd79f8fd-k6j79 to the reviews service:
+  istioctl x trace-request productpage-v1-7bbd79f8fd-k6j79 --host reviews --port 9080 --path /reviews/0
+
+  # Trace a request with a header through an ingress gateway config dump saved to a file:
+  istioctl x trace-request -f gateway_config_dump.json --host bookinfo.example.com --port 80 \
+    --path /productpage -H end-user=jason`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("trace-request requires only pod name")
+			}
+			if req.Host == "" || req.Port == 0 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("--host and --port are required")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req.Headers = map[string]string{}
+			for _, h := range headers {
+				kv := strings.SplitN(h, "=", 2)
+				if len(kv) != 2 {
+					return fmt.Errorf("invalid header %q, expecting key=value", h)
+				}
+				req.Headers[kv[0]] = kv[1]
+			}
+
+			var configDump *configdump.Wrapper
+			var endpoints *clusters.Wrapper
+			var err error
+			if configDumpFile != "" {
+				configDump, err = getConfigDumpFromFile(configDumpFile)
+				if err != nil {
+					return fmt.Errorf("failed to get config dump from file %s: %s", configDumpFile, err)
+				}
+				if endpointsFile != "" {
+					data, err := ioutil.ReadFile(endpointsFile)
+					if err != nil {
+						return err
+					}
+					if endpoints, err = parseClusters(data); err != nil {
+						return err
+					}
+				}
+			} else if len(args) == 1 {
+				podName, podNamespace := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
+				configDump, err = getConfigDumpFromPod(podName, podNamespace)
+				if err != nil {
+					return fmt.Errorf("failed to get config dump from pod %s", args[0])
+				}
+				endpoints, err = getClustersFromPod(podName, podNamespace)
+				if err != nil {
+					return err
+				}
+			} else {
+				return fmt.Errorf("expecting pod name or config dump, found: %d", len(args))
+			}
+
+			tracer, err := tracerequest.NewTracer(configDump, endpoints)
+			if err != nil {
+				return err
+			}
+			tracer.Trace(req).Print(cmd.OutOrStdout())
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
+		"Envoy config dump JSON file")
+	cmd.PersistentFlags().StringVar(&endpointsFile, "endpoints-file", "",
+		"Envoy clusters JSON file (the output of /clusters?format=json), used with --file")
+	cmd.PersistentFlags().StringVar(&req.Host, "host", "", "Host of the request")
+	cmd.PersistentFlags().Uint32Var(&req.Port, "port", 0, "Destination port of the request")
+	cmd.PersistentFlags().StringVar(&req.Path, "path", "/", "Path of the request")
+	cmd.PersistentFlags().StringVar(&req.Method, "method", "GET", "HTTP method of the request")
+	cmd.PersistentFlags().StringArrayVarP(&headers, "header", "H", nil,
+		"Header of the request as key=value, can be repeated")
+	cmd.PersistentFlags().StringVar(&req.SourceIP, "source-ip", "", "Source IP address of the request")
+	cmd.PersistentFlags().StringVar(&req.DestinationIP, "destination-ip", "",
+		"Original destination IP address of the request, e.g. the service cluster IP")
+	cmd.PersistentFlags().StringVar(&req.SNI, "sni", "", "Server name of a TLS connection")
+
+	return cmd
+}
+
+func getClustersFromPod(podName, podNamespace string) (*clusters.Wrapper, error) {
+	kubeClient, err := kubernetes.NewClient(kubeconfig, configContext)
+	if err != nil {
+		return nil, err
+	}
+	data, err := kubeClient.EnvoyDo(podName, podNamespace, "GET", "clusters?format=json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clusters for %s.%s: %s", podName, podNamespace, err)
+	}
+	return parseClusters(data)
+}
+
+func parseClusters(data []byte) (*clusters.Wrapper, error) {
+	endpoints := &clusters.Wrapper{}
+	if err := endpoints.UnmarshalJSON(data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal clusters: %s", err)
+	}
+	return endpoints, nil
+}
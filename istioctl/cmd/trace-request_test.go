@@ -0,0 +1,52 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTraceRequest(t *testing.T) {
+	testCases := []struct {
+		name   string
+		in     string
+		args   string
+		golden string
+	}{
+		{
+			name:   "gateway virtual service",
+			in:     "testdata/describe/istio-ingressgateway-5bf6c9887-vvvmj.json",
+			args:   "--host bookinfo.example.com --port 80 --path /productpage",
+			golden: "testdata/trace-request/gateway.golden",
+		},
+		{
+			name:   "sidecar outbound",
+			in:     "testdata/describe/productpage-v1-7bbd79f8fd-k6j79.json",
+			args:   "--host reviews --port 9080 --path /reviews/0 -H end-user=jason",
+			golden: "testdata/trace-request/sidecar.golden",
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			command := fmt.Sprintf("experimental trace-request -f %s %s", c.in, c.args)
+			runCommandWantOutput(command, c.golden, t)
+		})
+	}
+
+	runCommandWantError("experimental trace-request -f testdata/describe/ratings-v1-f745cf57b-vfwcv.json",
+		"--host and --port are required", t)
+}
@@ -19,6 +19,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,6 +34,7 @@ import (
 	"istio.io/istio/galley/pkg/config/schema/collection"
 	"istio.io/istio/galley/pkg/config/schema/collections"
 	"istio.io/istio/istioctl/pkg/kubernetes"
+	"istio.io/istio/istioctl/pkg/util/clusters"
 	"istio.io/istio/istioctl/pkg/util/handlers"
 	"istio.io/istio/pilot/pkg/model"
 	v2 "istio.io/istio/pilot/pkg/proxy/envoy/v2"
@@ -45,92 +49,92 @@ var (
 	verbose         bool
 	targetSchema    collection.Schema
 	clientGetter    func(string, string) (dynamic.Interface, error)
+
+	proxySelector string
+	revision      string
 )
 
-const pollInterval = time.Second
+const (
+	pollInterval = time.Second
+
+	// revisionLabel is the label of the pods identifying the revision of the control plane managing their proxy.
+	revisionLabel = "istio.io/rev"
+)
 
 // waitCmd represents the wait command
 func waitCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "wait [flags] <type> <name>[.<namespace>]",
+		Use:   "wait [flags] <type> [<name>[.<namespace>]]",
 		Short: "Wait for an Istio resource",
-		Long:  `Waits for the specified condition to be true of an Istio resource.`,
+		Long: `Waits for the specified condition to be true of an Istio resource.
+
+Without a name, waits for all the resources of the type in the namespace, optionally
+restricted by a label selector. The proxies taken into account can be restricted with
+a pod label selector or the revision of their control plane.
+
+With --for=endpoints, waits until the proxies have received the endpoints of a
+Kubernetes service through EDS.`,
 		Example: `
 # Wait until the bookinfo virtual service has been distributed to all proxies in the mesh
 istioctl experimental wait --for=distribution virtualservice bookinfo.default
 
 # Wait until 99% of the proxies receive the distribution, timing out after 5 minutes
 istioctl experimental wait --for=distribution --threshold=.99 --timeout=300 virtualservice bookinfo.default
+
+# Wait until all the virtual services labeled app=reviews in the default namespace have been distributed
+istioctl experimental wait -n default -l app=reviews virtualservice
+
+# Wait until the destination rules of the default namespace have been distributed to the productpage proxies
+istioctl experimental wait -n default --proxy-selector app=productpage destinationrule
+
+# Wait until the productpage proxies have received the endpoints of the reviews service
+istioctl experimental wait --for=endpoints --proxy-selector app=productpage service reviews.default
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			printVerbosef(cmd, "kubeconfig %s", kubeconfig)
 			printVerbosef(cmd, "ctx %s", configContext)
-			if forFlag == "delete" {
-				return errors.New("wait for delete is not yet implemented")
-			} else if forFlag != "distribution" {
-				return fmt.Errorf("--for must be 'delete' or 'distribution', got: %s", forFlag)
-			}
-			var w *watcher
 			ctx, cancel := context.WithTimeout(context.Background(), timeout)
 			defer cancel()
-			if resourceVersion == "" {
-				w = getAndWatchResource(ctx) // setup version getter from kubernetes
-			} else {
-				w = withContext(ctx)
-				w.Go(func(result chan string) error {
-					result <- resourceVersion
-					return nil
-				})
-			}
-			// wait for all deployed versions to be contained in resourceVersions
-			t := time.NewTicker(pollInterval)
-			printVerbosef(cmd, "getting first version from chan")
-			firstVersion, err := w.BlockingRead()
-			if err != nil {
-				return fmt.Errorf("unable to retrieve kubernetes resource %s: %v", "", err)
-			}
-			resourceVersions := []string{firstVersion}
-			targetResource := model.Key(targetSchema.Resource().Kind(), nameflag, namespace)
-			for {
-				//run the check here as soon as we start
-				// because tickers won't run immediately
-				present, notpresent, err := poll(resourceVersions, targetResource)
-				printVerbosef(cmd, "Received poll result: %d/%d", present, present+notpresent)
-				if err != nil {
-					return err
-				} else if float32(present)/float32(present+notpresent) >= threshold {
-					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Resource %s present on %d out of %d sidecars\n",
-						targetResource, present, present+notpresent)
-					return nil
-				}
-				select {
-				case newVersion := <-w.resultsChan:
-					printVerbosef(cmd, "received new target version: %s", newVersion)
-					resourceVersions = append(resourceVersions, newVersion)
-				case <-t.C:
-					printVerbosef(cmd, "tick")
-					continue
-				case err = <-w.errorChan:
-					return fmt.Errorf("unable to retrieve kubernetes resource %s: %v", "", err)
-				case <-ctx.Done():
-					printVerbosef(cmd, "timeout")
-					// I think this means the timeout has happened:
-					t.Stop()
-					return fmt.Errorf("timeout expired before resource %s became effective on all sidecars",
-						targetResource)
-				}
+			switch forFlag {
+			case "delete":
+				return errors.New("wait for delete is not yet implemented")
+			case "distribution":
+				return waitForDistribution(ctx, cmd)
+			case "endpoints":
+				return waitForEndpoints(ctx, cmd)
+			default:
+				return fmt.Errorf("--for must be 'delete', 'distribution' or 'endpoints', got: %s", forFlag)
 			}
 		},
 		Args: func(cmd *cobra.Command, args []string) error {
-			if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+			if err := cobra.RangeArgs(1, 2)(cmd, args); err != nil {
 				return err
 			}
-			nameflag, namespace = handlers.InferPodInfo(args[1], handlers.HandleNamespace(namespace, defaultNamespace))
+			nameflag = ""
+			namespace = handlers.HandleNamespace(namespace, defaultNamespace)
+			if len(args) == 2 {
+				nameflag, namespace = handlers.InferPodInfo(args[1], namespace)
+			}
+			if nameflag != "" && labelSelector != "" {
+				return errors.New("a resource name can't be used with --selector")
+			}
+			if nameflag == "" && resourceVersion != "" {
+				return errors.New("--resource-version requires a resource name")
+			}
+			if proxySelector != "" && revision != "" {
+				return errors.New("--proxy-selector can't be used with --revision")
+			}
+			if forFlag == "endpoints" {
+				if !strings.EqualFold(args[0], "service") || nameflag == "" {
+					return errors.New("--for=endpoints requires a service name, e.g. service reviews.default")
+				}
+				return nil
+			}
 			return validateType(args[0])
 		},
 	}
 	cmd.PersistentFlags().StringVar(&forFlag, "for", "distribution",
-		"wait condition, must be 'distribution' or 'delete'")
+		"wait condition, must be 'distribution', 'endpoints' or 'delete'")
 	cmd.PersistentFlags().DurationVar(&timeout, "timeout", time.Second*30,
 		"the duration to wait before failing")
 	cmd.PersistentFlags().Float32Var(&threshold, "threshold", 1,
@@ -138,11 +142,119 @@ istioctl experimental wait --for=distribution --threshold=.99 --timeout=300 virt
 	cmd.PersistentFlags().StringVar(&resourceVersion, "resource-version", "",
 		"wait for a specific version of config to become current, rather than using whatever is latest in "+
 			"kubernetes")
+	cmd.PersistentFlags().StringVarP(&labelSelector, "selector", "l", "",
+		"label selector of the resources to wait for when no name is given")
+	cmd.PersistentFlags().StringVar(&proxySelector, "proxy-selector", "",
+		"label selector of the pods whose proxies must receive the resources, instead of all the proxies")
+	cmd.PersistentFlags().StringVar(&revision, "revision", "",
+		"only wait for the proxies managed by this revision of the control plane")
 	cmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enables verbose output")
 	_ = cmd.PersistentFlags().MarkHidden("verbose")
 	return cmd
 }
 
+// waitForDistribution waits until the latest versions of the target resources have been acked by the proxies.
+func waitForDistribution(ctx context.Context, cmd *cobra.Command) error {
+	var w *watcher
+	if resourceVersion == "" {
+		w = getAndWatchResource(ctx) // setup version getter from kubernetes
+	} else {
+		w = withContext(ctx)
+		w.Go(func(result chan map[string]string) error {
+			result <- map[string]string{nameflag: resourceVersion}
+			return nil
+		})
+	}
+	proxies, err := targetProxies()
+	if err != nil {
+		return err
+	}
+	// wait for all deployed versions to be contained in resourceVersions
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+	printVerbosef(cmd, "getting first version from chan")
+	firstVersions, err := w.BlockingRead()
+	if err != nil {
+		return fmt.Errorf("unable to retrieve kubernetes resource %s: %v", "", err)
+	}
+	if len(firstVersions) == 0 {
+		return fmt.Errorf("no %s found in namespace %s matching selector %q",
+			targetSchema.Resource().Kind(), namespace, labelSelector)
+	}
+	// resourceVersions are the accepted versions of each target resource, by name
+	resourceVersions := map[string][]string{}
+	for name, version := range firstVersions {
+		resourceVersions[name] = []string{version}
+	}
+	for {
+		//run the check here as soon as we start
+		// because tickers won't run immediately
+		present, notpresent, err := pollResources(resourceVersions, proxies)
+		printVerbosef(cmd, "Received poll result: %d/%d", present, present+notpresent)
+		if err != nil {
+			return err
+		} else if present+notpresent > 0 && float32(present)/float32(present+notpresent) >= threshold {
+			d := describeResources(resourceVersions)
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s present on %d out of %d sidecars\n",
+				strings.ToUpper(d[:1])+d[1:], present, present+notpresent)
+			return nil
+		}
+		select {
+		case newVersions := <-w.resultsChan:
+			for name, version := range newVersions {
+				printVerbosef(cmd, "received new target version of %s: %s", name, version)
+				resourceVersions[name] = append(resourceVersions[name], version)
+			}
+		case <-t.C:
+			printVerbosef(cmd, "tick")
+			continue
+		case err = <-w.errorChan:
+			return fmt.Errorf("unable to retrieve kubernetes resource %s: %v", "", err)
+		case <-ctx.Done():
+			printVerbosef(cmd, "timeout")
+			return fmt.Errorf("timeout expired before %s became effective on all sidecars",
+				describeResources(resourceVersions))
+		}
+	}
+}
+
+// waitForEndpoints waits until the proxies have the ready endpoints of the target service in their clusters.
+func waitForEndpoints(ctx context.Context, cmd *cobra.Command) error {
+	proxies, err := targetProxies()
+	if err != nil {
+		return err
+	}
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+	for {
+		present, notpresent, err := pollEndpoints(proxies)
+		printVerbosef(cmd, "Received poll result: %d/%d", present, present+notpresent)
+		if err != nil {
+			return err
+		} else if present+notpresent > 0 && float32(present)/float32(present+notpresent) >= threshold {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Endpoints of service %s.%s present on %d out of %d sidecars\n",
+				nameflag, namespace, present, present+notpresent)
+			return nil
+		}
+		select {
+		case <-t.C:
+			printVerbosef(cmd, "tick")
+		case <-ctx.Done():
+			printVerbosef(cmd, "timeout")
+			return fmt.Errorf("timeout expired before the endpoints of service %s.%s became effective on all sidecars",
+				nameflag, namespace)
+		}
+	}
+}
+
+// describeResources describes the target resources in the messages of the command.
+func describeResources(resourceVersions map[string][]string) string {
+	if nameflag != "" {
+		return fmt.Sprintf("resource %s", model.Key(targetSchema.Resource().Kind(), nameflag, namespace))
+	}
+	return fmt.Sprintf("%d %s resources in namespace %s", len(resourceVersions), targetSchema.Resource().Kind(), namespace)
+}
+
 func printVerbosef(cmd *cobra.Command, template string, args ...interface{}) {
 	if verbose {
 		_, _ = fmt.Fprintf(cmd.OutOrStdout(), template+"\n", args...)
@@ -164,49 +276,181 @@ func validateType(kind string) error {
 	return fmt.Errorf("type %s is not recognized", originalKind)
 }
 
-func countVersions(versionCount map[string]int, configVersion string) {
-	if count, ok := versionCount[configVersion]; ok {
-		versionCount[configVersion] = count + 1
-	} else {
-		versionCount[configVersion] = 1
+// targetProxies returns the IDs of the proxies selected by --proxy-selector or --revision, or nil for all the proxies.
+func targetProxies() (map[string]bool, error) {
+	selector := proxySelector
+	if revision != "" {
+		selector = fmt.Sprintf("%s=%s", revisionLabel, revision)
+	}
+	if selector == "" {
+		return nil, nil
+	}
+	client, err := interfaceFactory(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the pods matching %q: %v", selector, err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, fmt.Errorf("no pods found matching %q", selector)
+	}
+	proxies := make(map[string]bool, len(pods.Items))
+	for _, pod := range pods.Items {
+		proxies[fmt.Sprintf("%s.%s", pod.Name, pod.Namespace)] = true
 	}
+	return proxies, nil
 }
 
-func poll(acceptedVersions []string, targetResource string) (present, notpresent int, err error) {
+// pollResources returns the number of proxies which have acked one of the accepted versions of all the target resources
+// and the number of those which haven't. The selected proxies which aren't connected to Pilot are counted as not present.
+func pollResources(resourceVersions map[string][]string, proxies map[string]bool) (present, notpresent int, err error) {
+	missing := map[string]bool{}
+	seen := map[string]bool{}
+	for name, acceptedVersions := range resourceVersions {
+		targetResource := model.Key(targetSchema.Resource().Kind(), name, namespace)
+		syncedVersions, err := poll(targetResource)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, v := range syncedVersions {
+			if proxies != nil && !proxies[v.ProxyID] {
+				continue
+			}
+			seen[v.ProxyID] = true
+			if !contains(acceptedVersions, v.ClusterVersion) || !contains(acceptedVersions, v.RouteVersion) ||
+				!contains(acceptedVersions, v.ListenerVersion) {
+				missing[v.ProxyID] = true
+			}
+		}
+	}
+	for proxy := range proxies {
+		if !seen[proxy] {
+			seen[proxy] = true
+			missing[proxy] = true
+		}
+	}
+	return len(seen) - len(missing), len(missing), nil
+}
+
+func poll(targetResource string) ([]v2.SyncedVersions, error) {
 	kubeClient, err := clientExecFactory(kubeconfig, configContext)
 	if err != nil {
-		return 0, 0, err
+		return nil, err
 	}
 	path := fmt.Sprintf("/debug/config_distribution?resource=%s", targetResource)
 	pilotResponses, err := kubeClient.AllPilotsDiscoveryDo(istioNamespace, "GET", path, nil)
 	if err != nil {
-		return 0, 0, fmt.Errorf("unable to query pilot for distribution "+
+		return nil, fmt.Errorf("unable to query pilot for distribution "+
 			"(are you using pilot version >= 1.4 with config distribution tracking on): %s", err)
 	}
-	versionCount := make(map[string]int)
+	var syncedVersions []v2.SyncedVersions
 	for _, response := range pilotResponses {
 		var configVersions []v2.SyncedVersions
 		err = json.Unmarshal(response, &configVersions)
 		if err != nil {
-			return 0, 0, err
+			return nil, err
 		}
-		for _, configVersion := range configVersions {
-			countVersions(versionCount, configVersion.ClusterVersion)
-			countVersions(versionCount, configVersion.RouteVersion)
-			countVersions(versionCount, configVersion.ListenerVersion)
+		syncedVersions = append(syncedVersions, configVersions...)
+	}
+	return syncedVersions, nil
+}
+
+// pollEndpoints returns the number of proxies whose clusters of the target service contain exactly its ready
+// endpoints, and the number of those whose clusters don't.
+func pollEndpoints(proxies map[string]bool) (present, notpresent int, err error) {
+	client, err := interfaceFactory(kubeconfig)
+	if err != nil {
+		return 0, 0, err
+	}
+	endpoints, err := client.CoreV1().Endpoints(namespace).Get(nameflag, metav1.GetOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to retrieve the endpoints of service %s.%s: %v", nameflag, namespace, err)
+	}
+	expected := map[string]bool{}
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			for _, port := range subset.Ports {
+				expected[net.JoinHostPort(address.IP, strconv.Itoa(int(port.Port)))] = true
+			}
 		}
 	}
 
-	for version, count := range versionCount {
-		if contains(acceptedVersions, version) {
-			present += count
+	kubeClient, err := clientExecFactory(kubeconfig, configContext)
+	if err != nil {
+		return 0, 0, err
+	}
+	if proxies == nil {
+		if proxies, err = connectedProxies(kubeClient); err != nil {
+			return 0, 0, err
+		}
+	}
+	for proxy := range proxies {
+		parts := strings.SplitN(proxy, ".", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		response, err := kubeClient.EnvoyDo(parts[0], parts[1], "GET", "clusters?format=json", nil)
+		if err != nil {
+			notpresent++
+			continue
+		}
+		actual, err := serviceEndpoints(response)
+		if err != nil {
+			return 0, 0, err
+		}
+		if reflect.DeepEqual(expected, actual) {
+			present++
 		} else {
-			notpresent += count
+			notpresent++
 		}
 	}
 	return present, notpresent, nil
 }
 
+// connectedProxies returns the IDs of the proxies connected to Pilot.
+func connectedProxies(kubeClient kubernetes.ExecClient) (map[string]bool, error) {
+	pilotResponses, err := kubeClient.AllPilotsDiscoveryDo(istioNamespace, "GET", "/debug/syncz", nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query pilot for the connected proxies: %v", err)
+	}
+	proxies := map[string]bool{}
+	for _, response := range pilotResponses {
+		var statuses []v2.SyncStatus
+		if err := json.Unmarshal(response, &statuses); err != nil {
+			return nil, err
+		}
+		for _, status := range statuses {
+			proxies[status.ProxyID] = true
+		}
+	}
+	return proxies, nil
+}
+
+// serviceEndpoints returns the addresses of the endpoints of the outbound clusters of the target service, given the
+// output of the Envoy /clusters?format=json admin endpoint.
+func serviceEndpoints(response []byte) (map[string]bool, error) {
+	cw := clusters.Wrapper{}
+	if err := json.Unmarshal(response, &cw); err != nil {
+		return nil, fmt.Errorf("error unmarshalling clusters response from Envoy: %v", err)
+	}
+	hostPrefix := fmt.Sprintf("%s.%s.svc.", nameflag, namespace)
+	addresses := map[string]bool{}
+	for _, cs := range cw.ClusterStatuses {
+		direction, subset, hostname, _ := model.ParseSubsetKey(cs.Name)
+		if direction != model.TrafficDirectionOutbound || subset != "" || !strings.HasPrefix(string(hostname), hostPrefix) {
+			continue
+		}
+		for _, hs := range cs.HostStatuses {
+			if addr := hs.Address.GetSocketAddress(); addr != nil {
+				addresses[net.JoinHostPort(addr.Address, strconv.Itoa(int(addr.GetPortValue())))] = true
+			}
+		}
+	}
+	return addresses, nil
+}
+
 func init() {
 	clientGetter = func(kubeconfig, context string) (dynamic.Interface, error) {
 		baseClient, err := kubernetes.NewClient(kubeconfig, context)
@@ -224,11 +468,12 @@ func init() {
 }
 
 // getAndWatchResource ensures that ResourceVersions always contains
-// the current resourceVersion of the targetResource, adding new versions
-// as they are created.
+// the current resourceVersion of the target resources, adding new versions
+// as they are created. The first result contains the versions of all the
+// target resources by name, the next ones the versions of the updated ones.
 func getAndWatchResource(ictx context.Context) *watcher {
 	g := withContext(ictx)
-	g.Go(func(result chan string) error {
+	g.Go(func(result chan map[string]string) error {
 		// retrieve resource version from Kubernetes
 		dclient, err := clientGetter(kubeconfig, configContext)
 		if err != nil {
@@ -239,13 +484,27 @@ func getAndWatchResource(ictx context.Context) *watcher {
 		version := targetSchema.Resource().Version()
 		resource := collectionParts[3]
 		r := dclient.Resource(schema.GroupVersionResource{Group: group, Version: version, Resource: resource}).Namespace(namespace)
-		obj, err := r.Get(nameflag, metav1.GetOptions{})
-		if err != nil {
-			return err
+		var localResourceVersion string
+		if nameflag != "" {
+			obj, err := r.Get(nameflag, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			localResourceVersion = obj.GetResourceVersion()
+			result <- map[string]string{nameflag: localResourceVersion}
+		} else {
+			list, err := r.List(metav1.ListOptions{LabelSelector: labelSelector})
+			if err != nil {
+				return err
+			}
+			versions := make(map[string]string, len(list.Items))
+			for _, obj := range list.Items {
+				versions[obj.GetName()] = obj.GetResourceVersion()
+			}
+			localResourceVersion = list.GetResourceVersion()
+			result <- versions
 		}
-		localResourceVersion := obj.GetResourceVersion()
-		result <- localResourceVersion
-		watch, err := r.Watch(metav1.ListOptions{ResourceVersion: localResourceVersion})
+		watch, err := r.Watch(metav1.ListOptions{ResourceVersion: localResourceVersion, LabelSelector: labelSelector})
 		if err != nil {
 			return err
 		}
@@ -255,12 +514,12 @@ func getAndWatchResource(ictx context.Context) *watcher {
 			if err != nil {
 				return err
 			}
-			if watchname == nameflag {
+			if nameflag == "" || watchname == nameflag {
 				newVersion, err := metaAccessor.ResourceVersion(w.Object)
 				if err != nil {
 					return err
 				}
-				result <- newVersion
+				result <- map[string]string{watchname: newVersion}
 			}
 			select {
 			case <-ictx.Done():
@@ -276,20 +535,20 @@ func getAndWatchResource(ictx context.Context) *watcher {
 }
 
 type watcher struct {
-	resultsChan chan string
+	resultsChan chan map[string]string
 	errorChan   chan error
 	ctx         context.Context
 }
 
 func withContext(ctx context.Context) *watcher {
 	return &watcher{
-		resultsChan: make(chan string, 1),
+		resultsChan: make(chan map[string]string, 1),
 		errorChan:   make(chan error, 1),
 		ctx:         ctx,
 	}
 }
 
-func (w *watcher) Go(f func(chan string) error) {
+func (w *watcher) Go(f func(chan map[string]string) error) {
 	go func() {
 		if err := f(w.resultsChan); err != nil {
 			w.errorChan <- err
@@ -297,13 +556,13 @@ func (w *watcher) Go(f func(chan string) error) {
 	}()
 }
 
-func (w *watcher) BlockingRead() (string, error) {
+func (w *watcher) BlockingRead() (map[string]string, error) {
 	select {
 	case err := <-w.errorChan:
-		return "", err
+		return nil, err
 	case res := <-w.resultsChan:
 		return res, nil
 	case <-w.ctx.Done():
-		return "", w.ctx.Err()
+		return nil, w.ctx.Err()
 	}
 }
@@ -20,6 +20,8 @@ import (
 	"strings"
 	"testing"
 
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
@@ -76,6 +78,162 @@ func TestWaitCmd(t *testing.T) {
 
 	_ = setupK8Sfake()
 
+	cases = append(cases, []execTestCase{
+		{
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait -n default -l app=foo virtualservice", " "),
+			wantException:    false,
+			expectedOutput:   "1 VirtualService resources in namespace default present on 1 out of 1 sidecars\n",
+		},
+		{
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait -n default --timeout 2s virtualservice", " "),
+			wantException:    true,
+			expectedOutput: "Error: timeout expired before 2 VirtualService resources in namespace default " +
+				"became effective on all sidecars\n",
+		},
+		{
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait -n default -l app=none virtualservice", " "),
+			wantException:    true,
+			expectedOutput:   "Error: no VirtualService found in namespace default matching selector \"app=none\"\n",
+		},
+		{
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait -l app=foo virtualservice foo.default", " "),
+			wantException:    true,
+		},
+	}...)
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecTestOutput(t, c)
+		})
+	}
+}
+
+func TestWaitCmdProxies(t *testing.T) {
+	cannedResponseObj := []v2.SyncedVersions{
+		{
+			ProxyID:         "productpage-v1.default",
+			ClusterVersion:  "1",
+			ListenerVersion: "1",
+			RouteVersion:    "1",
+		},
+		{
+			ProxyID:         "reviews-v1.default",
+			ClusterVersion:  "1",
+			ListenerVersion: "1",
+			RouteVersion:    "",
+		},
+	}
+	cannedResponse, _ := json.Marshal(cannedResponseObj)
+	cannedResponseMap := map[string][]byte{"onlyonepilot": cannedResponse}
+
+	_ = setupK8Sfake()
+	interfaceFactory = mockInterfaceFactoryGenerator([]runtime.Object{
+		newPod("productpage-v1", map[string]string{"app": "productpage", "istio.io/rev": "canary"}),
+		newPod("reviews-v1", map[string]string{"app": "reviews"}),
+		newPod("ratings-v1", map[string]string{"app": "ratings"}),
+	})
+	defer func() { interfaceFactory = createInterface }()
+
+	cases := []execTestCase{
+		{
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait --proxy-selector app=productpage virtualservice foo.default", " "),
+			wantException:    false,
+			expectedOutput:   "Resource VirtualService/default/foo present on 1 out of 1 sidecars\n",
+		},
+		{
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait --revision canary virtualservice foo.default", " "),
+			wantException:    false,
+			expectedOutput:   "Resource VirtualService/default/foo present on 1 out of 1 sidecars\n",
+		},
+		{
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait --threshold 0.5 virtualservice foo.default", " "),
+			wantException:    false,
+			expectedOutput:   "Resource VirtualService/default/foo present on 1 out of 2 sidecars\n",
+		},
+		{
+			// reviews-v1 hasn't acked the routes of the resource
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait --timeout 2s --proxy-selector app=reviews virtualservice foo.default", " "),
+			wantException:    true,
+		},
+		{
+			// ratings-v1 isn't connected to Pilot
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait --timeout 2s --proxy-selector app=ratings virtualservice foo.default", " "),
+			wantException:    true,
+		},
+		{
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait --proxy-selector app=details virtualservice foo.default", " "),
+			wantException:    true,
+			expectedOutput:   "Error: no pods found matching \"app=details\"\n",
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecTestOutput(t, c)
+		})
+	}
+}
+
+func TestWaitCmdEndpoints(t *testing.T) {
+	endpoints := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "reviews", Namespace: "default"},
+		Subsets: []v1.EndpointSubset{{
+			Addresses: []v1.EndpointAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.2"}},
+			Ports:     []v1.EndpointPort{{Port: 9080}},
+		}},
+	}
+	interfaceFactory = mockInterfaceFactoryGenerator([]runtime.Object{
+		endpoints,
+		newPod("productpage-v1", map[string]string{"app": "productpage"}),
+		newPod("ratings-v1", map[string]string{"app": "ratings"}),
+	})
+	defer func() { interfaceFactory = createInterface }()
+
+	clustersResponse := func(addresses ...string) []byte {
+		hosts := make([]string, 0, len(addresses))
+		for _, address := range addresses {
+			hosts = append(hosts, fmt.Sprintf(`{"address":{"socket_address":{"address":%q,"port_value":9080}}}`, address))
+		}
+		return []byte(`{"cluster_statuses":[` +
+			`{"name":"outbound|9080||reviews.default.svc.cluster.local","host_statuses":[` + strings.Join(hosts, ",") + `]},` +
+			`{"name":"outbound|9080|v1|reviews.default.svc.cluster.local","host_statuses":[` + hosts[0] + `]},` +
+			`{"name":"inbound|9080|http|productpage.default.svc.cluster.local"}]}`)
+	}
+	cannedResponseMap := map[string][]byte{
+		"productpage-v1": clustersResponse("10.0.0.2", "10.0.0.1"),
+		"ratings-v1":     clustersResponse("10.0.0.1"),
+	}
+
+	cases := []execTestCase{
+		{
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait --for=endpoints --proxy-selector app=productpage service reviews.default", " "),
+			wantException:    false,
+			expectedOutput:   "Endpoints of service reviews.default present on 1 out of 1 sidecars\n",
+		},
+		{
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait --for=endpoints --timeout 2s --proxy-selector app=ratings service reviews.default", " "),
+			wantException:    true,
+			expectedOutput:   "Error: timeout expired before the endpoints of service reviews.default became effective on all sidecars\n",
+		},
+		{
+			execClientConfig: cannedResponseMap,
+			args:             strings.Split("x wait --for=endpoints virtualservice reviews.default", " "),
+			wantException:    true,
+		},
+	}
+
 	for i, c := range cases {
 		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
 			verifyExecTestOutput(t, c)
@@ -83,9 +241,15 @@ func TestWaitCmd(t *testing.T) {
 	}
 }
 
+func newPod(name string, labels map[string]string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}}
+}
+
 func setupK8Sfake() *fake.FakeDynamicClient {
+	foo := newUnstructured("networking.istio.io/v1alpha3", "virtualservice", "default", "foo", "1")
+	foo.SetLabels(map[string]string{"app": "foo"})
 	objs := []runtime.Object{
-		newUnstructured("networking.istio.io/v1alpha3", "virtualservice", "default", "foo", "1"),
+		foo,
 		newUnstructured("networking.istio.io/v1alpha3", "virtualservice", "default", "bar", "3"),
 	}
 	client := fake.NewSimpleDynamicClient(runtime.NewScheme(), objs...)
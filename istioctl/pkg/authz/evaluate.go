@@ -0,0 +1,523 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	rbac "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v2"
+	envoy_matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+
+	authn_model "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/istio/pkg/spiffe"
+)
+
+const (
+	attrSrcPrincipal     = "source.principal"
+	attrRequestPrincipal = "request.auth.principal"
+	attrRequestAudiences = "request.auth.audiences"
+	attrRequestPresenter = "request.auth.presenter"
+	attrRequestClaims    = "request.auth.claims"
+)
+
+// Request is a synthetic request evaluated against the RBAC filters of a proxy.
+type Request struct {
+	// Port is the port the request is sent to, used to select the inbound listener.
+	Port uint32
+	// SourcePrincipal is the peer identity of a mutual TLS connection, e.g. "cluster.local/ns/default/sa/sleep".
+	SourcePrincipal string
+	// SourceNamespace is used to derive the peer identity, with the default service account, when
+	// SourcePrincipal is not set.
+	SourceNamespace string
+	// SourceIP is the IP address of the client.
+	SourceIP string
+	// SNI is the requested server name.
+	SNI string
+	// Host, Method, Path and Headers describe the HTTP request. They are ignored for TCP listeners.
+	Host    string
+	Method  string
+	Path    string
+	Headers map[string]string
+	// RequestPrincipal is the principal of the request credential, e.g. "issuer/subject". It defaults to
+	// the "iss" and "sub" claims.
+	RequestPrincipal string
+	// Claims are the claims of the request credential.
+	Claims map[string][]string
+}
+
+func (r *Request) sourcePrincipal() string {
+	if r.SourcePrincipal != "" {
+		return strings.TrimPrefix(r.SourcePrincipal, spiffe.URIPrefix)
+	}
+	if r.SourceNamespace != "" {
+		return fmt.Sprintf("cluster.local/ns/%s/sa/default", r.SourceNamespace)
+	}
+	return ""
+}
+
+func (r *Request) requestPrincipal() string {
+	if r.RequestPrincipal != "" {
+		return r.RequestPrincipal
+	}
+	if iss, sub := r.claim("iss"), r.claim("sub"); iss != "" && sub != "" {
+		return iss + "/" + sub
+	}
+	return ""
+}
+
+func (r *Request) claim(name string) string {
+	if values := r.Claims[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (r *Request) headers() map[string]string {
+	method := r.Method
+	if method == "" {
+		method = "GET"
+	}
+	path := r.Path
+	if path == "" {
+		path = "/"
+	}
+	out := map[string]string{
+		":method":    method,
+		":path":      path,
+		":authority": r.Host,
+	}
+	for k, v := range r.Headers {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+// metadata returns the dynamic metadata emitted by the Istio authentication filter for the request.
+func (r *Request) metadata() map[string]*structpb.Struct {
+	fields := map[string]*structpb.Value{}
+	setString := func(key, value string) {
+		if value != "" {
+			fields[key] = &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: value}}
+		}
+	}
+	setString(attrSrcPrincipal, r.sourcePrincipal())
+	setString(attrRequestPrincipal, r.requestPrincipal())
+	setString(attrRequestAudiences, r.claim("aud"))
+	setString(attrRequestPresenter, r.claim("azp"))
+	if len(r.Claims) > 0 {
+		claims := map[string]*structpb.Value{}
+		for name, values := range r.Claims {
+			list := &structpb.ListValue{}
+			for _, v := range values {
+				list.Values = append(list.Values, &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: v}})
+			}
+			claims[name] = &structpb.Value{Kind: &structpb.Value_ListValue{ListValue: list}}
+		}
+		fields[attrRequestClaims] = &structpb.Value{Kind: &structpb.Value_StructValue{
+			StructValue: &structpb.Struct{Fields: claims}}}
+	}
+	return map[string]*structpb.Struct{authn_model.AuthnFilterName: {Fields: fields}}
+}
+
+// FilterResult is the result of evaluating the rules of one RBAC filter.
+type FilterResult struct {
+	// Filter is the name of the filter, with its index in the filter chain.
+	Filter string
+	// Shadow is true for the shadow rules, which are only logged and never enforced.
+	Shadow bool
+	// Action is the action of the rules, ALLOW or DENY.
+	Action string
+	// Allowed is whether the rules allow the request.
+	Allowed bool
+	// Policy is the policy which matched the request, if any.
+	Policy string
+}
+
+// Evaluation is the result of evaluating a request against the RBAC filters of a listener.
+type Evaluation struct {
+	Listener string
+	Request  *Request
+	HTTP     bool
+	Results  []FilterResult
+	// Allowed is the final decision.
+	Allowed bool
+	// DeniedBy is the enforced result which denied the request, if any.
+	DeniedBy *FilterResult
+}
+
+// Evaluate evaluates the request against the RBAC filters of the inbound listener handling its port.
+func (a *Analyzer) Evaluate(req *Request) (*Evaluation, error) {
+	var listener *ParsedListener
+	for _, l := range a.getParsedListeners() {
+		if l.port != strconv.Itoa(int(req.Port)) {
+			continue
+		}
+		// Prefer the inbound listener bound to the node IP over the outbound wildcard listener.
+		if listener == nil || l.ip == a.nodeIP {
+			listener = l
+		}
+	}
+	if listener == nil || len(listener.filterChains) == 0 {
+		return nil, fmt.Errorf("no listener found for port %d with node IP %s", req.Port, a.nodeIP)
+	}
+
+	index := selectFilterChain(listener.filterChains, req.sourcePrincipal() != "")
+	fc := listener.filterChains[index]
+	name := listener.name
+	if len(listener.filterChains) > 1 {
+		name = fmt.Sprintf("%s[%d]", name, index)
+	}
+
+	port, _ := strconv.Atoi(listener.port)
+	ctx := &evalContext{
+		req:             req,
+		http:            fc.routeHTTP != "",
+		destinationIP:   a.nodeIP,
+		destinationPort: uint32(port),
+		principal:       req.sourcePrincipal(),
+		headers:         req.headers(),
+		metadata:        req.metadata(),
+	}
+	e := &Evaluation{Listener: name, Request: req, HTTP: ctx.http, Allowed: true}
+	for i, f := range fc.rbacTCP {
+		e.evaluate(ctx, fmt.Sprintf("envoy.filters.network.rbac[%d]", i), f.Rules, f.ShadowRules)
+	}
+	if ctx.http {
+		for i, f := range fc.rbacHTTP {
+			e.evaluate(ctx, fmt.Sprintf("envoy.filters.http.rbac[%d]", i), f.Rules, f.ShadowRules)
+		}
+	}
+	return e, nil
+}
+
+// selectFilterChain returns the index of the filter chain accepting the connection, based on whether
+// the connection uses mutual TLS.
+func selectFilterChain(chains []*filterChain, mTLS bool) int {
+	for i, fc := range chains {
+		if fc.tlsContext.GetRequireClientCertificate().GetValue() == mTLS {
+			return i
+		}
+	}
+	return 0
+}
+
+func (e *Evaluation) evaluate(ctx *evalContext, filter string, rules, shadowRules *rbac.RBAC) {
+	if e.DeniedBy != nil {
+		return
+	}
+	if shadowRules != nil {
+		e.Results = append(e.Results, ctx.evaluateRules(filter, shadowRules, true))
+	}
+	if rules != nil {
+		result := ctx.evaluateRules(filter, rules, false)
+		e.Results = append(e.Results, result)
+		if !result.Allowed {
+			e.Allowed = false
+			e.DeniedBy = &e.Results[len(e.Results)-1]
+		}
+	}
+}
+
+// Print writes the result of the evaluation.
+func (e *Evaluation) Print(writer io.Writer) {
+	req := e.Request
+	source := req.sourcePrincipal()
+	if source == "" {
+		source = "plaintext peer"
+	}
+	if req.SourceIP != "" {
+		source = fmt.Sprintf("%s (%s)", source, req.SourceIP)
+	}
+	if e.HTTP {
+		h := req.headers()
+		_, _ = fmt.Fprintf(writer, "Evaluated %s %s from %s on listener %s.\n", h[":method"], h[":path"], source, e.Listener)
+	} else {
+		_, _ = fmt.Fprintf(writer, "Evaluated TCP connection from %s on listener %s.\n", source, e.Listener)
+	}
+
+	if len(e.Results) == 0 {
+		_, _ = fmt.Fprintln(writer, "Result: ALLOW (no authorization policy applies)")
+		return
+	}
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 5, ' ', 0)
+	_, _ = fmt.Fprintln(w, "FILTER\tRULES\tACTION\tRESULT\tMATCHED POLICY")
+	for _, r := range e.Results {
+		rules := "enforced"
+		if r.Shadow {
+			rules = "shadow"
+		}
+		result := "allow"
+		if !r.Allowed {
+			result = "deny"
+		}
+		policy := r.Policy
+		if policy == "" {
+			policy = "none"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Filter, rules, r.Action, result, policy)
+	}
+	_ = w.Flush()
+
+	switch {
+	case e.DeniedBy == nil:
+		_, _ = fmt.Fprintln(writer, "Result: ALLOW")
+	case e.DeniedBy.Policy != "":
+		_, _ = fmt.Fprintf(writer, "Result: DENY (policy %s in %s)\n", e.DeniedBy.Policy, e.DeniedBy.Filter)
+	default:
+		_, _ = fmt.Fprintf(writer, "Result: DENY (no ALLOW policy in %s matched)\n", e.DeniedBy.Filter)
+	}
+}
+
+type evalContext struct {
+	req             *Request
+	http            bool
+	destinationIP   string
+	destinationPort uint32
+	principal       string
+	headers         map[string]string
+	metadata        map[string]*structpb.Struct
+}
+
+// evaluateRules evaluates the policies of the rules, ALLOW rules allow a request matching any policy and
+// DENY rules deny it.
+func (c *evalContext) evaluateRules(filter string, rules *rbac.RBAC, shadow bool) FilterResult {
+	names := make([]string, 0, len(rules.Policies))
+	for name := range rules.Policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	matched := ""
+	for _, name := range names {
+		if c.matchPolicy(rules.Policies[name]) {
+			matched = name
+			break
+		}
+	}
+	deny := rules.Action == rbac.RBAC_DENY
+	return FilterResult{
+		Filter:  filter,
+		Shadow:  shadow,
+		Action:  rules.Action.String(),
+		Allowed: (matched != "") != deny,
+		Policy:  matched,
+	}
+}
+
+func (c *evalContext) matchPolicy(p *rbac.Policy) bool {
+	return c.matchAnyPermission(p.Permissions) && c.matchAnyPrincipal(p.Principals)
+}
+
+func (c *evalContext) matchAnyPermission(permissions []*rbac.Permission) bool {
+	for _, p := range permissions {
+		if c.matchPermission(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *evalContext) matchPermission(p *rbac.Permission) bool {
+	switch r := p.Rule.(type) {
+	case *rbac.Permission_AndRules:
+		for _, rule := range r.AndRules.Rules {
+			if !c.matchPermission(rule) {
+				return false
+			}
+		}
+		return true
+	case *rbac.Permission_OrRules:
+		return c.matchAnyPermission(r.OrRules.Rules)
+	case *rbac.Permission_Any:
+		return r.Any
+	case *rbac.Permission_Header:
+		return c.http && matchHeader(r.Header, c.headers)
+	case *rbac.Permission_DestinationIp:
+		return matchCidr(r.DestinationIp, c.destinationIP)
+	case *rbac.Permission_DestinationPort:
+		return r.DestinationPort == c.destinationPort
+	case *rbac.Permission_Metadata:
+		return c.http && matchMetadata(r.Metadata, c.metadata)
+	case *rbac.Permission_NotRule:
+		return !c.matchPermission(r.NotRule)
+	case *rbac.Permission_RequestedServerName:
+		return matchString(r.RequestedServerName, c.req.SNI)
+	}
+	return false
+}
+
+func (c *evalContext) matchAnyPrincipal(principals []*rbac.Principal) bool {
+	for _, p := range principals {
+		if c.matchPrincipal(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *evalContext) matchPrincipal(p *rbac.Principal) bool {
+	switch id := p.Identifier.(type) {
+	case *rbac.Principal_AndIds:
+		for _, principal := range id.AndIds.Ids {
+			if !c.matchPrincipal(principal) {
+				return false
+			}
+		}
+		return true
+	case *rbac.Principal_OrIds:
+		return c.matchAnyPrincipal(id.OrIds.Ids)
+	case *rbac.Principal_Any:
+		return id.Any
+	case *rbac.Principal_Authenticated_:
+		if c.principal == "" {
+			return false
+		}
+		return id.Authenticated.PrincipalName == nil || matchString(id.Authenticated.PrincipalName, spiffe.URIPrefix+c.principal)
+	case *rbac.Principal_SourceIp:
+		return matchCidr(id.SourceIp, c.req.SourceIP)
+	case *rbac.Principal_Header:
+		return c.http && matchHeader(id.Header, c.headers)
+	case *rbac.Principal_Metadata:
+		return c.http && matchMetadata(id.Metadata, c.metadata)
+	case *rbac.Principal_NotId:
+		return !c.matchPrincipal(id.NotId)
+	}
+	return false
+}
+
+func matchCidr(cidr *core.CidrRange, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil || cidr == nil {
+		return false
+	}
+	_, ipNet, err := net.ParseCIDR(fmt.Sprintf("%s/%d", cidr.AddressPrefix, cidr.PrefixLen.GetValue()))
+	return err == nil && ipNet.Contains(addr)
+}
+
+func matchHeader(h *route.HeaderMatcher, headers map[string]string) bool {
+	value, present := headers[strings.ToLower(h.Name)]
+	var matched bool
+	switch m := h.HeaderMatchSpecifier.(type) {
+	case *route.HeaderMatcher_ExactMatch:
+		matched = present && value == m.ExactMatch
+	case *route.HeaderMatcher_RegexMatch:
+		matched = present && matchRegex(m.RegexMatch, value)
+	case *route.HeaderMatcher_SafeRegexMatch:
+		matched = present && matchRegex(m.SafeRegexMatch.GetRegex(), value)
+	case *route.HeaderMatcher_RangeMatch:
+		v, err := strconv.ParseInt(value, 10, 64)
+		matched = present && err == nil && v >= m.RangeMatch.Start && v < m.RangeMatch.End
+	case *route.HeaderMatcher_PresentMatch:
+		matched = present == m.PresentMatch
+	case *route.HeaderMatcher_PrefixMatch:
+		matched = present && strings.HasPrefix(value, m.PrefixMatch)
+	case *route.HeaderMatcher_SuffixMatch:
+		matched = present && strings.HasSuffix(value, m.SuffixMatch)
+	default:
+		matched = present
+	}
+	return matched != h.InvertMatch
+}
+
+func matchString(m *envoy_matcher.StringMatcher, value string) bool {
+	switch p := m.MatchPattern.(type) {
+	case *envoy_matcher.StringMatcher_Exact:
+		return value == p.Exact
+	case *envoy_matcher.StringMatcher_Prefix:
+		return strings.HasPrefix(value, p.Prefix)
+	case *envoy_matcher.StringMatcher_Suffix:
+		return strings.HasSuffix(value, p.Suffix)
+	case *envoy_matcher.StringMatcher_Regex:
+		return matchRegex(p.Regex, value)
+	case *envoy_matcher.StringMatcher_SafeRegex:
+		return matchRegex(p.SafeRegex.GetRegex(), value)
+	}
+	return false
+}
+
+// matchRegex returns whether the regular expression matches the whole value, as Envoy does.
+func matchRegex(expr, value string) bool {
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	return err == nil && re.MatchString(value)
+}
+
+func matchMetadata(m *envoy_matcher.MetadataMatcher, metadata map[string]*structpb.Struct) bool {
+	s := metadata[m.Filter]
+	if s == nil || len(m.Path) == 0 {
+		return false
+	}
+	var value *structpb.Value
+	for i, segment := range m.Path {
+		if i > 0 {
+			s = value.GetStructValue()
+			if s == nil {
+				return false
+			}
+		}
+		value = s.Fields[segment.GetKey()]
+		if value == nil {
+			return false
+		}
+	}
+	return matchValue(m.Value, value)
+}
+
+func matchValue(m *envoy_matcher.ValueMatcher, value *structpb.Value) bool {
+	switch p := m.GetMatchPattern().(type) {
+	case *envoy_matcher.ValueMatcher_NullMatch_:
+		_, ok := value.Kind.(*structpb.Value_NullValue)
+		return ok
+	case *envoy_matcher.ValueMatcher_StringMatch:
+		s, ok := value.Kind.(*structpb.Value_StringValue)
+		return ok && matchString(p.StringMatch, s.StringValue)
+	case *envoy_matcher.ValueMatcher_BoolMatch:
+		b, ok := value.Kind.(*structpb.Value_BoolValue)
+		return ok && b.BoolValue == p.BoolMatch
+	case *envoy_matcher.ValueMatcher_DoubleMatch:
+		d, ok := value.Kind.(*structpb.Value_NumberValue)
+		if !ok {
+			return false
+		}
+		switch dm := p.DoubleMatch.MatchPattern.(type) {
+		case *envoy_matcher.DoubleMatcher_Exact:
+			return d.NumberValue == dm.Exact
+		case *envoy_matcher.DoubleMatcher_Range:
+			return d.NumberValue >= dm.Range.Start && d.NumberValue < dm.Range.End
+		}
+	case *envoy_matcher.ValueMatcher_PresentMatch:
+		return p.PresentMatch
+	case *envoy_matcher.ValueMatcher_ListMatch:
+		list := value.GetListValue()
+		if list == nil {
+			return false
+		}
+		for _, v := range list.Values {
+			if matchValue(p.ListMatch.GetOneOf(), v) {
+				return true
+			}
+		}
+	}
+	return false
+}
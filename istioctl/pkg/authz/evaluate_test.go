@@ -0,0 +1,98 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	rbac "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v2"
+
+	"istio.io/istio/pilot/pkg/security/authz/model/matcher"
+	authn_model "istio.io/istio/pilot/pkg/security/model"
+)
+
+func TestEvaluateRules(t *testing.T) {
+	adminsOnly := &rbac.Policy{
+		Permissions: []*rbac.Permission{{Rule: &rbac.Permission_Header{Header: &route.HeaderMatcher{
+			Name:                 ":path",
+			HeaderMatchSpecifier: &route.HeaderMatcher_PrefixMatch{PrefixMatch: "/admin"},
+		}}}},
+		Principals: []*rbac.Principal{{Identifier: &rbac.Principal_NotId{NotId: &rbac.Principal{
+			Identifier: &rbac.Principal_Metadata{
+				Metadata: matcher.MetadataListMatcher(authn_model.AuthnFilterName,
+					[]string{attrRequestClaims, "groups"}, "admin", true),
+			},
+		}}}},
+	}
+	fromDefault := &rbac.Policy{
+		Permissions: []*rbac.Permission{{Rule: &rbac.Permission_Any{Any: true}}},
+		Principals: []*rbac.Principal{{Identifier: &rbac.Principal_Metadata{
+			Metadata: matcher.MetadataStringMatcher(authn_model.AuthnFilterName, attrSrcPrincipal,
+				matcher.StringMatcherRegex(".*/ns/default/.*")),
+		}}},
+	}
+	deny := &rbac.RBAC{Action: rbac.RBAC_DENY, Policies: map[string]*rbac.Policy{"ns[foo]-policy[admins]-rule[0]": adminsOnly}}
+	allow := &rbac.RBAC{Action: rbac.RBAC_ALLOW, Policies: map[string]*rbac.Policy{"ns[foo]-policy[default]-rule[0]": fromDefault}}
+
+	for _, tc := range []struct {
+		name     string
+		req      *Request
+		allowed  bool
+		deniedBy string
+	}{
+		{
+			name:    "allowed",
+			req:     &Request{SourcePrincipal: "spiffe://cluster.local/ns/default/sa/sleep", Path: "/api"},
+			allowed: true,
+		},
+		{
+			name:     "denied by deny policy",
+			req:      &Request{SourceNamespace: "default", Path: "/admin/users"},
+			deniedBy: "ns[foo]-policy[admins]-rule[0]",
+		},
+		{
+			name:    "admin claim",
+			req:     &Request{SourceNamespace: "default", Path: "/admin/users", Claims: map[string][]string{"groups": {"dev", "admin"}}},
+			allowed: true,
+		},
+		{
+			name: "no allow policy matched",
+			req:  &Request{SourceNamespace: "other", Path: "/api"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &evalContext{
+				req:       tc.req,
+				http:      true,
+				principal: tc.req.sourcePrincipal(),
+				headers:   tc.req.headers(),
+				metadata:  tc.req.metadata(),
+			}
+			e := &Evaluation{Request: tc.req, HTTP: true, Allowed: true}
+			e.evaluate(ctx, "deny", deny, nil)
+			e.evaluate(ctx, "allow", allow, deny)
+			if e.Allowed != tc.allowed {
+				t.Fatalf("want allowed %v, got %v: %+v", tc.allowed, e.Allowed, e.Results)
+			}
+			if tc.deniedBy != "" && e.DeniedBy.Policy != tc.deniedBy {
+				t.Errorf("want denied by %s, got %+v", tc.deniedBy, e.DeniedBy)
+			}
+			if tc.allowed && !e.Results[1].Shadow {
+				t.Errorf("want shadow rules evaluated before the rules of the filter: %+v", e.Results)
+			}
+		})
+	}
+}
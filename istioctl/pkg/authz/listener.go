@@ -55,8 +55,8 @@ type filterChain struct {
 	authN    *authn_filter.FilterConfig
 	envoyJWT *envoy_jwt.JwtAuthentication
 	istioJWT *jwt_filter.JwtAuthentication
-	rbacHTTP *rbac_http_filter.RBAC
-	rbacTCP  *rbac_tcp_filter.RBAC
+	rbacHTTP []*rbac_http_filter.RBAC
+	rbacTCP  []*rbac_tcp_filter.RBAC
 
 	routeHTTP string
 }
@@ -168,7 +168,7 @@ func ParseListener(listener *v2.Listener) *ParsedListener {
 							if err := getHTTPFilterConfig(httpFilter, rbacHTTP); err != nil {
 								log.Errorf("found RBAC HTTP filter but failed to parse: %s", err)
 							} else {
-								parsedFC.rbacHTTP = rbacHTTP
+								parsedFC.rbacHTTP = append(parsedFC.rbacHTTP, rbacHTTP)
 							}
 						}
 					}
@@ -184,7 +184,7 @@ func ParseListener(listener *v2.Listener) *ParsedListener {
 				if err := getFilterConfig(filter, rbacTCP); err != nil {
 					log.Errorf("found RBAC network filter but failed to parse: %s", err)
 				} else {
-					parsedFC.rbacTCP = rbacTCP
+					parsedFC.rbacTCP = append(parsedFC.rbacTCP, rbacTCP)
 				}
 			}
 		}
@@ -255,14 +255,18 @@ func (l *ParsedListener) print(w io.Writer, printAll bool) {
 		}
 
 		rbacPolicy := "no (none)"
-		if fc.rbacHTTP != nil || fc.rbacTCP != nil {
+		if len(fc.rbacHTTP) != 0 || len(fc.rbacTCP) != 0 {
 			rbacPolicy = "yes (none)"
 			rules := make([]string, 0)
-			for p := range fc.rbacHTTP.GetRules().GetPolicies() {
-				rules = append(rules, p)
+			for _, rbac := range fc.rbacHTTP {
+				for p := range rbac.GetRules().GetPolicies() {
+					rules = append(rules, p)
+				}
 			}
-			for p := range fc.rbacTCP.GetRules().GetPolicies() {
-				rules = append(rules, p)
+			for _, rbac := range fc.rbacTCP {
+				for p := range rbac.GetRules().GetPolicies() {
+					rules = append(rules, p)
+				}
 			}
 			if len(rules) != 0 {
 				rbacPolicy = fmt.Sprintf("yes (%d: %s)", len(rules), strings.Join(rules, ", "))
@@ -0,0 +1,113 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// Archive is a gzipped tarball of diagnostic files. The content of the files is redacted, and files
+// which would make the archive exceed its size limit are skipped.
+type Archive struct {
+	root     string
+	maxBytes int64
+	now      time.Time
+
+	gz      *gzip.Writer
+	tw      *tar.Writer
+	written int64
+	skipped []string
+	errors  []string
+}
+
+// NewArchive creates an archive writing to w, with all the files under the root directory. A maxBytes
+// of 0 disables the size limit, which applies to the uncompressed size of the files.
+func NewArchive(w io.Writer, root string, maxBytes int64) *Archive {
+	gz := gzip.NewWriter(w)
+	return &Archive{
+		root:     root,
+		maxBytes: maxBytes,
+		now:      time.Now(),
+		gz:       gz,
+		tw:       tar.NewWriter(gz),
+	}
+}
+
+// Add redacts the data and adds it to the archive under the given name.
+func (a *Archive) Add(name string, data []byte) error {
+	data = Redact(data)
+	if a.maxBytes > 0 && a.written+int64(len(data)) > a.maxBytes {
+		a.skipped = append(a.skipped, fmt.Sprintf("%s (%d bytes)", name, len(data)))
+		return nil
+	}
+	if err := a.write(name, data); err != nil {
+		return err
+	}
+	a.written += int64(len(data))
+	return nil
+}
+
+// AddError records a failure to collect some data, so that the archive tells what is missing.
+func (a *Archive) AddError(format string, args ...interface{}) {
+	a.errors = append(a.errors, fmt.Sprintf(format, args...))
+}
+
+// Skipped returns the files which were not added because of the size limit.
+func (a *Archive) Skipped() []string {
+	return a.skipped
+}
+
+// Errors returns the failures recorded with AddError.
+func (a *Archive) Errors() []string {
+	return a.errors
+}
+
+// Close writes the summary of the skipped files and errors, and flushes the archive. It does not close
+// the underlying writer.
+func (a *Archive) Close() error {
+	var errs error
+	if len(a.errors) > 0 {
+		errs = multierror.Append(errs, a.write("errors.txt", []byte(strings.Join(a.errors, "\n")+"\n")))
+	}
+	if len(a.skipped) > 0 {
+		summary := fmt.Sprintf("The following files were skipped to keep the bug report under %d bytes:\n%s\n",
+			a.maxBytes, strings.Join(a.skipped, "\n"))
+		errs = multierror.Append(errs, a.write("skipped.txt", []byte(summary)))
+	}
+	errs = multierror.Append(errs, a.tw.Close(), a.gz.Close())
+	return errs.(*multierror.Error).ErrorOrNil()
+}
+
+func (a *Archive) write(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    path.Join(a.root, name),
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: a.now,
+	}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := a.tw.Write(data)
+	return err
+}
@@ -0,0 +1,328 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bugreport collects the state of an Istio installation into a single archive, to be attached
+// to bug reports and support requests.
+package bugreport
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/galley/pkg/config/schema/collection"
+	istioctl_kubernetes "istio.io/istio/istioctl/pkg/kubernetes"
+)
+
+const proxyContainer = "istio-proxy"
+
+var (
+	// pilotDebugEndpoints are the Pilot debug endpoints collected from every Pilot instance.
+	pilotDebugEndpoints = []string{"syncz", "configz", "push_status", "registryz", "endpointz", "adsz"}
+
+	// proxyEndpoints are the Envoy admin endpoints collected from every selected proxy, with the name of
+	// the file they are stored in.
+	proxyEndpoints = map[string]string{
+		"config_dump":          "config_dump.json",
+		"stats":                "stats.txt",
+		"clusters?format=json": "clusters.json",
+		"server_info":          "server_info.json",
+	}
+
+	// meshConfigMaps are the config maps holding the mesh wide configuration in the Istio namespace.
+	meshConfigMaps = []string{"istio", "istio-sidecar-injector"}
+)
+
+// Collector collects the diagnostic data of an Istio installation.
+type Collector struct {
+	// Kube is the client used to fetch pods, logs and config maps.
+	Kube kubernetes.Interface
+	// Exec is the client used to query the Pilot and Envoy debug endpoints.
+	Exec istioctl_kubernetes.ExecClient
+	// Dynamic is the client used to fetch the Istio resources. They are not collected when nil.
+	Dynamic dynamic.Interface
+	// Schemas are the Istio resources to collect.
+	Schemas collection.Schemas
+
+	// IstioNamespace is the namespace of the control plane.
+	IstioNamespace string
+	// Proxies are the proxies to collect, as <pod-name>.<namespace>.
+	Proxies []string
+	// ProxySelector is a label selector of proxies to collect in all namespaces.
+	ProxySelector string
+
+	// LogLimitBytes limits the size of each container log, 0 means no limit.
+	LogLimitBytes int64
+	// LogSince limits the logs to the given period, 0 means all the logs.
+	LogSince time.Duration
+
+	// Analyze returns the output of the Istio configuration analysis, it is skipped when nil.
+	Analyze func() (string, error)
+
+	// podLogs overrides how the logs are fetched, for testing.
+	podLogs func(pod *v1.Pod, opts *v1.PodLogOptions) ([]byte, error)
+}
+
+// Collect adds all the diagnostic data to the archive. The failures to collect some data are recorded
+// in the archive, and only the failures to write it are returned.
+func (c *Collector) Collect(a *Archive) error {
+	for _, collect := range []func(*Archive) error{
+		c.collectVersions,
+		c.collectControlPlane,
+		c.collectPilotDebug,
+		c.collectProxies,
+		c.collectMeshConfig,
+		c.collectResources,
+		c.collectAnalysis,
+	} {
+		if err := collect(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Collector) collectVersions(a *Archive) error {
+	versions := map[string]interface{}{}
+	if sv, err := c.Kube.Discovery().ServerVersion(); err != nil {
+		a.AddError("failed to get the Kubernetes version: %v", err)
+	} else {
+		versions["kubernetes"] = sv
+	}
+	if mi, err := c.Exec.GetIstioVersions(c.IstioNamespace); err != nil {
+		a.AddError("failed to get the Istio control plane versions: %v", err)
+	} else {
+		versions["istio"] = mi
+	}
+	return addYAML(a, "versions.yaml", versions)
+}
+
+func (c *Collector) collectControlPlane(a *Archive) error {
+	pods, err := c.Kube.CoreV1().Pods(c.IstioNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		a.AddError("failed to list the control plane pods: %v", err)
+		return nil
+	}
+	if err := addYAML(a, "control-plane/pods.yaml", pods); err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if err := c.collectLogs(a, path.Join("control-plane", "logs", pod.Name), pod, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectLogs adds the logs of the containers of the pod, or only of the given container. The logs of
+// the previous instance of a restarted container are added as well.
+func (c *Collector) collectLogs(a *Archive, dir string, pod *v1.Pod, only *string) error {
+	restarts := map[string]int32{}
+	for _, s := range pod.Status.ContainerStatuses {
+		restarts[s.Name] = s.RestartCount
+	}
+	for _, container := range pod.Spec.Containers {
+		if only != nil && container.Name != *only {
+			continue
+		}
+		if err := c.collectLog(a, path.Join(dir, container.Name+".log"), pod, container.Name, false); err != nil {
+			return err
+		}
+		if restarts[container.Name] > 0 {
+			if err := c.collectLog(a, path.Join(dir, container.Name+"_previous.log"), pod, container.Name, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Collector) collectLog(a *Archive, name string, pod *v1.Pod, container string, previous bool) error {
+	opts := &v1.PodLogOptions{Container: container, Previous: previous}
+	if c.LogLimitBytes > 0 {
+		opts.LimitBytes = &c.LogLimitBytes
+	}
+	if c.LogSince > 0 {
+		seconds := int64(c.LogSince.Seconds())
+		opts.SinceSeconds = &seconds
+	}
+	getLogs := c.podLogs
+	if getLogs == nil {
+		getLogs = func(pod *v1.Pod, opts *v1.PodLogOptions) ([]byte, error) {
+			return c.Kube.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts).Do().Raw()
+		}
+	}
+	data, err := getLogs(pod, opts)
+	if err != nil {
+		a.AddError("failed to get the logs of %s.%s/%s: %v", pod.Name, pod.Namespace, container, err)
+		return nil
+	}
+	return a.Add(name, data)
+}
+
+func (c *Collector) collectPilotDebug(a *Archive) error {
+	for _, endpoint := range pilotDebugEndpoints {
+		results, err := c.Exec.AllPilotsDiscoveryDo(c.IstioNamespace, "GET", "/debug/"+endpoint, nil)
+		if err != nil {
+			a.AddError("failed to get /debug/%s from Pilot: %v", endpoint, err)
+			continue
+		}
+		for pilot, data := range results {
+			if err := a.Add(path.Join("pilot", pilot, endpoint+".json"), data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// selectProxies returns the pods of the proxies to collect.
+func (c *Collector) selectProxies(a *Archive) []v1.Pod {
+	var pods []v1.Pod
+	for _, proxy := range c.Proxies {
+		name, namespace := proxy, v1.NamespaceDefault
+		if i := strings.LastIndex(proxy, "."); i > 0 {
+			name, namespace = proxy[:i], proxy[i+1:]
+		}
+		pod, err := c.Kube.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			a.AddError("failed to get proxy %s: %v", proxy, err)
+			continue
+		}
+		pods = append(pods, *pod)
+	}
+	if c.ProxySelector != "" {
+		list, err := c.Kube.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{LabelSelector: c.ProxySelector})
+		if err != nil {
+			a.AddError("failed to list the proxies matching %q: %v", c.ProxySelector, err)
+		} else {
+			for _, pod := range list.Items {
+				if hasContainer(&pod, proxyContainer) {
+					pods = append(pods, pod)
+				}
+			}
+		}
+	}
+	return pods
+}
+
+func (c *Collector) collectProxies(a *Archive) error {
+	endpoints := make([]string, 0, len(proxyEndpoints))
+	for endpoint := range proxyEndpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+
+	container := proxyContainer
+	proxies := c.selectProxies(a)
+	for i := range proxies {
+		pod := &proxies[i]
+		dir := path.Join("proxies", pod.Namespace, pod.Name)
+		for _, endpoint := range endpoints {
+			data, err := c.Exec.EnvoyDo(pod.Name, pod.Namespace, "GET", endpoint, nil)
+			if err != nil {
+				a.AddError("failed to get %s from proxy %s.%s: %v", endpoint, pod.Name, pod.Namespace, err)
+				continue
+			}
+			if err := a.Add(path.Join(dir, proxyEndpoints[endpoint]), data); err != nil {
+				return err
+			}
+		}
+		if err := c.collectLogs(a, dir, pod, &container); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Collector) collectMeshConfig(a *Archive) error {
+	for _, name := range meshConfigMaps {
+		cm, err := c.Kube.CoreV1().ConfigMaps(c.IstioNamespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			a.AddError("failed to get config map %s.%s: %v", name, c.IstioNamespace, err)
+			continue
+		}
+		if err := addYAML(a, path.Join("mesh", name+".yaml"), cm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Collector) collectResources(a *Archive) error {
+	if c.Dynamic == nil {
+		return nil
+	}
+	for _, s := range c.Schemas.All() {
+		r := s.Resource()
+		gvr := schema.GroupVersionResource{Group: r.Group(), Version: r.Version(), Resource: r.Plural()}
+		list, err := c.Dynamic.Resource(gvr).Namespace(metav1.NamespaceAll).List(metav1.ListOptions{})
+		if err != nil {
+			a.AddError("failed to list %s: %v", s.Name(), err)
+			continue
+		}
+		if len(list.Items) == 0 {
+			continue
+		}
+		if err := addYAML(a, path.Join("config", r.Plural()+"."+r.Group()+".yaml"), list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Collector) collectAnalysis(a *Archive) error {
+	if c.Analyze == nil {
+		return nil
+	}
+	out, err := c.Analyze()
+	if err != nil {
+		a.AddError("failed to analyze the Istio configuration: %v", err)
+		return nil
+	}
+	return a.Add("analyze.txt", []byte(out))
+}
+
+func addYAML(a *Archive, name string, obj interface{}) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		a.AddError("failed to marshal %s: %v", name, err)
+		return nil
+	}
+	return a.Add(name, data)
+}
+
+func hasContainer(pod *v1.Pod, name string) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultArchiveName returns the name of an archive created at the given time.
+func DefaultArchiveName(t time.Time) string {
+	return fmt.Sprintf("bug-report-%s.tar.gz", t.UTC().Format("20060102-150405"))
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/istioctl/pkg/kubernetes"
+	"istio.io/pkg/version"
+)
+
+type fakeExecClient struct {
+	kubernetes.ExecClient
+}
+
+func (fakeExecClient) EnvoyDo(podName, podNamespace, method, path string, body []byte) ([]byte, error) {
+	if path == "stats" {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return []byte(fmt.Sprintf(`{"pod": %q, "path": %q}`, podName, path)), nil
+}
+
+func (fakeExecClient) AllPilotsDiscoveryDo(pilotNamespace, method, path string, body []byte) (map[string][]byte, error) {
+	return map[string][]byte{"istio-pilot-1": []byte(`{"debug": "` + path + `"}`)}, nil
+}
+
+func (fakeExecClient) GetIstioVersions(namespace string) (*version.MeshInfo, error) {
+	return &version.MeshInfo{}, nil
+}
+
+func pod(name, namespace string, labels map[string]string, containers ...string) *v1.Pod {
+	p := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}}
+	for _, c := range containers {
+		p.Spec.Containers = append(p.Spec.Containers, v1.Container{Name: c})
+	}
+	return p
+}
+
+// readArchive returns the content of the files of a gzipped tarball, by name.
+func readArchive(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(content)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	kube := fake.NewSimpleClientset(
+		pod("istio-pilot-1", "istio-system", nil, "discovery", "istio-proxy"),
+		pod("productpage-v1", "default", nil, "productpage", "istio-proxy"),
+		pod("istio-ingressgateway-1", "istio-system", map[string]string{"istio": "ingressgateway"}, "istio-proxy"),
+		pod("no-sidecar", "default", map[string]string{"istio": "ingressgateway"}, "app"),
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+			Data:       map[string]string{"mesh": "accessLogFile: /dev/stdout\nsdsUdsPath: unix:/var/run/sds\n"},
+		},
+	)
+	c := &Collector{
+		Kube:           kube,
+		Exec:           fakeExecClient{},
+		IstioNamespace: "istio-system",
+		Proxies:        []string{"productpage-v1.default", "missing.default"},
+		ProxySelector:  "istio=ingressgateway",
+		Analyze:        func() (string, error) { return "No validation issues found.\n", nil },
+		podLogs: func(pod *v1.Pod, opts *v1.PodLogOptions) ([]byte, error) {
+			return []byte(fmt.Sprintf("logs of %s/%s\n", pod.Name, opts.Container)), nil
+		},
+	}
+
+	var buf bytes.Buffer
+	a := NewArchive(&buf, "report", 0)
+	if err := c.Collect(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files := readArchive(t, buf.Bytes())
+
+	for _, name := range []string{
+		"report/versions.yaml",
+		"report/control-plane/pods.yaml",
+		"report/control-plane/logs/istio-pilot-1/discovery.log",
+		"report/control-plane/logs/istio-pilot-1/istio-proxy.log",
+		"report/pilot/istio-pilot-1/syncz.json",
+		"report/pilot/istio-pilot-1/push_status.json",
+		"report/proxies/default/productpage-v1/config_dump.json",
+		"report/proxies/default/productpage-v1/istio-proxy.log",
+		"report/proxies/istio-system/istio-ingressgateway-1/clusters.json",
+		"report/mesh/istio.yaml",
+		"report/analyze.txt",
+		"report/errors.txt",
+	} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s in the archive", name)
+		}
+	}
+	for name := range files {
+		if strings.Contains(name, "no-sidecar") || strings.Contains(name, "productpage-v1/productpage.log") {
+			t.Errorf("unexpected %s in the archive", name)
+		}
+	}
+	errors := files["report/errors.txt"]
+	for _, want := range []string{"failed to get proxy missing.default", "failed to get stats from proxy productpage-v1.default"} {
+		if !strings.Contains(errors, want) {
+			t.Errorf("want error %q, got:\n%s", want, errors)
+		}
+	}
+}
+
+func TestArchiveSizeLimit(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewArchive(&buf, "report", 10)
+	for _, f := range []struct {
+		name string
+		data string
+	}{
+		{"small.txt", "12345"},
+		{"large.txt", "1234567890"},
+		{"fits.txt", "12345"},
+	} {
+		if err := a.Add(f.name, []byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := readArchive(t, buf.Bytes())
+	if _, ok := files["report/large.txt"]; ok {
+		t.Errorf("large.txt should have been skipped")
+	}
+	if files["report/small.txt"] != "12345" || files["report/fits.txt"] != "12345" {
+		t.Errorf("missing files under the limit: %v", files)
+	}
+	if !strings.Contains(files["report/skipped.txt"], "large.txt (10 bytes)") {
+		t.Errorf("skipped file not reported: %q", files["report/skipped.txt"])
+	}
+}
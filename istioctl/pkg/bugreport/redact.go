@@ -0,0 +1,56 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bugreport
+
+import (
+	"regexp"
+)
+
+const redacted = "[redacted]"
+
+var (
+	// privateKeyPattern matches PEM encoded private keys.
+	privateKeyPattern = regexp.MustCompile(`-----BEGIN ([A-Z ]*)PRIVATE KEY-----[\s\S]*?-----END ([A-Z ]*)PRIVATE KEY-----`)
+
+	// sensitiveValuePattern matches the value of sensitive YAML or JSON keys, e.g. `password: foo` or
+	// `"client_secret": "foo"`.
+	sensitiveValuePattern = regexp.MustCompile(
+		`(?i)("?([\w.-]*(?:password|passwd|secret|token|private[_-]?key|api[_-]?key|credential)[\w.-]*)"?[ \t]*[:=][ \t]*)("[^"\n]*"|'[^'\n]*'|[^\s,}\n]+)`)
+
+	// referencePattern matches the sensitive keys which only refer to a secret, e.g. `credentialName` or
+	// `secretName`, and which are kept as they are useful to debug.
+	referencePattern = regexp.MustCompile(`(?i)(name|path|file|dir|ttl|audience)s?$`)
+
+	// authorizationPattern matches credentials in authorization headers.
+	authorizationPattern = regexp.MustCompile(`(?i)((?:proxy-)?authorization"?\s*[:=]\s*"?(?:bearer|basic)\s+)[A-Za-z0-9._~+/=-]+`)
+)
+
+// Redact removes private keys, credentials and the values of sensitive keys from the data.
+func Redact(data []byte) []byte {
+	data = privateKeyPattern.ReplaceAll(data, []byte(redacted))
+	data = authorizationPattern.ReplaceAll(data, []byte("${1}"+redacted))
+	return sensitiveValuePattern.ReplaceAllFunc(data, func(m []byte) []byte {
+		groups := sensitiveValuePattern.FindSubmatch(m)
+		key, value := groups[2], groups[3]
+		if referencePattern.Match(key) || string(value) == `""` || string(value) == `"`+redacted+`"` {
+			return m
+		}
+		out := append([]byte{}, groups[1]...)
+		if value[0] == '"' || value[0] == '\'' {
+			return append(out, []byte(string(value[0])+redacted+string(value[0]))...)
+		}
+		return append(out, redacted...)
+	})
+}
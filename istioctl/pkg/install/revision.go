@@ -0,0 +1,426 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/api/annotation"
+
+	"istio.io/istio/operator/pkg/kubectlcmd"
+	"istio.io/istio/operator/pkg/manifest"
+	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/revision"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+)
+
+const (
+	// pilotSelector selects the Pilot deployments of all the revisions, whose pods have their own istio label.
+	pilotSelector = "app=pilot"
+)
+
+var (
+	// gatewaySelector selects the gateway deployments installed by the operator, which are shared by all the
+	// revisions.
+	gatewaySelector = func() string {
+		components := make([]string, 0, len(revision.GatewayComponents))
+		for c := range revision.GatewayComponents {
+			components = append(components, string(c))
+		}
+		sort.Strings(components)
+		return fmt.Sprintf("%s/component in (%s)", name.OperatorAPINamespace, strings.Join(components, ","))
+	}()
+)
+
+var (
+	revisionClientFactory = createRevisionClient
+	// deleteRevision deletes the resources of a revision of the control plane.
+	deleteRevision = manifest.DeleteRevision
+)
+
+// controlPlaneRevision describes a revision of the control plane installed in the cluster.
+type controlPlaneRevision struct {
+	name    string
+	version string
+	pilot   string
+	// namespaces are the namespaces whose pods are injected by the revision.
+	namespaces []string
+	// proxies are the injected pods managed by the revision, as <name>.<namespace>.
+	proxies []string
+	// gateways are the gateway deployments using the revision.
+	gateways []string
+}
+
+// NewRevisionCommand creates the command managing the revisions of the control plane installed side by side.
+func NewRevisionCommand() *cobra.Command {
+	var (
+		kubeConfigFlags = &genericclioptions.ConfigFlags{
+			Context:    strPtr(""),
+			Namespace:  strPtr(""),
+			KubeConfig: strPtr(""),
+		}
+		istioNamespace string
+	)
+	cmd := &cobra.Command{
+		Use:   "revision",
+		Short: "Manages the revisions of the control plane installed side by side",
+		Long: `
+		A revision of the control plane is installed next to the control plane installed
+		without a revision, the default one, with 'istioctl manifest apply --revision <revision>'.
+		The namespaces are moved one at a time to the new revision, the gateways shared by
+		all the revisions are moved by promoting the new revision, and the old revision is
+		removed once no proxy is managed by it anymore. The default revision can be removed
+		too, its components shared by all the revisions are kept.
+`,
+		Example: `
+		# Install the canary revision next to the default control plane
+		istioctl manifest apply --revision canary
+
+		# Move the namespace bookinfo to the canary revision, then restart its workloads
+		istioctl x revision migrate bookinfo --to canary
+		kubectl rollout restart deployment -n bookinfo
+
+		# Move the gateways to the canary revision
+		istioctl x revision promote canary
+
+		# Remove the default revision once no namespace, proxy or gateway uses it
+		istioctl x revision remove default
+`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			c.HelpFunc()(c, args)
+			return nil
+		},
+	}
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&istioNamespace, "istioNamespace", "i", controller.IstioNamespace,
+		"Istio system namespace")
+	kubeConfigFlags.AddFlags(flags)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Lists the revisions of the control plane, with the namespaces and the proxies they manage",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			client, err := revisionClientFactory(kubeConfigFlags)
+			if err != nil {
+				return fmt.Errorf("failed to initialize the Kubernetes client: %v", err)
+			}
+			return listRevisions(client, istioNamespace, c.OutOrStdout())
+		},
+	})
+
+	var to string
+	migrateCmd := &cobra.Command{
+		Use:   "migrate <namespace>...",
+		Short: "Moves namespaces to a revision of the control plane",
+		Long: `
+		migrate labels the namespaces for the injector of the target revision to inject their
+		pods. The running pods keep the proxies of their current revision until they are
+		restarted.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			client, err := revisionClientFactory(kubeConfigFlags)
+			if err != nil {
+				return fmt.Errorf("failed to initialize the Kubernetes client: %v", err)
+			}
+			return migrateNamespaces(client, istioNamespace, args, to, c.OutOrStdout())
+		},
+	}
+	migrateCmd.Flags().StringVar(&to, "to", revision.Default, "Revision the namespaces are moved to")
+	cmd.AddCommand(migrateCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "promote <revision>",
+		Short: "Moves the gateways shared by all the revisions to a revision of the control plane",
+		Long: `
+		promote points the gateways to the control plane of the revision, restarting their pods.
+		The gateways are installed with the default revision, and use it until another revision
+		is promoted.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			client, err := revisionClientFactory(kubeConfigFlags)
+			if err != nil {
+				return fmt.Errorf("failed to initialize the Kubernetes client: %v", err)
+			}
+			return promoteRevision(client, istioNamespace, args[0], c.OutOrStdout())
+		},
+	})
+
+	var dryRun bool
+	removeCmd := &cobra.Command{
+		Use:   "remove <revision>",
+		Short: "Removes a revision of the control plane once no namespace, proxy or gateway uses it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			client, err := revisionClientFactory(kubeConfigFlags)
+			if err != nil {
+				return fmt.Errorf("failed to initialize the Kubernetes client: %v", err)
+			}
+			opts := kubectlcmd.Options{
+				Kubeconfig: *kubeConfigFlags.KubeConfig,
+				Context:    *kubeConfigFlags.Context,
+				DryRun:     dryRun,
+			}
+			return removeRevision(client, istioNamespace, args[0], opts, c.OutOrStdout())
+		},
+	}
+	removeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only check the revision can be removed")
+	cmd.AddCommand(removeCmd)
+	return cmd
+}
+
+func createRevisionClient(restClientGetter genericclioptions.RESTClientGetter) (kubernetes.Interface, error) {
+	restConfig, err := restClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// namespaceRevision returns the revision injecting the pods of a namespace, or "" if none does.
+func namespaceRevision(ns *v1.Namespace) string {
+	if rev, ok := ns.Labels[revision.Label]; ok {
+		return rev
+	}
+	if ns.Labels["istio-injection"] == "enabled" {
+		return revision.Default
+	}
+	return ""
+}
+
+// podRevision returns the revision managing the proxy of a pod, or "" if the pod is not injected.
+func podRevision(pod *v1.Pod) string {
+	if _, ok := pod.Annotations[annotation.SidecarStatus.Name]; !ok {
+		return ""
+	}
+	if rev, ok := pod.Labels[revision.Label]; ok {
+		return rev
+	}
+	return revision.Default
+}
+
+// getRevisions returns the revisions of the control plane installed in the cluster, and the ones still used by
+// namespaces or proxies, sorted by name.
+func getRevisions(client kubernetes.Interface, istioNamespace string) ([]*controlPlaneRevision, error) {
+	revisions := map[string]*controlPlaneRevision{}
+	get := func(name string) *controlPlaneRevision {
+		if revisions[name] == nil {
+			revisions[name] = &controlPlaneRevision{name: name}
+		}
+		return revisions[name]
+	}
+
+	deployments, err := client.AppsV1().Deployments(istioNamespace).List(meta_v1.ListOptions{LabelSelector: pilotSelector})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments.Items {
+		rev := revision.Default
+		if r, ok := d.Labels[revision.Label]; ok {
+			rev = r
+		}
+		r := get(rev)
+		r.pilot = d.Name
+		if containers := d.Spec.Template.Spec.Containers; len(containers) > 0 {
+			if i := strings.LastIndex(containers[0].Image, ":"); i >= 0 {
+				r.version = containers[0].Image[i+1:]
+			}
+		}
+	}
+
+	gateways, err := client.AppsV1().Deployments(istioNamespace).List(meta_v1.ListOptions{LabelSelector: gatewaySelector})
+	if err != nil {
+		return nil, err
+	}
+	for i := range gateways.Items {
+		r := get(revision.GatewayRevision(&gateways.Items[i]))
+		r.gateways = append(r.gateways, gateways.Items[i].Name)
+	}
+
+	namespaces, err := client.CoreV1().Namespaces().List(meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range namespaces.Items {
+		if rev := namespaceRevision(&namespaces.Items[i]); rev != "" {
+			r := get(rev)
+			r.namespaces = append(r.namespaces, namespaces.Items[i].Name)
+		}
+	}
+
+	pods, err := client.CoreV1().Pods(meta_v1.NamespaceAll).List(meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		if rev := podRevision(&pods.Items[i]); rev != "" {
+			r := get(rev)
+			r.proxies = append(r.proxies, pods.Items[i].Name+"."+pods.Items[i].Namespace)
+		}
+	}
+
+	out := make([]*controlPlaneRevision, 0, len(revisions))
+	for _, r := range revisions {
+		sort.Strings(r.namespaces)
+		sort.Strings(r.proxies)
+		sort.Strings(r.gateways)
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out, nil
+}
+
+func listRevisions(client kubernetes.Interface, istioNamespace string, writer io.Writer) error {
+	revisions, err := getRevisions(client, istioNamespace)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(writer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "REVISION\tVERSION\tPILOT\tNAMESPACES\tPROXIES\tGATEWAYS")
+	for _, r := range revisions {
+		pilot := r.pilot
+		if pilot == "" {
+			pilot = "<not installed>"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\n", r.name, r.version, pilot, len(r.namespaces), len(r.proxies),
+			len(r.gateways))
+	}
+	return w.Flush()
+}
+
+// checkInstalled returns an error if the revision rev is not installed.
+func checkInstalled(revisions []*controlPlaneRevision, istioNamespace, rev string) error {
+	for _, r := range revisions {
+		if r.name == rev && r.pilot != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("revision %s is not installed in namespace %s", rev, istioNamespace)
+}
+
+func migrateNamespaces(client kubernetes.Interface, istioNamespace string, namespaces []string, to string, writer io.Writer) error {
+	revisions, err := getRevisions(client, istioNamespace)
+	if err != nil {
+		return err
+	}
+	if err := checkInstalled(revisions, istioNamespace, to); err != nil {
+		return err
+	}
+
+	add, remove := revision.NamespaceLabels(to)
+	for _, name := range namespaces {
+		ns, err := client.CoreV1().Namespaces().Get(name, meta_v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if ns.Labels == nil {
+			ns.Labels = map[string]string{}
+		}
+		for k, v := range add {
+			ns.Labels[k] = v
+		}
+		for _, k := range remove {
+			delete(ns.Labels, k)
+		}
+		if _, err := client.CoreV1().Namespaces().Update(ns); err != nil {
+			return err
+		}
+		fmt.Fprintf(writer, "Namespace %s now uses revision %s. Restart its workloads to move their proxies, "+
+			"e.g. kubectl rollout restart deployment -n %s\n", name, to, name)
+	}
+	return nil
+}
+
+func promoteRevision(client kubernetes.Interface, istioNamespace, rev string, writer io.Writer) error {
+	revisions, err := getRevisions(client, istioNamespace)
+	if err != nil {
+		return err
+	}
+	if err := checkInstalled(revisions, istioNamespace, rev); err != nil {
+		return err
+	}
+
+	gateways, err := client.AppsV1().Deployments(istioNamespace).List(meta_v1.ListOptions{LabelSelector: gatewaySelector})
+	if err != nil {
+		return err
+	}
+	for i := range gateways.Items {
+		d := &gateways.Items[i]
+		if revision.GatewayRevision(d) == rev {
+			continue
+		}
+		revision.MoveGateway(d, rev)
+		if _, err := client.AppsV1().Deployments(istioNamespace).Update(d); err != nil {
+			return err
+		}
+		fmt.Fprintf(writer, "Gateway %s now uses revision %s.\n", d.Name, rev)
+	}
+	return nil
+}
+
+func removeRevision(client kubernetes.Interface, istioNamespace, rev string, opts kubectlcmd.Options, writer io.Writer) error {
+	revisions, err := getRevisions(client, istioNamespace)
+	if err != nil {
+		return err
+	}
+	var r *controlPlaneRevision
+	for _, cur := range revisions {
+		if cur.name == rev {
+			r = cur
+		}
+	}
+	if r == nil {
+		return fmt.Errorf("revision %s is not installed in namespace %s", rev, istioNamespace)
+	}
+	if len(r.namespaces) > 0 {
+		return fmt.Errorf("revision %s still injects the namespaces %s, migrate them to another revision first",
+			rev, strings.Join(r.namespaces, ", "))
+	}
+	if len(r.proxies) > 0 {
+		return fmt.Errorf("revision %s still manages the proxies of %d pods, restart them first: %s",
+			rev, len(r.proxies), strings.Join(r.proxies, ", "))
+	}
+	if len(r.gateways) > 0 {
+		return fmt.Errorf("revision %s still manages the gateways %s, promote another revision first",
+			rev, strings.Join(r.gateways, ", "))
+	}
+
+	deleted, err := deleteRevision(rev, opts)
+	for _, o := range deleted {
+		fmt.Fprintf(writer, "%s %s deleted\n", o.Kind, o.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove revision %s: %v", rev, err)
+	}
+	if opts.DryRun {
+		fmt.Fprintf(writer, "Revision %s can be removed.\n", rev)
+	} else {
+		fmt.Fprintf(writer, "Revision %s removed.\n", rev)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/operator/pkg/kubectlcmd"
+	"istio.io/istio/operator/pkg/manifest"
+	"istio.io/istio/operator/pkg/object"
+)
+
+func pilotDeployment(name, version string, labels map[string]string) *appsv1.Deployment {
+	l := map[string]string{"app": "pilot"}
+	for k, v := range labels {
+		l[k] = v
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: l},
+		Spec: appsv1.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "discovery", Image: "docker.io/istio/pilot:" + version}},
+		}}},
+	}
+}
+
+func gatewayDeployment(name string, annotations map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "istio-system",
+			Labels:      map[string]string{"operator.istio.io/component": "IngressGateways"},
+			Annotations: annotations,
+		},
+		Spec: appsv1.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Name: "istio-proxy",
+				Args: []string{"proxy", "router", "--discoveryAddress", "istio-pilot.istio-system.svc:15012"},
+				Env:  []v1.EnvVar{{Name: "CA_ADDR", Value: "istio-pilot.istio-system.svc:15012"}},
+			}},
+		}}},
+	}
+}
+
+func revisionObjects(extra ...runtime.Object) []runtime.Object {
+	return append([]runtime.Object{
+		pilotDeployment("istio-pilot", "1.5.0", nil),
+		pilotDeployment("istio-pilot-canary", "1.6.0", map[string]string{"istio.io/rev": "canary"}),
+		gatewayDeployment("istio-ingressgateway", nil),
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"istio-injection": "enabled"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "bookinfo", Labels: map[string]string{"istio-injection": "enabled"}}},
+		injectedPod("productpage", "bookinfo", "current", nil),
+		injectedPod("reviews", "default", "current", nil),
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "not-injected", Namespace: "default"}},
+	}, extra...)
+}
+
+func TestListRevisions(t *testing.T) {
+	canaryPod := injectedPod("ratings", "canary", "current", nil)
+	canaryPod.Labels = map[string]string{"istio.io/rev": "canary"}
+	client := fake.NewSimpleClientset(revisionObjects(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "canary", Labels: map[string]string{"istio.io/rev": "canary"}}},
+		canaryPod,
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "old", Labels: map[string]string{"istio.io/rev": "old"}}},
+	)...)
+
+	var out bytes.Buffer
+	if err := listRevisions(client, "istio-system", &out); err != nil {
+		t.Fatal(err)
+	}
+	want := `REVISION  VERSION  PILOT               NAMESPACES  PROXIES  GATEWAYS
+canary    1.6.0    istio-pilot-canary  1           1        0
+default   1.5.0    istio-pilot         2           2        1
+old                <not installed>     1           0        0
+`
+	if out.String() != want {
+		t.Errorf("listRevisions() output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestMigrateNamespaces(t *testing.T) {
+	client := fake.NewSimpleClientset(revisionObjects()...)
+
+	var out bytes.Buffer
+	if err := migrateNamespaces(client, "istio-system", []string{"bookinfo"}, "canary", &out); err != nil {
+		t.Fatal(err)
+	}
+	ns, _ := client.CoreV1().Namespaces().Get("bookinfo", metav1.GetOptions{})
+	if ns.Labels["istio.io/rev"] != "canary" || ns.Labels["istio-injection"] != "" {
+		t.Errorf("got labels %v after the migration to canary", ns.Labels)
+	}
+	if !strings.Contains(out.String(), "kubectl rollout restart deployment -n bookinfo") {
+		t.Errorf("expected the output to tell to restart the workloads, got %s", out.String())
+	}
+
+	if err := migrateNamespaces(client, "istio-system", []string{"bookinfo"}, "default", &out); err != nil {
+		t.Fatal(err)
+	}
+	ns, _ = client.CoreV1().Namespaces().Get("bookinfo", metav1.GetOptions{})
+	if ns.Labels["istio.io/rev"] != "" || ns.Labels["istio-injection"] != "enabled" {
+		t.Errorf("got labels %v after the migration back to default", ns.Labels)
+	}
+
+	if err := migrateNamespaces(client, "istio-system", []string{"bookinfo"}, "unknown", &out); err == nil {
+		t.Errorf("expected the migration to a revision not installed to fail")
+	}
+}
+
+func TestPromoteRevision(t *testing.T) {
+	client := fake.NewSimpleClientset(revisionObjects()...)
+
+	var out bytes.Buffer
+	if err := promoteRevision(client, "istio-system", "canary", &out); err != nil {
+		t.Fatal(err)
+	}
+	d, _ := client.AppsV1().Deployments("istio-system").Get("istio-ingressgateway", metav1.GetOptions{})
+	if d.Annotations["istio.io/rev"] != "canary" {
+		t.Errorf("got annotations %v after the promotion of canary", d.Annotations)
+	}
+	if _, ok := d.Labels["istio.io/rev"]; ok {
+		t.Errorf("expected the gateway not to be labelled with the revision, which would delete it with the revision")
+	}
+	c := d.Spec.Template.Spec.Containers[0]
+	if c.Args[3] != "istio-pilot-canary.istio-system.svc:15012" || c.Env[0].Value != "istio-pilot-canary.istio-system.svc:15012" {
+		t.Errorf("got args %v and env %v after the promotion of canary", c.Args, c.Env)
+	}
+	if !strings.Contains(out.String(), "Gateway istio-ingressgateway now uses revision canary") {
+		t.Errorf("expected the output to list the moved gateways, got %s", out.String())
+	}
+
+	if err := promoteRevision(client, "istio-system", "default", &out); err != nil {
+		t.Fatal(err)
+	}
+	d, _ = client.AppsV1().Deployments("istio-system").Get("istio-ingressgateway", metav1.GetOptions{})
+	if _, ok := d.Annotations["istio.io/rev"]; ok || d.Spec.Template.Spec.Containers[0].Args[3] != "istio-pilot.istio-system.svc:15012" {
+		t.Errorf("got annotations %v and args %v after the promotion of default", d.Annotations, d.Spec.Template.Spec.Containers[0].Args)
+	}
+
+	if err := promoteRevision(client, "istio-system", "unknown", &out); err == nil {
+		t.Errorf("expected the promotion of a revision not installed to fail")
+	}
+}
+
+func TestRemoveRevision(t *testing.T) {
+	canaryPod := injectedPod("ratings", "default", "current", nil)
+	canaryPod.Labels = map[string]string{"istio.io/rev": "canary"}
+	// The default revision retired: the namespaces, the proxies and the gateways use canary.
+	retiredDefault := []runtime.Object{
+		pilotDeployment("istio-pilot", "1.5.0", nil),
+		pilotDeployment("istio-pilot-canary", "1.6.0", map[string]string{"istio.io/rev": "canary"}),
+		gatewayDeployment("istio-ingressgateway", map[string]string{"istio.io/rev": "canary"}),
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "bookinfo", Labels: map[string]string{"istio.io/rev": "canary"}}},
+		canaryPod,
+	}
+
+	cases := []struct {
+		name        string
+		objects     []runtime.Object
+		rev         string
+		wantErr     string
+		wantDeleted bool
+	}{
+		{
+			name:        "unused revision",
+			objects:     revisionObjects(),
+			rev:         "canary",
+			wantDeleted: true,
+		},
+		{
+			name: "revision injecting a namespace",
+			objects: revisionObjects(
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "canary", Labels: map[string]string{"istio.io/rev": "canary"}}},
+			),
+			rev:     "canary",
+			wantErr: "still injects the namespaces canary",
+		},
+		{
+			name:    "revision managing proxies",
+			objects: revisionObjects(canaryPod),
+			rev:     "canary",
+			wantErr: "still manages the proxies of 1 pods, restart them first: ratings.default",
+		},
+		{
+			name:        "retired default revision",
+			objects:     retiredDefault,
+			rev:         "default",
+			wantDeleted: true,
+		},
+		{
+			name: "revision managing gateways",
+			objects: []runtime.Object{
+				pilotDeployment("istio-pilot", "1.5.0", nil),
+				gatewayDeployment("istio-ingressgateway", nil),
+			},
+			rev:     "default",
+			wantErr: "still manages the gateways istio-ingressgateway, promote another revision first",
+		},
+		{
+			name:    "unknown revision",
+			objects: revisionObjects(),
+			rev:     "unknown",
+			wantErr: "not installed",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var deleted string
+			deleteRevision = func(rev string, opts kubectlcmd.Options) (object.K8sObjects, error) {
+				deleted = rev
+				return nil, nil
+			}
+			defer func() { deleteRevision = manifest.DeleteRevision }()
+
+			var out bytes.Buffer
+			err := removeRevision(fake.NewSimpleClientset(c.objects...), "istio-system", c.rev, kubectlcmd.Options{}, &out)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("removeRevision() = %v, want an error containing %q", err, c.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("removeRevision() = %v", err)
+			}
+			if c.wantDeleted != (deleted == c.rev) {
+				t.Errorf("got revision %q deleted, want it deleted: %v", deleted, c.wantDeleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ghodss/yaml"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/spf13/cobra"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/api/annotation"
+	"istio.io/pkg/log"
+	"istio.io/pkg/version"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/upgrade"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/local"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/galley/pkg/config/resource"
+	"istio.io/istio/galley/pkg/config/schema"
+	cfgKube "istio.io/istio/galley/pkg/config/source/kube"
+	"istio.io/istio/operator/pkg/revision"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/kube/inject"
+)
+
+const (
+	injectConfigMapKey = "config"
+	analysisTimeout    = 30 * time.Second
+)
+
+var (
+	upgradeClientFactory = createUpgradeClient
+)
+
+// upgradeClient gives access to the cluster checked before an upgrade.
+type upgradeClient struct {
+	kube kubernetes.Interface
+	// analyze runs the upgrade analyzer over the Istio configuration and the pods of the cluster.
+	analyze func(a analysis.Analyzer, istioNamespace string) (diag.Messages, error)
+}
+
+// NewUpgradePreCheckCommand creates the command checking the cluster before an upgrade of Istio.
+func NewUpgradePreCheckCommand() *cobra.Command {
+	var (
+		kubeConfigFlags = &genericclioptions.ConfigFlags{
+			Context:    strPtr(""),
+			Namespace:  strPtr(""),
+			KubeConfig: strPtr(""),
+		}
+		istioNamespace string
+		targetVersion  string
+	)
+	cmd := &cobra.Command{
+		Use:   "precheck",
+		Short: "Checks the cluster for configuration and proxies incompatible with the target version of an upgrade",
+		Long: `
+		precheck loads the Istio configuration and the pods of the cluster into the analysis
+		pipeline, and reports what would break or be deprecated after upgrading the control
+		plane to the target version:
+
+		  - Istio resources and fields deprecated or removed in the target version, e.g. the
+		    v1alpha1 authentication and RBAC APIs, the Mixer configuration and the v1 EnvoyFilter API
+		  - proxies whose version is outside the skew supported by the target version
+		  - injected pods whose sidecar template differs from the current injection template
+`,
+		Example: `
+		# Check the cluster before upgrading to the version of istioctl
+		istioctl x precheck
+
+		# Check the cluster before upgrading to Istio 1.5
+		istioctl x precheck --target-version 1.5
+`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := upgrade.ValidateVersion(targetVersion); err != nil {
+				return fmt.Errorf("invalid --target-version: %v", err)
+			}
+			return upgradePreCheck(targetVersion, istioNamespace, kubeConfigFlags, c.OutOrStdout())
+		},
+	}
+
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&istioNamespace, "istioNamespace", "i", controller.IstioNamespace,
+		"Istio system namespace")
+	flags.StringVar(&targetVersion, "target-version", version.Info.Version,
+		"Istio version targeted by the upgrade, defaults to the version of istioctl")
+	kubeConfigFlags.AddFlags(flags)
+	return cmd
+}
+
+func upgradePreCheck(targetVersion, istioNamespace string, restClientGetter genericclioptions.RESTClientGetter, writer io.Writer) error {
+	fmt.Fprintf(writer, "\n")
+	fmt.Fprintf(writer, "Checking the cluster to make sure it is ready for the upgrade to Istio %s...\n", targetVersion)
+	fmt.Fprintf(writer, "\n")
+
+	c, err := upgradeClientFactory(restClientGetter)
+	if err != nil {
+		return fmt.Errorf("failed to initialize the Kubernetes client: %v", err)
+	}
+
+	var errs error
+	messages, err := c.analyze(&upgrade.Analyzer{TargetVersion: targetVersion}, istioNamespace)
+	if err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("failed to analyze the cluster: %v", err))
+	}
+	var configMessages, proxyMessages diag.Messages
+	for _, m := range messages {
+		if m.Type == msg.ProxyVersionOutsideSupportedSkew {
+			proxyMessages = append(proxyMessages, m)
+		} else {
+			configMessages = append(configMessages, m)
+		}
+		if m.Type.Level() == diag.Error {
+			errs = multierror.Append(errs, fmt.Errorf("%s", m.String()))
+		}
+	}
+
+	fmt.Fprintf(writer, "#1. Istio-config\n")
+	fmt.Fprintf(writer, "-----------------------\n")
+	if len(configMessages) == 0 {
+		fmt.Fprintf(writer, "No Istio configuration incompatible with Istio %s found.\n", targetVersion)
+	}
+	for _, m := range configMessages {
+		fmt.Fprintln(writer, m.String())
+	}
+
+	fmt.Fprintf(writer, "\n")
+	fmt.Fprintf(writer, "#2. Proxy-versions\n")
+	fmt.Fprintf(writer, "-----------------------\n")
+	if len(proxyMessages) == 0 {
+		fmt.Fprintf(writer, "All the proxies are supported by Istio %s.\n", targetVersion)
+	}
+	for _, m := range proxyMessages {
+		fmt.Fprintln(writer, m.String())
+	}
+
+	fmt.Fprintf(writer, "\n")
+	fmt.Fprintf(writer, "#3. Sidecar-templates\n")
+	fmt.Fprintf(writer, "-----------------------\n")
+	stale, err := stalePods(c.kube, istioNamespace)
+	if err != nil {
+		errs = multierror.Append(errs, err)
+		fmt.Fprintf(writer, "Failed to check the sidecar templates: %v.\n", err)
+	} else if len(stale) == 0 {
+		fmt.Fprintf(writer, "All the injected pods use the current sidecar template.\n")
+	} else {
+		for _, p := range stale {
+			fmt.Fprintf(writer, "Pod %s was injected with an outdated sidecar template.\n", p)
+		}
+		fmt.Fprintf(writer, "Restart these pods to inject the current sidecar template.\n")
+	}
+
+	fmt.Fprintf(writer, "\n")
+	fmt.Fprintf(writer, "-----------------------\n")
+	if errs == nil {
+		fmt.Fprintf(writer, "Upgrade Pre-Check passed! The cluster is ready for the upgrade to Istio %s.\n", targetVersion)
+	}
+	fmt.Fprintf(writer, "\n")
+	return errs
+}
+
+// stalePods returns the injected pods, as <name>.<namespace>, whose sidecar was injected with another
+// version of the template they would be injected with now by their revision. The pods whose template can't be
+// selected are skipped.
+func stalePods(client kubernetes.Interface, istioNamespace string) ([]string, error) {
+	namespaces, err := client.CoreV1().Namespaces().List(meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	namespaceLabels := make(map[string]map[string]string, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		namespaceLabels[ns.Name] = ns.Labels
+	}
+
+	pods, err := client.CoreV1().Pods(meta_v1.NamespaceAll).List(meta_v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// the injection configurations by revision, loaded once needed
+	injectConfigs := map[string]*inject.Config{}
+	var stale []string
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		rev := podRevision(pod)
+		if rev == "" {
+			continue
+		}
+		var status inject.SidecarInjectionStatus
+		if err := json.Unmarshal([]byte(pod.Annotations[annotation.SidecarStatus.Name]), &status); err != nil {
+			continue
+		}
+		injectConfig, ok := injectConfigs[rev]
+		if !ok {
+			if injectConfig, err = getInjectConfig(client, istioNamespace, rev); err != nil {
+				return nil, err
+			}
+			injectConfigs[rev] = injectConfig
+		}
+		_, template, err := injectConfig.SelectTemplate(namespaceLabels[pod.Namespace], &pod.ObjectMeta)
+		if err != nil {
+			log.Warnf("skipping pod %s.%s: could not select its sidecar template: %v", pod.Name, pod.Namespace, err)
+			continue
+		}
+		if status.Version != inject.TemplateVersion(template) {
+			stale = append(stale, pod.Name+"."+pod.Namespace)
+		}
+	}
+	return stale, nil
+}
+
+// getInjectConfig returns the sidecar injection configuration of the revision rev.
+func getInjectConfig(client kubernetes.Interface, istioNamespace, rev string) (*inject.Config, error) {
+	cm, err := client.CoreV1().ConfigMaps(istioNamespace).Get(revision.InjectorConfigMapName(rev), meta_v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get the sidecar injection configuration of revision %s: %v", rev, err)
+	}
+	var injectConfig inject.Config
+	if err := yaml.Unmarshal([]byte(cm.Data[injectConfigMapKey]), &injectConfig); err != nil {
+		return nil, fmt.Errorf("could not parse the sidecar injection configuration of revision %s: %v", rev, err)
+	}
+	return &injectConfig, nil
+}
+
+func createUpgradeClient(restClientGetter genericclioptions.RESTClientGetter) (*upgradeClient, error) {
+	restConfig, err := restClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	k, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &upgradeClient{
+		kube: k,
+		analyze: func(a analysis.Analyzer, istioNamespace string) (diag.Messages, error) {
+			sa := local.NewSourceAnalyzer(schema.MustGet(), analysis.Combine("upgrade", a),
+				resource.Namespace(""), resource.Namespace(istioNamespace), nil, true, analysisTimeout)
+			sa.AddRunningKubeSource(cfgKube.NewInterfaces(restConfig))
+			result, err := sa.Analyze(make(chan struct{}))
+			if err != nil {
+				return nil, err
+			}
+			return result.Messages.SortedDedupedCopy(), nil
+		},
+	}, nil
+}
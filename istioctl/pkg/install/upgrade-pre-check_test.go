@@ -0,0 +1,170 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube/inject"
+)
+
+func injectedPod(name, namespace, template string, annotations map[string]string) *v1.Pod {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations["sidecar.istio.io/status"] = fmt.Sprintf(`{"version":%q,"containers":["istio-proxy"]}`,
+		inject.TemplateVersion(template))
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations}}
+}
+
+func withLabels(pod *v1.Pod, labels map[string]string) *v1.Pod {
+	pod.Labels = labels
+	return pod
+}
+
+func TestUpgradePreCheck(t *testing.T) {
+	injectorConfigMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "istio-sidecar-injector", Namespace: "istio-system"},
+		Data: map[string]string{
+			"config": "policy: enabled\ntemplate: current\ntemplates:\n  gateway: gateway-current\n",
+		},
+	}
+	namespaces := []runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "gateways", Labels: map[string]string{"istio-injection-template": "gateway"}}},
+	}
+
+	cases := []struct {
+		name          string
+		objects       []runtime.Object
+		messages      diag.Messages
+		analyzeErr    error
+		wantException bool
+		wantOutput    []string
+	}{
+		{
+			name: "ready for the upgrade",
+			objects: []runtime.Object{
+				injectorConfigMap,
+				injectedPod("productpage", "default", "current", nil),
+				injectedPod("ingress", "gateways", "gateway-current", nil),
+				&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "not-injected", Namespace: "default"}},
+			},
+			messages: diag.Messages{
+				msg.NewDeprecatedInTargetVersion(nil, "rbac.istio.io/v1alpha1", "1.5", "use AuthorizationPolicy"),
+			},
+			wantOutput: []string{
+				"Warn [IST0120] rbac.istio.io/v1alpha1 is deprecated in Istio 1.5; use AuthorizationPolicy",
+				"All the proxies are supported by Istio 1.5.",
+				"All the injected pods use the current sidecar template.",
+				"Upgrade Pre-Check passed!",
+			},
+		},
+		{
+			name: "removed config and unsupported proxies",
+			objects: []runtime.Object{
+				injectorConfigMap,
+			},
+			messages: diag.Messages{
+				msg.NewRemovedInTargetVersion(nil, "EnvoyFilter.filters", "1.5", "use EnvoyFilter.configPatches"),
+				msg.NewProxyVersionOutsideSupportedSkew(nil, "1.3.5", "1.5"),
+			},
+			wantException: true,
+			wantOutput: []string{
+				"#1. Istio-config\n-----------------------\nError [IST0119] EnvoyFilter.filters is removed",
+				"#2. Proxy-versions\n-----------------------\nError [IST0121] The proxy version 1.3.5",
+			},
+		},
+		{
+			name: "stale sidecar templates",
+			objects: []runtime.Object{
+				injectorConfigMap,
+				injectedPod("productpage", "default", "previous", nil),
+				injectedPod("ingress", "gateways", "current", nil),
+			},
+			wantOutput: []string{
+				"No Istio configuration incompatible with Istio 1.5 found.",
+				"Pod ingress.gateways was injected with an outdated sidecar template.",
+				"Pod productpage.default was injected with an outdated sidecar template.",
+				"Upgrade Pre-Check passed!",
+			},
+		},
+		{
+			name: "revisions and unknown templates",
+			objects: []runtime.Object{
+				injectorConfigMap,
+				&v1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "istio-sidecar-injector-canary", Namespace: "istio-system"},
+					Data:       map[string]string{"config": "policy: enabled\ntemplate: canary\n"},
+				},
+				injectedPod("productpage", "default", "current", nil),
+				withLabels(injectedPod("reviews", "default", "canary", nil), map[string]string{"istio.io/rev": "canary"}),
+				injectedPod("custom", "default", "current", map[string]string{"sidecar.istio.io/template": "unknown"}),
+			},
+			wantOutput: []string{
+				"All the injected pods use the current sidecar template.",
+				"Upgrade Pre-Check passed!",
+			},
+		},
+		{
+			name: "missing injection configuration",
+			objects: []runtime.Object{
+				injectedPod("productpage", "default", "current", nil),
+			},
+			analyzeErr:    fmt.Errorf("unreachable"),
+			wantException: true,
+			wantOutput: []string{
+				"Failed to check the sidecar templates",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			upgradeClientFactory = func(genericclioptions.RESTClientGetter) (*upgradeClient, error) {
+				return &upgradeClient{
+					kube: fake.NewSimpleClientset(append(c.objects, namespaces...)...),
+					analyze: func(analysis.Analyzer, string) (diag.Messages, error) {
+						return c.messages, c.analyzeErr
+					},
+				}, nil
+			}
+			defer func() { upgradeClientFactory = createUpgradeClient }()
+
+			var out bytes.Buffer
+			err := upgradePreCheck("1.5", "istio-system", nil, &out)
+			if c.wantException != (err != nil) {
+				t.Fatalf("upgradePreCheck() returned %v, want an error: %v", err, c.wantException)
+			}
+			for _, want := range c.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("upgradePreCheck() output does not contain %q:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
@@ -129,10 +129,10 @@ func (client *Client) PodExec(podName, podNamespace, container string, command [
 	return &stdout, &stderr, err
 }
 
-// AllPilotsDiscoveryDo makes an http request to each Pilot discovery instance
+// AllPilotsDiscoveryDo makes an http request to each Pilot discovery instance, of all the revisions of the control plane
 func (client *Client) AllPilotsDiscoveryDo(pilotNamespace, method, path string, body []byte) (map[string][]byte, error) {
 	pilots, err := client.GetIstioPods(pilotNamespace, map[string]string{
-		"labelSelector": "istio=pilot",
+		"labelSelector": "app=pilot",
 		"fieldSelector": "status.phase=Running",
 	})
 	if err != nil {
@@ -23,8 +23,10 @@ import (
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
 
+	istioctlkube "istio.io/istio/istioctl/pkg/kubernetes"
 	"istio.io/istio/pkg/kube"
 )
 
@@ -33,6 +35,8 @@ type ConditionFunc func() (done bool, err error)
 type Environment interface {
 	GetConfig() *api.Config
 	CreateClientSet(context string) (kubernetes.Interface, error)
+	CreateClientSetFromKubeconfig(kubeconfig []byte) (kubernetes.Interface, error)
+	CreateExecClient(context string) (istioctlkube.ExecClient, error)
 	Stdout() io.Writer
 	Stderr() io.Writer
 	ReadFile(filename string) ([]byte, error)
@@ -52,6 +56,18 @@ func (e *KubeEnvironment) CreateClientSet(context string) (kubernetes.Interface,
 	return kube.CreateClientset(e.kubeconfig, context)
 }
 
+func (e *KubeEnvironment) CreateClientSetFromKubeconfig(kubeconfig []byte) (kubernetes.Interface, error) {
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+func (e *KubeEnvironment) CreateExecClient(context string) (istioctlkube.ExecClient, error) {
+	return istioctlkube.NewClient(e.kubeconfig, context)
+}
+
 func (e *KubeEnvironment) Printf(format string, a ...interface{}) {
 	_, _ = fmt.Fprintf(e.stdout, format, a...)
 }
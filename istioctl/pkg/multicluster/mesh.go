@@ -135,6 +135,7 @@ func NewMulticlusterCommand() *cobra.Command {
 		NewGenerateCommand(),
 		NewApplyCommand(),
 		NewDescribeCommand(),
+		NewValidateCommand(),
 	)
 
 	return c
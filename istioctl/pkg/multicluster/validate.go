@@ -0,0 +1,406 @@
+// Copyright 2019 Istio Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/mesh/v1alpha1"
+
+	"istio.io/istio/pkg/config/mesh"
+)
+
+const (
+	meshConfigMapName      = "istio"
+	meshNetworksConfigKey  = "meshNetworks"
+	pilotRegistryDebugPath = "/debug/registryz"
+)
+
+var (
+	// lookupHost resolves the hostnames of the gateways. It is overridden in tests.
+	lookupHost = net.LookupHost
+)
+
+// checkResult is the result of one check of the validation of the mesh.
+type checkResult struct {
+	passed bool
+	// reason explains why the check failed.
+	reason string
+}
+
+func pass() *checkResult {
+	return &checkResult{passed: true}
+}
+
+func fail(format string, a ...interface{}) *checkResult {
+	return &checkResult{reason: fmt.Sprintf(format, a...)}
+}
+
+func (r *checkResult) String() string {
+	switch {
+	case r == nil:
+		return "-"
+	case r.passed:
+		return "PASS"
+	default:
+		return "FAIL"
+	}
+}
+
+// validationMatrix holds the results of a check between each row, a cluster, and each column, e.g. another
+// cluster or a network. A nil result means the check doesn't apply.
+type validationMatrix struct {
+	title   string
+	rows    []string
+	columns []string
+	results map[string]map[string]*checkResult
+}
+
+func newValidationMatrix(title string, rows, columns []string) *validationMatrix {
+	results := make(map[string]map[string]*checkResult, len(rows))
+	for _, row := range rows {
+		results[row] = make(map[string]*checkResult, len(columns))
+	}
+	return &validationMatrix{title: title, rows: rows, columns: columns, results: results}
+}
+
+func (m *validationMatrix) set(row, column string, result *checkResult) {
+	m.results[row][column] = result
+}
+
+func (m *validationMatrix) print(env Environment) {
+	env.Printf("%v\n", m.title)
+	tw := tabwriter.NewWriter(env.Stdout(), 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "    \t%v\n", strings.Join(m.columns, "\t"))
+	for _, row := range m.rows {
+		cells := make([]string, 0, len(m.columns))
+		for _, column := range m.columns {
+			cells = append(cells, m.results[row][column].String())
+		}
+		_, _ = fmt.Fprintf(tw, "    %v\t%v\n", row, strings.Join(cells, "\t"))
+	}
+	_ = tw.Flush()
+}
+
+// failures returns the reasons of the failed checks.
+func (m *validationMatrix) failures() []string {
+	var failures []string
+	for _, row := range m.rows {
+		for _, column := range m.columns {
+			if r := m.results[row][column]; r != nil && !r.passed {
+				failures = append(failures, fmt.Sprintf("%v: %v -> %v: %v", m.title, row, column, r.reason))
+			}
+		}
+	}
+	return failures
+}
+
+// checkRemoteSecret checks that the remote secret of other in the cluster is valid, and that the cluster can
+// reach the Kubernetes API of other with it.
+func checkRemoteSecret(env Environment, secrets remoteSecrets, other *Cluster) *checkResult {
+	state, _ := secretStateAndServer(env, secrets, other)
+	if state != rsStatusOk {
+		return fail("remote secret is %v", state)
+	}
+	client, err := env.CreateClientSetFromKubeconfig(secrets[other.uid].Data[string(other.uid)])
+	if err != nil {
+		return fail("could not create a client from the remote secret: %v", err)
+	}
+	uid, err := clusterUID(client)
+	if err != nil {
+		return fail("Kubernetes API is not reachable with the remote secret: %v", err)
+	}
+	if uid != other.uid {
+		return fail("remote secret gives access to cluster %v instead of %v", uid, other.uid)
+	}
+	return pass()
+}
+
+// rootCert returns the root certificate of the cluster, with the plugged-in CA certificates taking precedence
+// over the self-signed ones.
+func (cs *CACerts) rootCert() *x509.Certificate {
+	if cs.externalRootCert != nil {
+		return cs.externalRootCert
+	}
+	return cs.selfSignedCACert
+}
+
+// caCert returns the certificate the cluster signs the workload certificates with.
+func (cs *CACerts) caCert() *x509.Certificate {
+	if cs.externalCACert != nil {
+		return cs.externalCACert
+	}
+	return cs.selfSignedCACert
+}
+
+// checkTrust checks that the CA certificate of a cluster is trusted by the root certificate of the other cluster.
+func checkTrust(c *Cluster, certs *CACerts, other *Cluster, otherCerts *CACerts) *checkResult {
+	ca, root := certs.caCert(), otherCerts.rootCert()
+	switch {
+	case ca == nil:
+		return fail("no CA certificate found in cluster %v", c.Context)
+	case root == nil:
+		return fail("no root certificate found in cluster %v", other.Context)
+	case bytes.Equal(ca.Raw, root.Raw):
+		return pass()
+	}
+	if err := ca.CheckSignatureFrom(root); err != nil {
+		return fail("CA certificate %q is not signed by root certificate %q: %v", ca.Subject, root.Subject, err)
+	}
+	return pass()
+}
+
+type registryService struct {
+	Hostname    string            `json:"hostname"`
+	ClusterVIPs map[string]string `json:"cluster-vips"`
+}
+
+// readRegistry returns the clusters of the services known by each Pilot instance of the cluster.
+func (c *Cluster) readRegistry(env Environment) ([]map[string]bool, error) {
+	client, err := env.CreateExecClient(c.Context)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := client.AllPilotsDiscoveryDo(c.Namespace, "GET", pilotRegistryDebugPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no Pilot instance found in namespace %v", c.Namespace)
+	}
+	registries := make([]map[string]bool, 0, len(responses))
+	for pilot, response := range responses {
+		var services []registryService
+		if err := json.Unmarshal(response, &services); err != nil {
+			return nil, fmt.Errorf("could not parse the registry of %v: %v", pilot, err)
+		}
+		clusters := map[string]bool{}
+		for _, svc := range services {
+			for cluster := range svc.ClusterVIPs {
+				clusters[cluster] = true
+			}
+		}
+		registries = append(registries, clusters)
+	}
+	return registries, nil
+}
+
+// checkRegistry checks that all the Pilot instances of a cluster know services of the other cluster.
+func checkRegistry(registries []map[string]bool, err error, other *Cluster) *checkResult {
+	if err != nil {
+		return fail("could not read the Pilot registry: %v", err)
+	}
+	for _, clusters := range registries {
+		if !clusters[string(other.uid)] {
+			return fail("Pilot does not know any service of cluster %v", other.uid)
+		}
+	}
+	return pass()
+}
+
+// readMeshNetworks returns the mesh networks configuration of the control plane of the cluster.
+func (c *Cluster) readMeshNetworks() (*v1alpha1.MeshNetworks, error) {
+	cm, err := c.client.CoreV1().ConfigMaps(c.Namespace).Get(meshConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	networks, ok := cm.Data[meshNetworksConfigKey]
+	if !ok {
+		return nil, fmt.Errorf("%q not found in configmap %v", meshNetworksConfigKey, meshConfigMapName)
+	}
+	return mesh.ParseMeshNetworks(networks)
+}
+
+// checkGateways checks that the mesh networks configuration of a cluster has gateways for the network, and that they
+// resolve to an address.
+func checkGateways(m *Mesh, networks *v1alpha1.MeshNetworks, err error, network string) *checkResult {
+	if err != nil {
+		return fail("could not read the mesh networks: %v", err)
+	}
+	n, ok := networks.Networks[network]
+	if !ok {
+		return fail("network %v not found in the mesh networks", network)
+	}
+	if len(n.Gateways) == 0 {
+		return fail("no gateway configured for network %v", network)
+	}
+	for _, gw := range n.Gateways {
+		if address := gw.GetAddress(); address != "" {
+			if net.ParseIP(address) != nil {
+				continue
+			}
+			if _, err := lookupHost(address); err != nil {
+				return fail("gateway %v does not resolve: %v", address, err)
+			}
+			continue
+		}
+		name := gw.GetRegistryServiceName()
+		if !m.registryServiceResolves(name, network) {
+			return fail("gateway service %v has no load balancer address in network %v", name, network)
+		}
+	}
+	return pass()
+}
+
+// registryServiceResolves returns true if a cluster of the network has a load balancer address for the service,
+// given as <name>.<namespace>[.svc.<domain>].
+func (m *Mesh) registryServiceResolves(name, network string) bool {
+	parts := strings.Split(name, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	for _, c := range m.SortedClusters() {
+		if c.Network != network {
+			continue
+		}
+		svc, err := c.client.CoreV1().Services(parts[1]).Get(parts[0], metav1.GetOptions{})
+		if err == nil && len(gatewaysFromServiceStatus(&svc.Status, c)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the clusters of the mesh can access each other, share a common trust, discover the services
+// of each other and have reachable gateways for each network, and prints the results as pass/fail matrices.
+func Validate(opt validateOptions, env Environment) error {
+	m, err := meshFromFileDesc(opt.filename, env)
+	if err != nil {
+		return err
+	}
+
+	clusters := m.SortedClusters()
+	contexts := make([]string, 0, len(clusters))
+	networkSet := map[string]bool{}
+	for _, c := range clusters {
+		contexts = append(contexts, c.Context)
+		networkSet[c.Network] = true
+	}
+	networkNames := make([]string, 0, len(networkSet))
+	for n := range networkSet {
+		networkNames = append(networkNames, n)
+	}
+	sort.Strings(networkNames)
+
+	secretsMatrix := newValidationMatrix("Remote secrets", contexts, contexts)
+	trustMatrix := newValidationMatrix("Trust", contexts, contexts)
+	registryMatrix := newValidationMatrix("Service registry", contexts, contexts)
+	gatewaysMatrix := newValidationMatrix("Network gateways", contexts, networkNames)
+
+	caCerts := make(map[string]*CACerts, len(clusters))
+	for _, c := range clusters {
+		caCerts[c.Context] = c.readCACerts(env)
+	}
+
+	for _, c := range clusters {
+		secrets := c.readRemoteSecrets(env)
+		registries, registryErr := c.readRegistry(env)
+		for _, other := range clusters {
+			if other.uid == c.uid {
+				continue
+			}
+			trustMatrix.set(c.Context, other.Context, checkTrust(c, caCerts[c.Context], other, caCerts[other.Context]))
+			if c.DisableRegistryJoin || other.DisableRegistryJoin {
+				continue
+			}
+			secretsMatrix.set(c.Context, other.Context, checkRemoteSecret(env, secrets, other))
+			registryMatrix.set(c.Context, other.Context, checkRegistry(registries, registryErr, other))
+		}
+
+		// Only the other networks are reached through their gateways
+		if c.DisableRegistryJoin {
+			continue
+		}
+		networks, networksErr := c.readMeshNetworks()
+		for _, network := range networkNames {
+			if network != c.Network {
+				gatewaysMatrix.set(c.Context, network, checkGateways(m, networks, networksErr, network))
+			}
+		}
+	}
+
+	var failures []string
+	for _, matrix := range []*validationMatrix{secretsMatrix, trustMatrix, registryMatrix, gatewaysMatrix} {
+		matrix.print(env)
+		env.Printf("\n")
+		failures = append(failures, matrix.failures()...)
+	}
+
+	if len(failures) > 0 {
+		env.Printf("Failures:\n")
+		for _, f := range failures {
+			env.Printf("    %v\n", f)
+		}
+		return fmt.Errorf("%v checks of the multicluster mesh failed", len(failures))
+	}
+	env.Printf("All checks of the multicluster mesh passed.\n")
+	return nil
+}
+
+type validateOptions struct {
+	KubeOptions
+	filenameOption
+}
+
+func (o *validateOptions) prepare(flags *pflag.FlagSet) error {
+	o.KubeOptions.prepare(flags)
+	return o.filenameOption.prepare()
+}
+
+func (o *validateOptions) addFlags(flags *pflag.FlagSet) {
+	o.filenameOption.addFlags(flags)
+}
+
+func NewValidateCommand() *cobra.Command {
+	opt := validateOptions{}
+	c := &cobra.Command{
+		Use:   "validate -f <mesh.yaml>",
+		Short: `Validate the topology of the multi-cluster mesh`,
+		Long: `Validate the topology of the multi-cluster mesh and print a pass/fail matrix for each check:
+
+  - the remote secrets of each cluster are valid and give access to the Kubernetes API of the other clusters
+  - the CA certificate of each cluster is trusted by the root certificate of the other clusters
+  - the Pilot instances of each cluster know the services of the other clusters
+  - the mesh networks configuration of each cluster has gateways for the other networks which resolve
+`,
+		Example: `
+# Validate the multi-cluster mesh described in mesh.yaml
+istioctl x multicluster validate -f mesh.yaml
+`,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := opt.prepare(c.Flags()); err != nil {
+				return err
+			}
+			env, err := NewEnvironmentFromCobra(opt.Kubeconfig, opt.Context, c)
+			if err != nil {
+				return err
+			}
+			return Validate(opt, env)
+		},
+	}
+	opt.addFlags(c.PersistentFlags())
+	return c
+}
@@ -0,0 +1,328 @@
+// Copyright 2019 Istio Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+
+	istioctlkube "istio.io/istio/istioctl/pkg/kubernetes"
+	"istio.io/istio/pkg/kube/secretcontroller"
+	"istio.io/pkg/version"
+)
+
+// fakeExecClient returns the canned responses of the Pilot instances of a cluster.
+type fakeExecClient struct {
+	istioctlkube.ExecClient
+	pilotResponses map[string][]byte
+}
+
+func (f *fakeExecClient) AllPilotsDiscoveryDo(pilotNamespace, method, path string, body []byte) (map[string][]byte, error) {
+	if path != pilotRegistryDebugPath {
+		return nil, fmt.Errorf("unexpected path %v", path)
+	}
+	return f.pilotResponses, nil
+}
+
+func (f *fakeExecClient) GetIstioVersions(namespace string) (*version.MeshInfo, error) {
+	return nil, nil
+}
+
+// validateEnvironment is a fake environment of a mesh with one fake client per cluster.
+type validateEnvironment struct {
+	KubeEnvironment
+
+	clients         map[string]kubernetes.Interface
+	clientsByServer map[string]kubernetes.Interface
+	execClients     map[string]istioctlkube.ExecClient
+	meshDesc        string
+	wOut            bytes.Buffer
+}
+
+func (e *validateEnvironment) CreateClientSet(context string) (kubernetes.Interface, error) {
+	return e.clients[context], nil
+}
+
+func (e *validateEnvironment) CreateClientSetFromKubeconfig(kubeconfig []byte) (kubernetes.Interface, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	client, ok := e.clientsByServer[config.Clusters[config.CurrentContext].Server]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return client, nil
+}
+
+func (e *validateEnvironment) CreateExecClient(context string) (istioctlkube.ExecClient, error) {
+	return e.execClients[context], nil
+}
+
+func (e *validateEnvironment) ReadFile(filename string) ([]byte, error) {
+	return []byte(e.meshDesc), nil
+}
+
+type validateCluster struct {
+	context  string
+	uid      types.UID
+	network  string
+	objs     []runtime.Object
+	registry string
+}
+
+func newValidateEnvironment(t *testing.T, clusters ...validateCluster) *validateEnvironment {
+	t.Helper()
+
+	config := api.NewConfig()
+	env := &validateEnvironment{
+		clients:         map[string]kubernetes.Interface{},
+		clientsByServer: map[string]kubernetes.Interface{},
+		execClients:     map[string]istioctlkube.ExecClient{},
+		meshDesc:        "mesh_id: test\ncontexts:\n",
+	}
+	env.KubeEnvironment = KubeEnvironment{config: config, stdout: &env.wOut, stderr: &env.wOut}
+	for _, c := range clusters {
+		server := "https://" + c.context
+		config.Clusters[c.context] = &api.Cluster{Server: server}
+		config.Contexts[c.context] = &api.Context{Cluster: c.context}
+
+		objs := append([]runtime.Object{
+			&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: c.uid}},
+			makeNamespace(defaultIstioNamespace),
+		}, c.objs...)
+		client := fake.NewSimpleClientset(objs...)
+		env.clients[c.context] = client
+		env.clientsByServer[server] = client
+		env.execClients[c.context] = &fakeExecClient{pilotResponses: map[string][]byte{"istio-pilot-0": []byte(c.registry)}}
+		env.meshDesc += fmt.Sprintf("  %v:\n    network: %v\n", c.context, c.network)
+	}
+	return env
+}
+
+func makeValidateRemoteSecret(uid types.UID, context string) *v1.Secret {
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://%[1]v
+  name: %[1]v
+contexts:
+- context:
+    cluster: %[1]v
+    user: %[1]v
+  name: %[1]v
+current-context: %[1]v
+users:
+- name: %[1]v
+  user:
+    token: token
+`, context)
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      remoteSecretNameFromUID(uid),
+			Namespace: defaultIstioNamespace,
+			Labels:    map[string]string{secretcontroller.MultiClusterSecretLabel: "true"},
+		},
+		Data: map[string][]byte{string(uid): []byte(kubeconfig)},
+	}
+}
+
+func makeCASecrets(certs map[string][]byte, name string) *v1.Secret {
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: defaultIstioNamespace},
+		Data:       certs,
+	}
+}
+
+func makeMeshNetworks(networks string) *v1.ConfigMap {
+	return &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: meshConfigMapName, Namespace: defaultIstioNamespace},
+		Data:       map[string]string{meshNetworksConfigKey: networks},
+	}
+}
+
+func makeRegistry(clusters ...types.UID) string {
+	vips := make([]string, 0, len(clusters))
+	for _, c := range clusters {
+		vips = append(vips, fmt.Sprintf("%q: \"10.0.0.1\"", c))
+	}
+	return fmt.Sprintf(`[{"hostname": "reviews.default.svc.cluster.local", "cluster-vips": {%v}},{}]`, strings.Join(vips, ", "))
+}
+
+const (
+	validateMeshNetworks = `
+networks:
+  network0:
+    gateways:
+    - address: 1.1.1.1
+      port: 443
+  network1:
+    gateways:
+    - registryServiceName: istio-ingressgateway.istio-system.svc.cluster.local
+      port: 443
+`
+)
+
+var (
+	externalCACerts = map[string][]byte{"ca-cert.pem": caCertPEM, "root-cert.pem": rootCertPEM}
+
+	ingressGateway = &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: istioIngressGatewayServiceName, Namespace: defaultIstioNamespace},
+		Status: v1.ServiceStatus{LoadBalancer: v1.LoadBalancerStatus{
+			Ingress: []v1.LoadBalancerIngress{{IP: "2.2.2.2"}},
+		}},
+	}
+)
+
+func TestValidate(t *testing.T) {
+	lookupHost = func(host string) ([]string, error) {
+		if host == "gateway.example.com" {
+			return []string{"3.3.3.3"}, nil
+		}
+		return nil, fmt.Errorf("no such host %v", host)
+	}
+	defer func() { lookupHost = net.LookupHost }()
+
+	cases := []struct {
+		name         string
+		clusters     []validateCluster
+		wantErr      bool
+		wantOutput   string
+		wantFailures []string
+	}{
+		{
+			name: "valid mesh",
+			clusters: []validateCluster{
+				{
+					context: "c0",
+					uid:     "uid0",
+					network: "network0",
+					objs: []runtime.Object{
+						makeValidateRemoteSecret("uid1", "c1"),
+						makeCASecrets(externalCACerts, "cacerts"),
+						makeMeshNetworks(validateMeshNetworks),
+					},
+					registry: makeRegistry("Kubernetes", "uid1"),
+				},
+				{
+					context: "c1",
+					uid:     "uid1",
+					network: "network1",
+					objs: []runtime.Object{
+						makeValidateRemoteSecret("uid0", "c0"),
+						makeCASecrets(externalCACerts, "cacerts"),
+						makeMeshNetworks(strings.Replace(validateMeshNetworks, "1.1.1.1", "gateway.example.com", 1)),
+						ingressGateway,
+					},
+					registry: makeRegistry("Kubernetes", "uid0"),
+				},
+			},
+			wantOutput: `Remote secrets
+        c0    c1
+    c0  -     PASS
+    c1  PASS  -
+
+Trust
+        c0    c1
+    c0  -     PASS
+    c1  PASS  -
+
+Service registry
+        c0    c1
+    c0  -     PASS
+    c1  PASS  -
+
+Network gateways
+        network0  network1
+    c0  -         PASS
+    c1  PASS      -
+
+All checks of the multicluster mesh passed.
+`,
+		},
+		{
+			name: "broken mesh",
+			clusters: []validateCluster{
+				{
+					context: "c0",
+					uid:     "uid0",
+					network: "network0",
+					objs: []runtime.Object{
+						makeValidateRemoteSecret("uid1", "unreachable"),
+						makeCASecrets(externalCACerts, "cacerts"),
+						makeMeshNetworks(validateMeshNetworks),
+					},
+					registry: makeRegistry("Kubernetes", "uid1"),
+				},
+				{
+					context: "c1",
+					uid:     "uid1",
+					network: "network1",
+					objs: []runtime.Object{
+						makeCASecrets(map[string][]byte{"ca-cert.pem": selfSignedRootPEM}, "istio-ca-secret"),
+						makeMeshNetworks(strings.Replace(validateMeshNetworks, "1.1.1.1", "unknown.example.com", 1)),
+					},
+					registry: makeRegistry("Kubernetes"),
+				},
+			},
+			wantErr: true,
+			wantFailures: []string{
+				"Remote secrets: c0 -> c1: remote secret is serverAddrMismatch",
+				"Remote secrets: c1 -> c0: remote secret is notFound",
+				"Trust: c0 -> c1: CA certificate",
+				"Trust: c1 -> c0: CA certificate",
+				"Service registry: c1 -> c0: Pilot does not know any service of cluster uid0",
+				"Network gateways: c0 -> network1: gateway service istio-ingressgateway.istio-system.svc.cluster.local " +
+					"has no load balancer address in network network1",
+				"Network gateways: c1 -> network0: gateway unknown.example.com does not resolve: no such host unknown.example.com",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			env := newValidateEnvironment(t, c.clusters...)
+			err := Validate(validateOptions{filenameOption: filenameOption{filename: "mesh.yaml"}}, env)
+			if c.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			if c.wantOutput != "" {
+				g.Expect(env.wOut.String()).To(Equal(c.wantOutput))
+			}
+			for _, f := range c.wantFailures {
+				g.Expect(env.wOut.String()).To(ContainSubstring(f))
+			}
+		})
+	}
+}
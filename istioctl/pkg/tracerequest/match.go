@@ -0,0 +1,331 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracerequest
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+)
+
+// filterChainRank ranks a matching filter chain following the order in which Envoy narrows down the
+// candidate filter chains: destination port, destination IP, server name, transport protocol,
+// application protocols and source IP. A higher rank is more specific.
+type filterChainRank [6]int
+
+func (r filterChainRank) moreSpecificThan(o filterChainRank) bool {
+	for i := range r {
+		if r[i] != o[i] {
+			return r[i] > o[i]
+		}
+	}
+	return false
+}
+
+// matchFilterChain returns whether the request matches the filter chain match, and how specific the match is.
+func matchFilterChain(m *listener.FilterChainMatch, req *Request) (bool, filterChainRank) {
+	var rank filterChainRank
+	if m == nil {
+		return true, rank
+	}
+	if m.DestinationPort != nil {
+		if m.DestinationPort.Value != req.Port {
+			return false, rank
+		}
+		rank[0] = 1
+	}
+	if len(m.PrefixRanges) > 0 {
+		l, ok := longestPrefixMatch(m.PrefixRanges, req.DestinationIP)
+		if !ok {
+			return false, rank
+		}
+		rank[1] = l + 1
+	}
+	if len(m.ServerNames) > 0 {
+		s, ok := matchServerNames(m.ServerNames, req.SNI)
+		if !ok {
+			return false, rank
+		}
+		rank[2] = s
+	}
+	if m.TransportProtocol != "" {
+		if m.TransportProtocol != req.transportProtocol() {
+			return false, rank
+		}
+		rank[3] = 1
+	}
+	if len(m.ApplicationProtocols) > 0 {
+		if !intersects(m.ApplicationProtocols, req.applicationProtocols()) {
+			return false, rank
+		}
+		rank[4] = 1
+	}
+	if len(m.SourcePrefixRanges) > 0 {
+		l, ok := longestPrefixMatch(m.SourcePrefixRanges, req.SourceIP)
+		if !ok {
+			return false, rank
+		}
+		rank[5] = l + 1
+	}
+	return true, rank
+}
+
+// describeFilterChainMatch renders the criteria of a filter chain match.
+func describeFilterChainMatch(m *listener.FilterChainMatch) string {
+	if m == nil {
+		return "matches all connections"
+	}
+	var criteria []string
+	if m.DestinationPort != nil {
+		criteria = append(criteria, fmt.Sprintf("destination port %d", m.DestinationPort.Value))
+	}
+	if len(m.PrefixRanges) > 0 {
+		criteria = append(criteria, "destination "+renderCidrRanges(m.PrefixRanges))
+	}
+	if len(m.ServerNames) > 0 {
+		criteria = append(criteria, "SNI "+strings.Join(m.ServerNames, ","))
+	}
+	if m.TransportProtocol != "" {
+		criteria = append(criteria, "transport "+m.TransportProtocol)
+	}
+	if len(m.ApplicationProtocols) > 0 {
+		criteria = append(criteria, "ALPN "+strings.Join(m.ApplicationProtocols, ","))
+	}
+	if len(m.SourcePrefixRanges) > 0 {
+		criteria = append(criteria, "source "+renderCidrRanges(m.SourcePrefixRanges))
+	}
+	if len(criteria) == 0 {
+		return "matches all connections"
+	}
+	return strings.Join(criteria, ", ")
+}
+
+func renderCidrRanges(ranges []*core.CidrRange) string {
+	out := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		prefixLen := uint32(0)
+		if r.PrefixLen != nil {
+			prefixLen = r.PrefixLen.Value
+		}
+		out = append(out, fmt.Sprintf("%s/%d", r.AddressPrefix, prefixLen))
+	}
+	return strings.Join(out, ",")
+}
+
+// longestPrefixMatch returns the length of the longest range containing ip.
+func longestPrefixMatch(ranges []*core.CidrRange, ip string) (int, bool) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return 0, false
+	}
+	best, found := 0, false
+	for _, r := range ranges {
+		prefixLen := 0
+		if r.PrefixLen != nil {
+			prefixLen = int(r.PrefixLen.Value)
+		}
+		_, cidr, err := net.ParseCIDR(fmt.Sprintf("%s/%d", r.AddressPrefix, prefixLen))
+		if err != nil || !cidr.Contains(addr) {
+			continue
+		}
+		if !found || prefixLen > best {
+			best, found = prefixLen, true
+		}
+	}
+	return best, found
+}
+
+// matchServerNames returns 2 for an exact match of the SNI, 1 for a wildcard match.
+func matchServerNames(names []string, sni string) (int, bool) {
+	if sni == "" {
+		return 0, false
+	}
+	best := 0
+	for _, name := range names {
+		if name == sni {
+			return 2, true
+		}
+		if strings.HasPrefix(name, "*.") && strings.HasSuffix(sni, name[1:]) {
+			best = 1
+		}
+	}
+	return best, best > 0
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchVirtualHost returns the virtual host serving the authority, and the domain which matched, following
+// the Envoy precedence: exact domains, then the longest suffix wildcard, then the longest prefix wildcard,
+// and finally the catch-all.
+func matchVirtualHost(vhosts []*route.VirtualHost, authority string) (*route.VirtualHost, string) {
+	authority = strings.ToLower(authority)
+	var match *route.VirtualHost
+	matchDomain, matchRank, matchLen := "", 0, 0
+	for _, vh := range vhosts {
+		for _, domain := range vh.Domains {
+			d := strings.ToLower(domain)
+			rank := 0
+			switch {
+			case d == authority:
+				rank = 4
+			case strings.HasPrefix(d, "*") && d != "*" && strings.HasSuffix(authority, d[1:]):
+				rank = 3
+			case strings.HasSuffix(d, "*") && d != "*" && strings.HasPrefix(authority, d[:len(d)-1]):
+				rank = 2
+			case d == "*":
+				rank = 1
+			}
+			if rank > matchRank || (rank == matchRank && rank > 0 && len(d) > matchLen) {
+				match, matchDomain, matchRank, matchLen = vh, domain, rank, len(d)
+			}
+		}
+	}
+	return match, matchDomain
+}
+
+// matchRoute returns whether the route match selects the request.
+func matchRoute(m *route.RouteMatch, req *Request) bool {
+	if m == nil {
+		return false
+	}
+	path := req.path()
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	caseSensitive := m.CaseSensitive == nil || m.CaseSensitive.Value
+	fold := func(s string) string {
+		if caseSensitive {
+			return s
+		}
+		return strings.ToLower(s)
+	}
+
+	switch p := m.PathSpecifier.(type) {
+	case *route.RouteMatch_Prefix:
+		if !strings.HasPrefix(fold(path), fold(p.Prefix)) {
+			return false
+		}
+	case *route.RouteMatch_Path:
+		if fold(path) != fold(p.Path) {
+			return false
+		}
+	case *route.RouteMatch_Regex:
+		if !fullMatch(p.Regex, path) {
+			return false
+		}
+	case *route.RouteMatch_SafeRegex:
+		if !fullMatch(p.SafeRegex.GetRegex(), path) {
+			return false
+		}
+	}
+
+	headers := req.headers()
+	for _, h := range m.Headers {
+		if matchHeader(h, headers) == h.InvertMatch {
+			return false
+		}
+	}
+	return true
+}
+
+func matchHeader(h *route.HeaderMatcher, headers map[string]string) bool {
+	value, present := headers[strings.ToLower(h.Name)]
+	if !present {
+		return false
+	}
+	switch s := h.HeaderMatchSpecifier.(type) {
+	case *route.HeaderMatcher_ExactMatch:
+		return value == s.ExactMatch
+	case *route.HeaderMatcher_RegexMatch:
+		return fullMatch(s.RegexMatch, value)
+	case *route.HeaderMatcher_SafeRegexMatch:
+		return fullMatch(s.SafeRegexMatch.GetRegex(), value)
+	case *route.HeaderMatcher_RangeMatch:
+		v, err := strconv.ParseInt(value, 10, 64)
+		return err == nil && v >= s.RangeMatch.Start && v < s.RangeMatch.End
+	case *route.HeaderMatcher_PresentMatch:
+		return s.PresentMatch
+	case *route.HeaderMatcher_PrefixMatch:
+		return strings.HasPrefix(value, s.PrefixMatch)
+	case *route.HeaderMatcher_SuffixMatch:
+		return strings.HasSuffix(value, s.SuffixMatch)
+	default:
+		// No specifier only checks for the presence of the header.
+		return true
+	}
+}
+
+// describeRouteMatch renders the criteria of a route match.
+func describeRouteMatch(m *route.RouteMatch) string {
+	var criteria []string
+	switch p := m.GetPathSpecifier().(type) {
+	case *route.RouteMatch_Prefix:
+		criteria = append(criteria, "prefix "+p.Prefix)
+	case *route.RouteMatch_Path:
+		criteria = append(criteria, "path "+p.Path)
+	case *route.RouteMatch_Regex:
+		criteria = append(criteria, "regex "+p.Regex)
+	case *route.RouteMatch_SafeRegex:
+		criteria = append(criteria, "regex "+p.SafeRegex.GetRegex())
+	}
+	for _, h := range m.GetHeaders() {
+		op := "="
+		if h.InvertMatch {
+			op = "!="
+		}
+		value := ""
+		switch s := h.HeaderMatchSpecifier.(type) {
+		case *route.HeaderMatcher_ExactMatch:
+			value = s.ExactMatch
+		case *route.HeaderMatcher_RegexMatch:
+			value = "~" + s.RegexMatch
+		case *route.HeaderMatcher_SafeRegexMatch:
+			value = "~" + s.SafeRegexMatch.GetRegex()
+		case *route.HeaderMatcher_PrefixMatch:
+			value = s.PrefixMatch + "*"
+		case *route.HeaderMatcher_SuffixMatch:
+			value = "*" + s.SuffixMatch
+		case *route.HeaderMatcher_RangeMatch:
+			value = fmt.Sprintf("[%d,%d)", s.RangeMatch.Start, s.RangeMatch.End)
+		default:
+			value = "*"
+		}
+		criteria = append(criteria, fmt.Sprintf("header %s%s%s", h.Name, op, value))
+	}
+	return strings.Join(criteria, ", ")
+}
+
+func fullMatch(expr, value string) bool {
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(value)
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracerequest
+
+import (
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	"github.com/golang/protobuf/ptypes/wrappers"
+)
+
+func TestMatchFilterChain(t *testing.T) {
+	cidr := func(prefix string, l uint32) []*core.CidrRange {
+		return []*core.CidrRange{{AddressPrefix: prefix, PrefixLen: &wrappers.UInt32Value{Value: l}}}
+	}
+	req := &Request{Host: "reviews", Port: 9080, DestinationIP: "10.0.0.5", SourceIP: "10.1.0.1"}
+
+	for _, tc := range []struct {
+		name  string
+		match *listener.FilterChainMatch
+		ok    bool
+	}{
+		{"nil", nil, true},
+		{"destination ip", &listener.FilterChainMatch{PrefixRanges: cidr("10.0.0.0", 24)}, true},
+		{"other destination ip", &listener.FilterChainMatch{PrefixRanges: cidr("10.2.0.0", 16)}, false},
+		{"destination port", &listener.FilterChainMatch{DestinationPort: &wrappers.UInt32Value{Value: 80}}, false},
+		{"tls", &listener.FilterChainMatch{TransportProtocol: "tls"}, false},
+		{"http", &listener.FilterChainMatch{ApplicationProtocols: []string{"http/1.1"}}, true},
+		{"source ip", &listener.FilterChainMatch{SourcePrefixRanges: cidr("10.1.0.0", 16)}, true},
+		{"server names without sni", &listener.FilterChainMatch{ServerNames: []string{"*.example.com"}}, false},
+	} {
+		if ok, _ := matchFilterChain(tc.match, req); ok != tc.ok {
+			t.Errorf("[%v] want %v, got %v", tc.name, tc.ok, ok)
+		}
+	}
+
+	_, wide := matchFilterChain(&listener.FilterChainMatch{PrefixRanges: cidr("10.0.0.0", 8)}, req)
+	_, narrow := matchFilterChain(&listener.FilterChainMatch{PrefixRanges: cidr("10.0.0.0", 24)}, req)
+	if !narrow.moreSpecificThan(wide) || wide.moreSpecificThan(narrow) {
+		t.Errorf("want %v more specific than %v", narrow, wide)
+	}
+}
+
+func TestMatchVirtualHost(t *testing.T) {
+	vhosts := []*route.VirtualHost{
+		{Name: "catch-all", Domains: []string{"*"}},
+		{Name: "prefix", Domains: []string{"reviews.*"}},
+		{Name: "suffix", Domains: []string{"*.example.com"}},
+		{Name: "exact", Domains: []string{"reviews:9080", "reviews.default:9080"}},
+	}
+	for _, tc := range []struct {
+		authority string
+		want      string
+	}{
+		{"reviews:9080", "exact"},
+		{"Reviews.Default:9080", "exact"},
+		{"reviews.other", "prefix"},
+		{"www.example.com", "suffix"},
+		{"ratings", "catch-all"},
+	} {
+		if vh, _ := matchVirtualHost(vhosts, tc.authority); vh == nil || vh.Name != tc.want {
+			t.Errorf("[%v] want %v, got %v", tc.authority, tc.want, vh)
+		}
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	req := &Request{Host: "reviews", Port: 9080, Path: "/reviews/0?x=1", Headers: map[string]string{"End-User": "jason"}}
+	for _, tc := range []struct {
+		name  string
+		match *route.RouteMatch
+		want  bool
+	}{
+		{"prefix", &route.RouteMatch{PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/reviews"}}, true},
+		{"path ignores query", &route.RouteMatch{PathSpecifier: &route.RouteMatch_Path{Path: "/reviews/0"}}, true},
+		{"case insensitive", &route.RouteMatch{
+			PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/REVIEWS"},
+			CaseSensitive: &wrappers.BoolValue{Value: false},
+		}, true},
+		{"regex is anchored", &route.RouteMatch{PathSpecifier: &route.RouteMatch_Regex{Regex: "/reviews"}}, false},
+		{"header", &route.RouteMatch{
+			PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+			Headers: []*route.HeaderMatcher{{
+				Name:                 "end-user",
+				HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "jason"},
+			}},
+		}, true},
+		{"inverted header", &route.RouteMatch{
+			PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+			Headers: []*route.HeaderMatcher{{
+				Name:                 "end-user",
+				HeaderMatchSpecifier: &route.HeaderMatcher_ExactMatch{ExactMatch: "jason"},
+				InvertMatch:          true,
+			}},
+		}, false},
+		{"authority", &route.RouteMatch{
+			PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+			Headers: []*route.HeaderMatcher{{
+				Name:                 ":authority",
+				HeaderMatchSpecifier: &route.HeaderMatcher_PrefixMatch{PrefixMatch: "reviews:"},
+			}},
+		}, true},
+	} {
+		if got := matchRoute(tc.match, req); got != tc.want {
+			t.Errorf("[%v] want %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracerequest simulates how an Envoy proxy handles a request, by walking its config dump from
+// the listener down to the endpoints, and reports each decision along with the Istio configuration
+// which produced it.
+package tracerequest
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	listener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	http_conn "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	tcp_proxy "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/tcp_proxy/v2"
+	"github.com/envoyproxy/go-control-plane/pkg/conversion"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+
+	"istio.io/istio/istioctl/pkg/util/clusters"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+)
+
+const (
+	httpConnectionManager = "envoy.http_connection_manager"
+	tcpProxy              = "envoy.tcp_proxy"
+)
+
+// Request describes the request to trace.
+type Request struct {
+	// Host is the host the request is sent to, used for the Host header.
+	Host string
+	// Port is the destination port.
+	Port uint32
+	// Method is the HTTP method, GET by default.
+	Method string
+	// Path is the HTTP path, including the query string. Requests without a path are traced as TCP
+	// connections unless the listener handles them as HTTP.
+	Path string
+	// Headers are the HTTP request headers.
+	Headers map[string]string
+	// SourceIP is the IP address of the client.
+	SourceIP string
+	// DestinationIP is the original destination IP address, typically the service cluster IP.
+	DestinationIP string
+	// SNI is the server name of a TLS connection. Connections without one are plaintext.
+	SNI string
+}
+
+// authority returns the Host header, which includes the port unless it is the scheme default, like curl does.
+func (r *Request) authority() string {
+	if strings.Contains(r.Host, ":") || r.Port == 0 || (r.Port == 80 && r.SNI == "") || (r.Port == 443 && r.SNI != "") {
+		return r.Host
+	}
+	return fmt.Sprintf("%s:%d", r.Host, r.Port)
+}
+
+func (r *Request) method() string {
+	if r.Method == "" {
+		return "GET"
+	}
+	return strings.ToUpper(r.Method)
+}
+
+func (r *Request) path() string {
+	if r.Path == "" {
+		return "/"
+	}
+	return r.Path
+}
+
+func (r *Request) transportProtocol() string {
+	if r.SNI != "" {
+		return "tls"
+	}
+	return "raw_buffer"
+}
+
+// applicationProtocols returns the protocols detected by the listener filters for the request.
+func (r *Request) applicationProtocols() []string {
+	if r.SNI != "" {
+		return nil
+	}
+	return []string{"http/1.1", "http/1.0", "h2c"}
+}
+
+// headers returns the request headers including the HTTP/2 pseudo headers, with lower case names.
+func (r *Request) headers() map[string]string {
+	out := map[string]string{
+		":authority": r.authority(),
+		":method":    r.method(),
+		":path":      r.path(),
+		":scheme":    "http",
+	}
+	for k, v := range r.Headers {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+// Step is one decision taken while tracing a request.
+type Step struct {
+	// Stage is the part of the configuration the decision was taken on, e.g. "Listener".
+	Stage string
+	// Decision describes what was selected and why.
+	Decision string
+	// IstioConfig is the Istio resource which produced the selected configuration, if known.
+	IstioConfig string
+}
+
+// Trace is the result of tracing a request.
+type Trace struct {
+	Request *Request
+	Steps   []Step
+	// Error is the reason the trace stopped before reaching a destination, if any.
+	Error string
+}
+
+func (t *Trace) add(stage, decision string, metadata *core.Metadata) {
+	t.Steps = append(t.Steps, Step{Stage: stage, Decision: decision, IstioConfig: istioConfig(metadata)})
+}
+
+func (t *Trace) fail(format string, args ...interface{}) *Trace {
+	t.Error = fmt.Sprintf(format, args...)
+	return t
+}
+
+// Print writes the trace as a table of decisions.
+func (t *Trace) Print(writer io.Writer) {
+	req := t.Request
+	_, _ = fmt.Fprintf(writer, "Tracing %s %s%s", req.method(), req.authority(), req.path())
+	if len(req.Headers) > 0 {
+		keys := make([]string, 0, len(req.Headers))
+		for k := range req.Headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		headers := make([]string, 0, len(keys))
+		for _, k := range keys {
+			headers = append(headers, k+": "+req.Headers[k])
+		}
+		_, _ = fmt.Fprintf(writer, " with headers %s", strings.Join(headers, ", "))
+	}
+	_, _ = fmt.Fprintln(writer)
+
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STAGE\tDECISION\tISTIO CONFIG")
+	for _, s := range t.Steps {
+		config := s.IstioConfig
+		if config == "" {
+			config = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", s.Stage, s.Decision, config)
+	}
+	_ = w.Flush()
+	if t.Error != "" {
+		_, _ = fmt.Fprintf(writer, "Trace stopped: %s\n", t.Error)
+	}
+}
+
+// Tracer traces requests through the configuration of a proxy.
+type Tracer struct {
+	listeners []*xdsapi.Listener
+	routes    map[string]*xdsapi.RouteConfiguration
+	clusters  map[string]*xdsapi.Cluster
+	endpoints map[string][]string
+}
+
+// NewTracer creates a tracer for the given config dump. The endpoints, as reported by the Envoy
+// /clusters?format=json admin endpoint, are optional.
+func NewTracer(configDump *configdump.Wrapper, endpoints *clusters.Wrapper) (*Tracer, error) {
+	t := &Tracer{
+		routes:    map[string]*xdsapi.RouteConfiguration{},
+		clusters:  map[string]*xdsapi.Cluster{},
+		endpoints: map[string][]string{},
+	}
+
+	listenerDump, err := configDump.GetListenerConfigDump()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listener dump: %v", err)
+	}
+	for _, l := range listenerDump.StaticListeners {
+		t.listeners = append(t.listeners, l.Listener)
+	}
+	for _, l := range listenerDump.DynamicListeners {
+		if l.ActiveState != nil {
+			t.listeners = append(t.listeners, l.ActiveState.Listener)
+		}
+	}
+
+	routeDump, err := configDump.GetRouteConfigDump()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get route dump: %v", err)
+	}
+	for _, r := range routeDump.StaticRouteConfigs {
+		t.routes[r.RouteConfig.Name] = r.RouteConfig
+	}
+	for _, r := range routeDump.DynamicRouteConfigs {
+		t.routes[r.RouteConfig.Name] = r.RouteConfig
+	}
+
+	clusterDump, err := configDump.GetClusterConfigDump()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster dump: %v", err)
+	}
+	for _, c := range clusterDump.StaticClusters {
+		t.clusters[c.Cluster.Name] = c.Cluster
+	}
+	for _, c := range clusterDump.DynamicActiveClusters {
+		t.clusters[c.Cluster.Name] = c.Cluster
+	}
+
+	if endpoints != nil && endpoints.Clusters != nil {
+		for _, cs := range endpoints.ClusterStatuses {
+			for _, hs := range cs.HostStatuses {
+				addr := hs.GetAddress().GetSocketAddress()
+				health := hs.GetHealthStatus().GetEdsHealthStatus().String()
+				t.endpoints[cs.Name] = append(t.endpoints[cs.Name],
+					fmt.Sprintf("%s:%d %s", addr.GetAddress(), addr.GetPortValue(), health))
+			}
+		}
+	}
+	return t, nil
+}
+
+// Trace walks the proxy configuration for the request.
+func (t *Tracer) Trace(req *Request) *Trace {
+	trace := &Trace{Request: req}
+
+	l, reason := t.selectListener(req)
+	if l == nil {
+		return trace.fail("no listener handles port %d", req.Port)
+	}
+	trace.add("Listener", fmt.Sprintf("%s (%s)", l.Name, reason), l.Metadata)
+
+	var chain *listener.FilterChain
+	var chainIndex int
+	var chainRank filterChainRank
+	for i, fc := range l.FilterChains {
+		if ok, rank := matchFilterChain(fc.FilterChainMatch, req); ok && (chain == nil || rank.moreSpecificThan(chainRank)) {
+			chain, chainIndex, chainRank = fc, i, rank
+		}
+	}
+	if chain == nil {
+		return trace.fail("no filter chain of listener %s matches the connection", l.Name)
+	}
+	trace.add("Filter chain", fmt.Sprintf("#%d (%s)", chainIndex, describeFilterChainMatch(chain.FilterChainMatch)), chain.Metadata)
+
+	for _, f := range chain.Filters {
+		switch f.Name {
+		case httpConnectionManager:
+			hcm := &http_conn.HttpConnectionManager{}
+			if err := decodeFilterConfig(f, hcm); err != nil {
+				return trace.fail("failed to decode %s: %v", f.Name, err)
+			}
+			return t.traceHTTP(trace, hcm)
+		case tcpProxy:
+			tcp := &tcp_proxy.TcpProxy{}
+			if err := decodeFilterConfig(f, tcp); err != nil {
+				return trace.fail("failed to decode %s: %v", f.Name, err)
+			}
+			return t.traceTCP(trace, tcp)
+		}
+	}
+	return trace.fail("filter chain #%d has neither an HTTP connection manager nor a TCP proxy", chainIndex)
+}
+
+// selectListener returns the listener accepting the connection, mimicking the original destination
+// handoff of the virtual outbound listener.
+func (t *Tracer) selectListener(req *Request) (*xdsapi.Listener, string) {
+	var wildcard, originalDst *xdsapi.Listener
+	for _, l := range t.listeners {
+		addr := l.GetAddress().GetSocketAddress()
+		if addr == nil {
+			continue
+		}
+		if addr.GetPortValue() == req.Port {
+			if req.DestinationIP != "" && addr.Address == req.DestinationIP {
+				return l, fmt.Sprintf("bound to %s:%d", addr.Address, req.Port)
+			}
+			if addr.Address == "0.0.0.0" || addr.Address == "::" {
+				wildcard = l
+			}
+		}
+		// nolint: staticcheck
+		if l.GetUseOriginalDst().GetValue() && originalDst == nil {
+			originalDst = l
+		}
+	}
+	if wildcard != nil {
+		return wildcard, fmt.Sprintf("bound to %s:%d", wildcard.Address.GetSocketAddress().Address, req.Port)
+	}
+	if originalDst != nil {
+		return originalDst, fmt.Sprintf("no listener for port %d, handled by the original destination listener", req.Port)
+	}
+	return nil, ""
+}
+
+func (t *Tracer) traceTCP(trace *Trace, tcp *tcp_proxy.TcpProxy) *Trace {
+	switch c := tcp.ClusterSpecifier.(type) {
+	case *tcp_proxy.TcpProxy_Cluster:
+		trace.add("TCP proxy", "cluster "+c.Cluster, nil)
+		t.traceCluster(trace, c.Cluster, "")
+	case *tcp_proxy.TcpProxy_WeightedClusters:
+		trace.add("TCP proxy", fmt.Sprintf("%d weighted clusters", len(c.WeightedClusters.Clusters)), nil)
+		for _, wc := range c.WeightedClusters.Clusters {
+			t.traceCluster(trace, wc.Name, fmt.Sprintf("weight %d", wc.Weight))
+		}
+	default:
+		return trace.fail("TCP proxy has no cluster")
+	}
+	return trace
+}
+
+func (t *Tracer) traceHTTP(trace *Trace, hcm *http_conn.HttpConnectionManager) *Trace {
+	var rc *xdsapi.RouteConfiguration
+	switch r := hcm.RouteSpecifier.(type) {
+	case *http_conn.HttpConnectionManager_Rds:
+		name := r.Rds.GetRouteConfigName()
+		rc = t.routes[name]
+		if rc == nil {
+			return trace.fail("route config %q has not been received", name)
+		}
+		trace.add("HTTP manager", fmt.Sprintf("route config %q (RDS)", name), nil)
+	case *http_conn.HttpConnectionManager_RouteConfig:
+		rc = r.RouteConfig
+		trace.add("HTTP manager", fmt.Sprintf("inline route config %q", rc.Name), nil)
+	default:
+		return trace.fail("HTTP connection manager has no route config")
+	}
+
+	vh, domain := matchVirtualHost(rc.VirtualHosts, trace.Request.authority())
+	if vh == nil {
+		return trace.fail("no virtual host of route config %q matches host %q", rc.Name, trace.Request.authority())
+	}
+	trace.add("Virtual host", fmt.Sprintf("%s (domain %q)", vh.Name, domain), nil)
+
+	var matched *route.Route
+	var routeIndex int
+	for i, r := range vh.Routes {
+		if matchRoute(r.Match, trace.Request) {
+			matched, routeIndex = r, i
+			break
+		}
+	}
+	if matched == nil {
+		return trace.fail("no route of virtual host %s matches the request, Envoy returns 404", vh.Name)
+	}
+	name := matched.Name
+	if name == "" {
+		name = fmt.Sprintf("#%d", routeIndex)
+	}
+	trace.add("Route", fmt.Sprintf("%s (%s)", name, describeRouteMatch(matched.Match)), matched.Metadata)
+
+	switch a := matched.Action.(type) {
+	case *route.Route_Route:
+		switch c := a.Route.ClusterSpecifier.(type) {
+		case *route.RouteAction_Cluster:
+			t.traceCluster(trace, c.Cluster, "")
+		case *route.RouteAction_WeightedClusters:
+			for _, wc := range c.WeightedClusters.Clusters {
+				t.traceCluster(trace, wc.Name, fmt.Sprintf("weight %d", wc.Weight.GetValue()))
+			}
+		case *route.RouteAction_ClusterHeader:
+			trace.add("Cluster", fmt.Sprintf("named by the %s header", c.ClusterHeader), nil)
+		}
+	case *route.Route_Redirect:
+		trace.add("Redirect", fmt.Sprintf("to %s%s", a.Redirect.GetHostRedirect(), a.Redirect.GetPathRedirect()), nil)
+	case *route.Route_DirectResponse:
+		trace.add("Direct response", fmt.Sprintf("status %d", a.DirectResponse.Status), nil)
+	default:
+		return trace.fail("route %s has no action", name)
+	}
+	return trace
+}
+
+func (t *Tracer) traceCluster(trace *Trace, name, weight string) {
+	decision := name
+	if weight != "" {
+		decision = fmt.Sprintf("%s (%s)", name, weight)
+	}
+	c := t.clusters[name]
+	if c == nil {
+		trace.add("Cluster", decision+", not found", nil)
+		return
+	}
+	trace.add("Cluster", decision, c.Metadata)
+
+	endpoints, found := t.endpoints[name]
+	switch {
+	case found:
+		for _, ep := range endpoints {
+			trace.add("Endpoint", ep, nil)
+		}
+	case len(t.endpoints) > 0:
+		trace.add("Endpoint", "none", nil)
+	}
+}
+
+// decodeFilterConfig decodes the configuration of a filter, whether typed or not.
+func decodeFilterConfig(filter *listener.Filter, out proto.Message) error {
+	switch c := filter.ConfigType.(type) {
+	case *listener.Filter_TypedConfig:
+		return ptypes.UnmarshalAny(c.TypedConfig, out)
+	case *listener.Filter_Config:
+		return conversion.StructToMessage(c.Config, out)
+	}
+	return nil
+}
+
+var istioConfigPattern = regexp.MustCompile(`^/apis/[^/]+/[^/]+/namespaces/(?P<namespace>[^/]+)/(?P<kind>[^/]+)/(?P<name>[^/]+)$`)
+
+// istioConfig returns the Istio resource recorded in the metadata, e.g. "virtual-service reviews.default".
+func istioConfig(metadata *core.Metadata) string {
+	path := metadata.GetFilterMetadata()["istio"].GetFields()["config"].GetStringValue()
+	if m := istioConfigPattern.FindStringSubmatch(path); m != nil {
+		return fmt.Sprintf("%s %s.%s", m[2], m[3], m[1])
+	}
+	return path
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/ghodss/yaml"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+)
+
+// Baseline lists the known messages to suppress, so that only the new ones are reported.
+type Baseline struct {
+	Suppressions []BaselineEntry `json:"suppressions"`
+}
+
+// BaselineEntry identifies a message by its code and resource.
+type BaselineEntry struct {
+	// Code is the code of the message, e.g. IST0101.
+	Code string `json:"code"`
+	// Resource is the resource of the message as printed by istioctl analyze, e.g. "VirtualService reviews.default",
+	// or empty if the message has no resource.
+	Resource string `json:"resource,omitempty"`
+}
+
+func entryOf(m diag.Message) BaselineEntry {
+	e := BaselineEntry{Code: m.Type.Code()}
+	if m.Resource != nil {
+		e.Resource = m.Resource.Origin.FriendlyName()
+	}
+	return e
+}
+
+// NewBaseline returns the baseline suppressing the messages.
+func NewBaseline(messages diag.Messages) *Baseline {
+	seen := map[BaselineEntry]bool{}
+	b := &Baseline{Suppressions: []BaselineEntry{}}
+	for _, m := range messages {
+		e := entryOf(m)
+		if !seen[e] {
+			seen[e] = true
+			b.Suppressions = append(b.Suppressions, e)
+		}
+	}
+	sort.Slice(b.Suppressions, func(i, j int) bool {
+		if b.Suppressions[i].Code != b.Suppressions[j].Code {
+			return b.Suppressions[i].Code < b.Suppressions[j].Code
+		}
+		return b.Suppressions[i].Resource < b.Suppressions[j].Resource
+	})
+	return b
+}
+
+// ReadBaseline reads a baseline file.
+func ReadBaseline(filename string) (*Baseline, error) {
+	in, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	b := &Baseline{}
+	if err := yaml.Unmarshal(in, b); err != nil {
+		return nil, fmt.Errorf("could not parse baseline %s: %v", filename, err)
+	}
+	return b, nil
+}
+
+// Write writes the baseline to a file.
+func (b *Baseline) Write(filename string) error {
+	out, err := yaml.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, out, 0644)
+}
+
+// Filter returns the messages which are not in the baseline, and the number of messages which are.
+func (b *Baseline) Filter(messages diag.Messages) (diag.Messages, int) {
+	known := make(map[BaselineEntry]bool, len(b.Suppressions))
+	for _, e := range b.Suppressions {
+		known[e] = true
+	}
+	var kept diag.Messages
+	suppressed := 0
+	for _, m := range messages {
+		if known[entryOf(m)] {
+			suppressed++
+			continue
+		}
+		kept = append(kept, m)
+	}
+	return kept, suppressed
+}
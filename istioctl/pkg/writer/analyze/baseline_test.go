@@ -0,0 +1,65 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/resource"
+)
+
+func TestBaseline(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "baseline")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "baseline.yaml")
+
+	g.Expect(NewBaseline(append(testMessages, testMessages[0])).Write(filename)).To(Succeed())
+	b, err := ReadBaseline(filename)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(b.Suppressions).To(Equal([]BaselineEntry{
+		{Code: "IST0042", Resource: "Pizza margherita.default"},
+		{Code: "IST0042", Resource: "Pizza quattro.default"},
+		{Code: "IST0043"},
+	}))
+
+	newMessage := diag.NewMessage(errorType, &resource.Instance{Origin: testOrigin("Pizza hawaii.default")}, "Pineapple")
+	kept, suppressed := b.Filter(append(testMessages, newMessage))
+	g.Expect(kept).To(Equal(diag.Messages{newMessage}))
+	g.Expect(suppressed).To(Equal(3))
+}
+
+func TestReadBaseline_Invalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	_, err := ReadBaseline("testdata/does-not-exist.yaml")
+	g.Expect(err).To(HaveOccurred())
+
+	f, err := ioutil.TempFile("", "baseline")
+	g.Expect(err).NotTo(HaveOccurred())
+	defer os.Remove(f.Name())
+	_, _ = f.WriteString("suppressions: not-a-list\n")
+	f.Close()
+	_, err = ReadBaseline(f.Name())
+	g.Expect(err).To(HaveOccurred())
+}
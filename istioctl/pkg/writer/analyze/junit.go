@@ -0,0 +1,86 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// PrintJUnit prints the messages as a JUnit XML report with one test case per message, so that they can be
+// displayed in CI test reports. The messages at least as severe as the failure level are reported as failures.
+func PrintJUnit(w io.Writer, messages diag.Messages, failureLevel diag.Level) error {
+	suite := junitTestSuite{Name: toolName}
+	for _, m := range messages {
+		tc := junitTestCase{
+			Name:      m.Type.Code(),
+			ClassName: toolName,
+		}
+		if m.Resource != nil {
+			tc.Name = fmt.Sprintf("%s %s", m.Type.Code(), m.Resource.Origin.FriendlyName())
+		}
+		if m.Type.Level().IsWorseThanOrEqualTo(failureLevel) {
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf(m.Type.Template(), m.Parameters...),
+				Type:    m.Type.Level().String(),
+				Text:    m.String(),
+			}
+			suite.Failures++
+		} else {
+			tc.SystemOut = m.String()
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	// Report a passing test case when there is nothing to report, since empty suites are hidden by some CI systems
+	if len(suite.TestCases) == 0 {
+		suite.TestCases = append(suite.TestCases, junitTestCase{Name: "analysis", ClassName: toolName})
+	}
+	suite.Tests = len(suite.TestCases)
+
+	out, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, out)
+	return err
+}
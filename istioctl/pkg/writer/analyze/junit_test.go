@@ -0,0 +1,76 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+)
+
+func TestPrintJUnit(t *testing.T) {
+	cases := []struct {
+		name         string
+		messages     diag.Messages
+		failureLevel diag.Level
+		wantCases    []string
+		wantFailures int
+	}{
+		{
+			name:         "errors fail",
+			messages:     testMessages,
+			failureLevel: diag.Error,
+			wantCases:    []string{"IST0042 Pizza margherita.default", "IST0043", "IST0042 Pizza quattro.default"},
+			wantFailures: 2,
+		},
+		{
+			name:         "warnings fail",
+			messages:     testMessages,
+			failureLevel: diag.Warning,
+			wantCases:    []string{"IST0042 Pizza margherita.default", "IST0043", "IST0042 Pizza quattro.default"},
+			wantFailures: 3,
+		},
+		{
+			name:         "no messages",
+			failureLevel: diag.Error,
+			wantCases:    []string{"analysis"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			var out bytes.Buffer
+			g.Expect(PrintJUnit(&out, c.messages, c.failureLevel)).To(Succeed())
+
+			var report junitTestSuites
+			g.Expect(xml.Unmarshal(out.Bytes(), &report)).To(Succeed())
+			g.Expect(report.Suites).To(HaveLen(1))
+			suite := report.Suites[0]
+			g.Expect(suite.Tests).To(Equal(len(c.wantCases)))
+			g.Expect(suite.Failures).To(Equal(c.wantFailures))
+			var names []string
+			for _, tc := range suite.TestCases {
+				names = append(names, tc.Name)
+			}
+			g.Expect(names).To(Equal(c.wantCases))
+		})
+	}
+}
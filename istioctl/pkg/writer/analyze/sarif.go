@@ -0,0 +1,176 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "istioctl analyze"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	HelpURI              string             `json:"helpUri"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI        string           `json:"uri"`
+	Properties *sarifProperties `json:"properties,omitempty"`
+}
+
+// sarifProperties is the property bag of an artifact location.
+type sarifProperties struct {
+	// Revision is the revision of the artifact, e.g. a commit.
+	Revision string `json:"revision"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifLevel maps the level of a message to a SARIF result level.
+func sarifLevel(l diag.Level) string {
+	switch l {
+	case diag.Error:
+		return "error"
+	case diag.Warning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// PrintSARIF prints the messages as a SARIF 2.1.0 log, with one rule per message code, so that they can be
+// displayed by code review tools.
+func PrintSARIF(w io.Writer, messages diag.Messages) error {
+	rules := map[string]sarifRule{}
+	results := make([]sarifResult, 0, len(messages))
+	for _, m := range messages {
+		code := m.Type.Code()
+		rules[code] = sarifRule{
+			ID:                   code,
+			HelpURI:              fmt.Sprintf("%s/%s", diag.DocPrefix, code),
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(m.Type.Level())},
+		}
+		result := sarifResult{
+			RuleID:  code,
+			Level:   sarifLevel(m.Type.Level()),
+			Message: sarifMessage{Text: fmt.Sprintf(m.Type.Template(), m.Parameters...)},
+		}
+		if m.Resource != nil {
+			l := sarifLocation{
+				LogicalLocations: []sarifLogicalLocation{{
+					FullyQualifiedName: m.Resource.Origin.FriendlyName(),
+					Kind:               "resource",
+				}},
+			}
+			if p, ok := m.Position(); ok {
+				l.PhysicalLocation = &sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(p.Filename)},
+					Region:           sarifRegion{StartLine: p.Line, StartColumn: p.Column},
+				}
+				if p.Revision != "" {
+					l.PhysicalLocation.ArtifactLocation.Properties = &sarifProperties{Revision: p.Revision}
+				}
+			}
+			result.Locations = []sarifLocation{l}
+		}
+		results = append(results, result)
+	}
+
+	driver := sarifDriver{
+		Name:           toolName,
+		InformationURI: diag.DocPrefix,
+		Rules:          make([]sarifRule, 0, len(rules)),
+	}
+	for _, r := range rules {
+		driver.Rules = append(driver.Rules, r)
+	}
+	sort.Slice(driver.Rules, func(i, j int) bool { return driver.Rules[i].ID < driver.Rules[j].ID })
+
+	out, err := json.MarshalIndent(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"istio.io/istio/galley/pkg/config/analysis/diag"
+	"istio.io/istio/galley/pkg/config/resource"
+)
+
+type testOrigin string
+
+func (o testOrigin) FriendlyName() string          { return string(o) }
+func (o testOrigin) Namespace() resource.Namespace { return "" }
+
+var (
+	errorType   = diag.NewMessageType(diag.Error, "IST0042", "Cheese type not found: %q")
+	warningType = diag.NewMessageType(diag.Warning, "IST0043", "Cheese is stale")
+
+	testMessages = diag.Messages{
+		diag.NewMessage(errorType, &resource.Instance{Origin: testOrigin("Pizza margherita.default")}, "Feta"),
+		diag.NewMessage(warningType, nil),
+		diag.NewMessage(errorType, &resource.Instance{Origin: testOrigin("Pizza quattro.default")}, "Gorgonzola"),
+	}
+)
+
+func TestPrintSARIF(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var out bytes.Buffer
+	g.Expect(PrintSARIF(&out, testMessages)).To(Succeed())
+
+	var log sarifLog
+	g.Expect(json.Unmarshal(out.Bytes(), &log)).To(Succeed())
+	g.Expect(log.Version).To(Equal(sarifVersion))
+	g.Expect(log.Runs).To(HaveLen(1))
+
+	run := log.Runs[0]
+	g.Expect(run.Tool.Driver.Rules).To(Equal([]sarifRule{
+		{
+			ID:                   "IST0042",
+			HelpURI:              diag.DocPrefix + "/IST0042",
+			DefaultConfiguration: sarifConfiguration{Level: "error"},
+		},
+		{
+			ID:                   "IST0043",
+			HelpURI:              diag.DocPrefix + "/IST0043",
+			DefaultConfiguration: sarifConfiguration{Level: "warning"},
+		},
+	}))
+	g.Expect(run.Results).To(HaveLen(3))
+	g.Expect(run.Results[0].RuleID).To(Equal("IST0042"))
+	g.Expect(run.Results[0].Message.Text).To(Equal(`Cheese type not found: "Feta"`))
+	g.Expect(run.Results[0].Locations[0].LogicalLocations[0].FullyQualifiedName).To(Equal("Pizza margherita.default"))
+	g.Expect(run.Results[1].Level).To(Equal("warning"))
+	g.Expect(run.Results[0].Locations[0].PhysicalLocation).To(BeNil())
+	g.Expect(run.Results[1].Locations).To(BeEmpty())
+}
+
+type testPositionedOrigin string
+
+func (o testPositionedOrigin) FriendlyName() string          { return string(o) }
+func (o testPositionedOrigin) Namespace() resource.Namespace { return "" }
+func (o testPositionedOrigin) FieldPosition(path string) (resource.Position, bool) {
+	return resource.Position{Filename: "pizza.yaml", Line: 7, Column: 3}, true
+}
+
+func TestPrintSARIF_PhysicalLocation(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	m := diag.NewMessage(errorType, &resource.Instance{Origin: testPositionedOrigin("Pizza margherita.default")}, "Feta").
+		WithField("spec.cheese")
+
+	var out bytes.Buffer
+	g.Expect(PrintSARIF(&out, diag.Messages{m})).To(Succeed())
+
+	var log sarifLog
+	g.Expect(json.Unmarshal(out.Bytes(), &log)).To(Succeed())
+	l := log.Runs[0].Results[0].Locations[0]
+	g.Expect(l.PhysicalLocation).To(Equal(&sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: "pizza.yaml"},
+		Region:           sarifRegion{StartLine: 7, StartColumn: 3},
+	}))
+	g.Expect(l.LogicalLocations[0].FullyQualifiedName).To(Equal("Pizza margherita.default"))
+}
+
+type testRevisionOrigin string
+
+func (o testRevisionOrigin) FriendlyName() string          { return string(o) }
+func (o testRevisionOrigin) Namespace() resource.Namespace { return "" }
+func (o testRevisionOrigin) FieldPosition(path string) (resource.Position, bool) {
+	return resource.Position{Filename: "pizza.yaml", Line: 7, Revision: "3f2a1b0c"}, true
+}
+
+func TestPrintSARIF_Revision(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	m := diag.NewMessage(errorType, &resource.Instance{Origin: testRevisionOrigin("Pizza margherita.default")}, "Feta")
+
+	var out bytes.Buffer
+	g.Expect(PrintSARIF(&out, diag.Messages{m})).To(Succeed())
+
+	var log sarifLog
+	g.Expect(json.Unmarshal(out.Bytes(), &log)).To(Succeed())
+	g.Expect(log.Runs[0].Results[0].Locations[0].PhysicalLocation.ArtifactLocation).To(Equal(sarifArtifactLocation{
+		URI:        "pizza.yaml",
+		Properties: &sarifProperties{Revision: "3f2a1b0c"},
+	}))
+}
+
+func TestPrintSARIF_NoMessages(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var out bytes.Buffer
+	g.Expect(PrintSARIF(&out, nil)).To(Succeed())
+	g.Expect(out.String()).To(ContainSubstring(`"results": []`))
+}
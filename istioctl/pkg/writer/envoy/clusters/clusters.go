@@ -25,9 +25,13 @@ import (
 
 	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v2alpha"
 	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/proto"
 
 	"istio.io/istio/istioctl/pkg/util/clusters"
 	protio "istio.io/istio/istioctl/pkg/util/proto"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
 )
 
 // EndpointFilter is used to pass filter information into route based config writer print functions
@@ -36,6 +40,11 @@ type EndpointFilter struct {
 	Port    uint32
 	Cluster string
 	Status  string
+
+	// FQDN, Subset and Direction filter the endpoints by the fields of the name of their cluster
+	FQDN      host.Name
+	Subset    string
+	Direction model.TrafficDirection
 }
 
 // ConfigWriter is a writer for processing responses from the Envoy Admin config_dump endpoint
@@ -90,7 +99,8 @@ func retrieveFailedOutlierCheck(l *adminapi.HostStatus) bool {
 
 // Verify returns true if the passed host matches the filter fields
 func (e *EndpointFilter) Verify(host *adminapi.HostStatus, cluster string) bool {
-	if e.Address == "" && e.Port == 0 && e.Cluster == "" && e.Status == "" {
+	if e.Address == "" && e.Port == 0 && e.Cluster == "" && e.Status == "" &&
+		e.FQDN == "" && e.Subset == "" && e.Direction == "" {
 		return true
 	}
 	if e.Address != "" && !strings.EqualFold(retrieveEndpointAddress(host), e.Address) {
@@ -102,6 +112,15 @@ func (e *EndpointFilter) Verify(host *adminapi.HostStatus, cluster string) bool
 	if e.Cluster != "" && !strings.EqualFold(cluster, e.Cluster) {
 		return false
 	}
+	if e.FQDN != "" && !strings.Contains(cluster, string(e.FQDN)) {
+		return false
+	}
+	if e.Direction != "" && !strings.Contains(cluster, string(e.Direction)) {
+		return false
+	}
+	if e.Subset != "" && !strings.Contains(cluster, e.Subset) {
+		return false
+	}
 	status := retrieveEndpointStatus(host)
 	if e.Status != "" && !strings.EqualFold(core.HealthStatus_name[int32(status)], e.Status) {
 		return false
@@ -145,26 +164,41 @@ func (c *ConfigWriter) PrintEndpointsSummary(filter EndpointFilter) error {
 	return w.Flush()
 }
 
-// PrintEndpoints prints the endpoints config to the ConfigWriter stdout
-func (c *ConfigWriter) PrintEndpoints(filter EndpointFilter) error {
+// PrintEndpoints prints the endpoints config to the ConfigWriter stdout in the given output format.
+// Only the matching hosts of each cluster are kept.
+func (c *ConfigWriter) PrintEndpoints(filter EndpointFilter, outputFormat string) error {
 	if c.clusters == nil {
 		return fmt.Errorf("config writer has not been primed")
 	}
 
 	filteredClusters := protio.MessageSlice{}
 	for _, cluster := range c.clusters.ClusterStatuses {
+		hosts := make([]*adminapi.HostStatus, 0, len(cluster.HostStatuses))
 		for _, host := range cluster.HostStatuses {
 			if filter.Verify(host, cluster.Name) {
-				filteredClusters = append(filteredClusters, cluster)
-				break
+				hosts = append(hosts, host)
 			}
 		}
-
+		if len(hosts) == 0 {
+			continue
+		}
+		if len(hosts) != len(cluster.HostStatuses) {
+			cluster = proto.Clone(cluster).(*adminapi.ClusterStatus)
+			cluster.HostStatuses = hosts
+		}
+		filteredClusters = append(filteredClusters, cluster)
 	}
 	out, err := json.MarshalIndent(filteredClusters, "", "    ")
 	if err != nil {
 		return err
 	}
+	if strings.EqualFold(outputFormat, "yaml") {
+		if out, err = yaml.JSONToYAML(out); err != nil {
+			return err
+		}
+		_, _ = c.Stdout.Write(out)
+		return nil
+	}
 	fmt.Fprintln(c.Stdout, string(out))
 	return nil
 }
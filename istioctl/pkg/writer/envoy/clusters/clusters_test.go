@@ -91,6 +91,7 @@ func TestConfigWriter_PrintClusterDump(t *testing.T) {
 	tests := []struct {
 		name           string
 		filter         EndpointFilter
+		outputFormat   string
 		wantOutputFile string
 		callPrime      bool
 		wantErr        bool
@@ -131,6 +132,25 @@ func TestConfigWriter_PrintClusterDump(t *testing.T) {
 			wantOutputFile: "testdata/clustermultihostsfiltered.txt",
 			callPrime:      true,
 		},
+		{
+			name:           "handles fqdn and direction filtering",
+			filter:         EndpointFilter{FQDN: "reviews.default.svc.cluster.local", Direction: "outbound"},
+			wantOutputFile: "testdata/clustermultihostsfiltered.txt",
+			callPrime:      true,
+		},
+		{
+			name:           "keeps only the matching hosts of a cluster",
+			filter:         EndpointFilter{FQDN: "reviews.default.svc.cluster.local", Address: "172.17.0.26"},
+			wantOutputFile: "testdata/clusterhostfiltered.txt",
+			callPrime:      true,
+		},
+		{
+			name:           "prints yaml output",
+			filter:         EndpointFilter{Cluster: "outbound|15014||istio-policy.istio-system.svc.cluster.local"},
+			outputFormat:   "yaml",
+			wantOutputFile: "testdata/clusterfiltered.yaml",
+			callPrime:      true,
+		},
 		{
 			name:      "errors if config writer is not primed",
 			callPrime: false,
@@ -145,7 +165,7 @@ func TestConfigWriter_PrintClusterDump(t *testing.T) {
 			if tt.callPrime {
 				cw.Prime(cd)
 			}
-			err := cw.PrintEndpoints(tt.filter)
+			err := cw.PrintEndpoints(tt.filter, tt.outputFormat)
 			if tt.wantOutputFile != "" {
 				util.CompareContent(gotOut.Bytes(), tt.wantOutputFile, t)
 			}
@@ -83,8 +83,8 @@ func (c *ConfigWriter) PrintClusterSummary(filter ClusterFilter) error {
 	return w.Flush()
 }
 
-// PrintClusterDump prints the relevant clusters in the config dump to the ConfigWriter stdout
-func (c *ConfigWriter) PrintClusterDump(filter ClusterFilter) error {
+// PrintClusterDump prints the relevant clusters in the config dump to the ConfigWriter stdout in the given output format
+func (c *ConfigWriter) PrintClusterDump(filter ClusterFilter, outputFormat string) error {
 	_, clusters, err := c.setupClusterConfigWriter()
 	if err != nil {
 		return err
@@ -99,8 +99,7 @@ func (c *ConfigWriter) PrintClusterDump(filter ClusterFilter) error {
 	if err != nil {
 		return err
 	}
-	_, _ = fmt.Fprintln(c.Stdout, string(out))
-	return nil
+	return c.printOutput(out, outputFormat)
 }
 
 func (c *ConfigWriter) setupClusterConfigWriter() (*tabwriter.Writer, []*xdsapi.Cluster, error) {
@@ -115,7 +115,7 @@ func TestConfigWriter_PrintClusterDump(t *testing.T) {
 			if tt.callPrime {
 				cw.Prime(cd)
 			}
-			err := cw.PrintClusterDump(tt.filter)
+			err := cw.PrintClusterDump(tt.filter, "json")
 			if tt.wantOutputFile != "" {
 				util.CompareContent(gotOut.Bytes(), tt.wantOutputFile, t)
 			}
@@ -18,7 +18,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/ghodss/yaml"
 	"github.com/golang/protobuf/jsonpb"
 
 	"istio.io/istio/istioctl/pkg/util/configdump"
@@ -44,8 +46,8 @@ func (c *ConfigWriter) Prime(b []byte) error {
 	return nil
 }
 
-// PrintBootstrapDump prints just the bootstrap config dump to the ConfigWriter stdout
-func (c *ConfigWriter) PrintBootstrapDump() error {
+// PrintBootstrapDump prints just the bootstrap config dump to the ConfigWriter stdout in the given output format
+func (c *ConfigWriter) PrintBootstrapDump(outputFormat string) error {
 	if c.configDump == nil {
 		return fmt.Errorf("config writer has not been primed")
 	}
@@ -54,14 +56,15 @@ func (c *ConfigWriter) PrintBootstrapDump() error {
 		return err
 	}
 	jsonm := &jsonpb.Marshaler{Indent: "    "}
-	if err := jsonm.Marshal(c.Stdout, bootstrapDump); err != nil {
+	out, err := jsonm.MarshalToString(bootstrapDump)
+	if err != nil {
 		return fmt.Errorf("unable to marshal bootstrap in Envoy config dump")
 	}
-	return nil
+	return c.printOutput([]byte(out), outputFormat)
 }
 
-// PrintSecretDump prints just the secret config dump to the ConfigWriter stdout
-func (c *ConfigWriter) PrintSecretDump() error {
+// PrintSecretDump prints just the secret config dump to the ConfigWriter stdout in the given output format
+func (c *ConfigWriter) PrintSecretDump(outputFormat string) error {
 	if c.configDump == nil {
 		return fmt.Errorf("config writer has not been primed")
 	}
@@ -70,10 +73,11 @@ func (c *ConfigWriter) PrintSecretDump() error {
 		return fmt.Errorf("sidecar doesn't support secrets: %v", err)
 	}
 	jsonm := &jsonpb.Marshaler{Indent: "    "}
-	if err := jsonm.Marshal(c.Stdout, secretDump); err != nil {
+	out, err := jsonm.MarshalToString(secretDump)
+	if err != nil {
 		return fmt.Errorf("unable to marshal secrets in Envoy config dump")
 	}
-	return nil
+	return c.printOutput([]byte(out), outputFormat)
 }
 
 // PrintSecretSummary prints a summary of dynamic active secrets from the config dump
@@ -95,3 +99,17 @@ func (c *ConfigWriter) PrintSecretSummary() error {
 	secretWriter := sdscompare.NewSDSWriter(c.Stdout, sdscompare.TABULAR)
 	return secretWriter.PrintSecretItems(secretItems)
 }
+
+// printOutput prints the JSON output to the ConfigWriter stdout, converted to YAML if the output format is yaml
+func (c *ConfigWriter) printOutput(out []byte, outputFormat string) error {
+	if strings.EqualFold(outputFormat, "yaml") {
+		y, err := yaml.JSONToYAML(out)
+		if err != nil {
+			return err
+		}
+		_, _ = c.Stdout.Write(y)
+		return nil
+	}
+	_, _ = fmt.Fprintln(c.Stdout, string(out))
+	return nil
+}
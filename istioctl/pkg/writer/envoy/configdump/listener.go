@@ -96,8 +96,8 @@ func (c *ConfigWriter) PrintListenerSummary(filter ListenerFilter) error {
 	return w.Flush()
 }
 
-// PrintListenerDump prints the relevant listeners in the config dump to the ConfigWriter stdout
-func (c *ConfigWriter) PrintListenerDump(filter ListenerFilter) error {
+// PrintListenerDump prints the relevant listeners in the config dump to the ConfigWriter stdout in the given output format
+func (c *ConfigWriter) PrintListenerDump(filter ListenerFilter, outputFormat string) error {
 	_, listeners, err := c.setupListenerConfigWriter()
 	if err != nil {
 		return err
@@ -112,8 +112,7 @@ func (c *ConfigWriter) PrintListenerDump(filter ListenerFilter) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(c.Stdout, string(out))
-	return nil
+	return c.printOutput(out, outputFormat)
 }
 
 func (c *ConfigWriter) setupListenerConfigWriter() (*tabwriter.Writer, []*xdsapi.Listener, error) {
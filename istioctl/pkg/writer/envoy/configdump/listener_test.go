@@ -127,7 +127,7 @@ func TestConfigWriter_PrintListenerDump(t *testing.T) {
 			if tt.callPrime {
 				cw.Prime(cd)
 			}
-			err := cw.PrintListenerDump(tt.filter)
+			err := cw.PrintListenerDump(tt.filter, "json")
 			if tt.wantOutputFile != "" {
 				util.CompareContent(gotOut.Bytes(), tt.wantOutputFile, t)
 			}
@@ -19,9 +19,12 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	"github.com/golang/protobuf/proto"
 
 	protio "istio.io/istio/istioctl/pkg/util/proto"
 )
@@ -29,16 +32,41 @@ import (
 // RouteFilter is used to pass filter information into route based config writer print functions
 type RouteFilter struct {
 	Name string
+	FQDN string
 }
 
 // Verify returns true if the passed route matches the filter fields
-func (r *RouteFilter) Verify(route *xdsapi.RouteConfiguration) bool {
-	if r.Name != "" && r.Name != route.Name {
+func (r *RouteFilter) Verify(rc *xdsapi.RouteConfiguration) bool {
+	if r.Name != "" && r.Name != rc.Name {
+		return false
+	}
+	if r.FQDN != "" && len(r.filterVirtualHosts(rc)) == 0 {
 		return false
 	}
 	return true
 }
 
+// filterVirtualHosts returns the virtual hosts of the route with a domain matching the FQDN of the filter,
+// ignoring the port of the domain
+func (r *RouteFilter) filterVirtualHosts(rc *xdsapi.RouteConfiguration) []*route.VirtualHost {
+	if r.FQDN == "" {
+		return rc.GetVirtualHosts()
+	}
+	vhosts := make([]*route.VirtualHost, 0)
+	for _, vhost := range rc.GetVirtualHosts() {
+		for _, domain := range vhost.GetDomains() {
+			if i := strings.LastIndex(domain, ":"); i > 0 && !strings.HasSuffix(domain, "]") {
+				domain = domain[:i]
+			}
+			if strings.EqualFold(domain, r.FQDN) {
+				vhosts = append(vhosts, vhost)
+				break
+			}
+		}
+	}
+	return vhosts
+}
+
 // PrintRouteSummary prints a summary of the relevant routes in the config dump to the ConfigWriter stdout
 func (c *ConfigWriter) PrintRouteSummary(filter RouteFilter) error {
 	w, routes, err := c.setupRouteConfigWriter()
@@ -49,14 +77,14 @@ func (c *ConfigWriter) PrintRouteSummary(filter RouteFilter) error {
 	fmt.Fprintln(w, "NAME\tVIRTUAL HOSTS")
 	for _, route := range routes {
 		if filter.Verify(route) {
-			fmt.Fprintf(w, "%v\t%v\n", route.Name, len(route.GetVirtualHosts()))
+			fmt.Fprintf(w, "%v\t%v\n", route.Name, len(filter.filterVirtualHosts(route)))
 		}
 	}
 	return w.Flush()
 }
 
-// PrintRouteDump prints the relevant routes in the config dump to the ConfigWriter stdout
-func (c *ConfigWriter) PrintRouteDump(filter RouteFilter) error {
+// PrintRouteDump prints the relevant routes in the config dump to the ConfigWriter stdout in the given output format
+func (c *ConfigWriter) PrintRouteDump(filter RouteFilter, outputFormat string) error {
 	_, routes, err := c.setupRouteConfigWriter()
 	if err != nil {
 		return err
@@ -64,6 +92,11 @@ func (c *ConfigWriter) PrintRouteDump(filter RouteFilter) error {
 	filteredRoutes := protio.MessageSlice{}
 	for _, route := range routes {
 		if filter.Verify(route) {
+			if filter.FQDN != "" {
+				// Only keep the matching virtual hosts in the dump
+				route = proto.Clone(route).(*xdsapi.RouteConfiguration)
+				route.VirtualHosts = filter.filterVirtualHosts(route)
+			}
 			filteredRoutes = append(filteredRoutes, route)
 		}
 	}
@@ -71,8 +104,7 @@ func (c *ConfigWriter) PrintRouteDump(filter RouteFilter) error {
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(c.Stdout, string(out))
-	return nil
+	return c.printOutput(out, outputFormat)
 }
 
 func (c *ConfigWriter) setupRouteConfigWriter() (*tabwriter.Writer, []*xdsapi.RouteConfiguration, error) {
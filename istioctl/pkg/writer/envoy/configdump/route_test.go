@@ -42,6 +42,12 @@ func TestConfigWriter_PrintRouteSummary(t *testing.T) {
 			wantOutputFile: "testdata/routesummaryfiltered.txt",
 			callPrime:      true,
 		},
+		{
+			name:           "filter virtual hosts by fqdn in the summary",
+			filter:         RouteFilter{FQDN: "istio-policy.istio-system"},
+			wantOutputFile: "testdata/routesummaryfqdnfiltered.txt",
+			callPrime:      true,
+		},
 		{
 			name:      "errors if config writer is not primed",
 			callPrime: false,
@@ -73,6 +79,7 @@ func TestConfigWriter_PrintRouteDump(t *testing.T) {
 	tests := []struct {
 		name           string
 		filter         RouteFilter
+		outputFormat   string
 		wantOutputFile string
 		callPrime      bool
 		wantErr        bool
@@ -89,6 +96,13 @@ func TestConfigWriter_PrintRouteDump(t *testing.T) {
 			wantOutputFile: "testdata/routedumpfiltered.json",
 			callPrime:      true,
 		},
+		{
+			name:           "filter virtual hosts by fqdn in the yaml dump",
+			filter:         RouteFilter{FQDN: "istio-policy.istio-system.svc.cluster.local"},
+			outputFormat:   "yaml",
+			wantOutputFile: "testdata/routedumpfqdnfiltered.yaml",
+			callPrime:      true,
+		},
 		{
 			name:      "errors if config writer is not primed",
 			callPrime: false,
@@ -103,7 +117,7 @@ func TestConfigWriter_PrintRouteDump(t *testing.T) {
 			if tt.callPrime {
 				cw.Prime(cd)
 			}
-			err := cw.PrintRouteDump(tt.filter)
+			err := cw.PrintRouteDump(tt.filter, tt.outputFormat)
 			if tt.wantOutputFile != "" {
 				util.CompareContent(gotOut.Bytes(), tt.wantOutputFile, t)
 			}
@@ -22,6 +22,9 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+
+	"istio.io/istio/operator/pkg/kubectlcmd"
+	"istio.io/istio/operator/pkg/name"
 )
 
 type manifestApplyArgs struct {
@@ -33,8 +36,12 @@ type manifestApplyArgs struct {
 	context string
 	// readinessTimeout is maximum time to wait for all Istio resources to be ready.
 	readinessTimeout time.Duration
+	// componentReadinessTimeouts overrides readinessTimeout for some components, e.g. Pilot=10m.
+	componentReadinessTimeouts map[string]string
 	// wait is flag that indicates whether to wait resources ready before exiting.
 	wait bool
+	// rollback indicates whether to apply the manifests of the previous successful install if a component fails.
+	rollback bool
 	// skipConfirmation determines whether the user is prompted for confirmation.
 	// If set to true, the user is not prompted and a Yes response is assumed in all cases.
 	skipConfirmation bool
@@ -43,6 +50,8 @@ type manifestApplyArgs struct {
 	// set is a string with element format "path=value" where path is an IstioOperator path and the value is a
 	// value to set the node at that path to.
 	set []string
+	// revision is the revision of the control plane to install, side by side with the other revisions.
+	revision string
 }
 
 func addManifestApplyFlags(cmd *cobra.Command, args *manifestApplyArgs) {
@@ -53,9 +62,16 @@ func addManifestApplyFlags(cmd *cobra.Command, args *manifestApplyArgs) {
 	cmd.PersistentFlags().BoolVar(&args.force, "force", false, "Proceed even with validation errors")
 	cmd.PersistentFlags().DurationVar(&args.readinessTimeout, "readiness-timeout", 300*time.Second, "Maximum seconds to wait for all Istio resources to be ready."+
 		" The --wait flag must be set for this flag to apply")
+	cmd.PersistentFlags().StringToStringVar(&args.componentReadinessTimeouts, "component-readiness-timeout", nil,
+		"Maximum time to wait for the resources of some components to be ready, overriding --readiness-timeout, e.g. "+
+			"IngressGateways=10m. The --wait flag must be set for this flag to apply")
 	cmd.PersistentFlags().BoolVarP(&args.wait, "wait", "w", false, "Wait, if set will wait until all Pods, Services, and minimum number of Pods "+
-		"of a Deployment are in a ready state before the command exits. It will wait for a maximum duration of --readiness-timeout seconds")
+		"of a Deployment are in a ready state before the command exits. Each component waits for the components it depends on to be ready, "+
+		"for a maximum duration of --readiness-timeout seconds per component")
+	cmd.PersistentFlags().BoolVar(&args.rollback, "rollback", false, "If a component fails to apply or to be ready, apply the manifests "+
+		"of the previous successful install again")
 	cmd.PersistentFlags().StringSliceVarP(&args.set, "set", "s", nil, SetFlagHelpStr)
+	cmd.PersistentFlags().StringVar(&args.revision, "revision", "", revisionFlagHelpStr)
 }
 
 func manifestApplyCmd(rootArgs *rootArgs, maArgs *manifestApplyArgs) *cobra.Command {
@@ -81,8 +97,22 @@ func manifestApply(args *rootArgs, maArgs *manifestApplyArgs, l *Logger) error {
 	if err := configLogs(args.logToStdErr); err != nil {
 		return fmt.Errorf("could not configure logs: %s", err)
 	}
-	if err := genApplyManifests(maArgs.set, maArgs.inFilename, maArgs.force, args.dryRun, args.verbose,
-		maArgs.kubeConfigPath, maArgs.context, maArgs.wait, maArgs.readinessTimeout, l); err != nil {
+	componentTimeouts, err := parseComponentTimeouts(maArgs.componentReadinessTimeouts)
+	if err != nil {
+		return err
+	}
+	opts := &kubectlcmd.Options{
+		DryRun:                args.dryRun,
+		Verbose:               args.verbose,
+		Wait:                  maArgs.wait,
+		WaitTimeout:           maArgs.readinessTimeout,
+		ComponentWaitTimeouts: componentTimeouts,
+		Rollback:              maArgs.rollback,
+		Kubeconfig:            maArgs.kubeConfigPath,
+		Context:               maArgs.context,
+		Revision:              maArgs.revision,
+	}
+	if err := genApplyManifests(maArgs.set, maArgs.inFilename, maArgs.force, opts, l); err != nil {
 		return fmt.Errorf("failed to generate and apply manifests, error: %v", err)
 	}
 
@@ -104,3 +134,19 @@ func confirm(msg string, writer io.Writer) bool {
 
 	return false
 }
+
+// parseComponentTimeouts parses the timeouts of the components, keyed by component name.
+func parseComponentTimeouts(timeouts map[string]string) (map[string]time.Duration, error) {
+	out := make(map[string]time.Duration, len(timeouts))
+	for c, t := range timeouts {
+		if cn := name.ComponentName(c); !cn.IsCoreComponent() && !cn.IsGateway() && !cn.IsAddon() {
+			return nil, fmt.Errorf("invalid --component-readiness-timeout: unknown component %s", c)
+		}
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --component-readiness-timeout for %s: %v", c, err)
+		}
+		out[c] = d
+	}
+	return out, nil
+}
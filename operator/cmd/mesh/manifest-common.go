@@ -19,7 +19,6 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/ghodss/yaml"
 
@@ -29,6 +28,7 @@ import (
 	"istio.io/istio/operator/pkg/kubectlcmd"
 	"istio.io/istio/operator/pkg/manifest"
 	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/revision"
 	"istio.io/istio/operator/pkg/tpath"
 	"istio.io/istio/operator/pkg/translate"
 	"istio.io/istio/operator/pkg/util"
@@ -43,8 +43,8 @@ var (
 	}
 )
 
-func genApplyManifests(setOverlay []string, inFilename []string, force bool, dryRun bool, verbose bool,
-	kubeConfigPath string, context string, wait bool, waitTimeout time.Duration, l *Logger) error {
+// genApplyManifests generates the manifests and applies them with opts to the cluster.
+func genApplyManifests(setOverlay []string, inFilename []string, force bool, opts *kubectlcmd.Options, l *Logger) error {
 	overlayFromSet, err := MakeTreeFromSetList(setOverlay, force, l)
 	if err != nil {
 		return fmt.Errorf("failed to generate tree from the set overlay, error: %v", err)
@@ -54,13 +54,16 @@ func genApplyManifests(setOverlay []string, inFilename []string, force bool, dry
 	if err != nil {
 		return fmt.Errorf("failed to generate manifest: %v", err)
 	}
-	opts := &kubectlcmd.Options{
-		DryRun:      dryRun,
-		Verbose:     verbose,
-		Wait:        wait,
-		WaitTimeout: waitTimeout,
-		Kubeconfig:  kubeConfigPath,
-		Context:     context,
+	if opts.Revision != "" {
+		if manifests, err = revision.Apply(manifests, opts.Revision); err != nil {
+			return fmt.Errorf("failed to generate the manifest of revision %s: %v", opts.Revision, err)
+		}
+	}
+	if opts.HistoryNamespace == "" {
+		// Keep the manifests of the successful installs in the namespace of the control plane
+		if ns, err := name.Namespace(name.PilotComponentName, iops); err == nil {
+			opts.HistoryNamespace = ns
+		}
 	}
 
 	for _, cn := range name.DeprecatedNames {
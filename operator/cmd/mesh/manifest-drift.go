@@ -0,0 +1,135 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/operator/pkg/drift"
+	"istio.io/istio/operator/pkg/kubectlcmd"
+	"istio.io/istio/operator/pkg/manifest"
+	"istio.io/istio/operator/pkg/revision"
+	"istio.io/istio/operator/version"
+)
+
+var (
+	// detectDrift and correctDrift are replaced in tests.
+	detectDrift  = manifest.DetectDrift
+	correctDrift = manifest.CorrectDrift
+)
+
+type manifestDriftArgs struct {
+	// inFilename is an array of paths to the input IstioOperator CR files.
+	inFilename []string
+	// kubeConfigPath is the path to kube config file.
+	kubeConfigPath string
+	// context is the cluster context in the kube config
+	context string
+	// force proceeds even if there are validation errors
+	force bool
+	// set is a string with element format "path=value" where path is an IstioOperator path and the value is a
+	// value to set the node at that path to.
+	set []string
+	// revision is the revision of the control plane to check.
+	revision string
+	// ignorePaths are the paths of the fields ignored in the comparison, in addition to drift.DefaultIgnorePaths.
+	ignorePaths []string
+	// correct re-applies the drifted objects.
+	correct bool
+}
+
+func addManifestDriftFlags(cmd *cobra.Command, args *manifestDriftArgs) {
+	cmd.PersistentFlags().StringSliceVarP(&args.inFilename, "filename", "f", nil, filenameFlagHelpStr)
+	cmd.PersistentFlags().StringVarP(&args.kubeConfigPath, "kubeconfig", "c", "", "Path to kube config")
+	cmd.PersistentFlags().StringVar(&args.context, "context", "", "The name of the kubeconfig context to use")
+	cmd.PersistentFlags().BoolVar(&args.force, "force", false, "Proceed even with validation errors")
+	cmd.PersistentFlags().StringSliceVarP(&args.set, "set", "s", nil, SetFlagHelpStr)
+	cmd.PersistentFlags().StringVar(&args.revision, "revision", "", revisionFlagHelpStr)
+	cmd.PersistentFlags().StringSliceVar(&args.ignorePaths, "ignore", nil,
+		"Paths of the fields ignored in the comparison, e.g. spec.replicas or spec.template.spec.containers.*.image")
+	cmd.PersistentFlags().BoolVar(&args.correct, "correct", false, "Apply the manifests of the drifted objects again")
+}
+
+func manifestDriftCmd(rootArgs *rootArgs, mdArgs *manifestDriftArgs) *cobra.Command {
+	return &cobra.Command{
+		Use:   "drift",
+		Short: "Reports the Istio resources of the cluster which differ from the generated manifest.",
+		Long: "The drift subcommand generates an Istio install manifest and compares its resources with the ones of the " +
+			"cluster, reporting the resources which are missing or whose fields were changed, e.g. with kubectl edit. " +
+			"Fields set by the cluster but not by the manifest are not drift. It fails if drift is found and not corrected.",
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			l := NewLogger(rootArgs.logToStdErr, cmd.OutOrStdout(), cmd.ErrOrStderr())
+			return manifestDrift(rootArgs, mdArgs, cmd.OutOrStdout(), l)
+		}}
+}
+
+func manifestDrift(args *rootArgs, mdArgs *manifestDriftArgs, writer io.Writer, l *Logger) error {
+	if err := configLogs(args.logToStdErr); err != nil {
+		return fmt.Errorf("could not configure logs: %s", err)
+	}
+	overlayFromSet, err := MakeTreeFromSetList(mdArgs.set, mdArgs.force, l)
+	if err != nil {
+		return fmt.Errorf("failed to generate tree from the set overlay, error: %v", err)
+	}
+	manifests, _, err := GenManifests(mdArgs.inFilename, overlayFromSet, mdArgs.force, l)
+	if err != nil {
+		return fmt.Errorf("failed to generate manifest: %v", err)
+	}
+	if mdArgs.revision != "" {
+		if manifests, err = revision.Apply(manifests, mdArgs.revision); err != nil {
+			return fmt.Errorf("failed to generate the manifest of revision %s: %v", mdArgs.revision, err)
+		}
+	}
+
+	opts := kubectlcmd.Options{
+		DryRun:     args.dryRun,
+		Verbose:    args.verbose,
+		Kubeconfig: mdArgs.kubeConfigPath,
+		Context:    mdArgs.context,
+		Revision:   mdArgs.revision,
+	}
+	drifts, err := detectDrift(manifests, opts, append(drift.DefaultIgnorePaths, mdArgs.ignorePaths...))
+	if err != nil {
+		return fmt.Errorf("failed to detect drift: %v", err)
+	}
+	return reportDrift(drifts, mdArgs.correct, opts, writer)
+}
+
+// reportDrift prints the drifted objects, and applies them again if correct is set. It returns an error if some
+// objects drifted and were not corrected.
+func reportDrift(drifts []*drift.Drift, correct bool, opts kubectlcmd.Options, writer io.Writer) error {
+	if len(drifts) == 0 {
+		fmt.Fprintln(writer, "✔ No drift from the manifest found.")
+		return nil
+	}
+	for _, d := range drifts {
+		fmt.Fprintln(writer, d.String())
+	}
+	if !correct {
+		return fmt.Errorf("%d objects drifted from the manifest, use --correct to apply them again", len(drifts))
+	}
+	applied, err := correctDrift(drifts, version.OperatorBinaryVersion.String(), opts)
+	for _, o := range applied {
+		fmt.Fprintf(writer, "✔ %s corrected.\n", o.Hash())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to correct drift: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"istio.io/istio/operator/pkg/drift"
+	"istio.io/istio/operator/pkg/kubectlcmd"
+	"istio.io/istio/operator/pkg/manifest"
+	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/object"
+)
+
+func TestReportDrift(t *testing.T) {
+	deployment, err := object.ParseYAMLToK8sObject([]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istio-pilot
+  namespace: istio-system
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	drifts := []*drift.Drift{{Component: name.PilotComponentName, Object: deployment, Diff: "spec:\n  replicas: 1 -> 3\n"}}
+
+	var corrected []*drift.Drift
+	correctDrift = func(drifts []*drift.Drift, _ string, _ kubectlcmd.Options) (object.K8sObjects, error) {
+		corrected = drifts
+		return object.K8sObjects{deployment}, nil
+	}
+	defer func() { correctDrift = manifest.CorrectDrift }()
+
+	tests := []struct {
+		desc          string
+		drifts        []*drift.Drift
+		correct       bool
+		wantOut       string
+		wantErr       bool
+		wantCorrected int
+	}{
+		{
+			desc:    "no drift",
+			wantOut: "No drift from the manifest found.",
+		},
+		{
+			desc:    "alert",
+			drifts:  drifts,
+			wantOut: "Pilot: Deployment:istio-system:istio-pilot differs from the manifest:\nspec:\n  replicas: 1 -> 3\n",
+			wantErr: true,
+		},
+		{
+			desc:          "correct",
+			drifts:        drifts,
+			correct:       true,
+			wantOut:       "Deployment:istio-system:istio-pilot corrected.",
+			wantCorrected: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			corrected = nil
+			var out bytes.Buffer
+			err := reportDrift(tt.drifts, tt.correct, kubectlcmd.Options{}, &out)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("reportDrift() got error %v, want error %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("reportDrift() got output:\n%s\nwant it to contain:\n%s", out.String(), tt.wantOut)
+			}
+			if len(corrected) != tt.wantCorrected {
+				t.Errorf("reportDrift() corrected %s, want %d objects", fmt.Sprint(corrected), tt.wantCorrected)
+			}
+		})
+	}
+}
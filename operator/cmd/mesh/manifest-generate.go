@@ -26,6 +26,7 @@ import (
 
 	"istio.io/istio/operator/pkg/manifest"
 	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/revision"
 )
 
 type manifestGenerateArgs struct {
@@ -38,6 +39,8 @@ type manifestGenerateArgs struct {
 	set []string
 	// force proceeds even if there are validation errors
 	force bool
+	// revision is the revision of the control plane to generate, side by side with the other revisions.
+	revision string
 }
 
 func addManifestGenerateFlags(cmd *cobra.Command, args *manifestGenerateArgs) {
@@ -45,6 +48,7 @@ func addManifestGenerateFlags(cmd *cobra.Command, args *manifestGenerateArgs) {
 	cmd.PersistentFlags().StringVarP(&args.outFilename, "output", "o", "", "Manifest output directory path")
 	cmd.PersistentFlags().StringSliceVarP(&args.set, "set", "s", nil, SetFlagHelpStr)
 	cmd.PersistentFlags().BoolVar(&args.force, "force", false, "Proceed even with validation errors")
+	cmd.PersistentFlags().StringVar(&args.revision, "revision", "", revisionFlagHelpStr)
 }
 
 func manifestGenerateCmd(rootArgs *rootArgs, mgArgs *manifestGenerateArgs) *cobra.Command {
@@ -78,6 +82,11 @@ func manifestGenerate(args *rootArgs, mgArgs *manifestGenerateArgs, l *Logger) e
 	if err != nil {
 		return err
 	}
+	if mgArgs.revision != "" {
+		if manifests, err = revision.Apply(manifests, mgArgs.revision); err != nil {
+			return err
+		}
+	}
 
 	if mgArgs.outFilename == "" {
 		for _, m := range orderedManifests(manifests) {
@@ -22,9 +22,11 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/gogo/protobuf/jsonpb"
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 
 	iopv1alpha1 "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
-	"istio.io/istio/operator/pkg/kubectlcmd"
+	"istio.io/istio/operator/pkg/manifest"
 	"istio.io/istio/operator/pkg/translate"
 	"istio.io/istio/operator/pkg/util"
 	binversion "istio.io/istio/operator/version"
@@ -120,23 +122,20 @@ func migrateFromClusterConfig(rootArgs *rootArgs, mmArgs *manifestMigrateArgs, l
 
 	l.logAndPrint("translating in cluster specs\n")
 
-	c := kubectlcmd.New()
-	opts := &kubectlcmd.Options{
-		Namespace: mmArgs.namespace,
-		ExtraArgs: []string{"jsonpath='{.data.values}'"},
+	config, err := manifest.BuildClientConfig("", "")
+	if err != nil {
+		return err
 	}
-	output, stderr, err := c.GetConfigMap("istio-sidecar-injector", opts)
+	cs, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return err
 	}
-	if stderr != "" {
-		l.logAndPrint("error: ", stderr, "\n")
+	cm, err := cs.CoreV1().ConfigMaps(mmArgs.namespace).Get("istio-sidecar-injector", metav1.GetOptions{})
+	if err != nil {
+		return err
 	}
 	var value map[string]interface{}
-	if len(output) > 1 {
-		output = output[1 : len(output)-1]
-	}
-	err = json.Unmarshal([]byte(output), &value)
+	err = json.Unmarshal([]byte(cm.Data["values"]), &value)
 	if err != nil {
 		return fmt.Errorf("error unmarshaling JSON to untyped map %s", err)
 	}
@@ -23,7 +23,7 @@ func ManifestCmd() *cobra.Command {
 	mc := &cobra.Command{
 		Use:   "manifest",
 		Short: "Commands related to Istio manifests",
-		Long:  "The manifest subcommand generates, applies, diffs or migrates Istio manifests.",
+		Long:  "The manifest subcommand generates, applies, diffs, migrates or checks the drift of Istio manifests.",
 	}
 
 	mgcArgs := &manifestGenerateArgs{}
@@ -31,6 +31,7 @@ func ManifestCmd() *cobra.Command {
 	macArgs := &manifestApplyArgs{}
 	mvArgs := &manifestVersionsArgs{}
 	mmcArgs := &manifestMigrateArgs{}
+	mdrcArgs := &manifestDriftArgs{}
 
 	args := &rootArgs{}
 
@@ -39,6 +40,7 @@ func ManifestCmd() *cobra.Command {
 	mac := manifestApplyCmd(args, macArgs)
 	mvc := manifestVersionsCmd(args, mvArgs)
 	mmc := manifestMigrateCmd(args, mmcArgs)
+	mdrc := manifestDriftCmd(args, mdrcArgs)
 
 	addFlags(mc, args)
 	addFlags(mgc, args)
@@ -46,18 +48,21 @@ func ManifestCmd() *cobra.Command {
 	addFlags(mac, args)
 	addFlags(mvc, args)
 	addFlags(mmc, args)
+	addFlags(mdrc, args)
 
 	addManifestGenerateFlags(mgc, mgcArgs)
 	addManifestDiffFlags(mdc, mdcArgs)
 	addManifestApplyFlags(mac, macArgs)
 	addManifestVersionsFlags(mvc, mvArgs)
 	addManifestMigrateFlags(mmc, mmcArgs)
+	addManifestDriftFlags(mdrc, mdrcArgs)
 
 	mc.AddCommand(mgc)
 	mc.AddCommand(mdc)
 	mc.AddCommand(mac)
 	mc.AddCommand(mmc)
 	mc.AddCommand(mvc)
+	mc.AddCommand(mdrc)
 
 	return mc
 }
@@ -23,7 +23,6 @@ import (
 
 	"istio.io/istio/operator/pkg/kubectlcmd"
 	"istio.io/istio/operator/pkg/manifest"
-	"istio.io/istio/operator/pkg/object"
 	"istio.io/pkg/log"
 )
 
@@ -103,30 +102,16 @@ func operatorRemove(args *rootArgs, orArgs *operatorRemoveArgs, l *Logger, delet
 
 func deleteManifest(manifestStr, componentName string, opts *kubectlcmd.Options, l *Logger) bool {
 	l.logAndPrintf("Deleting manifest for component %s...", componentName)
-	objs, err := object.ParseK8sObjectsFromYAMLManifest(manifestStr)
-	if err != nil {
-		l.logAndPrint("Parse error: ", err, "\n")
-		return false
-	}
-	stdout, stderr, err := kubectlcmd.New().Delete(manifestStr, opts)
-
-	success := true
+	objs, err := manifest.DeleteManifest(manifestStr, *opts)
 	if err != nil {
 		cs := fmt.Sprintf("Component %s delete returned the following errors:", componentName)
 		l.logAndPrintf("\n%s\n%s", cs, strings.Repeat("=", len(cs)))
 		l.logAndPrint("Error: ", err, "\n")
-		success = false
-	} else {
-		l.logAndPrintf("Component %s deleted successfully.", componentName)
-		if opts.Verbose {
-			l.logAndPrintf("The following objects were deleted:\n%s", k8sObjectsString(objs))
-		}
+		return false
 	}
-
-	if !ignoreError(stderr) {
-		l.logAndPrint("Error detail:\n", stderr, "\n")
-		l.logAndPrint(stdout, "\n")
-		success = false
+	l.logAndPrintf("Component %s deleted successfully.", componentName)
+	if opts.Verbose {
+		l.logAndPrintf("The following objects were deleted:\n%s", k8sObjectsString(objs))
 	}
-	return success
+	return true
 }
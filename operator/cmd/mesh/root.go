@@ -31,6 +31,9 @@ customization file`
 If set to true, the user is not prompted and a Yes response is assumed in all cases.`
 	filenameFlagHelpStr = `Path to file containing IstioOperator CustomResource
 This flag can be specified multiple times to overlay multiple files. Multiple files are overlaid in left to right order.`
+	revisionFlagHelpStr = `Revision of the control plane, installed side by side with the other revisions, e.g. canary.
+The resources of the revision are suffixed with its name, and its injector only injects the namespaces labelled
+with istio.io/rev=<revision>. The gateways and addons are left to the control plane installed without a revision.`
 )
 
 type rootArgs struct {
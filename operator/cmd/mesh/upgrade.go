@@ -24,6 +24,7 @@ import (
 
 	"istio.io/istio/operator/pkg/compare"
 	"istio.io/istio/operator/pkg/hooks"
+	"istio.io/istio/operator/pkg/kubectlcmd"
 	"istio.io/istio/operator/pkg/manifest"
 	pkgversion "istio.io/istio/operator/pkg/version"
 	"istio.io/pkg/log"
@@ -62,6 +63,8 @@ type upgradeArgs struct {
 	skipConfirmation bool
 	// force means directly applying the upgrade without eligibility checks.
 	force bool
+	// rollback means applying the manifests of the current version again if a component fails to upgrade.
+	rollback bool
 }
 
 // addUpgradeFlags adds upgrade related flags into cobra command
@@ -83,6 +86,9 @@ func addUpgradeFlags(cmd *cobra.Command, args *upgradeArgs) {
 			upgradeWaitCheckVerMaxAttempts).String())
 	cmd.PersistentFlags().BoolVar(&args.force, "force", false,
 		"Apply the upgrade without eligibility checks")
+	cmd.PersistentFlags().BoolVar(&args.rollback, "rollback", false,
+		"If a component fails to upgrade or, with --wait, to be ready, apply the manifests of the previous "+
+			"successful install again")
 }
 
 // Upgrade command upgrades Istio control plane in-place with eligibility checks
@@ -187,8 +193,17 @@ func upgrade(rootArgs *rootArgs, args *upgradeArgs, l *Logger) (err error) {
 	}
 
 	// Apply the Istio Control Plane specs reading from inFilename to the cluster
-	err = genApplyManifests(nil, args.inFilename, args.force, rootArgs.dryRun,
-		rootArgs.verbose, args.kubeConfigPath, args.context, args.wait, upgradeWaitSecWhenApply, l)
+	opts := &kubectlcmd.Options{
+		DryRun:           rootArgs.dryRun,
+		Verbose:          rootArgs.verbose,
+		Wait:             args.wait,
+		WaitTimeout:      upgradeWaitSecWhenApply,
+		Rollback:         args.rollback,
+		HistoryNamespace: istioNamespace,
+		Kubeconfig:       args.kubeConfigPath,
+		Context:          args.context,
+	}
+	err = genApplyManifests(nil, args.inFilename, args.force, opts, l)
 	if err != nil {
 		return fmt.Errorf("failed to apply the Istio Control Plane specs. Error: %v", err)
 	}
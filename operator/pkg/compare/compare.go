@@ -21,9 +21,11 @@ import (
 	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"sigs.k8s.io/yaml"
 
 	"istio.io/istio/operator/pkg/object"
@@ -337,6 +339,116 @@ func filterResourceWithSelectAndIgnore(aom map[string]*object.K8sObject, sm, im
 	return aosm, nil
 }
 
+// ObjectDrift compares the desired object, e.g. from a rendered manifest, with the live object in the cluster, and
+// returns a tree based diff text of the fields set in desired whose value differs in live, ignoring the paths in
+// ignorePaths. The fields only set in live, like the ones defaulted by the API server or the status, are not drift.
+func ObjectDrift(desired, live *object.K8sObject, ignorePaths []string) (string, error) {
+	dy, err := desired.YAML()
+	if err != nil {
+		return "", err
+	}
+	lj, err := live.JSON()
+	if err != nil {
+		return "", err
+	}
+	do, lo := make(map[string]interface{}), make(map[string]interface{})
+	if err := yaml.Unmarshal(dy, &do); err != nil {
+		return "", err
+	}
+	if err := yaml.Unmarshal(lj, &lo); err != nil {
+		return "", err
+	}
+	ly, err := yaml.Marshal(projectFields(lo, do))
+	if err != nil {
+		return "", err
+	}
+	return YAMLCmpWithIgnore(string(dy), string(ly), ignorePaths, ""), nil
+}
+
+// projectFields returns the fields of live which are set in desired. The items of lists are projected one by one.
+func projectFields(live, desired interface{}) interface{} {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+		out := make(map[string]interface{}, len(d))
+		for k, dv := range d {
+			lv, ok := l[k]
+			switch {
+			case !ok:
+			case quantityFields[k]:
+				out[k] = projectQuantities(lv, dv)
+			default:
+				out[k] = projectFields(lv, dv)
+			}
+		}
+		return out
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok {
+			return live
+		}
+		out := make([]interface{}, len(l))
+		for i, lv := range l {
+			if i < len(d) {
+				lv = projectFields(lv, d[i])
+			}
+			out[i] = lv
+		}
+		return out
+	}
+	return live
+}
+
+// quantityFields are the fields holding maps of quantities, which the API server stores in their canonical form,
+// e.g. 2048Mi as 2Gi or 1000m as 1.
+var quantityFields = map[string]bool{
+	"limits":   true,
+	"requests": true,
+}
+
+// projectQuantities returns the fields of live which are set in desired, like projectFields, with the desired value
+// of the quantities equal in both.
+func projectQuantities(live, desired interface{}) interface{} {
+	d, ok := desired.(map[string]interface{})
+	if !ok {
+		return projectFields(live, desired)
+	}
+	l, ok := live.(map[string]interface{})
+	if !ok {
+		return live
+	}
+	out := make(map[string]interface{}, len(d))
+	for k, dv := range d {
+		lv, ok := l[k]
+		if !ok {
+			continue
+		}
+		out[k] = lv
+		dq, err := parseQuantity(dv)
+		if err != nil {
+			continue
+		}
+		if lq, err := parseQuantity(lv); err == nil && dq.Cmp(lq) == 0 {
+			out[k] = dv
+		}
+	}
+	return out
+}
+
+// parseQuantity parses a quantity unmarshaled from YAML or JSON, either a string or a number.
+func parseQuantity(v interface{}) (resource.Quantity, error) {
+	switch q := v.(type) {
+	case string:
+		return resource.ParseQuantity(q)
+	case float64:
+		return resource.ParseQuantity(strconv.FormatFloat(q, 'f', -1, 64))
+	}
+	return resource.Quantity{}, fmt.Errorf("%v is not a quantity", v)
+}
+
 // buildResourceRegexp translates the resource indicator to regexp.
 func buildResourceRegexp(s string) (*regexp.Regexp, error) {
 	hash := strings.Split(s, ":")
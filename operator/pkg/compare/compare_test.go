@@ -1487,3 +1487,141 @@ Object Deployment:istio-system:istio-citadel is missing in B:
 		})
 	}
 }
+
+func TestObjectDrift(t *testing.T) {
+	desired := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istio-pilot
+  namespace: istio-system
+  labels:
+    app: pilot
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: discovery
+        image: pilot:1.5.0
+        env:
+        - name: PILOT_TRACE_SAMPLING
+          value: "1"
+`
+	tests := []struct {
+		desc             string
+		live             string
+		desiredResources string
+		ignorePaths      []string
+		want             string
+	}{
+		{
+			desc: "fields defaulted by the server",
+			live: desired + `  strategy:
+    type: RollingUpdate
+status:
+  replicas: 1
+`,
+			want: ``,
+		},
+		{
+			desc: "edited fields",
+			live: strings.Replace(strings.Replace(desired, "pilot:1.5.0", "pilot:debug", 1), "replicas: 1", "replicas: 3", 1),
+			want: `spec:
+  replicas: 1 -> 3
+  template:
+    spec:
+      containers:
+        '[0]':
+          image: pilot:1.5.0 -> pilot:debug
+`,
+		},
+		{
+			desc: "removed fields",
+			live: strings.Replace(desired, "  labels:\n    app: pilot\n", "", 1),
+			want: `metadata:
+  labels: map[app:pilot] ->
+`,
+		},
+		{
+			desc: "added list items",
+			live: desired + `        - name: PILOT_DEBUG
+          value: "true"
+`,
+			want: `spec:
+  template:
+    spec:
+      containers:
+        '[0]':
+          env:
+            '[?->1]': -> map[name:PILOT_DEBUG value:true]
+`,
+		},
+		{
+			desc: "canonical quantities",
+			live: desired + `        resources:
+          limits:
+            cpu: "1"
+            memory: 2Gi
+          requests:
+            cpu: 100m
+            memory: 1Gi
+`,
+			desiredResources: `        resources:
+          limits:
+            cpu: 1000m
+            memory: 2048Mi
+          requests:
+            cpu: 0.1
+            memory: 1024Mi
+`,
+			want: ``,
+		},
+		{
+			desc: "edited quantities",
+			live: desired + `        resources:
+          limits:
+            cpu: "2"
+            memory: 2Gi
+`,
+			desiredResources: `        resources:
+          limits:
+            cpu: 1000m
+            memory: 2048Mi
+`,
+			want: `spec:
+  template:
+    spec:
+      containers:
+        '[0]':
+          resources:
+            limits:
+              cpu: 1000m -> 2
+`,
+		},
+		{
+			desc:        "ignored paths",
+			live:        strings.Replace(desired, "replicas: 1", "replicas: 3", 1),
+			ignorePaths: []string{"spec.replicas"},
+			want:        ``,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			d, err := object.ParseYAMLToK8sObject([]byte(desired + tt.desiredResources))
+			if err != nil {
+				t.Fatal(err)
+			}
+			l, err := object.ParseYAMLToK8sObject([]byte(tt.live))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ObjectDrift(d, l, tt.ignorePaths)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ObjectDrift() got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,7 +15,11 @@
 package istiocontrolplane
 
 import (
+	"time"
+
 	"github.com/spf13/cobra"
+
+	"istio.io/istio/operator/pkg/drift"
 )
 
 // Options represents the details used to configure the controller.
@@ -26,13 +30,21 @@ type Options struct {
 	// DefaultChartPath is the relative path used added to BaseChartPath when no value is specified in
 	// IstioOperator.Spec.ChartPath
 	DefaultChartPath string
+	// DriftCheckInterval is the interval between the checks of the resources of an unchanged IstioOperator for
+	// drift from the rendered manifests, e.g. after a kubectl edit. Drift is not checked if 0.
+	DriftCheckInterval time.Duration
+	// DriftPolicy is what is done when drift is detected: alert only reports it in the drift annotation of the
+	// IstioOperator, correct applies the manifests again.
+	DriftPolicy string
 }
 
 // ControllerOptions represents the options used by the controller
 var controllerOptions = &Options{
 	// XXX: update this once we add charts to the operator
-	BaseChartPath:    "/etc/istio-operator/helm",
-	DefaultChartPath: "istio",
+	BaseChartPath:      "/etc/istio-operator/helm",
+	DefaultChartPath:   "istio",
+	DriftCheckInterval: 5 * time.Minute,
+	DriftPolicy:        string(drift.PolicyAlert),
 }
 
 // AttachCobraFlags attaches a set of Cobra flags to the given Cobra command.
@@ -45,4 +57,9 @@ func AttachCobraFlags(cmd *cobra.Command) {
 			"This will be used as the base path for any IstioOperator instances specifying a relative ChartPath.")
 	cmd.PersistentFlags().StringVar(&controllerOptions.BaseChartPath, "default-chart-path", "",
 		"A path relative to base-chart-path containing charts to be used when no ChartPath is specified by an IstioOperator resource, e.g. 1.1.0/istio")
+	cmd.PersistentFlags().DurationVar(&controllerOptions.DriftCheckInterval, "drift-check-interval", controllerOptions.DriftCheckInterval,
+		"The interval between the checks of the Istio resources for drift from the rendered manifests. Drift is not checked if 0")
+	cmd.PersistentFlags().StringVar(&controllerOptions.DriftPolicy, "drift-policy", controllerOptions.DriftPolicy,
+		"What is done when Istio resources drifted from the rendered manifests: alert reports them in the "+
+			"install.operator.istio.io/drift annotation of the IstioOperator, correct applies the manifests again")
 }
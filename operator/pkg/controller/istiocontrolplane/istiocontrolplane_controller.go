@@ -17,6 +17,9 @@ package istiocontrolplane
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -36,6 +39,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	iop "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
+	"istio.io/istio/operator/pkg/drift"
 	"istio.io/istio/operator/pkg/helmreconciler"
 	"istio.io/pkg/log"
 )
@@ -54,13 +58,25 @@ const (
 // Add creates a new IstioOperator Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	r, err := newReconciler(mgr)
+	if err != nil {
+		return err
+	}
+	return add(mgr, r)
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+func newReconciler(mgr manager.Manager) (reconcile.Reconciler, error) {
 	factory := &helmreconciler.Factory{CustomizerFactory: &IstioRenderingCustomizerFactory{}}
-	return &ReconcileIstioOperator{client: mgr.GetClient(), scheme: mgr.GetScheme(), factory: factory}
+	if controllerOptions.DriftCheckInterval > 0 {
+		policy, err := drift.ParsePolicy(controllerOptions.DriftPolicy)
+		if err != nil {
+			return nil, err
+		}
+		factory.DriftPolicy = policy
+	}
+	return &ReconcileIstioOperator{client: mgr.GetClient(), scheme: mgr.GetScheme(), factory: factory,
+		driftCheckInterval: controllerOptions.DriftCheckInterval}, nil
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -95,6 +111,9 @@ type ReconcileIstioOperator struct {
 	client  client.Client
 	scheme  *runtime.Scheme
 	factory *helmreconciler.Factory
+	// driftCheckInterval is the interval after which an IstioOperator is reconciled again to check its resources for
+	// drift, or 0 if drift is not checked.
+	driftCheckInterval time.Duration
 }
 
 // Reconcile reads that state of the cluster for a IstioOperator object and makes changes based on the state read
@@ -191,7 +210,8 @@ func (r *ReconcileIstioOperator) Reconcile(request reconcile.Request) (reconcile
 		log.Errorf("failed to create reconciler: %s", err)
 	}
 
-	return reconcile.Result{}, err
+	// Reconcile again later to check the resources for drift from the manifests.
+	return reconcile.Result{RequeueAfter: r.driftCheckInterval}, err
 }
 
 var (
@@ -237,7 +257,9 @@ func (r *ReconcileIstioOperator) getOrCreateReconciler(iop *iop.IstioOperator) (
 		reconciler.SetNeedUpdateAndPrune(false)
 		oldInstance := reconciler.GetInstance()
 		reconciler.SetInstance(iop)
-		if reconciler.GetInstance() != oldInstance {
+		// Without drift policy, the resources are updated on every reconcile. Otherwise, they are only updated when
+		// the spec changed, or to correct their drift.
+		if r.factory.DriftPolicy == "" || !proto.Equal(iop.Spec, oldInstance.Spec) {
 			//regenerate the reconciler
 			if reconciler, err = r.factory.New(iop, r.client); err == nil {
 				reconcilers[key] = reconciler
@@ -32,6 +32,7 @@ import (
 	mesh "istio.io/api/mesh/v1alpha1"
 	"istio.io/api/operator/v1alpha1"
 	iop "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
+	"istio.io/istio/operator/pkg/drift"
 	"istio.io/istio/operator/pkg/helmreconciler"
 	"istio.io/istio/operator/pkg/name"
 )
@@ -184,6 +185,58 @@ func testSwitchProfile(t *testing.T, c testCase) {
 	}
 }
 
+func TestGetOrCreateReconciler(t *testing.T) {
+	tests := []struct {
+		desc        string
+		driftPolicy drift.Policy
+		profile     string
+		wantNew     bool
+	}{
+		{
+			desc:    "no drift policy",
+			profile: "default",
+			wantNew: true,
+		},
+		{
+			desc:        "drift policy with unchanged spec",
+			driftPolicy: drift.PolicyAlert,
+			profile:     "default",
+			wantNew:     false,
+		},
+		{
+			desc:        "drift policy with changed spec",
+			driftPolicy: drift.PolicyAlert,
+			profile:     "demo",
+			wantNew:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			newInstance := func(profile string) *iop.IstioOperator {
+				return &iop.IstioOperator{
+					ObjectMeta: metav1.ObjectMeta{Name: "example-istiocontrolplane", Namespace: "istio-system"},
+					Spec:       &v1alpha1.IstioOperatorSpec{Profile: profile},
+				}
+			}
+			factory := &helmreconciler.Factory{CustomizerFactory: &IstioRenderingCustomizerFactory{}, DriftPolicy: tt.driftPolicy}
+			r := &ReconcileIstioOperator{client: fake.NewFakeClient(), factory: factory}
+			first, err := r.getOrCreateReconciler(newInstance("default"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer delete(reconcilers, reconcilersMapKey(first.GetInstance()))
+
+			second, err := r.getOrCreateReconciler(newInstance(tt.profile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotNew := second != first; gotNew != tt.wantNew {
+				t.Errorf("getOrCreateReconciler() regenerated the reconciler: %v, want %v", gotNew, tt.wantNew)
+			}
+		})
+	}
+}
+
 func statusExpected(s1, s2 *v1alpha1.InstallStatus_VersionStatus) bool {
 	return s1.Status.String() == s2.Status.String()
 }
@@ -17,6 +17,8 @@ package istiocontrolplane
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -32,13 +34,18 @@ import (
 
 	"istio.io/api/operator/v1alpha1"
 	iop "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
+	"istio.io/istio/operator/pkg/drift"
 	"istio.io/istio/operator/pkg/helmreconciler"
+	"istio.io/istio/operator/pkg/util"
 	"istio.io/pkg/log"
 )
 
 const (
 	// ChartOwnerKey is the annotation key used to store the name of the chart that created the resource
 	ChartOwnerKey = MetadataNamespace + "/chart-owner"
+	// DriftKey is the annotation key used to report the resources which drifted from the rendered manifests on the
+	// IstioOperator, one line per component.
+	DriftKey = MetadataNamespace + "/drift"
 
 	finalizerRemovalBackoffSteps    = 10
 	finalizerRemovalBackoffDuration = 6 * time.Second
@@ -89,10 +96,32 @@ func (u *IstioStatusUpdater) EndReconcile(_ runtime.Object, status *v1alpha1.Ins
 	if err := u.reconciler.GetClient().Get(context.TODO(), namespacedName, iop); err != nil {
 		return fmt.Errorf("failed to get IstioOperator before updating status due to %v", err)
 	}
+	if err := u.updateDrift(iop); err != nil {
+		return fmt.Errorf("failed to update IstioOperator drift due to %v", err)
+	}
 	iop.Status = status
 	return u.reconciler.GetClient().Status().Update(context.TODO(), iop)
 }
 
+// updateDrift sets the drift annotation of instance to the drift reported by the last reconcile, or deletes it if none.
+func (u *IstioStatusUpdater) updateDrift(instance *iop.IstioOperator) error {
+	var lines []string
+	for c, ds := range u.reconciler.GetDrifts() {
+		lines = append(lines, fmt.Sprintf("%s: %s", c, drift.Summary(ds)))
+	}
+	sort.Strings(lines)
+	value := strings.Join(lines, "\n")
+	if old, _ := util.GetAnnotation(instance, DriftKey); old == value {
+		return nil
+	}
+	if value == "" {
+		util.DeleteAnnotation(instance, DriftKey)
+	} else if err := util.SetAnnotation(instance, DriftKey, value); err != nil {
+		return err
+	}
+	return u.reconciler.GetClient().Update(context.TODO(), instance)
+}
+
 // RegisterReconciler registers the HelmReconciler with this object
 func (u *IstioStatusUpdater) RegisterReconciler(reconciler *helmreconciler.HelmReconciler) {
 	u.reconciler = reconciler
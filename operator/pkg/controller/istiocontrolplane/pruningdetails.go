@@ -77,6 +77,7 @@ var (
 		{Group: "apps", Version: "v1", Kind: "Deployment"},
 		{Group: "apps", Version: "v1", Kind: "DaemonSet"},
 		{Group: "extensions", Version: "v1beta1", Kind: "Ingress"},
+		{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"},
 		{Group: "", Version: "v1", Kind: "Service"},
 		{Group: "", Version: "v1", Kind: "Endpoints"},
 		{Group: "", Version: "v1", Kind: "ConfigMap"},
@@ -0,0 +1,143 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drift detects the resources of an Istio install which differ from the rendered manifests, e.g. after a
+// managed Deployment was edited with kubectl.
+package drift
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"istio.io/istio/operator/pkg/compare"
+	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/object"
+)
+
+// Policy is what is done when drift is detected.
+type Policy string
+
+const (
+	// PolicyAlert only reports the drift.
+	PolicyAlert Policy = "alert"
+	// PolicyCorrect re-applies the rendered manifests to correct the drift.
+	PolicyCorrect Policy = "correct"
+)
+
+var (
+	// DefaultIgnorePaths are the paths of the fields set in the rendered manifests which are expected to be
+	// changed in the cluster.
+	DefaultIgnorePaths = []string{
+		// The CA bundle of the webhooks is patched by the webhook servers.
+		"webhooks.*.clientConfig.caBundle",
+	}
+)
+
+// ParsePolicy returns the drift policy named s.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(s); p {
+	case PolicyAlert, PolicyCorrect:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown drift policy %q, must be one of %s, %s", s, PolicyAlert, PolicyCorrect)
+}
+
+// Getter returns the live object of the cluster with the kind, namespace and name of o, or nil if it doesn't exist.
+type Getter func(o *object.K8sObject) (*object.K8sObject, error)
+
+// Drift is an object of the rendered manifests which differs from the live object in the cluster.
+type Drift struct {
+	// Component is the component the object belongs to.
+	Component name.ComponentName
+	// Object is the desired object of the rendered manifest.
+	Object *object.K8sObject
+	// Missing is true if the object doesn't exist in the cluster.
+	Missing bool
+	// Diff is the tree based diff text of the fields of the desired object which differ in the live object.
+	Diff string
+}
+
+// String implements the Stringer interface.
+func (d *Drift) String() string {
+	if d.Missing {
+		return fmt.Sprintf("%s: %s is missing", d.Component, d.Object.Hash())
+	}
+	return fmt.Sprintf("%s: %s differs from the manifest:\n%s", d.Component, d.Object.Hash(), d.Diff)
+}
+
+// Detect compares the objects of the rendered manifests of each component with the live objects returned by get,
+// ignoring the fields matching ignorePaths, and returns the drifted objects ordered by component and object.
+func Detect(manifests name.ManifestMap, get Getter, ignorePaths []string) ([]*Drift, error) {
+	var drifts []*Drift
+	for c, ms := range manifests {
+		objects, err := object.ParseK8sObjectsFromYAMLManifest(strings.Join(ms, object.YAMLSeparator))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the manifest of %s: %v", c, err)
+		}
+		for _, o := range objects {
+			live, err := get(o)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get %s: %v", o.Hash(), err)
+			}
+			if live == nil {
+				drifts = append(drifts, &Drift{Component: c, Object: o, Missing: true})
+				continue
+			}
+			diff, err := compare.ObjectDrift(o, live, ignorePaths)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare %s: %v", o.Hash(), err)
+			}
+			if diff != "" {
+				drifts = append(drifts, &Drift{Component: c, Object: o, Diff: diff})
+			}
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool {
+		if drifts[i].Component != drifts[j].Component {
+			return drifts[i].Component < drifts[j].Component
+		}
+		return drifts[i].Object.Hash() < drifts[j].Object.Hash()
+	})
+	return drifts, nil
+}
+
+// Summary returns a one line summary of the drifted objects of a component, as reported on the IstioOperator.
+func Summary(drifts []*Drift) string {
+	var missing, changed []string
+	for _, d := range drifts {
+		if d.Missing {
+			missing = append(missing, d.Object.Hash())
+		} else {
+			changed = append(changed, d.Object.Hash())
+		}
+	}
+	var out []string
+	if len(changed) > 0 {
+		out = append(out, fmt.Sprintf("drifted from the manifest: %s", strings.Join(changed, ", ")))
+	}
+	if len(missing) > 0 {
+		out = append(out, fmt.Sprintf("missing: %s", strings.Join(missing, ", ")))
+	}
+	return strings.Join(out, "; ")
+}
+
+// ByComponent groups the drifted objects by component.
+func ByComponent(drifts []*Drift) map[name.ComponentName][]*Drift {
+	out := make(map[name.ComponentName][]*Drift)
+	for _, d := range drifts {
+		out[d.Component] = append(out[d.Component], d)
+	}
+	return out
+}
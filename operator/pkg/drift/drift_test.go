@@ -0,0 +1,103 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drift
+
+import (
+	"strings"
+	"testing"
+
+	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/object"
+)
+
+const (
+	pilotDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istio-pilot
+  namespace: istio-system
+spec:
+  replicas: 1
+`
+	pilotService = `apiVersion: v1
+kind: Service
+metadata:
+  name: istio-pilot
+  namespace: istio-system
+spec:
+  ports:
+  - port: 15010
+`
+	injectorWebhook = `apiVersion: admissionregistration.k8s.io/v1beta1
+kind: MutatingWebhookConfiguration
+metadata:
+  name: istio-sidecar-injector
+webhooks:
+- name: sidecar-injector.istio.io
+  clientConfig:
+    caBundle: ""
+`
+)
+
+func TestDetect(t *testing.T) {
+	manifests := name.ManifestMap{
+		name.PilotComponentName:           {pilotDeployment, pilotService},
+		name.SidecarInjectorComponentName: {injectorWebhook},
+	}
+	live := map[string]string{
+		"Deployment:istio-system:istio-pilot": strings.Replace(pilotDeployment, "replicas: 1", "replicas: 3", 1) +
+			"  strategy:\n    type: RollingUpdate\n",
+		"MutatingWebhookConfiguration::istio-sidecar-injector": strings.Replace(injectorWebhook, `caBundle: ""`, "caBundle: Zm9v", 1),
+	}
+	get := func(o *object.K8sObject) (*object.K8sObject, error) {
+		y, ok := live[o.Hash()]
+		if !ok {
+			return nil, nil
+		}
+		return object.ParseYAMLToK8sObject([]byte(y))
+	}
+
+	drifts, err := Detect(manifests, get, DefaultIgnorePaths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range drifts {
+		got = append(got, d.String())
+	}
+	want := []string{
+		"Pilot: Deployment:istio-system:istio-pilot differs from the manifest:\nspec:\n  replicas: 1 -> 3\n",
+		"Pilot: Service:istio-system:istio-pilot is missing",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Detect() got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	wantSummary := "drifted from the manifest: Deployment:istio-system:istio-pilot; missing: Service:istio-system:istio-pilot"
+	if got := Summary(ByComponent(drifts)[name.PilotComponentName]); got != wantSummary {
+		t.Errorf("Summary() got %q, want %q", got, wantSummary)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, s := range []string{"alert", "correct"} {
+		if p, err := ParsePolicy(s); err != nil || string(p) != s {
+			t.Errorf("ParsePolicy(%q) = %v, %v", s, p, err)
+		}
+	}
+	if _, err := ParsePolicy("ignore"); err == nil {
+		t.Errorf("ParsePolicy(ignore) got no error")
+	}
+}
@@ -15,15 +15,21 @@
 package helmreconciler
 
 import (
+	"context"
 	"sync"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"istio.io/api/operator/v1alpha1"
 	iop "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
+	"istio.io/istio/operator/pkg/drift"
 	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/object"
 	"istio.io/istio/operator/pkg/util"
 	"istio.io/pkg/log"
 )
@@ -35,12 +41,18 @@ type HelmReconciler struct {
 	customizer         RenderingCustomizer
 	instance           *iop.IstioOperator
 	needUpdateAndPrune bool
+	driftPolicy        drift.Policy
+	// drifts are the objects which drifted from the manifests in the last reconcile, by component.
+	drifts map[name.ComponentName][]*drift.Drift
 }
 
 // Factory is a factory for creating HelmReconciler objects using the specified CustomizerFactory.
 type Factory struct {
 	// CustomizerFactory is a factory for creating the Customizer object for the HelmReconciler.
 	CustomizerFactory RenderingCustomizerFactory
+	// DriftPolicy is what is done when the resources of the cluster drifted from the manifests while the custom
+	// resource is unchanged. Drift is not checked if empty.
+	DriftPolicy drift.Policy
 }
 
 // New Returns a new HelmReconciler for the custom resource.
@@ -56,7 +68,8 @@ func (f *Factory) New(instance *iop.IstioOperator, client client.Client) (*HelmR
 	if err != nil {
 		return nil, err
 	}
-	reconciler := &HelmReconciler{client: client, customizer: wrappedcustomizer, instance: instance, needUpdateAndPrune: true}
+	reconciler := &HelmReconciler{client: client, customizer: wrappedcustomizer, instance: instance, needUpdateAndPrune: true,
+		driftPolicy: f.DriftPolicy}
 	wrappedcustomizer.RegisterReconciler(reconciler)
 	return reconciler, nil
 }
@@ -108,8 +121,31 @@ func (h *HelmReconciler) Reconcile() error {
 	//	}
 	//	manifestMap[chartName] = newManifests
 	//}
+
+	// Check whether the resources drifted from the manifests when they are not updated anyway, to correct them.
+	if !h.needUpdateAndPrune && h.driftPolicy == drift.PolicyCorrect {
+		if drifts, err := h.detectDrift(manifestMap); err != nil {
+			log.Errorf("failed to detect drift from the manifests: %s", err)
+		} else if len(drifts) != 0 {
+			log.Infof("resources of %d components drifted from the manifests, correcting them", len(drifts))
+			h.needUpdateAndPrune = true
+		}
+	}
+
 	status := h.processRecursive(manifestMap)
 
+	// Check whether the resources drifted from the manifests once processed, to report them: the missing objects
+	// are created by the processing.
+	h.drifts = nil
+	if !h.needUpdateAndPrune && h.driftPolicy == drift.PolicyAlert {
+		if h.drifts, err = h.detectDrift(manifestMap); err != nil {
+			log.Errorf("failed to detect drift from the manifests: %s", err)
+		}
+		for c, ds := range h.drifts {
+			log.Warnf("resources of component %s %s", c, drift.Summary(ds))
+		}
+	}
+
 	// Delete any resources not in the manifest but managed by operator.
 	var errs util.Errors
 	if h.needUpdateAndPrune {
@@ -197,6 +233,39 @@ func (h *HelmReconciler) processRecursive(manifests ChartManifestsMap) *v1alpha1
 	return out
 }
 
+// detectDrift returns the objects of the manifests which differ from the live objects of the cluster, by component.
+func (h *HelmReconciler) detectDrift(manifests ChartManifestsMap) (map[name.ComponentName][]*drift.Drift, error) {
+	mm := make(name.ManifestMap, len(manifests))
+	for c, ms := range manifests {
+		for _, m := range ms {
+			mm[name.ComponentName(c)] = append(mm[name.ComponentName(c)], m.Content)
+		}
+	}
+	drifts, err := drift.Detect(mm, h.getLiveObject, drift.DefaultIgnorePaths)
+	if err != nil {
+		return nil, err
+	}
+	return drift.ByComponent(drifts), nil
+}
+
+// getLiveObject returns the live object of the cluster with the kind, namespace and name of o, or nil if it doesn't
+// exist.
+func (h *HelmReconciler) getLiveObject(o *object.K8sObject) (*object.K8sObject, error) {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(o.GroupVersionKind())
+	namespace := o.Namespace
+	if namespace == "" {
+		namespace = h.customizer.Input().GetTargetNamespace()
+	}
+	if err := h.client.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: o.Name}, u); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return object.NewK8sObject(u, nil, nil), nil
+}
+
 // Delete resources associated with the custom resource instance
 func (h *HelmReconciler) Delete() error {
 	h.needUpdateAndPrune = true
@@ -251,6 +320,12 @@ func (h *HelmReconciler) SetInstance(instance *iop.IstioOperator) {
 	h.instance = instance
 }
 
+// GetDrifts returns the objects which drifted from the manifests in the last reconcile, by component, when the drift
+// is only reported.
+func (h *HelmReconciler) GetDrifts() map[name.ComponentName][]*drift.Drift {
+	return h.drifts
+}
+
 // SetNeedUpdateAndPrune set the needUpdateAndPrune flag associated with this HelmReconciler
 func (h *HelmReconciler) SetNeedUpdateAndPrune(u bool) {
 	h.needUpdateAndPrune = u
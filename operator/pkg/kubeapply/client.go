@@ -0,0 +1,315 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kubeapply applies, lists and deletes Kubernetes objects in process with the dynamic client, so that
+// installing Istio doesn't depend on a kubectl binary.
+package kubeapply
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	kubectl "k8s.io/kubectl/pkg/util"
+
+	"istio.io/istio/operator/pkg/object"
+	"istio.io/pkg/log"
+)
+
+const (
+	// FieldManager is the manager of the fields set by the operator, as recorded by server-side apply.
+	FieldManager = "istio-operator"
+)
+
+// Client applies, lists and deletes Kubernetes objects with the dynamic client.
+type Client struct {
+	dynamic dynamic.Interface
+	mapper  meta.RESTMapper
+	// clientSideApply is set once the cluster rejected a server-side apply, e.g. before Kubernetes 1.16 where it
+	// is disabled by default.
+	clientSideApply bool
+}
+
+// resettable is a mapper caching the discovered resources, e.g. restmapper.DeferredDiscoveryRESTMapper.
+type resettable interface {
+	Reset()
+}
+
+// Errors holds the errors of the objects which could not be applied or deleted, keyed by object hash.
+type Errors map[string]error
+
+// Error implements error
+func (e Errors) Error() string {
+	lines := make([]string, 0, len(e))
+	for h, err := range e {
+		lines = append(lines, fmt.Sprintf("%s: %v", h, err))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// New creates a Client for the cluster of the REST config.
+func New(config *rest.Config) (*Client, error) {
+	d, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewForClients(d, restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc))), nil
+}
+
+// NewForClients creates a Client from a dynamic client and a mapper of the kinds to the resources of the cluster.
+func NewForClients(d dynamic.Interface, mapper meta.RESTMapper) *Client {
+	return &Client{dynamic: d, mapper: mapper}
+}
+
+// Apply applies the objects in order with server-side apply, taking the ownership of the fields managed by others.
+// If the cluster doesn't support server-side apply, the objects are applied client-side like kubectl apply does.
+// All the objects are applied even if some fail: it returns the applied objects and the Errors of the others.
+func (c *Client) Apply(objs object.K8sObjects) (object.K8sObjects, error) {
+	var applied object.K8sObjects
+	errs := Errors{}
+	for _, o := range objs {
+		ri, err := c.resource(o.GroupVersionKind(), o.Namespace)
+		if err != nil {
+			errs[o.Hash()] = err
+			continue
+		}
+		if err := c.apply(ri, o); err != nil {
+			errs[o.Hash()] = err
+			continue
+		}
+		log.Infof("applied %s", o.Hash())
+		applied = append(applied, o)
+	}
+	if len(errs) > 0 {
+		return applied, errs
+	}
+	return applied, nil
+}
+
+func (c *Client) apply(ri dynamic.ResourceInterface, o *object.K8sObject) error {
+	if !c.clientSideApply {
+		data, err := o.JSON()
+		if err != nil {
+			return err
+		}
+		force := true
+		_, err = ri.Patch(o.Name, types.ApplyPatchType, data, metav1.PatchOptions{
+			FieldManager: FieldManager,
+			Force:        &force,
+		})
+		if !errors.IsUnsupportedMediaType(err) && !errors.IsNotAcceptable(err) {
+			return err
+		}
+		log.Infof("server-side apply is not supported by the cluster, falling back to client-side apply")
+		c.clientSideApply = true
+	}
+	return applyClientSide(ri, o)
+}
+
+// applyClientSide creates the object, or updates it with a three-way merge patch of the live object, the
+// configuration last applied and the object, recording the object as the configuration last applied.
+func applyClientSide(ri dynamic.ResourceInterface, o *object.K8sObject) error {
+	u := o.UnstructuredObject().DeepCopy()
+	live, err := ri.Get(u.GetName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		if err := kubectl.CreateApplyAnnotation(u, unstructured.UnstructuredJSONScheme); err != nil {
+			return err
+		}
+		_, err = ri.Create(u, metav1.CreateOptions{FieldManager: FieldManager})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	modified, err := kubectl.GetModifiedConfiguration(u, true, unstructured.UnstructuredJSONScheme)
+	if err != nil {
+		return err
+	}
+	original, err := kubectl.GetOriginalConfiguration(live)
+	if err != nil {
+		return err
+	}
+	current, err := live.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	var patch []byte
+	patchType := types.MergePatchType
+	if versioned, err := scheme.Scheme.New(o.GroupVersionKind()); err == nil {
+		// Built-in kinds merge their lists by key, e.g. the containers by name.
+		patchMeta, err := strategicpatch.NewPatchMetaFromStruct(versioned)
+		if err != nil {
+			return err
+		}
+		patch, err = strategicpatch.CreateThreeWayMergePatch(original, modified, current, patchMeta, true)
+		if err != nil {
+			return err
+		}
+		patchType = types.StrategicMergePatchType
+	} else {
+		patch, err = jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, current)
+		if err != nil {
+			return err
+		}
+	}
+	if string(patch) == "{}" {
+		return nil
+	}
+	_, err = ri.Patch(u.GetName(), patchType, patch, metav1.PatchOptions{FieldManager: FieldManager})
+	return err
+}
+
+// Delete deletes the objects, ignoring those which don't exist. All the objects are deleted even if some fail: it
+// returns the deleted objects and the Errors of the others.
+func (c *Client) Delete(objs object.K8sObjects) (object.K8sObjects, error) {
+	var deleted object.K8sObjects
+	errs := Errors{}
+	propagation := metav1.DeletePropagationBackground
+	for _, o := range objs {
+		ri, err := c.resource(o.GroupVersionKind(), o.Namespace)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				// The kind is not served anymore, e.g. the CRD was deleted with its instances
+				continue
+			}
+			errs[o.Hash()] = err
+			continue
+		}
+		err = ri.Delete(o.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !errors.IsNotFound(err) {
+			errs[o.Hash()] = err
+			continue
+		}
+		log.Infof("deleted %s", o.Hash())
+		deleted = append(deleted, o)
+	}
+	if len(errs) > 0 {
+		return deleted, errs
+	}
+	return deleted, nil
+}
+
+// Get returns the live object of the cluster with the kind, namespace and name of o, or nil if it doesn't exist.
+func (c *Client) Get(o *object.K8sObject) (*object.K8sObject, error) {
+	ri, err := c.resource(o.GroupVersionKind(), o.Namespace)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	u, err := ri.Get(o.Name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	u.SetGroupVersionKind(o.GroupVersionKind())
+	return object.NewK8sObject(u, nil, nil), nil
+}
+
+// List returns the objects of the kinds matching the label selector in all the namespaces. The kinds which are not
+// served by the cluster are skipped.
+func (c *Client) List(gvks []schema.GroupVersionKind, selector string) (object.K8sObjects, error) {
+	var objs object.K8sObjects
+	for _, gvk := range gvks {
+		mapping, err := c.mapping(gvk)
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, err
+		}
+		list, err := c.dynamic.Resource(mapping.Resource).List(metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			if errors.IsNotFound(err) || errors.IsMethodNotSupported(err) {
+				continue
+			}
+			return nil, fmt.Errorf("could not list %s: %v", mapping.Resource, err)
+		}
+		for i := range list.Items {
+			u := list.Items[i]
+			u.SetGroupVersionKind(gvk)
+			objs = append(objs, object.NewK8sObject(&u, nil, nil))
+		}
+	}
+	return objs, nil
+}
+
+// Prune deletes the objects of the kinds matching the label selector which are not in keep. It returns the deleted
+// objects.
+func (c *Client) Prune(gvks []schema.GroupVersionKind, selector string, keep object.K8sObjects) (object.K8sObjects, error) {
+	current, err := c.List(gvks, selector)
+	if err != nil {
+		return nil, err
+	}
+	kept := keep.ToMap()
+	var stale object.K8sObjects
+	for _, o := range current {
+		if _, ok := kept[o.Hash()]; !ok {
+			stale = append(stale, o)
+		}
+	}
+	return c.Delete(stale)
+}
+
+// resource returns the client of the resource of the kind, in the namespace if the kind is namespaced.
+func (c *Client) resource(gvk schema.GroupVersionKind, namespace string) (dynamic.ResourceInterface, error) {
+	mapping, err := c.mapping(gvk)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return c.dynamic.Resource(mapping.Resource), nil
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	return c.dynamic.Resource(mapping.Resource).Namespace(namespace), nil
+}
+
+// mapping returns the resource of the kind. Kinds created after the discovery, e.g. by the CRDs of the base
+// component, are found by discovering the resources of the cluster again.
+func (c *Client) mapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		if r, ok := c.mapper.(resettable); ok {
+			r.Reset()
+			mapping, err = c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		}
+	}
+	return mapping, err
+}
@@ -0,0 +1,260 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeapply
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"istio.io/istio/operator/pkg/object"
+)
+
+var (
+	configMapGVK   = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	clusterRoleGVK = schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}
+	operatorGVK    = schema.GroupVersionKind{Group: "install.istio.io", Version: "v1alpha1", Kind: "IstioOperator"}
+)
+
+func newObject(gvk schema.GroupVersionKind, namespace, name string, labels map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	u.SetLabels(labels)
+	return u
+}
+
+func newTestClient(objs ...runtime.Object) (*Client, *fake.FakeDynamicClient) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configMapGVK, meta.RESTScopeNamespace)
+	mapper.Add(clusterRoleGVK, meta.RESTScopeRoot)
+
+	d := fake.NewSimpleDynamicClient(runtime.NewScheme(), objs...)
+	// The fake client doesn't support server-side apply, which creates or updates the object
+	d.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		if patch.GetName() == "forbidden" {
+			return true, nil, errors.New("forbidden")
+		}
+		u := &unstructured.Unstructured{}
+		if err := u.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		return true, u, nil
+	})
+	return NewForClients(d, mapper), d
+}
+
+func TestApply(t *testing.T) {
+	client, d := newTestClient()
+
+	objs := object.K8sObjects{
+		object.NewK8sObject(newObject(configMapGVK, "", "defaulted", nil), nil, nil),
+		object.NewK8sObject(newObject(configMapGVK, "istio-system", "forbidden", nil), nil, nil),
+		object.NewK8sObject(newObject(schema.GroupVersionKind{Group: "unknown", Version: "v1", Kind: "Unknown"}, "", "u", nil), nil, nil),
+		object.NewK8sObject(newObject(clusterRoleGVK, "", "istio-reader", nil), nil, nil),
+	}
+	applied, err := client.Apply(objs)
+
+	if got, want := len(applied), 2; got != want {
+		t.Errorf("Apply() applied %d objects, want %d", got, want)
+	}
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("Apply() returned %v, want Errors", err)
+	}
+	if _, ok := errs[objs[1].Hash()]; !ok || len(errs) != 2 {
+		t.Errorf("Apply() returned errors %v, want errors for %s and %s", errs, objs[1].Hash(), objs[2].Hash())
+	}
+
+	var patches []k8stesting.PatchAction
+	for _, a := range d.Actions() {
+		if p, ok := a.(k8stesting.PatchAction); ok {
+			patches = append(patches, p)
+		}
+	}
+	if len(patches) != 3 {
+		t.Fatalf("Apply() sent %d patches, want 3", len(patches))
+	}
+	if ns := patches[0].GetNamespace(); ns != metav1.NamespaceDefault {
+		t.Errorf("Apply() applied a namespaced object without namespace to %q, want %q", ns, metav1.NamespaceDefault)
+	}
+	if ns := patches[2].GetNamespace(); ns != "" {
+		t.Errorf("Apply() applied a cluster scoped object to namespace %q", ns)
+	}
+}
+
+func TestApplyClientSide(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configMapGVK, meta.RESTScopeNamespace)
+	mapper.Add(operatorGVK, meta.RESTScopeNamespace)
+
+	lastApplied := `{"apiVersion":"v1","data":{"a":"1","b":"2"},"kind":"ConfigMap",` +
+		`"metadata":{"name":"current","namespace":"istio-system"}}`
+	current := newObject(configMapGVK, "istio-system", "current", nil)
+	current.SetAnnotations(map[string]string{corev1.LastAppliedConfigAnnotation: lastApplied})
+	// A field set by another client is kept.
+	_ = unstructured.SetNestedStringMap(current.Object, map[string]string{"a": "1", "b": "2", "other": "3"}, "data")
+	operator := newObject(operatorGVK, "istio-system", "installed", nil)
+	_ = unstructured.SetNestedField(operator.Object, "default", "spec", "profile")
+
+	d := fake.NewSimpleDynamicClient(runtime.NewScheme(), current, operator)
+	// Clusters before Kubernetes 1.16 don't support server-side apply.
+	d.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		return true, nil, kerrors.NewGenericServerResponse(415, "patch", schema.GroupResource{}, patch.GetName(), "", 0, false)
+	})
+	// The fake client can't apply strategic merge patches to unstructured objects, they are checked below.
+	d.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return action.(k8stesting.PatchAction).GetPatchType() == types.StrategicMergePatchType, nil, nil
+	})
+	client := NewForClients(d, mapper)
+
+	updated := newObject(configMapGVK, "istio-system", "current", nil)
+	_ = unstructured.SetNestedStringMap(updated.Object, map[string]string{"a": "1", "c": "4"}, "data")
+	updatedOperator := newObject(operatorGVK, "istio-system", "installed", nil)
+	_ = unstructured.SetNestedField(updatedOperator.Object, "demo", "spec", "profile")
+	objs := object.K8sObjects{
+		object.NewK8sObject(newObject(configMapGVK, "istio-system", "created", nil), nil, nil),
+		object.NewK8sObject(updated, nil, nil),
+		object.NewK8sObject(updatedOperator, nil, nil),
+	}
+	applied, err := client.Apply(objs)
+	if err != nil || len(applied) != 3 {
+		t.Fatalf("Apply() = %v, %v, want all the objects applied", applied, err)
+	}
+
+	applyPatches := 0
+	var strategicPatches []k8stesting.PatchAction
+	for _, a := range d.Actions() {
+		if p, ok := a.(k8stesting.PatchAction); ok {
+			switch p.GetPatchType() {
+			case types.ApplyPatchType:
+				applyPatches++
+			case types.StrategicMergePatchType:
+				strategicPatches = append(strategicPatches, p)
+			}
+		}
+	}
+	if applyPatches != 1 {
+		t.Errorf("Apply() sent %d server-side apply patches, want only the first one", applyPatches)
+	}
+	if len(strategicPatches) != 1 || strategicPatches[0].GetName() != "current" {
+		t.Fatalf("Apply() sent strategic merge patches %v, want one for the current config map", strategicPatches)
+	}
+	live, err := current.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err := strategicpatch.StrategicMergePatch(live, strategicPatches[0].GetPatch(), corev1.ConfigMap{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &unstructured.Unstructured{}
+	if err := got.UnmarshalJSON(patched); err != nil {
+		t.Fatal(err)
+	}
+	data, _, _ := unstructured.NestedStringMap(got.Object, "data")
+	if want := map[string]string{"a": "1", "c": "4", "other": "3"}; !reflect.DeepEqual(data, want) {
+		t.Errorf("Apply() updated the config map data to %v, want %v", data, want)
+	}
+
+	cms := d.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).Namespace("istio-system")
+	created, err := cms.Get("created", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Apply() didn't create the config map: %v", err)
+	}
+	if _, ok := created.GetAnnotations()[corev1.LastAppliedConfigAnnotation]; !ok {
+		t.Errorf("Apply() created %v without the last applied configuration", created)
+	}
+
+	ops := d.Resource(schema.GroupVersionResource{Group: "install.istio.io", Version: "v1alpha1", Resource: "istiooperators"})
+	gotOperator, err := ops.Namespace("istio-system").Get("installed", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile, _, _ := unstructured.NestedString(gotOperator.Object, "spec", "profile"); profile != "demo" {
+		t.Errorf("Apply() updated the profile to %q, want demo", profile)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	component := map[string]string{"operator.istio.io/component": "Pilot"}
+	client, d := newTestClient(
+		newObject(configMapGVK, "istio-system", "current", component),
+		newObject(configMapGVK, "istio-system", "stale", component),
+		newObject(configMapGVK, "istio-system", "other", map[string]string{"operator.istio.io/component": "Galley"}),
+	)
+
+	keep := object.K8sObjects{object.NewK8sObject(newObject(configMapGVK, "istio-system", "current", component), nil, nil)}
+	pruned, err := client.Prune([]schema.GroupVersionKind{configMapGVK, clusterRoleGVK}, "operator.istio.io/component=Pilot", keep)
+	if err != nil {
+		t.Fatalf("Prune() returned %v", err)
+	}
+	if len(pruned) != 1 || pruned[0].Name != "stale" {
+		t.Fatalf("Prune() deleted %v, want only the stale config map", pruned)
+	}
+
+	var deleted []string
+	for _, a := range d.Actions() {
+		if del, ok := a.(k8stesting.DeleteAction); ok {
+			deleted = append(deleted, del.GetNamespace()+"/"+del.GetName())
+		}
+	}
+	if len(deleted) != 1 || deleted[0] != "istio-system/stale" {
+		t.Errorf("Prune() deleted %v, want istio-system/stale", deleted)
+	}
+
+	// Deleting objects which don't exist anymore is not an error
+	if _, err := client.Delete(pruned); err != nil {
+		t.Errorf("Delete() returned %v for a deleted object", err)
+	}
+}
+
+func TestGet(t *testing.T) {
+	client, _ := newTestClient(newObject(configMapGVK, "istio-system", "istio", nil))
+
+	got, err := client.Get(object.NewK8sObject(newObject(configMapGVK, "istio-system", "istio", nil), nil, nil))
+	if err != nil || got == nil || got.Name != "istio" || got.Kind != "ConfigMap" {
+		t.Errorf("Get() = %v, %v, want the istio config map", got, err)
+	}
+	for _, o := range []*unstructured.Unstructured{
+		newObject(configMapGVK, "istio-system", "missing", nil),
+		newObject(schema.GroupVersionKind{Group: "unknown", Version: "v1", Kind: "Unknown"}, "", "u", nil),
+	} {
+		if got, err := client.Get(object.NewK8sObject(o, nil, nil)); err != nil || got != nil {
+			t.Errorf("Get() = %v, %v for %s, want nil", got, err, o.GetName())
+		}
+	}
+}
@@ -57,6 +57,16 @@ type Options struct {
 	Prune *bool
 	// Maximum amount of time to wait for resources to be ready after install when Wait=true.
 	WaitTimeout time.Duration
+	// ComponentWaitTimeouts overrides WaitTimeout for the components it contains, keyed by component name.
+	ComponentWaitTimeouts map[string]time.Duration
+	// Rollback re-applies the manifests of the previous successful install when a component fails to apply or to
+	// become ready.
+	Rollback bool
+	// HistoryNamespace is the namespace storing the manifests of the successful installs, used by Rollback.
+	HistoryNamespace string
+	// Revision is the revision of the control plane the manifests belong to, empty for the control plane installed
+	// without a revision. Pruning and the manifest history are scoped to the revision.
+	Revision string
 
 	// stdin - cmd stdin input as string
 	Stdin string
@@ -0,0 +1,63 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"istio.io/istio/operator/pkg/drift"
+	"istio.io/istio/operator/pkg/kubectlcmd"
+	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/object"
+	"istio.io/pkg/log"
+)
+
+// DetectDrift returns the objects of the rendered manifests which differ from the live objects in the cluster,
+// ignoring the fields matching ignorePaths.
+func DetectDrift(manifests name.ManifestMap, opts kubectlcmd.Options, ignorePaths []string) ([]*drift.Drift, error) {
+	if err := InitK8SRestClient(opts.Kubeconfig, opts.Context); err != nil {
+		return nil, err
+	}
+	client, err := newKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	return drift.Detect(manifests, client.Get, ignorePaths)
+}
+
+// CorrectDrift re-applies the desired objects of the drifts to the cluster, labeled as managed by the operator
+// version. It returns the applied objects, and the errors of the objects which could not be applied.
+func CorrectDrift(drifts []*drift.Drift, version string, opts kubectlcmd.Options) (object.K8sObjects, error) {
+	if len(drifts) == 0 {
+		return nil, nil
+	}
+	var objects object.K8sObjects
+	for _, d := range drifts {
+		o := d.Object
+		addOperatorLabels(object.K8sObjects{o}, d.Component, version)
+		objects = append(objects, o)
+	}
+	objects.Sort(defaultObjectOrder())
+	if opts.DryRun {
+		log.Infof("dry run mode: would be applying %d drifted objects", len(objects))
+		return nil, nil
+	}
+	if err := InitK8SRestClient(opts.Kubeconfig, opts.Context); err != nil {
+		return nil, err
+	}
+	client, err := newKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.Apply(objects)
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/operator/pkg/helm"
+	"istio.io/istio/operator/pkg/name"
+)
+
+const (
+	// defaultHistoryNamespace is the namespace storing the manifests of the successful installs if unset.
+	defaultHistoryNamespace = "istio-system"
+	// maxManifestRevisions is the number of successful installs whose manifests are kept.
+	maxManifestRevisions = 5
+)
+
+var (
+	// manifestRevisionLabelStr labels the config maps holding the manifests of a successful install with its revision.
+	manifestRevisionLabelStr = name.OperatorAPINamespace + "/manifest-revision"
+)
+
+// ManifestRevision holds the manifests of a successful install.
+type ManifestRevision struct {
+	// Revision is the number of the install, increasing with each successful install.
+	Revision int
+	// Version is the version of the operator which installed the manifests.
+	Version string
+	// Manifests are the applied manifests of each component.
+	Manifests name.ManifestMap
+}
+
+func manifestRevisionName(revision int, rev string) string {
+	if rev == "" {
+		return fmt.Sprintf("istio-manifests-%d", revision)
+	}
+	return fmt.Sprintf("istio-manifests-%s-%d", rev, revision)
+}
+
+// listManifestRevisions returns the config maps of the manifest revisions of the control plane revision rev, the
+// latest first.
+func listManifestRevisions(cs kubernetes.Interface, namespace, rev string) ([]v1.ConfigMap, error) {
+	cms, err := cs.CoreV1().ConfigMaps(namespace).List(metav1.ListOptions{
+		LabelSelector: manifestRevisionLabelStr + "," + controlPlaneRevisionSelector(rev),
+	})
+	if err != nil {
+		return nil, err
+	}
+	revisions := cms.Items
+	sort.Slice(revisions, func(i, j int) bool {
+		ri, _ := strconv.Atoi(revisions[i].Labels[manifestRevisionLabelStr])
+		rj, _ := strconv.Atoi(revisions[j].Labels[manifestRevisionLabelStr])
+		return ri > rj
+	})
+	return revisions, nil
+}
+
+// LatestManifestRevision returns the manifests of the latest successful install of the control plane revision rev,
+// empty for the control plane installed without a revision, or nil if there is none.
+func LatestManifestRevision(cs kubernetes.Interface, namespace, rev string) (*ManifestRevision, error) {
+	revisions, err := listManifestRevisions(cs, namespace, rev)
+	if err != nil || len(revisions) == 0 {
+		return nil, err
+	}
+	cm := revisions[0]
+	revision, err := strconv.Atoi(cm.Labels[manifestRevisionLabelStr])
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest revision %s: %v", cm.Name, err)
+	}
+	mr := &ManifestRevision{
+		Revision:  revision,
+		Version:   cm.Labels[istioVersionLabelStr],
+		Manifests: name.ManifestMap{},
+	}
+	for c, data := range cm.BinaryData {
+		m, err := gunzip(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest of %s in revision %s: %v", c, cm.Name, err)
+		}
+		mr.Manifests[name.ComponentName(c)] = []string{m}
+	}
+	return mr, nil
+}
+
+// saveManifestRevision stores the manifests of a successful install of the control plane revision rev as a new
+// revision, and deletes the revisions older than the last maxManifestRevisions ones. It returns the new revision.
+func saveManifestRevision(cs kubernetes.Interface, namespace, rev string, manifests name.ManifestMap, version string) (int, error) {
+	revisions, err := listManifestRevisions(cs, namespace, rev)
+	if err != nil {
+		return 0, err
+	}
+	revision := 1
+	if len(revisions) > 0 {
+		latest, _ := strconv.Atoi(revisions[0].Labels[manifestRevisionLabelStr])
+		revision = latest + 1
+	}
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      manifestRevisionName(revision, rev),
+			Namespace: namespace,
+			Labels: map[string]string{
+				manifestRevisionLabelStr: strconv.Itoa(revision),
+				istioVersionLabelStr:     version,
+				operatorLabelStr:         operatorReconcileStr,
+			},
+		},
+		BinaryData: map[string][]byte{},
+	}
+	if rev != "" {
+		cm.Labels[istioRevisionLabelStr] = rev
+	}
+	for c, m := range manifests {
+		// The manifests are compressed to stay far below the size limit of config maps, with the CRDs of the base component.
+		data, err := gzipString(strings.Join(m, helm.YAMLSeparator))
+		if err != nil {
+			return 0, err
+		}
+		cm.BinaryData[string(c)] = data
+	}
+	if _, err := cs.CoreV1().ConfigMaps(namespace).Create(cm); err != nil {
+		return 0, err
+	}
+
+	for i := maxManifestRevisions - 1; i < len(revisions); i++ {
+		err := cs.CoreV1().ConfigMaps(namespace).Delete(revisions[i].Name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return revision, err
+		}
+	}
+	return revision, nil
+}
+
+func gzipString(s string) ([]byte, error) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write([]byte(s)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func gunzip(data []byte) (string, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	out, err := ioutil.ReadAll(r)
+	return string(out), err
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/operator/pkg/name"
+)
+
+func TestManifestRevisions(t *testing.T) {
+	cs := fake.NewSimpleClientset()
+
+	latest, err := LatestManifestRevision(cs, "istio-system", "")
+	if err != nil || latest != nil {
+		t.Fatalf("LatestManifestRevision() = %v, %v without revisions, want nil", latest, err)
+	}
+
+	for i := 1; i <= maxManifestRevisions+2; i++ {
+		manifests := name.ManifestMap{
+			name.IstioBaseComponentName: {"kind: Namespace", "kind: CustomResourceDefinition"},
+			name.PilotComponentName:     {"kind: Deployment"},
+		}
+		revision, err := saveManifestRevision(cs, "istio-system", "", manifests, "1.5.0")
+		if err != nil {
+			t.Fatalf("saveManifestRevision() returned %v", err)
+		}
+		if revision != i {
+			t.Fatalf("saveManifestRevision() returned revision %d, want %d", revision, i)
+		}
+	}
+
+	latest, err = LatestManifestRevision(cs, "istio-system", "")
+	if err != nil {
+		t.Fatalf("LatestManifestRevision() returned %v", err)
+	}
+	want := &ManifestRevision{
+		Revision: maxManifestRevisions + 2,
+		Version:  "1.5.0",
+		Manifests: name.ManifestMap{
+			name.IstioBaseComponentName: {"kind: Namespace\n---\nkind: CustomResourceDefinition"},
+			name.PilotComponentName:     {"kind: Deployment"},
+		},
+	}
+	if !reflect.DeepEqual(latest, want) {
+		t.Errorf("LatestManifestRevision() = %+v, want %+v", latest, want)
+	}
+
+	// The control plane revisions have their own history.
+	canary := name.ManifestMap{name.PilotComponentName: {"kind: Deployment"}}
+	if revision, err := saveManifestRevision(cs, "istio-system", "canary", canary, "1.6.0"); err != nil || revision != 1 {
+		t.Fatalf("saveManifestRevision() = %d, %v for the canary revision, want 1", revision, err)
+	}
+	latest, err = LatestManifestRevision(cs, "istio-system", "canary")
+	if err != nil || latest == nil || latest.Revision != 1 || latest.Version != "1.6.0" {
+		t.Errorf("LatestManifestRevision() = %+v, %v for the canary revision, want revision 1", latest, err)
+	}
+	latest, err = LatestManifestRevision(cs, "istio-system", "")
+	if err != nil || latest == nil || latest.Revision != maxManifestRevisions+2 {
+		t.Errorf("LatestManifestRevision() = %+v, %v after a canary install, want revision %d", latest, err, maxManifestRevisions+2)
+	}
+
+	cms, _ := cs.CoreV1().ConfigMaps("istio-system").List(metav1.ListOptions{})
+	if len(cms.Items) != maxManifestRevisions+1 {
+		t.Errorf("got %d manifest revisions, want the last %d and the canary one", len(cms.Items), maxManifestRevisions)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time" // For kubeclient GCP auth
@@ -47,9 +48,11 @@ import (
 
 	"istio.io/api/operator/v1alpha1"
 	"istio.io/istio/operator/pkg/helm"
+	"istio.io/istio/operator/pkg/kubeapply"
 	"istio.io/istio/operator/pkg/kubectlcmd"
 	"istio.io/istio/operator/pkg/name"
 	"istio.io/istio/operator/pkg/object"
+	"istio.io/istio/operator/pkg/revision"
 	"istio.io/istio/operator/pkg/util"
 	pkgversion "istio.io/istio/operator/pkg/version"
 	"istio.io/pkg/log"
@@ -72,6 +75,8 @@ var (
 	istioComponentLabelStr = name.OperatorAPINamespace + "/component"
 	// istioVersionLabelStr indicates the Istio version of the installation.
 	istioVersionLabelStr = name.OperatorAPINamespace + "/version"
+	// istioRevisionLabelStr indicates which revision of the control plane a resource belongs to.
+	istioRevisionLabelStr = revision.Label
 )
 
 // ComponentApplyOutput is used to capture errors and stdout/stderr outputs for a command, per component.
@@ -114,9 +119,35 @@ var (
 		},
 	}
 
-	installTree      = make(componentTree)
-	dependencyWaitCh = make(map[name.ComponentName]chan struct{})
-	kubectl          = kubectlcmd.New()
+	// pruneKinds are the kinds of the objects pruned when they are not in the manifest of their component anymore.
+	pruneKinds = []schema.GroupVersionKind{
+		{Group: "", Version: "v1", Kind: "ConfigMap"},
+		{Group: "", Version: "v1", Kind: "Endpoints"},
+		{Group: "", Version: "v1", Kind: "Namespace"},
+		{Group: "", Version: "v1", Kind: "PersistentVolumeClaim"},
+		{Group: "", Version: "v1", Kind: "Pod"},
+		{Group: "", Version: "v1", Kind: "ReplicationController"},
+		{Group: "", Version: "v1", Kind: "Secret"},
+		{Group: "", Version: "v1", Kind: "Service"},
+		{Group: "", Version: "v1", Kind: "ServiceAccount"},
+		{Group: "admissionregistration.k8s.io", Version: "v1beta1", Kind: "MutatingWebhookConfiguration"},
+		{Group: "admissionregistration.k8s.io", Version: "v1beta1", Kind: "ValidatingWebhookConfiguration"},
+		{Group: "apps", Version: "v1", Kind: "DaemonSet"},
+		{Group: "apps", Version: "v1", Kind: "Deployment"},
+		{Group: "apps", Version: "v1", Kind: "StatefulSet"},
+		{Group: "autoscaling", Version: "v2beta1", Kind: "HorizontalPodAutoscaler"},
+		{Group: "batch", Version: "v1", Kind: "Job"},
+		{Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"},
+		{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRoleBinding"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"},
+	}
+
+	installTree = make(componentTree)
+	// newKubeClient creates the client applying the manifests to the cluster.
+	newKubeClient = defaultKubeClient
 
 	k8sRESTConfig     *rest.Config
 	currentKubeconfig string
@@ -125,12 +156,6 @@ var (
 
 func init() {
 	buildInstallTree()
-	for _, parent := range componentDependencies {
-		for _, child := range parent {
-			dependencyWaitCh[child] = make(chan struct{}, 1)
-		}
-	}
-
 }
 
 // ParseK8SYAMLToIstioOperatorSpec parses a IstioOperator CustomResource YAML string and unmarshals in into
@@ -195,7 +220,10 @@ func renderRecursive(manifests name.ManifestMap, installTree componentTree, outp
 	return nil
 }
 
-// ApplyAll applies all given manifests using kubectl client.
+// ApplyAll applies all given manifests to the cluster, in the order of the component dependency tree. The components
+// depending on a component which fails to apply, or to become ready when opts.Wait is set, are not applied. When
+// all the components are applied, their manifests are stored in the cluster as a new revision. Otherwise, if
+// opts.Rollback is set, the manifests of the previous revision are applied again.
 func ApplyAll(manifests name.ManifestMap, version pkgversion.Version, opts *kubectlcmd.Options) (CompositeOutput, error) {
 	log.Infof("Preparing manifests for these components:")
 	for c := range manifests {
@@ -205,45 +233,140 @@ func ApplyAll(manifests name.ManifestMap, version pkgversion.Version, opts *kube
 	if err := InitK8SRestClient(opts.Kubeconfig, opts.Context); err != nil {
 		return nil, err
 	}
-	return applyRecursive(manifests, version, opts)
+	out := applyRecursive(manifests, version.String(), opts)
+	if opts.DryRun {
+		return out, nil
+	}
+
+	cs, err := kubernetes.NewForConfig(k8sRESTConfig)
+	if err != nil {
+		return out, fmt.Errorf("k8s client error: %s", err)
+	}
+	historyNamespace := opts.HistoryNamespace
+	if historyNamespace == "" {
+		historyNamespace = defaultHistoryNamespace
+	}
+
+	failed := out.failedComponents()
+	if len(failed) == 0 {
+		revision, err := saveManifestRevision(cs, historyNamespace, opts.Revision, manifests, version.String())
+		if err != nil {
+			// The install succeeded, only a later rollback to it is not possible
+			logAndPrint("Could not store the applied manifests for rollbacks: %v", err)
+			return out, nil
+		}
+		log.Infof("Stored the applied manifests as revision %d.", revision)
+		return out, nil
+	}
+	if !opts.Rollback {
+		return out, nil
+	}
+
+	previous, err := LatestManifestRevision(cs, historyNamespace, opts.Revision)
+	if err != nil {
+		return out, fmt.Errorf("components %v failed and the previous manifests could not be read for the rollback: %v", failed, err)
+	}
+	if previous == nil {
+		return out, fmt.Errorf("components %v failed and there are no previous manifests to roll back to", failed)
+	}
+	logAndPrint("Components %v failed, rolling back to the manifests of revision %d (version %s)...",
+		failed, previous.Revision, previous.Version)
+	rollbackOpts := *opts
+	rollbackOpts.Rollback = false
+	rollbackOut := applyRecursive(previous.Manifests, previous.Version, &rollbackOpts)
+	if rollbackFailed := rollbackOut.failedComponents(); len(rollbackFailed) > 0 {
+		for _, c := range rollbackFailed {
+			logAndPrint("✘ Rollback of component %s failed: %v", c, rollbackOut[c].Err)
+		}
+		return out, fmt.Errorf("components %v failed and the rollback to revision %d failed for components %v",
+			failed, previous.Revision, rollbackFailed)
+	}
+	return out, fmt.Errorf("components %v failed, rolled back to revision %d (version %s)", failed, previous.Revision, previous.Version)
 }
 
-func applyRecursive(manifests name.ManifestMap, version pkgversion.Version, opts *kubectlcmd.Options) (CompositeOutput, error) {
+// failedComponents returns the sorted names of the components which failed.
+func (o CompositeOutput) failedComponents() []name.ComponentName {
+	var failed []name.ComponentName
+	for c, out := range o {
+		if out.Err != nil {
+			failed = append(failed, c)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i] < failed[j] })
+	return failed
+}
+
+// applyRecursive applies the components once the components they depend on are applied, and ready if opts.Wait is
+// set. The components depending on a component which failed are skipped.
+func applyRecursive(manifests name.ManifestMap, version string, opts *kubectlcmd.Options) CompositeOutput {
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	out := CompositeOutput{}
-	allAppliedObjects := object.K8sObjects{}
+	// The channels of the components waiting for a prerequisite, receiving whether the prerequisite succeeded.
+	waitCh := make(map[name.ComponentName]chan bool)
+	for parent, children := range componentDependencies {
+		if _, ok := manifests[parent]; !ok {
+			continue
+		}
+		for _, child := range children {
+			waitCh[child] = make(chan bool, 1)
+		}
+	}
 	for c, m := range manifests {
 		c := c
 		m := m
 		wg.Add(1)
 		go func() {
-			if s := dependencyWaitCh[c]; s != nil {
+			defer wg.Done()
+			var applyOut *ComponentApplyOutput
+			prerequisiteSucceeded := true
+			if s := waitCh[c]; s != nil {
 				log.Infof("%s is waiting on a prerequisite...", c)
-				<-s
+				prerequisiteSucceeded = <-s
+			}
+			if prerequisiteSucceeded {
 				log.Infof("Prerequisite for %s has completed, proceeding with install.", c)
+				var appliedObjects object.K8sObjects
+				applyOut, appliedObjects = ApplyManifest(c, strings.Join(m, helm.YAMLSeparator), version, *opts)
+				if applyOut.Err == nil && opts.Wait {
+					applyOut.Err = waitForComponent(c, appliedObjects, opts)
+				}
+			} else {
+				logAndPrint("✘ Skipping component %s because a prerequisite failed.", c)
+				applyOut = &ComponentApplyOutput{Err: fmt.Errorf("skipped because a prerequisite component failed")}
 			}
-			applyOut, appliedObjects := ApplyManifest(c, strings.Join(m, helm.YAMLSeparator), version.String(), *opts)
 			mu.Lock()
 			out[c] = applyOut
-			allAppliedObjects = append(allAppliedObjects, appliedObjects...)
 			mu.Unlock()
 
 			// Signal all the components that depend on us.
 			for _, ch := range componentDependencies[c] {
 				log.Infof("unblocking child %s.", ch)
-				dependencyWaitCh[ch] <- struct{}{}
+				waitCh[ch] <- applyOut.Err == nil
 			}
-			wg.Done()
 		}()
 	}
 	wg.Wait()
-	if opts.Wait {
-		return out, waitForResources(allAppliedObjects, opts)
+	return out
+}
+
+// waitForComponent waits for the applied objects of a component to be ready, for the timeout of the component.
+func waitForComponent(c name.ComponentName, objects object.K8sObjects, opts *kubectlcmd.Options) error {
+	waitOpts := *opts
+	if timeout, ok := opts.ComponentWaitTimeouts[string(c)]; ok {
+		waitOpts.WaitTimeout = timeout
 	}
-	return out, nil
+	if err := waitForResources(objects, &waitOpts); err != nil {
+		logAndPrint("✘ Component %s is not ready.", c)
+		return err
+	}
+	logAndPrint("✔ Component %s is ready.", c)
+	return nil
 }
 
+// ApplyManifest applies the manifest of a component to the cluster with server-side apply, and prunes the objects of
+// the component which are not in the manifest anymore. The objects which could not be applied are reported in the
+// Stderr of the output, one per line.
 func ApplyManifest(componentName name.ComponentName, manifestStr, version string,
 	opts kubectlcmd.Options) (*ComponentApplyOutput, object.K8sObjects) {
 	stdout, stderr := "", ""
@@ -252,55 +375,40 @@ func ApplyManifest(componentName name.ComponentName, manifestStr, version string
 	if err != nil {
 		return buildComponentApplyOutput(stdout, stderr, appliedObjects, err), appliedObjects
 	}
-	componentLabel := fmt.Sprintf("%s=%s", istioComponentLabelStr, componentName)
+	componentLabel := fmt.Sprintf("%s=%s,%s", istioComponentLabelStr, componentName, controlPlaneRevisionSelector(opts.Revision))
 
-	// TODO: remove this when `kubectl --prune` supports empty objects
-	//  (https://github.com/kubernetes/kubernetes/issues/40635)
-	// Delete all resources for a disabled component
-	if len(objects) == 0 {
-		getOpts := opts
-		getOpts.Output = "yaml"
-		getOpts.ExtraArgs = []string{"--all-namespaces", "--selector", componentLabel}
-		stdoutGet, stderrGet, err := kubectl.GetAll(&getOpts)
-		if err != nil {
-			stdout += "\n" + stdoutGet
-			stderr += "\n" + stderrGet
+	var client *kubeapply.Client
+	if !opts.DryRun {
+		if client, err = newKubeClient(); err != nil {
 			return buildComponentApplyOutput(stdout, stderr, appliedObjects, err), appliedObjects
 		}
-		items, err := GetKubectlGetItems(stdoutGet)
-		if err != nil {
-			return buildComponentApplyOutput(stdout, stderr, appliedObjects, err), appliedObjects
+	}
+
+	// Delete all resources for a disabled component
+	if len(objects) == 0 {
+		if opts.DryRun {
+			log.Infof("dry run mode: would be pruning the objects of disabled component %s", componentName)
+			return buildComponentApplyOutput(stdout, stderr, appliedObjects, nil), appliedObjects
 		}
-		if len(items) == 0 {
+		existing, err := client.List(pruneKinds, componentLabel)
+		if err != nil || len(existing) == 0 {
 			return buildComponentApplyOutput(stdout, stderr, appliedObjects, err), appliedObjects
 		}
 
 		logAndPrint("- Pruning objects for disabled component %s...", componentName)
-		delObjects, err := object.ParseK8sObjectsFromYAMLManifest(stdoutGet)
-		if err != nil {
-			return buildComponentApplyOutput(stdout, stderr, appliedObjects, err), appliedObjects
-		}
-		delOpts := opts
-		delOpts.ExtraArgs = []string{"--selector", componentLabel}
-		stdoutDel, stderrDel, err := kubectl.Delete(stdoutGet, &delOpts)
-		stdout += "\n" + stdoutDel
-		stderr += "\n" + stderrDel
+		deleted, err := client.Delete(existing)
+		stdout, stderr = appendResults(stdout, stderr, deleted, "deleted", err)
+		appliedObjects = append(appliedObjects, deleted...)
 		if err != nil {
 			logAndPrint("✘ Finished pruning objects for disabled component %s.", componentName)
 			return buildComponentApplyOutput(stdout, stderr, appliedObjects, err), appliedObjects
 		}
-		appliedObjects = append(appliedObjects, delObjects...)
 		logAndPrint("✔ Finished pruning objects for disabled component %s.", componentName)
 		return buildComponentApplyOutput(stdout, stderr, appliedObjects, err), appliedObjects
 	}
 
-	for _, o := range objects {
-		o.AddLabels(map[string]string{istioComponentLabelStr: string(componentName)})
-		o.AddLabels(map[string]string{operatorLabelStr: operatorReconcileStr})
-		o.AddLabels(map[string]string{istioVersionLabelStr: version})
-	}
+	addOperatorLabels(objects, componentName, version)
 
-	opts.ExtraArgs = []string{"--force", "--selector", componentLabel}
 	// Base components include namespaces and CRDs, pruning them will remove user configs, which makes it hard to roll back.
 	if componentName != name.IstioBaseComponentName && opts.Prune == nil {
 		opts.Prune = pointer.BoolPtr(true)
@@ -310,7 +418,7 @@ func ApplyManifest(componentName name.ComponentName, manifestStr, version string
 
 	// Apply namespace resources first, then wait.
 	nsObjects := nsKindObjects(objects)
-	stdout, stderr, err = applyObjects(nsObjects, &opts, stdout, stderr)
+	stdout, stderr, err = applyObjects(client, nsObjects, &opts, stdout, stderr)
 	if err != nil {
 		return buildComponentApplyOutput(stdout, stderr, appliedObjects, err), appliedObjects
 	}
@@ -321,7 +429,7 @@ func ApplyManifest(componentName name.ComponentName, manifestStr, version string
 
 	// Apply CRDs, then wait.
 	crdObjects := cRDKindObjects(objects)
-	stdout, stderr, err = applyObjects(crdObjects, &opts, stdout, stderr)
+	stdout, stderr, err = applyObjects(client, crdObjects, &opts, stdout, stderr)
 	if err != nil {
 		return buildComponentApplyOutput(stdout, stderr, appliedObjects, err), appliedObjects
 	}
@@ -332,7 +440,12 @@ func ApplyManifest(componentName name.ComponentName, manifestStr, version string
 
 	// Apply all remaining objects.
 	nonNsCrdObjects := objectsNotInLists(objects, nsObjects, crdObjects)
-	stdout, stderr, err = applyObjects(nonNsCrdObjects, &opts, stdout, stderr)
+	stdout, stderr, err = applyObjects(client, nonNsCrdObjects, &opts, stdout, stderr)
+	if err == nil && opts.Prune != nil && *opts.Prune && !opts.DryRun {
+		var pruned object.K8sObjects
+		pruned, err = client.Prune(pruneKinds, componentLabel, objects)
+		stdout, stderr = appendResults(stdout, stderr, pruned, "pruned", err)
+	}
 	mark := "✔"
 	if err != nil {
 		mark = "✘"
@@ -345,23 +458,82 @@ func ApplyManifest(componentName name.ComponentName, manifestStr, version string
 	return buildComponentApplyOutput(stdout, stderr, appliedObjects, err), appliedObjects
 }
 
-func GetKubectlGetItems(stdoutGet string) ([]interface{}, error) {
-	yamlGet := make(map[string]interface{})
-	err := yaml.Unmarshal([]byte(stdoutGet), &yamlGet)
+// addOperatorLabels labels the objects of a component as managed by the operator version.
+func addOperatorLabels(objects object.K8sObjects, componentName name.ComponentName, version string) {
+	for _, o := range objects {
+		o.AddLabels(map[string]string{istioComponentLabelStr: string(componentName)})
+		o.AddLabels(map[string]string{operatorLabelStr: operatorReconcileStr})
+		o.AddLabels(map[string]string{istioVersionLabelStr: version})
+	}
+}
+
+// controlPlaneRevisionSelector returns the label selector of the resources of the control plane revision rev, empty
+// for the control plane installed without a revision.
+func controlPlaneRevisionSelector(rev string) string {
+	if rev == "" {
+		return "!" + istioRevisionLabelStr
+	}
+	return fmt.Sprintf("%s=%s", istioRevisionLabelStr, rev)
+}
+
+// revisionComponentsSelector returns the label selector of the resources of the components installed once per
+// revision.
+func revisionComponentsSelector() string {
+	components := make([]string, 0, len(revision.Components))
+	for c := range revision.Components {
+		components = append(components, string(c))
+	}
+	sort.Strings(components)
+	return fmt.Sprintf("%s in (%s)", istioComponentLabelStr, strings.Join(components, ","))
+}
+
+// DeleteManifest deletes the objects of the manifest from the cluster. It returns the deleted objects, and the errors
+// of the objects which could not be deleted.
+func DeleteManifest(manifestStr string, opts kubectlcmd.Options) (object.K8sObjects, error) {
+	objects, err := object.ParseK8sObjectsFromYAMLManifest(manifestStr)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		log.Infof("dry run mode: would be deleting %d objects", len(objects))
+		return nil, nil
+	}
+	client, err := newKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.Delete(objects)
+}
+
+// DeleteRevision deletes the resources of the control plane revision rev installed by the operator, including the
+// manifests stored for its rollbacks. For the default revision, only the resources of the components installed once
+// per revision are deleted, the shared ones are kept. It returns the deleted objects, and the errors of the objects
+// which could not be deleted.
+func DeleteRevision(rev string, opts kubectlcmd.Options) (object.K8sObjects, error) {
+	if rev == "" {
+		return nil, fmt.Errorf("a revision is required")
+	}
+	if err := InitK8SRestClient(opts.Kubeconfig, opts.Context); err != nil {
+		return nil, err
+	}
+	client, err := newKubeClient()
 	if err != nil {
 		return nil, err
 	}
-	if yamlGet["kind"] != "List" {
-		return nil, fmt.Errorf("`kubectl get` returned a yaml whose kind is not List")
+	selector := fmt.Sprintf("%s=%s,%s", operatorLabelStr, operatorReconcileStr, controlPlaneRevisionSelector(rev))
+	if rev == revision.Default {
+		selector = fmt.Sprintf("%s=%s,%s,%s", operatorLabelStr, operatorReconcileStr, controlPlaneRevisionSelector(""),
+			revisionComponentsSelector())
 	}
-	if _, ok := yamlGet["items"]; !ok {
-		return nil, fmt.Errorf("`kubectl get` returned a yaml without 'items' in the root")
+	existing, err := client.List(pruneKinds, selector)
+	if err != nil {
+		return nil, err
 	}
-	switch items := yamlGet["items"].(type) {
-	case []interface{}:
-		return items, nil
+	if opts.DryRun {
+		log.Infof("dry run mode: would be deleting %d objects of revision %s", len(existing), rev)
+		return nil, nil
 	}
-	return nil, fmt.Errorf("`kubectl get` returned a yaml incorrecnt type 'items' in the root")
+	return client.Delete(existing)
 }
 
 func DeploymentExists(kubeconfig, context, namespace, name string) (bool, error) {
@@ -381,25 +553,42 @@ func DeploymentExists(kubeconfig, context, namespace, name string) (bool, error)
 	return d != nil, nil
 }
 
-func applyObjects(objs object.K8sObjects, opts *kubectlcmd.Options, stdout, stderr string) (string, string, error) {
+func applyObjects(client *kubeapply.Client, objs object.K8sObjects, opts *kubectlcmd.Options, stdout, stderr string) (string, string, error) {
 	if len(objs) == 0 {
 		return stdout, stderr, nil
 	}
 
 	objs.Sort(defaultObjectOrder())
 
-	mns, err := objs.JSONManifest()
-	if err != nil {
-		return stdout, stderr, err
+	if opts.DryRun {
+		mns, err := objs.YAMLManifest()
+		if err != nil {
+			return stdout, stderr, err
+		}
+		if opts.Verbose {
+			log.Infof("dry run mode: would be applying:\n%s\n", mns)
+		} else {
+			log.Infof("dry run mode: would be applying %d objects, use --verbose to see them", len(objs))
+		}
+		return stdout, stderr, nil
 	}
 
-	stdoutApply, stderrApply, err := kubectl.Apply(mns, opts)
-	stdout += "\n" + stdoutApply
-	stderr += "\n" + stderrApply
-
+	applied, err := client.Apply(objs)
+	stdout, stderr = appendResults(stdout, stderr, applied, "applied", err)
 	return stdout, stderr, err
 }
 
+// appendResults appends a line per object to stdout, and the errors of the objects which failed to stderr.
+func appendResults(stdout, stderr string, objs object.K8sObjects, verb string, err error) (string, string) {
+	for _, o := range objs {
+		stdout += fmt.Sprintf("\n%s/%s %s", strings.ToLower(o.Kind), o.Name, verb)
+	}
+	if err != nil {
+		stderr += "\n" + err.Error()
+	}
+	return stdout, stderr
+}
+
 func buildComponentApplyOutput(stdout string, stderr string, objects object.K8sObjects, err error) *ComponentApplyOutput {
 	manifest, _ := objects.YAMLManifest()
 	return &ComponentApplyOutput{
@@ -746,6 +935,11 @@ func buildInstallTreeString(componentName name.ComponentName, prefix string, sb
 	}
 }
 
+// defaultKubeClient creates the client applying the manifests to the cluster of k8sRESTConfig.
+func defaultKubeClient() (*kubeapply.Client, error) {
+	return kubeapply.New(k8sRESTConfig)
+}
+
 func InitK8SRestClient(kubeconfig, context string) error {
 	var err error
 	if kubeconfig == currentKubeconfig && context == currentContext && k8sRESTConfig != nil {
@@ -0,0 +1,93 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/rest"
+
+	"istio.io/istio/operator/pkg/kubeapply"
+	"istio.io/istio/operator/pkg/kubectlcmd"
+	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/revision"
+)
+
+func TestApplyRecursiveSkipsDependentsOfFailedComponents(t *testing.T) {
+	newKubeClient = func() (*kubeapply.Client, error) {
+		return nil, errors.New("unreachable")
+	}
+	defer func() { newKubeClient = defaultKubeClient }()
+
+	manifests := name.ManifestMap{
+		name.IstioBaseComponentName: {"apiVersion: v1\nkind: Namespace\nmetadata:\n  name: istio-system\n"},
+		name.PilotComponentName:     {"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: istio\n"},
+	}
+	out := applyRecursive(manifests, "1.5.0", &kubectlcmd.Options{})
+
+	if err := out[name.IstioBaseComponentName].Err; err == nil || err.Error() != "unreachable" {
+		t.Errorf("applyRecursive() returned %v for the base component, want unreachable", err)
+	}
+	if err := out[name.PilotComponentName].Err; err == nil || !strings.Contains(err.Error(), "skipped") {
+		t.Errorf("applyRecursive() returned %v for a component depending on a failed component, want it skipped", err)
+	}
+	want := []name.ComponentName{name.IstioBaseComponentName, name.PilotComponentName}
+	if got := out.failedComponents(); !reflect.DeepEqual(got, want) {
+		t.Errorf("failedComponents() = %v, want %v", got, want)
+	}
+}
+
+func TestDeleteDefaultRevision(t *testing.T) {
+	configMapGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	configMap := func(n string, labels map[string]string) runtime.Object {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(configMapGVK)
+		u.SetNamespace("istio-system")
+		u.SetName(n)
+		labels[operatorLabelStr] = operatorReconcileStr
+		u.SetLabels(labels)
+		return u
+	}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configMapGVK, meta.RESTScopeNamespace)
+	d := fake.NewSimpleDynamicClient(runtime.NewScheme(),
+		configMap("istio", map[string]string{istioComponentLabelStr: string(name.PilotComponentName)}),
+		configMap("istio-canary", map[string]string{istioComponentLabelStr: string(name.PilotComponentName), revision.Label: "canary"}),
+		configMap("istio-security", map[string]string{istioComponentLabelStr: string(name.CitadelComponentName)}),
+	)
+	newKubeClient = func() (*kubeapply.Client, error) {
+		return kubeapply.NewForClients(d, mapper), nil
+	}
+	defer func() { newKubeClient = defaultKubeClient }()
+	k8sRESTConfig, currentKubeconfig, currentContext = &rest.Config{}, "", ""
+	defer func() { k8sRESTConfig = nil }()
+
+	deleted, err := DeleteRevision(revision.Default, kubectlcmd.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The shared components and the other revisions are kept.
+	if len(deleted) != 1 || deleted[0].Name != "istio" {
+		t.Errorf("DeleteRevision() deleted %v, want only the config map of the default Pilot", deleted)
+	}
+}
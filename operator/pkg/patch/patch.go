@@ -76,6 +76,52 @@ ADD
   value:
     new_attr: v3
 
+STRATEGIC MERGE AND JSON PATCHES
+
+The reserved paths below patch the whole object, like the patchesStrategicMerge and patchesJson6902 of kustomize.
+They can be mixed with path patches and are applied in order.
+
+1. Strategic merge patch of the object. Kinds unknown to the Kubernetes client, e.g. custom resources, are patched
+with a JSON merge patch.
+
+  path: $patchStrategicMerge
+  value:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: discovery
+            env:
+            - name: PILOT_TRACE_SAMPLING
+              value: "10"
+
+2. JSON 6902 patch of the object.
+
+  path: $patchJson6902
+  value:
+  - op: replace
+    path: /spec/replicas
+    value: 2
+
+NEW RESOURCES
+
+An overlay for an object which is not in the manifest of the component adds it when one of its patches has the
+reserved path $resource, with the object as value. The kind, apiVersion and name of the overlay are used when the
+object doesn't set them, and its namespace defaults to the one of the component. The object is then owned and pruned
+like the other objects of the component. The other patches of the overlay are applied to the new object.
+
+  kind: PodDisruptionBudget
+  name: istio-pilot-strict
+  patches:
+  - path: $resource
+    value:
+      apiVersion: policy/v1beta1
+      spec:
+        minAvailable: 2
+        selector:
+          matchLabels:
+            app: pilot
+
 *NOTES*
 - Due to loss of string quoting during unmarshaling, keys and values should not be string quoted, even if they appear
 that way in the object being patched.
@@ -84,12 +130,20 @@ that way in the object being patched.
 package patch
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
 
+	jsonpatch "github.com/evanphx/json-patch"
+	ghodssyaml "github.com/ghodss/yaml"
 	"github.com/kr/pretty"
 	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
 
 	"istio.io/api/operator/v1alpha1"
 	"istio.io/istio/operator/pkg/object"
@@ -98,6 +152,15 @@ import (
 	"istio.io/pkg/log"
 )
 
+const (
+	// StrategicMergePatchPath is the reserved path of a patch whose value is a strategic merge patch of the object.
+	StrategicMergePatchPath = "$patchStrategicMerge"
+	// JSONPatchPath is the reserved path of a patch whose value is a list of JSON 6902 patch operations.
+	JSONPatchPath = "$patchJson6902"
+	// ResourcePath is the reserved path of a patch whose value is an object added to the manifest.
+	ResourcePath = "$resource"
+)
+
 var (
 	scope = log.RegisterScope("patch", "patch", 0)
 )
@@ -126,7 +189,12 @@ func YAMLManifestPatch(baseYAML string, namespace string, overlays []*v1alpha1.K
 	for _, k := range keys {
 		oo := oom[k]
 		bo := bom[k]
-		if bo == nil {
+		if bo == nil && hasResourcePatch(oo.Patches) {
+			if bo, err = newResource(oo, namespace); err != nil {
+				errs = util.AppendErr(errs, fmt.Errorf("overlay for %s: %s", k, err))
+				continue
+			}
+		} else if bo == nil {
 			os := ""
 			for k2 := range bom {
 				os += k2 + "\n"
@@ -134,8 +202,11 @@ func YAMLManifestPatch(baseYAML string, namespace string, overlays []*v1alpha1.K
 			errs = util.AppendErr(errs, fmt.Errorf("overlay for %s does not match any object in output manifest:\n%s\n\nAvailable objects are:\n%s",
 				k, pretty.Sprint(oo), os))
 			continue
+		} else if hasResourcePatch(oo.Patches) {
+			errs = util.AppendErr(errs, fmt.Errorf("overlay for %s adds an object which is already in the output manifest", k))
+			continue
 		}
-		patched, err := applyPatches(bo, oo)
+		patched, err := applyPatches(bo, oo.Patches)
 		if err != nil {
 			errs = util.AppendErr(errs, fmt.Errorf("patch error: %s", err))
 			continue
@@ -193,6 +264,19 @@ func applyPatches(base *object.K8sObject, patches []*v1alpha1.K8SObjectOverlay_P
 			continue
 		}
 		scope.Debugf("applying path=%s, value=%s\n", p.Path, p.Value)
+		switch p.Path {
+		case ResourcePath:
+			// The object was created from the patch.
+			continue
+		case StrategicMergePatchPath, JSONPatchPath:
+			patched, err := applyObjectPatch(bo, base.GroupVersionKind(), p)
+			if err != nil {
+				errs = util.AppendErr(errs, fmt.Errorf("%s: %s", p.Path, err))
+				continue
+			}
+			bo = patched
+			continue
+		}
 		inc, _, err := tpath.GetPathContext(bo, util.PathFromString(p.Path))
 		if err != nil {
 			errs = util.AppendErr(errs, err)
@@ -207,12 +291,111 @@ func applyPatches(base *object.K8sObject, patches []*v1alpha1.K8SObjectOverlay_P
 	return oy, errs
 }
 
+// applyObjectPatch applies a strategic merge or JSON 6902 patch p to the object bo of kind gvk, and returns the
+// patched object.
+func applyObjectPatch(bo map[interface{}]interface{}, gvk schema.GroupVersionKind, p *v1alpha1.K8SObjectOverlay_PathValue) (
+	map[interface{}]interface{}, error) {
+	by, err := yaml.Marshal(bo)
+	if err != nil {
+		return nil, err
+	}
+	bj, err := ghodssyaml.YAMLToJSON(by)
+	if err != nil {
+		return nil, err
+	}
+	pj, err := json.Marshal(p.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	if p.Path == JSONPatchPath {
+		jp, err := jsonpatch.DecodePatch(pj)
+		if err != nil {
+			return nil, err
+		}
+		if patched, err = jp.Apply(bj); err != nil {
+			return nil, err
+		}
+	} else if versioned, err := scheme.Scheme.New(gvk); err == nil {
+		if patched, err = strategicpatch.StrategicMergePatch(bj, pj, versioned); err != nil {
+			return nil, err
+		}
+	} else {
+		// Kinds without a Go type don't have patch strategies, e.g. custom resources.
+		if patched, err = jsonpatch.MergePatch(bj, pj); err != nil {
+			return nil, err
+		}
+	}
+
+	py, err := ghodssyaml.JSONToYAML(patched)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal(py, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// hasResourcePatch reports whether the patches add a new object.
+func hasResourcePatch(patches []*v1alpha1.K8SObjectOverlay_PathValue) bool {
+	for _, p := range patches {
+		if p.Path == ResourcePath {
+			return true
+		}
+	}
+	return false
+}
+
+// newResource returns the object added by the $resource patch of the overlay o. The kind, apiVersion and name of o
+// are used when the object doesn't set them, and its namespace defaults to namespace.
+func newResource(o *v1alpha1.K8SObjectOverlay, namespace string) (*object.K8sObject, error) {
+	var value interface{}
+	for _, p := range o.Patches {
+		if p.Path == ResourcePath {
+			value = p.Value
+		}
+	}
+	oj, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(oj); err != nil && !runtime.IsMissingKind(err) && !runtime.IsMissingVersion(err) {
+		return nil, err
+	}
+	if u.Object == nil {
+		u.Object = make(map[string]interface{})
+	}
+	if u.GetKind() == "" {
+		u.SetKind(o.Kind)
+	}
+	if u.GetAPIVersion() == "" {
+		u.SetAPIVersion(o.ApiVersion)
+	}
+	if u.GetName() == "" {
+		u.SetName(o.Name)
+	}
+	if u.GetNamespace() == "" {
+		u.SetNamespace(namespace)
+	}
+	if u.GetKind() != o.Kind || u.GetName() != o.Name {
+		return nil, fmt.Errorf("%s %s of the overlay differs from the %s %s of its object", o.Kind, o.Name, u.GetKind(), u.GetName())
+	}
+	if u.GetAPIVersion() == "" {
+		return nil, fmt.Errorf("the apiVersion of the object is missing")
+	}
+	return object.NewK8sObject(u, nil, nil), nil
+}
+
 // objectOverrideMap converts oos, a slice of object overlays, into a map of the same overlays where the key is the
 // object manifest.Hash.
-func objectOverrideMap(oos []*v1alpha1.K8SObjectOverlay, namespace string) map[string][]*v1alpha1.K8SObjectOverlay_PathValue {
-	ret := make(map[string][]*v1alpha1.K8SObjectOverlay_PathValue)
+func objectOverrideMap(oos []*v1alpha1.K8SObjectOverlay, namespace string) map[string]*v1alpha1.K8SObjectOverlay {
+	ret := make(map[string]*v1alpha1.K8SObjectOverlay)
 	for _, o := range oos {
-		ret[object.Hash(o.Kind, namespace, o.Name)] = o.Patches
+		ret[object.Hash(o.Kind, namespace, o.Name)] = o
 	}
 	return ret
 }
@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"istio.io/api/operator/v1alpha1"
+	"istio.io/istio/operator/pkg/object"
 	"istio.io/istio/operator/pkg/util"
 )
 
@@ -466,6 +467,182 @@ spec:
 	}
 }
 
+func TestPatchYAMLManifestObjectPatches(t *testing.T) {
+	base := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istio-pilot
+  namespace: istio-system
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: discovery
+        image: pilot
+        env:
+        - name: PILOT_TRACE_SAMPLING
+          value: "1"
+      - name: istio-proxy
+        image: proxyv2
+`
+
+	tests := []struct {
+		desc     string
+		overlays string
+		want     string
+		wantErr  string
+	}{
+		{
+			desc: "StrategicMergeAndJSONPatch",
+			overlays: `
+overlays:
+- kind: Deployment
+  name: istio-pilot
+  patches:
+  - path: $patchStrategicMerge
+    value:
+      spec:
+        template:
+          spec:
+            containers:
+            - name: discovery
+              env:
+              - name: PILOT_TRACE_SAMPLING
+                value: "10"
+              - name: PILOT_DEBUG
+                value: "true"
+  - path: $patchJson6902
+    value:
+    - op: replace
+      path: /spec/replicas
+      value: 2
+  - path: spec.template.spec.containers.[name:istio-proxy].image
+    value: proxyv2:debug
+`,
+			want: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istio-pilot
+  namespace: istio-system
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: discovery
+        image: pilot
+        env:
+        - name: PILOT_TRACE_SAMPLING
+          value: "10"
+        - name: PILOT_DEBUG
+          value: "true"
+      - name: istio-proxy
+        image: proxyv2:debug
+`,
+		},
+		{
+			desc: "AddResource",
+			overlays: `
+overlays:
+- kind: PodDisruptionBudget
+  name: istio-pilot-strict
+  patches:
+  - path: $resource
+    value:
+      apiVersion: policy/v1beta1
+      spec:
+        minAvailable: 1
+        selector:
+          matchLabels:
+            app: pilot
+  - path: spec.minAvailable
+    value: 2
+`,
+			want: base + `
+---
+apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: istio-pilot-strict
+  namespace: istio-system
+spec:
+  minAvailable: 2
+  selector:
+    matchLabels:
+      app: pilot
+`,
+		},
+		{
+			desc: "AddExistingResource",
+			overlays: `
+overlays:
+- kind: Deployment
+  name: istio-pilot
+  patches:
+  - path: $resource
+    value:
+      apiVersion: apps/v1
+`,
+			wantErr: "overlay for Deployment:istio-system:istio-pilot adds an object which is already in the output manifest",
+		},
+		{
+			desc: "InvalidJSONPatch",
+			overlays: `
+overlays:
+- kind: Deployment
+  name: istio-pilot
+  patches:
+  - path: $patchJson6902
+    value:
+    - op: remove
+      path: /spec/unknown
+`,
+			wantErr: "patch error: $patchJson6902: error in remove for path: '/spec/unknown': Unable to remove nonexistent key: unknown: missing value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			rc := &v1alpha1.KubernetesResourcesSpec{}
+			if err := util.UnmarshalWithJSONPB(tt.overlays, rc); err != nil {
+				t.Fatalf("unmarshalWithJSONPB(%s): got error %s", tt.desc, err)
+			}
+			got, err := YAMLManifestPatch(base, "istio-system", rc.Overlays)
+			if gotErr, wantErr := errToString(err), tt.wantErr; gotErr != wantErr {
+				t.Fatalf("YAMLManifestPatch(%s): gotErr:%s, wantErr:%s", tt.desc, gotErr, wantErr)
+			}
+			if tt.wantErr != "" {
+				return
+			}
+			gotObjs, err := object.ParseK8sObjectsFromYAMLManifest(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantObjs, err := object.ParseK8sObjectsFromYAMLManifest(tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotMap, wantMap := gotObjs.ToMap(), wantObjs.ToMap()
+			if len(gotMap) != len(wantMap) {
+				t.Fatalf("YAMLManifestPatch(%s): got:\n%s\n\nwant:\n%s", tt.desc, got, tt.want)
+			}
+			for k, w := range wantMap {
+				g, ok := gotMap[k]
+				if !ok {
+					t.Fatalf("YAMLManifestPatch(%s): missing %s in:\n%s", tt.desc, k, got)
+				}
+				gy, _ := g.YAML()
+				wy, _ := w.YAML()
+				if !util.IsYAMLEqual(string(gy), string(wy)) {
+					t.Errorf("YAMLManifestPatch(%s): got:\n%s\n\nwant:\n%s\nDiff:\n%s\n", tt.desc, gy, wy, util.YAMLDiff(string(gy), string(wy)))
+				}
+			}
+		})
+	}
+}
+
 func makeOverlayHeader(path, value string) string {
 	const (
 		patchCommon = `overlays:
@@ -0,0 +1,96 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"regexp"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+
+	"istio.io/istio/operator/pkg/name"
+)
+
+var (
+	// GatewayComponents are the components of the gateways, which are shared by all the revisions and use the
+	// control plane of one of them.
+	GatewayComponents = map[name.ComponentName]bool{
+		name.IngressComponentName: true,
+		name.EgressComponentName:  true,
+	}
+
+	// controlPlaneServices are the services of the components in Components which the gateways connect to.
+	controlPlaneServices = []string{"istio-pilot", "istio-policy", "istio-telemetry"}
+)
+
+// ServiceName returns the name in the revision rev of a service of the control plane.
+func ServiceName(svc, rev string) string {
+	if rev == "" || rev == Default {
+		return svc
+	}
+	return svc + "-" + rev
+}
+
+// GatewayRevision returns the revision whose control plane the gateway deployment uses. The revision is recorded as
+// an annotation rather than a label, for the gateway not to be deleted with the objects of the revision.
+func GatewayRevision(d *appsv1.Deployment) string {
+	if rev, ok := d.Annotations[Label]; ok {
+		return rev
+	}
+	return Default
+}
+
+// MoveGateway points the proxies of a gateway deployment to the control plane of the revision rev. The gateway
+// pods are restarted by the rollout of the deployment.
+func MoveGateway(d *appsv1.Deployment, rev string) {
+	from := GatewayRevision(d)
+	var hosts []*regexp.Regexp
+	var replacements []string
+	for _, svc := range controlPlaneServices {
+		hosts = append(hosts, regexp.MustCompile(`(^|[^-.\w])`+regexp.QuoteMeta(ServiceName(svc, from))+`([.:])`))
+		replacements = append(replacements, "${1}"+ServiceName(svc, rev)+"${2}")
+	}
+	replace := func(s string) string {
+		for i, h := range hosts {
+			s = h.ReplaceAllString(s, replacements[i])
+		}
+		return s
+	}
+
+	spec := &d.Spec.Template.Spec
+	for _, containers := range [][]v1.Container{spec.InitContainers, spec.Containers} {
+		for i := range containers {
+			c := &containers[i]
+			for j := range c.Command {
+				c.Command[j] = replace(c.Command[j])
+			}
+			for j := range c.Args {
+				c.Args[j] = replace(c.Args[j])
+			}
+			for j := range c.Env {
+				c.Env[j].Value = replace(c.Env[j].Value)
+			}
+		}
+	}
+
+	if rev == Default {
+		delete(d.Annotations, Label)
+		return
+	}
+	if d.Annotations == nil {
+		d.Annotations = map[string]string{}
+	}
+	d.Annotations[Label] = rev
+}
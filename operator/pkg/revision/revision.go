@@ -0,0 +1,392 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package revision turns the manifests of the control plane into the manifests of a revision of the control plane,
+// which can be installed side by side with the default one for canary upgrades.
+package revision
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"istio.io/istio/operator/pkg/helm"
+	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/object"
+	"istio.io/pkg/log"
+)
+
+const (
+	// Label is the label of the objects of a revision, of the namespaces whose pods are injected by this revision,
+	// and of the pods injected by this revision.
+	Label = "istio.io/rev"
+	// Default is the name of the control plane installed without a revision.
+	Default = "default"
+
+	// injectionLabel is the namespace label selecting the injector of the default revision.
+	injectionLabel = "istio-injection"
+	// serviceLabel is the pod label selected by the services of the default revision. Other pod labels, like app, are
+	// shared by all the revisions.
+	serviceLabel = "istio"
+	// injectorConfigMapName is the name of the config map holding the injection configuration.
+	injectorConfigMapName = "istio-sidecar-injector"
+	// injectorConfigKey is the key of the injection configuration in the injector config map.
+	injectorConfigKey = "config"
+)
+
+var (
+	// Components are the components installed once per revision. The other components are shared by all the
+	// revisions and are left to the default one, except for the base component holding the CRDs: Citadel and the node
+	// agent, which serve the certificates of all the proxies, and the gateways, which are moved between revisions
+	// with MoveGateway.
+	Components = map[name.ComponentName]bool{
+		name.PilotComponentName:           true,
+		name.GalleyComponentName:          true,
+		name.SidecarInjectorComponentName: true,
+		name.PolicyComponentName:          true,
+		name.TelemetryComponentName:       true,
+	}
+
+	// renamedGroups are the API groups of the objects whose name gets the revision suffix. Istio configuration, like
+	// the mixer adapters, is shared by all the revisions.
+	renamedGroups = map[string]bool{
+		"":                             true,
+		"apps":                         true,
+		"extensions":                   true,
+		"policy":                       true,
+		"autoscaling":                  true,
+		"rbac.authorization.k8s.io":    true,
+		"admissionregistration.k8s.io": true,
+	}
+
+	// workloadKinds are the kinds of the objects with a pod template.
+	workloadKinds = map[string]bool{
+		"Deployment":  true,
+		"DaemonSet":   true,
+		"StatefulSet": true,
+		"ReplicaSet":  true,
+		"Job":         true,
+	}
+)
+
+// Validate returns an error if rev can't be used as a revision.
+func Validate(rev string) error {
+	if rev == Default {
+		return fmt.Errorf("revision %q is reserved for the control plane installed without a revision", Default)
+	}
+	if errs := validation.IsDNS1123Label(rev); len(errs) > 0 {
+		return fmt.Errorf("invalid revision %q: %s", rev, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// InjectorConfigMapName returns the name of the config map holding the injection configuration of the revision rev.
+func InjectorConfigMapName(rev string) string {
+	if rev == "" || rev == Default {
+		return injectorConfigMapName
+	}
+	return injectorConfigMapName + "-" + rev
+}
+
+// NamespaceLabels returns the labels selecting the injector of the revision rev for the pods of a namespace, and
+// the labels to remove for the other injectors not to select it.
+func NamespaceLabels(rev string) (map[string]string, []string) {
+	if rev == "" || rev == Default {
+		return map[string]string{injectionLabel: "enabled"}, []string{Label}
+	}
+	return map[string]string{Label: rev}, []string{injectionLabel}
+}
+
+// Apply turns the manifests of the control plane into the manifests of its revision rev. The objects of the
+// components in Components get the revision suffix in their name, and the revision label. The references between
+// these objects follow the renames: service accounts, config maps and secrets of the pods, role bindings, webhook
+// services and the service hosts in configuration. The injector webhook only selects the namespaces labelled with
+// the revision, and the injector labels the pods it injects with the revision.
+func Apply(manifests name.ManifestMap, rev string) (name.ManifestMap, error) {
+	if err := Validate(rev); err != nil {
+		return nil, err
+	}
+	objects := map[name.ComponentName]object.K8sObjects{}
+	out := name.ManifestMap{}
+	for c, m := range manifests {
+		switch {
+		case c == name.IstioBaseComponentName:
+			out[c] = m
+		case Components[c]:
+			objs, err := object.ParseK8sObjectsFromYAMLManifest(strings.Join(m, helm.YAMLSeparator))
+			if err != nil {
+				return nil, fmt.Errorf("invalid manifest of component %s: %v", c, err)
+			}
+			objects[c] = objs
+		default:
+			log.Infof("Component %s is shared by all the revisions, skipping it for revision %s.", c, rev)
+		}
+	}
+
+	r := newRenamer(rev)
+	for _, objs := range objects {
+		for _, o := range objs {
+			if renamedGroups[o.Group] && o.Kind != "Namespace" {
+				r.add(o.Kind, o.Name)
+			}
+		}
+	}
+	for c, objs := range objects {
+		revObjs := make(object.K8sObjects, 0, len(objs))
+		for _, o := range objs {
+			u := o.UnstructuredObject().DeepCopy()
+			if err := r.apply(u); err != nil {
+				return nil, fmt.Errorf("%s %s of component %s: %v", o.Kind, o.Name, c, err)
+			}
+			revObjs = append(revObjs, object.NewK8sObject(u, nil, nil))
+		}
+		m, err := revObjs.YAMLManifest()
+		if err != nil {
+			return nil, err
+		}
+		out[c] = []string{m}
+	}
+	return out, nil
+}
+
+// renamer renames the objects of a revision and their references.
+type renamer struct {
+	rev string
+	// names are the renamed objects, by kind and name.
+	names map[string]map[string]bool
+	// hosts matches the references to the renamed services in strings, like service hosts and addresses.
+	hosts []*regexp.Regexp
+}
+
+func newRenamer(rev string) *renamer {
+	return &renamer{rev: rev, names: map[string]map[string]bool{}}
+}
+
+func (r *renamer) add(kind, n string) {
+	if r.names[kind] == nil {
+		r.names[kind] = map[string]bool{}
+	}
+	r.names[kind][n] = true
+	if kind == "Service" {
+		r.hosts = append(r.hosts, regexp.MustCompile(`(^|[^-.\w])(`+regexp.QuoteMeta(n)+`)([.:])`))
+	}
+}
+
+// name returns the name in the revision of the object of the given kind and name.
+func (r *renamer) name(kind, n string) string {
+	if !r.names[kind][n] {
+		return n
+	}
+	return n + "-" + r.rev
+}
+
+// replaceHosts replaces the references to the renamed services in s.
+func (r *renamer) replaceHosts(s string) string {
+	for _, h := range r.hosts {
+		s = h.ReplaceAllString(s, "${1}${2}-"+r.rev+"${3}")
+	}
+	return s
+}
+
+// replaceArg replaces the references to the renamed objects in a container argument or environment variable, either
+// the whole value or the value of a flag, like --webhookConfigName=istio-sidecar-injector.
+func (r *renamer) replaceArg(s string) string {
+	prefix, value := "", s
+	if i := strings.LastIndex(s, "="); i >= 0 {
+		prefix, value = s[:i+1], s[i+1:]
+	}
+	for kind := range r.names {
+		if n := r.name(kind, value); n != value {
+			return prefix + n
+		}
+	}
+	return r.replaceHosts(s)
+}
+
+func (r *renamer) apply(u *unstructured.Unstructured) error {
+	kind := u.GetKind()
+	u.SetName(r.name(kind, u.GetName()))
+	if renamedGroups[u.GroupVersionKind().Group] && kind != "Namespace" {
+		u.SetLabels(withRevision(u.GetLabels(), r.rev))
+	}
+
+	switch {
+	case workloadKinds[kind]:
+		r.applyWorkload(u.Object)
+	case kind == "Service":
+		relabel(nested(u.Object, "spec", "selector"), r.rev)
+	case kind == "PodDisruptionBudget":
+		relabel(nested(u.Object, "spec", "selector", "matchLabels"), r.rev)
+	case kind == "HorizontalPodAutoscaler":
+		if target := nested(u.Object, "spec", "scaleTargetRef"); target != nil {
+			target["name"] = r.name(fmt.Sprint(target["kind"]), fmt.Sprint(target["name"]))
+		}
+	case kind == "RoleBinding" || kind == "ClusterRoleBinding":
+		if ref := nested(u.Object, "roleRef"); ref != nil {
+			ref["name"] = r.name(fmt.Sprint(ref["kind"]), fmt.Sprint(ref["name"]))
+		}
+		for _, s := range maps(u.Object["subjects"]) {
+			s["name"] = r.name(fmt.Sprint(s["kind"]), fmt.Sprint(s["name"]))
+		}
+	case kind == "MutatingWebhookConfiguration" || kind == "ValidatingWebhookConfiguration":
+		for _, w := range maps(u.Object["webhooks"]) {
+			if svc := nested(w, "clientConfig", "service"); svc != nil {
+				svc["name"] = r.name("Service", fmt.Sprint(svc["name"]))
+			}
+			if kind == "MutatingWebhookConfiguration" {
+				// Only inject the pods of the namespaces labelled with the revision.
+				w["namespaceSelector"] = map[string]interface{}{
+					"matchLabels": map[string]interface{}{Label: r.rev},
+				}
+			}
+		}
+	case kind == "ConfigMap":
+		data := nested(u.Object, "data")
+		for k, v := range data {
+			data[k] = r.replaceHosts(fmt.Sprint(v))
+		}
+		if u.GetName() == r.name(kind, injectorConfigMapName) {
+			if err := setInjectorRevision(data, r.rev); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyWorkload renames the references of the pod template of a workload, and labels its pods with the revision.
+func (r *renamer) applyWorkload(obj map[string]interface{}) {
+	relabel(nested(obj, "spec", "selector", "matchLabels"), r.rev)
+	template := nested(obj, "spec", "template")
+	if template == nil {
+		return
+	}
+	meta := nested(template, "metadata")
+	if meta == nil {
+		meta = map[string]interface{}{}
+		template["metadata"] = meta
+	}
+	podLabels, _ := meta["labels"].(map[string]interface{})
+	if podLabels == nil {
+		podLabels = map[string]interface{}{}
+		meta["labels"] = podLabels
+	}
+	relabel(podLabels, r.rev)
+	podLabels[Label] = r.rev
+
+	spec := nested(template, "spec")
+	if spec == nil {
+		return
+	}
+	for _, key := range []string{"serviceAccountName", "serviceAccount"} {
+		if sa, ok := spec[key].(string); ok {
+			spec[key] = r.name("ServiceAccount", sa)
+		}
+	}
+	for _, v := range maps(spec["volumes"]) {
+		if cm := nested(v, "configMap"); cm != nil {
+			cm["name"] = r.name("ConfigMap", fmt.Sprint(cm["name"]))
+		}
+		if s := nested(v, "secret"); s != nil {
+			s["secretName"] = r.secretName(fmt.Sprint(s["secretName"]))
+		}
+	}
+	for _, containers := range []string{"initContainers", "containers"} {
+		for _, c := range maps(spec[containers]) {
+			for _, field := range []string{"command", "args"} {
+				args, _ := c[field].([]interface{})
+				for i, a := range args {
+					args[i] = r.replaceArg(fmt.Sprint(a))
+				}
+			}
+			for _, e := range maps(c["env"]) {
+				if v, ok := e["value"].(string); ok {
+					e["value"] = r.replaceArg(v)
+				}
+			}
+		}
+	}
+}
+
+// secretName returns the name in the revision of a secret, following the renames of the service accounts for the
+// secrets created by Citadel.
+func (r *renamer) secretName(n string) string {
+	if sa := strings.TrimPrefix(n, "istio."); sa != n && r.names["ServiceAccount"][sa] {
+		return "istio." + r.name("ServiceAccount", sa)
+	}
+	return r.name("Secret", n)
+}
+
+// setInjectorRevision configures the injector to label the pods it injects with the revision.
+func setInjectorRevision(data map[string]interface{}, rev string) error {
+	raw, ok := data[injectorConfigKey].(string)
+	if !ok {
+		return nil
+	}
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(raw), &config); err != nil {
+		return fmt.Errorf("invalid injection configuration: %v", err)
+	}
+	config["revision"] = rev
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	data[injectorConfigKey] = string(out)
+	return nil
+}
+
+// relabel changes the pod labels selected by the services of the default revision, for them not to select the pods
+// of the revision.
+func relabel(labels map[string]interface{}, rev string) {
+	if v, ok := labels[serviceLabel].(string); ok {
+		labels[serviceLabel] = v + "-" + rev
+	}
+}
+
+func withRevision(labels map[string]string, rev string) map[string]string {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[Label] = rev
+	return labels
+}
+
+// nested returns the map at the path in obj, or nil if there is none.
+func nested(obj map[string]interface{}, path ...string) map[string]interface{} {
+	for _, p := range path {
+		next, ok := obj[p].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		obj = next
+	}
+	return obj
+}
+
+// maps returns the maps of a list.
+func maps(list interface{}) []map[string]interface{} {
+	items, _ := list.([]interface{})
+	out := make([]map[string]interface{}, 0, len(items))
+	for _, i := range items {
+		if m, ok := i.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,281 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/object"
+)
+
+const (
+	citadelManifest = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istio-citadel
+  namespace: istio-system
+`
+	pilotManifest = `
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: istio-pilot-service-account
+  namespace: istio-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: istio-pilot-istio-system
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: istio-pilot-istio-system
+subjects:
+- kind: ServiceAccount
+  name: istio-pilot-service-account
+  namespace: istio-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: istio-pilot-istio-system
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: istio
+  namespace: istio-system
+data:
+  mesh: |-
+    defaultConfig:
+      discoveryAddress: istio-pilot.istio-system.svc:15012
+      zipkinAddress: zipkin.istio-system:9411
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: istio-pilot
+  namespace: istio-system
+spec:
+  selector:
+    istio: pilot
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istio-pilot
+  namespace: istio-system
+spec:
+  selector:
+    matchLabels:
+      istio: pilot
+  template:
+    metadata:
+      labels:
+        app: pilot
+        istio: pilot
+    spec:
+      serviceAccountName: istio-pilot-service-account
+      containers:
+      - name: discovery
+        args:
+        - --webhookConfigName=istio-sidecar-injector
+        - --log_output_level=default:info
+        env:
+        - name: INJECTION_WEBHOOK_CONFIG_NAME
+          value: istio-sidecar-injector
+        - name: PILOT_ADDRESS
+          value: istio-pilot.istio-system.svc:15012
+      volumes:
+      - name: config-volume
+        configMap:
+          name: istio
+      - name: istio-certs
+        secret:
+          secretName: istio.istio-pilot-service-account
+---
+apiVersion: autoscaling/v2beta1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: istio-pilot
+  namespace: istio-system
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: istio-pilot
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: metadata-exchange
+  namespace: istio-system
+`
+	injectorManifest = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: istio-sidecar-injector
+  namespace: istio-system
+data:
+  config: |-
+    policy: enabled
+    template: ""
+---
+apiVersion: admissionregistration.k8s.io/v1beta1
+kind: MutatingWebhookConfiguration
+metadata:
+  name: istio-sidecar-injector
+webhooks:
+- name: sidecar-injector.istio.io
+  clientConfig:
+    service:
+      name: istio-pilot
+      namespace: istio-system
+  namespaceSelector:
+    matchLabels:
+      istio-injection: enabled
+`
+	baseManifest = `
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: gateways.networking.istio.io
+`
+	gatewayManifest = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istio-ingressgateway
+  namespace: istio-system
+`
+)
+
+func TestApply(t *testing.T) {
+	manifests := name.ManifestMap{
+		name.IstioBaseComponentName:       {baseManifest},
+		name.PilotComponentName:           {pilotManifest},
+		name.SidecarInjectorComponentName: {injectorManifest},
+		name.IngressComponentName:         {gatewayManifest},
+		name.CitadelComponentName:         {citadelManifest},
+	}
+	got, err := Apply(manifests, "canary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got[name.IngressComponentName]; ok {
+		t.Errorf("expected the shared gateways not to be in the revision")
+	}
+	if _, ok := got[name.CitadelComponentName]; ok {
+		t.Errorf("expected the shared Citadel not to be in the revision, writing the secrets of all the revisions")
+	}
+	if got[name.IstioBaseComponentName][0] != baseManifest {
+		t.Errorf("expected the base component to be left unchanged, got %s", got[name.IstioBaseComponentName][0])
+	}
+
+	objects := map[string]*unstructured.Unstructured{}
+	for _, c := range []name.ComponentName{name.PilotComponentName, name.SidecarInjectorComponentName} {
+		objs, err := object.ParseK8sObjectsFromYAMLManifest(strings.Join(got[c], "\n---\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range objs {
+			objects[o.Kind+"/"+o.Name] = o.UnstructuredObject()
+		}
+	}
+
+	cases := []struct {
+		object string
+		path   []string
+		want   interface{}
+	}{
+		{"ServiceAccount/istio-pilot-service-account-canary", []string{"metadata", "labels", Label}, "canary"},
+		{"ClusterRoleBinding/istio-pilot-istio-system-canary", []string{"roleRef", "name"}, "istio-pilot-istio-system-canary"},
+		{"ConfigMap/istio-canary", []string{"data", "mesh"}, "defaultConfig:\n  discoveryAddress: istio-pilot-canary.istio-system.svc:15012\n" +
+			"  zipkinAddress: zipkin.istio-system:9411"},
+		{"Service/istio-pilot-canary", []string{"spec", "selector", "istio"}, "pilot-canary"},
+		{"Deployment/istio-pilot-canary", []string{"spec", "selector", "matchLabels", "istio"}, "pilot-canary"},
+		{"Deployment/istio-pilot-canary", []string{"spec", "template", "metadata", "labels", "app"}, "pilot"},
+		{"Deployment/istio-pilot-canary", []string{"spec", "template", "metadata", "labels", Label}, "canary"},
+		{"Deployment/istio-pilot-canary", []string{"spec", "template", "spec", "serviceAccountName"},
+			"istio-pilot-service-account-canary"},
+		{"HorizontalPodAutoscaler/istio-pilot-canary", []string{"spec", "scaleTargetRef", "name"}, "istio-pilot-canary"},
+		{"EnvoyFilter/metadata-exchange", []string{"metadata", "name"}, "metadata-exchange"},
+		{"MutatingWebhookConfiguration/istio-sidecar-injector-canary", []string{"metadata", "labels", Label}, "canary"},
+		{"ConfigMap/istio-sidecar-injector-canary", []string{"data", "config"}, "policy: enabled\nrevision: canary\ntemplate: \"\"\n"},
+	}
+	for _, c := range cases {
+		o, ok := objects[c.object]
+		if !ok {
+			t.Errorf("%s not found in the revision", c.object)
+			continue
+		}
+		got, _, _ := unstructured.NestedFieldNoCopy(o.Object, c.path...)
+		if got != c.want {
+			t.Errorf("%s %v: got %q, want %q", c.object, c.path, got, c.want)
+		}
+	}
+
+	pod := objects["Deployment/istio-pilot-canary"].Object["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	container := pod["containers"].([]interface{})[0].(map[string]interface{})
+	wantArgs := []string{"--webhookConfigName=istio-sidecar-injector-canary", "--log_output_level=default:info"}
+	for i, a := range container["args"].([]interface{}) {
+		if a != wantArgs[i] {
+			t.Errorf("arg %d: got %q, want %q", i, a, wantArgs[i])
+		}
+	}
+	wantEnv := []string{"istio-sidecar-injector-canary", "istio-pilot-canary.istio-system.svc:15012"}
+	for i, e := range container["env"].([]interface{}) {
+		if v := e.(map[string]interface{})["value"]; v != wantEnv[i] {
+			t.Errorf("env %d: got %q, want %q", i, v, wantEnv[i])
+		}
+	}
+	volumes := pod["volumes"].([]interface{})
+	if n := volumes[0].(map[string]interface{})["configMap"].(map[string]interface{})["name"]; n != "istio-canary" {
+		t.Errorf("got config map volume %q, want istio-canary", n)
+	}
+	if n := volumes[1].(map[string]interface{})["secret"].(map[string]interface{})["secretName"]; n != "istio.istio-pilot-service-account-canary" {
+		t.Errorf("got secret volume %q, want istio.istio-pilot-service-account-canary", n)
+	}
+
+	webhook := objects["MutatingWebhookConfiguration/istio-sidecar-injector-canary"].Object["webhooks"].([]interface{})[0].(map[string]interface{})
+	if svc, _, _ := unstructured.NestedString(webhook, "clientConfig", "service", "name"); svc != "istio-pilot-canary" {
+		t.Errorf("got webhook service %q, want istio-pilot-canary", svc)
+	}
+	selector, _, _ := unstructured.NestedStringMap(webhook, "namespaceSelector", "matchLabels")
+	if len(selector) != 1 || selector[Label] != "canary" {
+		t.Errorf("got webhook namespace selector %v, want %s=canary", selector, Label)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for rev, wantErr := range map[string]bool{
+		"canary":  false,
+		"1-5-0":   false,
+		"default": true,
+		"Canary":  true,
+		"1.5.0":   true,
+		"":        true,
+	} {
+		if err := Validate(rev); (err != nil) != wantErr {
+			t.Errorf("Validate(%q) = %v, want an error: %v", rev, err, wantErr)
+		}
+	}
+}
@@ -118,6 +118,12 @@ var (
 	sdsEnabledVar        = env.RegisterBoolVar("SDS_ENABLED", false, "")
 	autoMTLSEnabled      = env.RegisterBoolVar("ISTIO_AUTO_MTLS_ENABLED", false, "If true, auto mTLS is enabled, "+
 		"sidecar checks key/cert if SDS is not enabled.")
+
+	exitOnAppCompletionVar = env.RegisterBoolVar(envoy.ExitOnAppCompletionEnvName, false,
+		"If true, the proxy terminates once the application containers complete")
+	appCompletionGracePeriodVar = env.RegisterDurationVar(envoy.AppCompletionGracePeriodEnvName, 0,
+		"How long the application processes must be gone before the application is considered complete")
+
 	sdsUdsPathVar             = env.RegisterStringVar("SDS_UDS_PATH", "unix:/var/run/sds/uds_path", "SDS address")
 	stackdriverTracingEnabled = env.RegisterBoolVar("STACKDRIVER_TRACING_ENABLED", false, "If enabled, stackdriver will"+
 		" get configured as the tracer.")
@@ -545,6 +551,13 @@ var (
 			watcher := envoy.NewWatcher(tlsCertsToWatch, agent.Restart)
 			go watcher.Run(ctx)
 
+			// When the application containers complete, e.g. in the pod of a Job, drain and terminate
+			// the proxy as well so that the pod can complete.
+			if exitOnAppCompletionVar.Get() {
+				completionWatcher := envoy.NewCompletionWatcher(appCompletionGracePeriodVar.Get(), cancel)
+				go completionWatcher.Run(ctx)
+			}
+
 			// On SIGINT or SIGTERM, cancel the context, triggering a graceful shutdown
 			go cmd.WaitSignalFunc(cancel)
 
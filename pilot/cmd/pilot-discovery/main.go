@@ -129,6 +129,9 @@ func init() {
 		"Initial window size for MCP's gRPC connection")
 	discoveryCmd.PersistentFlags().IntVar(&serverArgs.MCPInitialConnWindowSize, "mcpInitialConnWindowSize", serverArgs.MCPInitialConnWindowSize,
 		"Initial connection window size for MCP's gRPC connection")
+	discoveryCmd.PersistentFlags().StringVar(&serverArgs.MCPCacheDir, "mcpCacheDir", serverArgs.MCPCacheDir,
+		"Directory persisting the config received over MCP, used on startup until the config sources are reachable. "+
+			"The config is not persisted if empty")
 
 	// Config Controller options
 	discoveryCmd.PersistentFlags().BoolVar(&serverArgs.Config.DisableInstallCRDs, "disable-install-crds", false,
@@ -20,6 +20,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -166,11 +167,11 @@ func (s *Server) initMCPConfigController(args *PilotArgs) (err error) {
 			return err
 		}
 		conns = append(conns, conn)
-		s.mcpController(mcpOptions, conn, reporter, &clients, &configStores)
+		s.mcpController(mcpOptions, conn, reporter, mcpCache(args, configSource, "config"), &clients, &configStores)
 
 		// create MCP SyntheticServiceEntryController
 		if resourceContains(configSource.SubscribedResources, meshconfig.Resource_SERVICE_REGISTRY) {
-			s.sseMCPController(args, conn, reporter, &clients, &configStores)
+			s.sseMCPController(args, conn, reporter, mcpCache(args, configSource, "sse"), &clients, &configStores)
 		}
 	}
 
@@ -277,17 +278,32 @@ func mcpSecurityOptions(ctx context.Context, configSource *meshconfig.ConfigSour
 	return securityOption, nil
 }
 
+// mcpCache returns the cache of the config received by the sink name from the config source, or nil if the config is
+// not persisted.
+func mcpCache(args *PilotArgs, configSource *meshconfig.ConfigSource, name string) sink.Cache {
+	if args.MCPCacheDir == "" {
+		return nil
+	}
+	c, err := sink.NewFileCache(filepath.Join(args.MCPCacheDir, url.PathEscape(configSource.Address), name))
+	if err != nil {
+		log.Errorf("Unable to create the MCP cache of %q, the config is not persisted: %v", configSource.Address, err)
+		return nil
+	}
+	return c
+}
+
 func (s *Server) mcpController(
 	opts *mcp.Options,
 	conn *grpc.ClientConn,
 	reporter monitoring.Reporter,
+	cache sink.Cache,
 	clients *[]*sink.Client,
 	configStores *[]model.ConfigStoreCache) {
 	clientNodeID := ""
 	all := collections.Pilot.All()
 	cols := make([]sink.CollectionOptions, 0, len(all))
 	for _, c := range all {
-		cols = append(cols, sink.CollectionOptions{Name: c.Name().String(), Incremental: false})
+		cols = append(cols, sink.CollectionOptions{Name: c.Name().String(), Incremental: true})
 	}
 
 	mcpController := mcp.NewController(opts)
@@ -296,6 +312,7 @@ func (s *Server) mcpController(
 		Updater:           mcpController,
 		ID:                clientNodeID,
 		Reporter:          reporter,
+		Cache:             cache,
 	}
 
 	cl := mcpapi.NewResourceSourceClient(conn)
@@ -308,6 +325,7 @@ func (s *Server) mcpController(
 func (s *Server) sseMCPController(args *PilotArgs,
 	conn *grpc.ClientConn,
 	reporter monitoring.Reporter,
+	cache sink.Cache,
 	clients *[]*sink.Client,
 	configStores *[]model.ConfigStoreCache) {
 	clientNodeID := "SSEMCP"
@@ -327,6 +345,7 @@ func (s *Server) sseMCPController(args *PilotArgs,
 		Updater:  ctl,
 		ID:       clientNodeID,
 		Reporter: reporter,
+		Cache:    cache,
 	}
 	incSrcClient := mcpapi.NewResourceSourceClient(conn)
 	incMcpClient := sink.NewClient(incSrcClient, incrementalSinkOptions)
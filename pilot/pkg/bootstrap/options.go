@@ -79,6 +79,7 @@ type PilotArgs struct {
 	MCPMaxMessageSize        int
 	MCPInitialWindowSize     int
 	MCPInitialConnWindowSize int
+	MCPCacheDir              string
 	KeepaliveOptions         *istiokeepalive.Options
 	// ForceStop is set as true when used for testing to make the server stop quickly
 	ForceStop bool
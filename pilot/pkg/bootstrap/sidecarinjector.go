@@ -70,6 +70,7 @@ func (s *Server) initSidecarInjector(args *PilotArgs) error {
 			HealthCheckInterval: 0,
 			// Disable monitoring. The injection metrics will be picked up by Pilots metrics exporter already
 			MonitoringPort: -1,
+			Client:         s.kubeClient,
 		}
 
 		wh, err := inject.NewWebhook(parameters)
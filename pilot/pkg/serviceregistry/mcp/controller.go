@@ -135,8 +135,47 @@ func (c *controller) Apply(change *sink.Change) error {
 
 	kind := s.Resource().GroupVersionKind()
 
+	c.configStoreMu.RLock()
+	prevStore := c.configStore[kind]
+	c.configStoreMu.RUnlock()
+
 	// innerStore is [namespace][name]
 	innerStore := make(map[string]map[string]*model.Config)
+	remove := func(namespace, name string) {
+		if namedConfig, ok := innerStore[namespace]; ok {
+			delete(namedConfig, name)
+			if len(namedConfig) == 0 {
+				delete(innerStore, namespace)
+			}
+		}
+	}
+	// discard drops an invalid resource. In incremental mode, its previous version is removed as well:
+	// the source no longer holds it, so keeping it would serve stale config.
+	discard := func(metadataName string) {
+		if !change.Incremental {
+			return
+		}
+		namespace, name := extractNameNamespace(metadataName)
+		if _, ok := innerStore[namespace][name]; !ok {
+			return
+		}
+		remove(namespace, name)
+		if err := c.ledger.Delete(model.Key(kind.Kind, name, namespace)); err != nil {
+			log.Warnf(ledgerLogf, err)
+		}
+	}
+	if change.Incremental {
+		// The change only holds the added, updated and removed resources: start from the previous ones.
+		for namespace, namedConfig := range prevStore {
+			innerStore[namespace] = make(map[string]*model.Config, len(namedConfig))
+			for name, conf := range namedConfig {
+				innerStore[namespace][name] = conf
+			}
+		}
+		for _, removed := range change.Removed {
+			remove(extractNameNamespace(removed))
+		}
+	}
 	for _, obj := range change.Objects {
 		namespace, name := extractNameNamespace(obj.Metadata.Name)
 
@@ -146,6 +185,7 @@ func (c *controller) Apply(change *sink.Change) error {
 			if createTime, err = types.TimestampFromProto(obj.Metadata.CreateTime); err != nil {
 				// Do not return an error, instead discard the resources so that Pilot can process the rest.
 				log.Warnf("Discarding incoming MCP resource: invalid resource timestamp (%s/%s): %v", namespace, name, err)
+				discard(obj.Metadata.Name)
 				continue
 			}
 		}
@@ -169,6 +209,7 @@ func (c *controller) Apply(change *sink.Change) error {
 		if err := s.Resource().ValidateProto(conf.Name, conf.Namespace, conf.Spec); err != nil {
 			// Do not return an error, instead discard the resources so that Pilot can process the rest.
 			log.Warnf("Discarding incoming MCP resource: validation failed (%s/%s): %v", conf.Namespace, conf.Name, err)
+			discard(obj.Metadata.Name)
 			continue
 		}
 
@@ -193,10 +234,7 @@ func (c *controller) Apply(change *sink.Change) error {
 		}
 	}
 
-	var prevStore map[string]map[string]*model.Config
-
 	c.configStoreMu.Lock()
-	prevStore = c.configStore[kind]
 	c.configStore[kind] = innerStore
 	c.configStoreMu.Unlock()
 	c.sync(change.Collection)
@@ -407,6 +407,41 @@ func TestApplyChangeNoObjects(t *testing.T) {
 	g.Expect(len(c)).To(Equal(0))
 }
 
+func TestApplyIncremental(t *testing.T) {
+	g := NewGomegaWithT(t)
+	controller := mcp.NewController(testControllerOptions)
+
+	typeURL := collections.IstioNetworkingV1Alpha3Gateways.Resource().Proto()
+	message := convertToResource(g, typeURL, gateway)
+	message2 := convertToResource(g, typeURL, gateway2)
+	message3 := convertToResource(g, typeURL, gateway3)
+
+	change := convertToChange([]proto.Message{message, message2},
+		[]string{"ns1/gateway1", "ns2/gateway2"},
+		setCollection(collections.IstioNetworkingV1Alpha3Gateways.Name().String()),
+		setTypeURL(collections.IstioNetworkingV1Alpha3Gateways.Resource().Proto()))
+	g.Expect(controller.Apply(change)).To(Succeed())
+
+	change = convertToChange([]proto.Message{message3},
+		[]string{"ns1/gateway3"},
+		setCollection(collections.IstioNetworkingV1Alpha3Gateways.Name().String()),
+		setTypeURL(collections.IstioNetworkingV1Alpha3Gateways.Resource().Proto()))
+	change.Incremental = true
+	change.Removed = []string{"ns2/gateway2"}
+	g.Expect(controller.Apply(change)).To(Succeed())
+
+	c, err := controller.List(gatewayGvk, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	names := make(map[string]proto.Message, len(c))
+	for _, conf := range c {
+		names[conf.Namespace+"/"+conf.Name] = conf.Spec
+	}
+	g.Expect(names).To(Equal(map[string]proto.Message{
+		"ns1/gateway1": message,
+		"ns1/gateway3": message3,
+	}))
+}
+
 func TestApplyConfigUpdate(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -569,6 +604,38 @@ func TestInvalidResource_BadTimestamp(t *testing.T) {
 	g.Expect(entries).To(HaveLen(0))
 }
 
+func TestInvalidResource_Incremental(t *testing.T) {
+	g := NewGomegaWithT(t)
+	controller := mcp.NewController(testControllerOptions)
+
+	typeURL := collections.IstioNetworkingV1Alpha3Gateways.Resource().Proto()
+	message := convertToResource(g, typeURL, gateway)
+	message2 := convertToResource(g, typeURL, gateway2)
+
+	change := convertToChange([]proto.Message{message, message2},
+		[]string{"ns1/gateway1", "ns2/gateway2"},
+		setCollection(collections.IstioNetworkingV1Alpha3Gateways.Name().String()),
+		setTypeURL(typeURL))
+	g.Expect(controller.Apply(change)).To(Succeed())
+
+	gw := proto.Clone(gateway).(*networking.Gateway)
+	gw.Servers[0].Hosts = nil
+	invalid := convertToResource(g, typeURL, gw)
+
+	change = convertToChange([]proto.Message{invalid},
+		[]string{"ns1/gateway1"},
+		setCollection(collections.IstioNetworkingV1Alpha3Gateways.Name().String()),
+		setTypeURL(typeURL))
+	change.Incremental = true
+	g.Expect(controller.Apply(change)).To(Succeed())
+
+	entries, err := controller.List(gatewayGvk, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(entries).To(HaveLen(1))
+	g.Expect(entries[0].Namespace).To(Equal("ns2"))
+	g.Expect(entries[0].Name).To(Equal("gateway2"))
+}
+
 func TestEventHandler(t *testing.T) {
 	controller := mcp.NewController(testControllerOptions)
 
@@ -0,0 +1,166 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"istio.io/pkg/log"
+)
+
+const (
+	// ExitOnAppCompletionEnvName is the name of the environment variable enabling the termination of
+	// the proxy once the application containers complete. It is set by the injector for pods which
+	// opted in and share their process namespace, such as the pods of Jobs.
+	ExitOnAppCompletionEnvName = "ISTIO_EXIT_ON_APP_COMPLETION"
+
+	// AppCompletionGracePeriodEnvName is the name of the environment variable holding how long the
+	// application processes must be gone before the application is considered complete. It covers
+	// the application containers being restarted by the kubelet.
+	AppCompletionGracePeriodEnvName = "ISTIO_APP_COMPLETION_GRACE_PERIOD"
+
+	// defaultCompletionPollInterval is how often the application processes are checked.
+	defaultCompletionPollInterval = time.Second
+
+	defaultProcDir = "/proc"
+)
+
+// CompletionWatcher waits for the application containers sharing the pod to complete, so that the
+// proxy can be drained and terminated instead of keeping the pod running forever.
+type CompletionWatcher interface {
+	// Run the watcher loop (blocking call)
+	Run(context.Context)
+}
+
+type completionWatcher struct {
+	procDir     string
+	self        int
+	interval    time.Duration
+	gracePeriod time.Duration
+	complete    func()
+}
+
+// NewCompletionWatcher creates a watcher calling complete once the processes of the application
+// containers have run and been gone for the grace period. It relies on the pod sharing its process
+// namespace, so that the processes of the other containers are visible.
+func NewCompletionWatcher(gracePeriod time.Duration, complete func()) CompletionWatcher {
+	return &completionWatcher{
+		procDir:     defaultProcDir,
+		self:        os.Getpid(),
+		interval:    defaultCompletionPollInterval,
+		gracePeriod: gracePeriod,
+		complete:    complete,
+	}
+}
+
+func (w *completionWatcher) Run(ctx context.Context) {
+	if w.self == 1 {
+		// The first process of a shared namespace is the pod infrastructure container.
+		log.Warnf("The process namespace of the pod is not shared, application completion is not watched")
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var lastSeen time.Time
+	for {
+		running, err := w.appProcesses()
+		switch {
+		case err != nil:
+			log.Warnf("Failed to list the application processes: %v", err)
+		case running > 0:
+			lastSeen = time.Now()
+		case !lastSeen.IsZero() && time.Since(lastSeen) >= w.gracePeriod:
+			log.Infof("All application containers completed, terminating the proxy")
+			w.complete()
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// appProcesses returns the number of processes running outside of the proxy, i.e. other than the
+// pod infrastructure process, this process and its descendants.
+func (w *completionWatcher) appProcesses() (int, error) {
+	entries, err := ioutil.ReadDir(w.procDir)
+	if err != nil {
+		return 0, err
+	}
+
+	parents := map[int]int{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		ppid, err := w.parent(pid)
+		if err != nil {
+			// The process exited since the directory was listed.
+			continue
+		}
+		parents[pid] = ppid
+	}
+
+	running := 0
+	for pid := range parents {
+		if pid != 1 && !w.descendsFromSelf(pid, parents) {
+			running++
+		}
+	}
+	return running, nil
+}
+
+// parent returns the parent of the given process, read from its stat file.
+func (w *completionWatcher) parent(pid int) (int, error) {
+	stat, err := ioutil.ReadFile(filepath.Join(w.procDir, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+	// The command name may contain spaces and parentheses, the fields following it are the state
+	// and the parent pid.
+	s := string(stat)
+	fields := strings.Fields(s[strings.LastIndex(s, ")")+1:])
+	if len(fields) < 2 {
+		return 0, os.ErrInvalid
+	}
+	return strconv.Atoi(fields[1])
+}
+
+func (w *completionWatcher) descendsFromSelf(pid int, parents map[int]int) bool {
+	// Bound the walk, in case pids were reused while listing.
+	for i := 0; i <= len(parents); i++ {
+		if pid == w.self {
+			return true
+		}
+		ppid, ok := parents[pid]
+		if !ok || ppid == 0 {
+			return false
+		}
+		pid = ppid
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeProcess adds a process to the fake proc directory.
+func writeProcess(t *testing.T, dir string, pid, ppid int, comm string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, fmt.Sprint(pid)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	stat := fmt.Sprintf("%d (%s) S %d 1 1 0 -1", pid, comm, ppid)
+	if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprint(pid), "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompletionWatcherAppProcesses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeProcess(t, dir, 1, 0, "pause")
+	writeProcess(t, dir, 10, 0, "pilot-agent")
+	writeProcess(t, dir, 12, 10, "envoy")
+	writeProcess(t, dir, 20, 0, "my (odd) app")
+	writeProcess(t, dir, 21, 20, "worker")
+	if err := os.Mkdir(filepath.Join(dir, "self"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &completionWatcher{procDir: dir, self: 10}
+	if got, err := w.appProcesses(); err != nil || got != 2 {
+		t.Fatalf("appProcesses() => %d, %v, want 2", got, err)
+	}
+}
+
+func TestCompletionWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeProcess(t, dir, 1, 0, "pause")
+	writeProcess(t, dir, 10, 0, "pilot-agent")
+
+	completed := make(chan struct{})
+	w := &completionWatcher{
+		procDir:     dir,
+		self:        10,
+		interval:    10 * time.Millisecond,
+		gracePeriod: 50 * time.Millisecond,
+		complete:    func() { close(completed) },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	// The application containers may start after the proxy.
+	select {
+	case <-completed:
+		t.Fatal("watcher completed before the application started")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	writeProcess(t, dir, 20, 0, "app")
+	select {
+	case <-completed:
+		t.Fatal("watcher completed while the application was running")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "20")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-completed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the watcher to complete")
+	}
+}
+
+func TestCompletionWatcherCanceled(t *testing.T) {
+	w := &completionWatcher{
+		procDir:  "/does/not/exist",
+		self:     10,
+		interval: 10 * time.Millisecond,
+		complete: func() { t.Error("unexpected completion") },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the watcher to stop")
+	}
+}
+
+func TestCompletionWatcherUnsharedNamespace(t *testing.T) {
+	w := &completionWatcher{
+		procDir:  "/does/not/exist",
+		self:     1,
+		interval: 10 * time.Millisecond,
+		complete: func() { t.Error("unexpected completion") },
+	}
+	// Returns immediately, as the application processes are not visible.
+	w.Run(context.Background())
+}
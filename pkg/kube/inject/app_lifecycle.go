@@ -0,0 +1,89 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file is focused on terminating the sidecar once the application containers complete, so that
+// the pods of Jobs and CronJobs can complete as well.
+package inject
+
+import (
+	"strconv"
+
+	"istio.io/istio/pkg/envoy"
+	"istio.io/pkg/log"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// ExitOnAppCompletionAnnotation controls whether the sidecar exits once the application containers
+	// complete. It is disabled by default. The pod process namespace gets shared, so that the sidecar
+	// can see the application processes terminate.
+	ExitOnAppCompletionAnnotation = "sidecar.istio.io/exitOnAppCompletion"
+
+	// restartedAppCompletionGracePeriod is how long the sidecar waits for failed application containers
+	// to be restarted, the maximum back-off of the kubelet.
+	restartedAppCompletionGracePeriod = "5m"
+)
+
+// ShouldExitOnAppCompletion returns if the sidecar should exit once the application containers
+// complete.
+func ShouldExitOnAppCompletion(annotations map[string]string) bool {
+	if value, ok := annotations[ExitOnAppCompletionAnnotation]; ok {
+		if exit, err := strconv.ParseBool(value); err == nil {
+			return exit
+		}
+	}
+	return false
+}
+
+// injectAppCompletion configures the sidecar in spec to exit once the application containers of
+// podSpec complete. It returns false if the sidecar was not found.
+func injectAppCompletion(podSpec *corev1.PodSpec, spec *SidecarInjectionSpec) bool {
+	sidecar := FindSidecar(spec.Containers)
+	if sidecar == nil {
+		log.Errorf("sidecar not found in the template, skip injectAppCompletion")
+		return false
+	}
+	// Application containers which are not restarted complete on their first exit.
+	gracePeriod := "0s"
+	if podSpec.RestartPolicy != corev1.RestartPolicyNever {
+		gracePeriod = restartedAppCompletionGracePeriod
+	}
+	sidecar.Env = append(sidecar.Env,
+		corev1.EnvVar{Name: envoy.ExitOnAppCompletionEnvName, Value: "true"},
+		corev1.EnvVar{Name: envoy.AppCompletionGracePeriodEnvName, Value: gracePeriod})
+	return true
+}
+
+// rewriteAppCompletion modifies the pod spec in place for kube-inject.
+func rewriteAppCompletion(annotations map[string]string, podSpec *corev1.PodSpec, spec *SidecarInjectionSpec) {
+	if !ShouldExitOnAppCompletion(annotations) || !injectAppCompletion(podSpec, spec) {
+		return
+	}
+	shareProcessNamespace := true
+	podSpec.ShareProcessNamespace = &shareProcessNamespace
+}
+
+// createAppCompletionPatch generates the patch for webhook.
+func createAppCompletionPatch(annotations map[string]string, podSpec *corev1.PodSpec,
+	spec *SidecarInjectionSpec) []rfc6902PatchOperation {
+	if !ShouldExitOnAppCompletion(annotations) || !injectAppCompletion(podSpec, spec) {
+		return nil
+	}
+	return []rfc6902PatchOperation{{
+		Op:    "add",
+		Path:  "/spec/shareProcessNamespace",
+		Value: true,
+	}}
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package inject
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pkg/envoy"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestShouldExitOnAppCompletion(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		annotations map[string]string
+		expected    bool
+	}{
+		{"no-annotation", nil, false},
+		{"disabled-in-annotations", map[string]string{ExitOnAppCompletionAnnotation: "false"}, false},
+		{"enabled-in-annotations", map[string]string{ExitOnAppCompletionAnnotation: "true"}, true},
+		{"invalid-annotation", map[string]string{ExitOnAppCompletionAnnotation: "maybe"}, false},
+	} {
+		if got := ShouldExitOnAppCompletion(tc.annotations); got != tc.expected {
+			t.Errorf("[%v] failed, want %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestCreateAppCompletionPatch(t *testing.T) {
+	annotations := map[string]string{ExitOnAppCompletionAnnotation: "true"}
+	for _, tc := range []struct {
+		name          string
+		restartPolicy corev1.RestartPolicy
+		gracePeriod   string
+	}{
+		{"never-restart", corev1.RestartPolicyNever, "0s"},
+		{"restart-on-failure", corev1.RestartPolicyOnFailure, restartedAppCompletionGracePeriod},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			podSpec := &corev1.PodSpec{
+				RestartPolicy: tc.restartPolicy,
+				Containers: []corev1.Container{
+					{Name: "worker", Command: []string{"run"}},
+					// The entrypoint of the image, with arguments.
+					{Name: "helper", Args: []string{"--once"}},
+				},
+			}
+			original := podSpec.DeepCopy()
+			spec := &SidecarInjectionSpec{Containers: []corev1.Container{{Name: ProxyContainerName}}}
+
+			if patch := createAppCompletionPatch(nil, podSpec, spec); len(patch) != 0 {
+				t.Fatalf("expected no patch without the annotation, got %v", patch)
+			}
+
+			want := []rfc6902PatchOperation{{Op: "add", Path: "/spec/shareProcessNamespace", Value: true}}
+			if patch := createAppCompletionPatch(annotations, podSpec, spec); !reflect.DeepEqual(patch, want) {
+				t.Fatalf("want patch %v, got %v", want, patch)
+			}
+			// The application containers run unmodified, whatever their entrypoint.
+			if !reflect.DeepEqual(podSpec, original) {
+				t.Errorf("application containers modified: %v", podSpec.Containers)
+			}
+
+			wantEnv := []corev1.EnvVar{
+				{Name: envoy.ExitOnAppCompletionEnvName, Value: "true"},
+				{Name: envoy.AppCompletionGracePeriodEnvName, Value: tc.gracePeriod},
+			}
+			if env := spec.Containers[0].Env; !reflect.DeepEqual(env, wantEnv) {
+				t.Errorf("want sidecar env %v, got %v", wantEnv, env)
+			}
+		})
+	}
+}
+
+func TestRewriteAppCompletion(t *testing.T) {
+	podSpec := &corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+		Containers:    []corev1.Container{{Name: "helper", Args: []string{"--once"}}},
+	}
+	spec := &SidecarInjectionSpec{Containers: []corev1.Container{{Name: ProxyContainerName}}}
+
+	rewriteAppCompletion(map[string]string{ExitOnAppCompletionAnnotation: "true"}, podSpec, spec)
+	if podSpec.ShareProcessNamespace == nil || !*podSpec.ShareProcessNamespace {
+		t.Errorf("process namespace not shared: %v", podSpec.ShareProcessNamespace)
+	}
+	if c := podSpec.Containers[0]; len(c.Command) != 0 || !reflect.DeepEqual(c.Args, []string{"--once"}) {
+		t.Errorf("application container modified: %v", c)
+	}
+}
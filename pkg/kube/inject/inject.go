@@ -86,6 +86,8 @@ var (
 		annotation.SidecarTrafficExcludeInboundPorts.Name:         ValidateExcludeInboundPorts,
 		annotation.SidecarTrafficExcludeOutboundPorts.Name:        ValidateExcludeOutboundPorts,
 		annotation.SidecarTrafficKubevirtInterfaces.Name:          alwaysValidFunc,
+		TemplateAnnotation:                                        alwaysValidFunc,
+		ExitOnAppCompletionAnnotation:                             validateBool,
 	}
 )
 
@@ -267,6 +269,11 @@ type Config struct {
 	// expansion over the `SidecarTemplateData`.
 	Template string `json:"template"`
 
+	// Templates are additional named templates, selected per pod with the
+	// `sidecar.istio.io/template` annotation or per namespace with the
+	// `istio-injection-template` label. Template remains the default.
+	Templates map[string]string `json:"templates,omitempty"`
+
 	// NeverInjectSelector: Refuses the injection on pods whose labels match this selector.
 	// It's an array of label selectors, that will be OR'ed, meaning we will iterate
 	// over it and stop at the first match
@@ -281,6 +288,43 @@ type Config struct {
 	// InjectedAnnotations are additional annotations that will be added to the pod spec after injection
 	// This is primarily to support PSP annotations.
 	InjectedAnnotations map[string]string `json:"injectedAnnotations"`
+
+	// Revision is the revision of the control plane of the injector, set on the injected pods with the
+	// `istio.io/rev` label. The control plane installed without a revision leaves it empty.
+	Revision string `json:"revision,omitempty"`
+}
+
+const (
+	// TemplateAnnotation selects the named injection template for a pod.
+	TemplateAnnotation = "sidecar.istio.io/template"
+
+	// TemplateLabel selects the named injection template for all pods of a namespace.
+	TemplateLabel = "istio-injection-template"
+
+	// DefaultTemplateName refers to the default injection template, `Config.Template`.
+	DefaultTemplateName = "default"
+
+	// RevisionLabel identifies the revision of the control plane managing the proxy of an injected pod.
+	RevisionLabel = "istio.io/rev"
+)
+
+// SelectTemplate returns the name and content of the injection template to use for a pod. The pod
+// annotation takes precedence over the namespace label, and the default template is used when
+// neither is set. Selecting a template which does not exist is an error.
+func (c *Config) SelectTemplate(namespaceLabels map[string]string, metadata *metav1.ObjectMeta) (string, string, error) {
+	name := DefaultTemplateName
+	if n, ok := metadata.GetAnnotations()[TemplateAnnotation]; ok {
+		name = n
+	} else if n, ok := namespaceLabels[TemplateLabel]; ok {
+		name = n
+	}
+	if name == DefaultTemplateName {
+		return name, c.Template, nil
+	}
+	if tmpl, ok := c.Templates[name]; ok {
+		return name, tmpl, nil
+	}
+	return "", "", fmt.Errorf("unknown injection template %q", name)
 }
 
 func validateCIDRList(cidrs string) error {
@@ -656,7 +700,7 @@ func parseTemplate(tmplStr string, funcMap map[string]interface{}, data SidecarT
 
 // IntoResourceFile injects the istio proxy into the specified
 // kubernetes YAML file.
-func IntoResourceFile(sidecarTemplate string, valuesConfig string, meshconfig *meshconfig.MeshConfig, in io.Reader, out io.Writer) error {
+func IntoResourceFile(injectConfig *Config, valuesConfig string, meshconfig *meshconfig.MeshConfig, in io.Reader, out io.Writer) error {
 	reader := yamlDecoder.NewYAMLReader(bufio.NewReaderSize(in, 4096))
 	for {
 		raw, err := reader.Read()
@@ -674,7 +718,7 @@ func IntoResourceFile(sidecarTemplate string, valuesConfig string, meshconfig *m
 
 		var updated []byte
 		if err == nil {
-			outObject, err := IntoObject(sidecarTemplate, valuesConfig, meshconfig, obj) // nolint: vetshadow
+			outObject, err := IntoObject(injectConfig, valuesConfig, meshconfig, obj) // nolint: vetshadow
 			if err != nil {
 				return err
 			}
@@ -715,7 +759,7 @@ func FromRawToObject(raw []byte) (runtime.Object, error) {
 }
 
 // IntoObject convert the incoming resources into Injected resources
-func IntoObject(sidecarTemplate string, valuesConfig string, meshconfig *meshconfig.MeshConfig, in runtime.Object) (interface{}, error) {
+func IntoObject(injectConfig *Config, valuesConfig string, meshconfig *meshconfig.MeshConfig, in runtime.Object) (interface{}, error) {
 	out := in.DeepCopyObject()
 
 	var deploymentMetadata *metav1.ObjectMeta
@@ -736,7 +780,7 @@ func IntoObject(sidecarTemplate string, valuesConfig string, meshconfig *meshcon
 				return nil, err
 			}
 
-			r, err := IntoObject(sidecarTemplate, valuesConfig, meshconfig, obj) // nolint: vetshadow
+			r, err := IntoObject(injectConfig, valuesConfig, meshconfig, obj) // nolint: vetshadow
 			if err != nil {
 				return nil, err
 			}
@@ -817,6 +861,12 @@ func IntoObject(sidecarTemplate string, valuesConfig string, meshconfig *meshcon
 		}
 	}
 
+	// kube-inject works offline, so only the annotation can select a template.
+	_, sidecarTemplate, err := injectConfig.SelectTemplate(nil, metadata)
+	if err != nil {
+		return nil, err
+	}
+
 	spec, status, err := InjectionData(
 		sidecarTemplate,
 		valuesConfig,
@@ -831,6 +881,9 @@ func IntoObject(sidecarTemplate string, valuesConfig string, meshconfig *meshcon
 		return nil, err
 	}
 
+	// Configure the sidecar to exit with the application before it is appended.
+	rewriteAppCompletion(metadata.Annotations, podSpec, spec)
+
 	podSpec.InitContainers = append(podSpec.InitContainers, spec.InitContainers...)
 
 	podSpec.Containers = append(podSpec.Containers, spec.Containers...)
@@ -1043,6 +1096,12 @@ func sidecarTemplateVersionHash(in string) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// TemplateVersion returns the version recorded in the SidecarInjectionStatus of the pods
+// injected with the given template.
+func TemplateVersion(template string) string {
+	return sidecarTemplateVersionHash(template)
+}
+
 func potentialPodName(metadata *metav1.ObjectMeta) string {
 	if metadata.Name != "" {
 		return metadata.Name
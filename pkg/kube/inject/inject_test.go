@@ -31,6 +31,7 @@ import (
 	"istio.io/istio/pkg/config/mesh"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -76,6 +77,67 @@ func TestImageName(t *testing.T) {
 	}
 }
 
+func TestSelectTemplate(t *testing.T) {
+	config := &Config{
+		Template: "default-template",
+		Templates: map[string]string{
+			"batch": "batch-template",
+			"debug": "debug-template",
+		},
+	}
+	cases := []struct {
+		name            string
+		namespaceLabels map[string]string
+		annotations     map[string]string
+		want            string
+		wantErr         bool
+	}{
+		{
+			name: "default",
+			want: "default-template",
+		},
+		{
+			name:            "namespace label",
+			namespaceLabels: map[string]string{TemplateLabel: "batch"},
+			want:            "batch-template",
+		},
+		{
+			name:            "annotation overrides namespace label",
+			namespaceLabels: map[string]string{TemplateLabel: "batch"},
+			annotations:     map[string]string{TemplateAnnotation: "debug"},
+			want:            "debug-template",
+		},
+		{
+			name:            "annotation selects default",
+			namespaceLabels: map[string]string{TemplateLabel: "batch"},
+			annotations:     map[string]string{TemplateAnnotation: DefaultTemplateName},
+			want:            "default-template",
+		},
+		{
+			name:        "unknown template",
+			annotations: map[string]string{TemplateAnnotation: "gateway"},
+			wantErr:     true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, got, err := config.SelectTemplate(c.namespaceLabels, &metav1.ObjectMeta{Annotations: c.annotations})
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("SelectTemplate() expected an error, got template %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectTemplate() returned an error: %v", err)
+			}
+			if got != c.want {
+				t.Errorf("SelectTemplate() got %q want %q", got, c.want)
+			}
+		})
+	}
+}
+
 func TestIntoResourceFile(t *testing.T) {
 	cases := []struct {
 		in                           string
@@ -364,6 +426,17 @@ func TestIntoResourceFile(t *testing.T) {
 			readinessPeriodSeconds:       DefaultReadinessPeriodSeconds,
 			readinessFailureThreshold:    DefaultReadinessFailureThreshold,
 		},
+		{
+			// Verifies that the sidecar exits with the application containers, whatever their entrypoint.
+			in:                           "job-exit-on-completion.yaml",
+			want:                         "job-exit-on-completion.yaml.injected",
+			includeIPRanges:              DefaultIncludeIPRanges,
+			includeInboundPorts:          DefaultIncludeInboundPorts,
+			statusPort:                   DefaultStatusPort,
+			readinessInitialDelaySeconds: DefaultReadinessInitialDelaySeconds,
+			readinessPeriodSeconds:       DefaultReadinessPeriodSeconds,
+			readinessFailureThreshold:    DefaultReadinessFailureThreshold,
+		},
 		{
 			in:                           "pod.yaml",
 			want:                         "pod.yaml.injected",
@@ -619,7 +692,7 @@ func TestIntoResourceFile(t *testing.T) {
 			}
 			defer func() { _ = in.Close() }()
 			var got bytes.Buffer
-			if err = IntoResourceFile(sidecarTemplate, valuesConfig, &m, in, &got); err != nil {
+			if err = IntoResourceFile(&Config{Template: sidecarTemplate}, valuesConfig, &m, in, &got); err != nil {
 				t.Fatalf("IntoResourceFile(%v) returned an error: %v", inputFilePath, err)
 			}
 
@@ -726,7 +799,7 @@ func TestRewriteAppProbe(t *testing.T) {
 			}
 			defer func() { _ = in.Close() }()
 			var got bytes.Buffer
-			if err = IntoResourceFile(sidecarTemplate, valuesConfig, &m, in, &got); err != nil {
+			if err = IntoResourceFile(&Config{Template: sidecarTemplate}, valuesConfig, &m, in, &got); err != nil {
 				t.Fatalf("IntoResourceFile(%v) returned an error: %v", inputFilePath, err)
 			}
 
@@ -830,7 +903,7 @@ func TestInvalidAnnotations(t *testing.T) {
 			}
 			defer func() { _ = in.Close() }()
 			var got bytes.Buffer
-			if err = IntoResourceFile(sidecarTemplate, valuesConfig, params.Mesh, in, &got); err == nil {
+			if err = IntoResourceFile(&Config{Template: sidecarTemplate}, valuesConfig, params.Mesh, in, &got); err == nil {
 				t.Fatalf("expected error")
 			} else if !strings.Contains(strings.ToLower(err.Error()), c.annotation) {
 				t.Fatalf("unexpected error: %v", err)
@@ -43,6 +43,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 var (
@@ -58,6 +61,8 @@ func init() {
 
 const (
 	watchDebounceDelay = 100 * time.Millisecond
+	// namespaceResyncPeriod is the resync period of the informer caching the namespaces
+	namespaceResyncPeriod = 10 * time.Minute
 )
 
 // Webhook implements a mutating webhook for automatic proxy injection.
@@ -81,6 +86,9 @@ type Webhook struct {
 	cert       *tls.Certificate
 	mon        *monitor
 	env        *model.Environment
+	client     kubernetes.Interface
+	// namespaces caches the namespaces whose labels select the injection template
+	namespaces coreinformers.NamespaceInformer
 }
 
 // env will be used for other things besides meshConfig - when webhook is running in Istiod it can take advantage
@@ -117,6 +125,9 @@ func loadConfig(injectFile, meshFile, valuesFile string, env *model.Environment)
 	log.Debugf("AlwaysInjectSelector: %v", c.AlwaysInjectSelector)
 	log.Debugf("NeverInjectSelector: %v", c.NeverInjectSelector)
 	log.Debugf("Template: |\n  %v", strings.Replace(c.Template, "\n", "\n  ", -1))
+	for name, tmpl := range c.Templates {
+		log.Debugf("Template %s: |\n  %v", name, strings.Replace(tmpl, "\n", "\n  ", -1))
+	}
 
 	return &c, meshConfig, string(valuesConfig), nil
 }
@@ -155,6 +166,10 @@ type WebhookParameters struct {
 	HealthCheckFile string
 
 	Env *model.Environment
+
+	// Client is used to look up the namespace labels selecting the injection
+	// template. Without it, templates can only be selected by pod annotation.
+	Client kubernetes.Interface
 }
 
 // NewWebhook creates a new instance of a mutating webhook for automatic sidecar injection.
@@ -205,6 +220,9 @@ func NewWebhook(p WebhookParameters) (*Webhook, error) {
 		cert:                   &pair,
 		env:                    p.Env,
 	}
+	if p.Client != nil {
+		wh.setClient(p.Client)
+	}
 	// mtls disabled because apiserver webhook cert usage is still TBD.
 	wh.server.TLSConfig = &tls.Config{GetCertificate: wh.getCert}
 	h := http.NewServeMux()
@@ -231,8 +249,19 @@ func NewWebhook(p WebhookParameters) (*Webhook, error) {
 	return wh, nil
 }
 
+// setClient sets the client looking up the namespace labels, with an informer caching the namespaces.
+func (wh *Webhook) setClient(client kubernetes.Interface) {
+	wh.client = client
+	wh.namespaces = informers.NewSharedInformerFactory(client, namespaceResyncPeriod).Core().V1().Namespaces()
+	// Register the informer, so that the lister is backed by it.
+	wh.namespaces.Informer()
+}
+
 // Run implements the webhook server
 func (wh *Webhook) Run(stop <-chan struct{}) {
+	if wh.namespaces != nil {
+		go wh.namespaces.Informer().Run(stop)
+	}
 	go func() {
 		if err := wh.server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("admission webhook ListenAndServeTLS failed: %v", err)
@@ -467,8 +496,21 @@ func escapeJSONPointerValue(in string) string {
 
 // adds labels to the target spec, will not overwrite label's value if it already exists
 func addLabels(target map[string]string, added map[string]string) []rfc6902PatchOperation {
+	// To ensure deterministic patches, we sort the keys
+	keys := make([]string, 0, len(added))
+	for key := range added {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	existing := make(map[string]string, len(target))
+	for key, value := range target {
+		existing[key] = value
+	}
+
 	patches := []rfc6902PatchOperation{}
-	for key, value := range added {
+	for _, key := range keys {
+		value := added[key]
 		patch := rfc6902PatchOperation{
 			Op:    "add",
 			Path:  "/metadata/labels/" + escapeJSONPointerValue(key),
@@ -476,18 +518,18 @@ func addLabels(target map[string]string, added map[string]string) []rfc6902Patch
 		}
 
 		if target == nil {
-			target = map[string]string{}
+			target = existing
 			patch.Path = "/metadata/labels"
 			patch.Value = map[string]string{
 				key: value,
 			}
 		}
 
-		if target[key] == "" {
+		if existing[key] == "" {
 			patches = append(patches, patch)
+			existing[key] = value
 		}
 	}
-
 	return patches
 }
 
@@ -525,7 +567,8 @@ func updateAnnotation(target map[string]string, added map[string]string) (patch
 	return patch
 }
 
-func createPatch(pod *corev1.Pod, prevStatus *SidecarInjectionStatus, annotations map[string]string, sic *SidecarInjectionSpec) ([]byte, error) {
+func createPatch(pod *corev1.Pod, prevStatus *SidecarInjectionStatus, annotations, labels map[string]string,
+	sic *SidecarInjectionSpec) ([]byte, error) {
 	var patch []rfc6902PatchOperation
 
 	// Remove any containers previously injected by kube-inject using
@@ -552,8 +595,12 @@ func createPatch(pod *corev1.Pod, prevStatus *SidecarInjectionStatus, annotation
 	}
 	addAppProberCmd()
 
+	// Configure the sidecar to exit with the application before it is added.
+	appCompletionPatch := createAppCompletionPatch(pod.Annotations, &pod.Spec, sic)
+
 	patch = append(patch, addContainer(pod.Spec.InitContainers, sic.InitContainers, "/spec/initContainers")...)
 	patch = append(patch, addContainer(pod.Spec.Containers, sic.Containers, "/spec/containers")...)
+	patch = append(patch, appCompletionPatch...)
 	patch = append(patch, addVolume(pod.Spec.Volumes, sic.Volumes, "/spec/volumes")...)
 	patch = append(patch, addImagePullSecrets(pod.Spec.ImagePullSecrets, sic.ImagePullSecrets, "/spec/imagePullSecrets")...)
 
@@ -567,7 +614,7 @@ func createPatch(pod *corev1.Pod, prevStatus *SidecarInjectionStatus, annotation
 
 	patch = append(patch, updateAnnotation(pod.Annotations, annotations)...)
 
-	patch = append(patch, addLabels(pod.Labels, map[string]string{model.TLSModeLabelName: model.IstioMutualTLSModeLabel})...)
+	patch = append(patch, addLabels(pod.Labels, labels)...)
 
 	if rewrite {
 		patch = append(patch, createProbeRewritePatch(pod.Annotations, &pod.Spec, sic)...)
@@ -615,6 +662,26 @@ func injectionStatus(pod *corev1.Pod) *SidecarInjectionStatus {
 	}
 }
 
+// namespaceLabels returns the labels of the namespace, or nil if they cannot be retrieved. The namespace is read
+// from the informer cache, or from the API server until the cache is synced.
+func (wh *Webhook) namespaceLabels(namespace string) map[string]string {
+	if wh.namespaces == nil || namespace == "" {
+		return nil
+	}
+	var ns *corev1.Namespace
+	var err error
+	if wh.namespaces.Informer().HasSynced() {
+		ns, err = wh.namespaces.Lister().Get(namespace)
+	} else {
+		ns, err = wh.client.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	}
+	if err != nil {
+		log.Warnf("Could not get namespace %s, ignoring its injection template label: %v", namespace, err)
+		return nil
+	}
+	return ns.Labels
+}
+
 func toAdmissionResponse(err error) *v1beta1.AdmissionResponse {
 	return &v1beta1.AdmissionResponse{Result: &metav1.Status{Message: err.Error()}}
 }
@@ -698,7 +765,17 @@ func (wh *Webhook) inject(ar *v1beta1.AdmissionReview) *v1beta1.AdmissionRespons
 		deployMeta.Name = pod.Name
 	}
 
-	spec, iStatus, err := InjectionData(wh.Config.Template, wh.valuesConfig, wh.sidecarTemplateVersion, typeMetadata, deployMeta, &pod.Spec, &pod.ObjectMeta, wh.meshConfig.DefaultConfig, wh.meshConfig) // nolint: lll
+	templateName, sidecarTemplate, err := wh.Config.SelectTemplate(wh.namespaceLabels(req.Namespace), &pod.ObjectMeta)
+	if err != nil {
+		handleError(fmt.Sprintf("Injection template: err=%v", err))
+		return toAdmissionResponse(err)
+	}
+	version := wh.sidecarTemplateVersion
+	if templateName != DefaultTemplateName {
+		version = sidecarTemplateVersionHash(sidecarTemplate)
+	}
+
+	spec, iStatus, err := InjectionData(sidecarTemplate, wh.valuesConfig, version, typeMetadata, deployMeta, &pod.Spec, &pod.ObjectMeta, wh.meshConfig.DefaultConfig, wh.meshConfig) // nolint: lll
 	if err != nil {
 		handleError(fmt.Sprintf("Injection data: err=%v spec=%v\n", err, iStatus))
 		return toAdmissionResponse(err)
@@ -711,7 +788,12 @@ func (wh *Webhook) inject(ar *v1beta1.AdmissionReview) *v1beta1.AdmissionRespons
 		annotations[k] = v
 	}
 
-	patchBytes, err := createPatch(&pod, injectionStatus(&pod), annotations, spec)
+	labels := map[string]string{model.TLSModeLabelName: model.IstioMutualTLSModeLabel}
+	if wh.Config.Revision != "" {
+		labels[RevisionLabel] = wh.Config.Revision
+	}
+
+	patchBytes, err := createPatch(&pod, injectionStatus(&pod), annotations, labels, spec)
 	if err != nil {
 		handleError(fmt.Sprintf("AdmissionResponse: err=%v spec=%v\n", err, spec))
 		return toAdmissionResponse(err)
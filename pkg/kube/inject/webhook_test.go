@@ -45,6 +45,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/helm/pkg/chartutil"
 	"k8s.io/helm/pkg/engine"
 	"k8s.io/helm/pkg/proto/hapi/chart"
@@ -667,6 +669,188 @@ func TestWebhookInject(t *testing.T) {
 	}
 }
 
+func TestWebhookInjectTemplateSelection(t *testing.T) {
+	configYaml := `
+policy: enabled
+template: |
+  containers:
+  - name: istio-proxy
+    image: default-proxy
+templates:
+  batch: |
+    containers:
+    - name: istio-proxy
+      image: batch-proxy
+  debug: |
+    containers:
+    - name: istio-proxy
+      image: debug-proxy
+`
+	cases := []struct {
+		name        string
+		nsLabels    map[string]string
+		annotations map[string]string
+		wantImage   string
+		wantErr     bool
+	}{
+		{
+			name:      "default",
+			wantImage: "default-proxy",
+		},
+		{
+			name:      "namespace label",
+			nsLabels:  map[string]string{TemplateLabel: "batch"},
+			wantImage: "batch-proxy",
+		},
+		{
+			name:        "pod annotation",
+			nsLabels:    map[string]string{TemplateLabel: "batch"},
+			annotations: map[string]string{TemplateAnnotation: "debug"},
+			wantImage:   "debug-proxy",
+		},
+		{
+			name:        "unknown template",
+			annotations: map[string]string{TemplateAnnotation: "gateway"},
+			wantErr:     true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			wh, cleanup := createTestWebhook(t, configYaml)
+			defer cleanup()
+			wh.setClient(fake.NewSimpleClientset(&corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: c.nsLabels},
+			}))
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test", Annotations: c.annotations},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}},
+			}
+			podJSON, err := json.Marshal(pod)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := wh.inject(&v1beta1.AdmissionReview{
+				Request: &v1beta1.AdmissionRequest{
+					Namespace: "test",
+					Object:    runtime.RawExtension{Raw: podJSON},
+				},
+			})
+			if c.wantErr {
+				if got.Result == nil {
+					t.Fatalf("expected injection to fail, got patch %s", got.Patch)
+				}
+				return
+			}
+			if got.Result != nil {
+				t.Fatalf("injection failed: %v", got.Result.Message)
+			}
+			if !strings.Contains(string(got.Patch), c.wantImage) {
+				t.Errorf("expected patch to use image %q, got %s", c.wantImage, got.Patch)
+			}
+		})
+	}
+}
+
+func TestWebhookNamespaceLabelsCache(t *testing.T) {
+	wh, cleanup := createTestWebhook(t, "")
+	defer cleanup()
+
+	client := fake.NewSimpleClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: map[string]string{TemplateLabel: "batch"}},
+	})
+	wh.setClient(client)
+
+	// Until the cache is synced, the namespace is read from the API server.
+	if got := wh.namespaceLabels("test")[TemplateLabel]; got != "batch" {
+		t.Fatalf("got template label %q before the cache is synced, want batch", got)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go wh.namespaces.Informer().Run(stop)
+	if !cache.WaitForCacheSync(stop, wh.namespaces.Informer().HasSynced) {
+		t.Fatal("namespace cache not synced")
+	}
+	client.ClearActions()
+
+	if got := wh.namespaceLabels("test")[TemplateLabel]; got != "batch" {
+		t.Fatalf("got template label %q, want batch", got)
+	}
+	if got := wh.namespaceLabels("missing"); got != nil {
+		t.Fatalf("got labels %v for a missing namespace, want none", got)
+	}
+	for _, a := range client.Actions() {
+		if a.GetVerb() == "get" {
+			t.Fatalf("namespace read from the API server once the cache is synced: %v", a)
+		}
+	}
+}
+
+func TestWebhookInjectRevisionLabel(t *testing.T) {
+	cases := []struct {
+		name      string
+		revision  string
+		podLabels map[string]string
+		wantPatch []string
+		skipPatch string
+	}{
+		{
+			name:      "default revision",
+			wantPatch: []string{`"path":"/metadata/labels","value":{"security.istio.io/tlsMode":"istio"}`},
+			skipPatch: RevisionLabel,
+		},
+		{
+			name:     "canary revision",
+			revision: "canary",
+			wantPatch: []string{
+				`"path":"/metadata/labels","value":{"istio.io/rev":"canary"}`,
+				`"path":"/metadata/labels/security.istio.io~1tlsMode","value":"istio"`,
+			},
+		},
+		{
+			name:      "existing labels",
+			revision:  "canary",
+			podLabels: map[string]string{"app": "app"},
+			wantPatch: []string{`"path":"/metadata/labels/istio.io~1rev","value":"canary"`},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			configYaml := "policy: enabled\ntemplate: |\n  containers:\n  - name: istio-proxy\n    image: proxy\n"
+			if c.revision != "" {
+				configYaml += "revision: " + c.revision + "\n"
+			}
+			wh, cleanup := createTestWebhook(t, configYaml)
+			defer cleanup()
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test", Labels: c.podLabels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}},
+			}
+			podJSON, err := json.Marshal(pod)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := wh.inject(&v1beta1.AdmissionReview{
+				Request: &v1beta1.AdmissionRequest{
+					Namespace: "test",
+					Object:    runtime.RawExtension{Raw: podJSON},
+				},
+			})
+			if got.Result != nil {
+				t.Fatalf("injection failed: %v", got.Result.Message)
+			}
+			for _, want := range c.wantPatch {
+				if !strings.Contains(string(got.Patch), want) {
+					t.Errorf("expected patch to contain %s, got %s", want, got.Patch)
+				}
+			}
+			if c.skipPatch != "" && strings.Contains(string(got.Patch), c.skipPatch) {
+				t.Errorf("expected patch not to contain %s, got %s", c.skipPatch, got.Patch)
+			}
+		})
+	}
+}
+
 // TestHelmInject tests the webhook injector with the installation configmap.yaml. It runs through many of the
 // same tests as TestIntoResourceFile in order to verify that the webhook performs the same way as the manual injector.
 func TestHelmInject(t *testing.T) {
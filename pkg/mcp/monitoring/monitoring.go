@@ -88,6 +88,14 @@ var (
 		"The number of times the sink has reconnected.",
 		monitoring.WithLabels(componentTag),
 	)
+
+	// collectionStale is 1 for the collections whose resources were loaded from the cache of the sink and are not
+	// synchronized with the source yet.
+	collectionStale = monitoring.NewGauge(
+		"istio_mcp_collection_stale",
+		"Whether the resources of the collection are loaded from the cache of the sink and not synchronized with the source yet.",
+		monitoring.WithLabels(componentTag, collectionTag),
+	)
 )
 
 // StatsContext enables metric collection backed by OpenCensus.
@@ -99,6 +107,7 @@ type StatsContext struct {
 	sendFailuresTotal        monitoring.Metric
 	recvFailuresTotal        monitoring.Metric
 	streamCreateSuccessTotal monitoring.Metric
+	collectionStale          monitoring.Metric
 }
 
 // Reporter is used to report metrics for an MCP server.
@@ -113,6 +122,7 @@ type Reporter interface {
 
 	SetStreamCount(clients int64)
 	RecordStreamCreateSuccess()
+	SetCollectionStale(collection string, stale bool)
 }
 
 var (
@@ -174,6 +184,18 @@ func (s *StatsContext) RecordStreamCreateSuccess() {
 	s.streamCreateSuccessTotal.Increment()
 }
 
+// SetCollectionStale records whether the resources of a collection are loaded from the cache of the sink and not
+// synchronized with the source yet.
+func (s *StatsContext) SetCollectionStale(collection string, stale bool) {
+	v := 0.0
+	if stale {
+		v = 1
+	}
+	s.collectionStale.With(
+		collectionTag.Value(collection),
+	).Record(v)
+}
+
 func (s *StatsContext) Close() error {
 	return nil
 }
@@ -191,6 +213,7 @@ func NewStatsContext(componentName string) *StatsContext {
 		sendFailuresTotal:        sendFailuresTotal.With(componentTag.Value(componentName)),
 		recvFailuresTotal:        recvFailuresTotal.With(componentTag.Value(componentName)),
 		streamCreateSuccessTotal: streamCreateSuccessTotal.With(componentTag.Value(componentName)),
+		collectionStale:          collectionStale.With(componentTag.Value(componentName)),
 	}
 
 	return ctx
@@ -205,5 +228,6 @@ func init() {
 		sendFailuresTotal,
 		recvFailuresTotal,
 		streamCreateSuccessTotal,
+		collectionStale,
 	)
 }
@@ -0,0 +1,97 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/gogo/protobuf/proto"
+
+	mcp "istio.io/api/mcp/v1alpha1"
+)
+
+// Cache persists the last ACK'd resources of the collections of a sink. On startup, the sink applies the cached
+// resources until it is synchronized with the source, e.g. when the source is unreachable.
+type Cache interface {
+	// Load returns the full state of the collection last saved, or nil if there is none.
+	Load(collection string) (*mcp.Resources, error)
+
+	// Save saves the full state of the collection.
+	Save(resources *mcp.Resources) error
+}
+
+// FileCache is a Cache storing the resources of each collection in a file of a directory.
+type FileCache struct {
+	dir string
+}
+
+var _ Cache = &FileCache{}
+
+// NewFileCache returns a FileCache storing the resources in the directory, which is created if needed.
+func NewFileCache(dir string) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileCache{dir: dir}, nil
+}
+
+func (c *FileCache) path(collection string) string {
+	return filepath.Join(c.dir, url.PathEscape(collection)+".pb")
+}
+
+// Load implements Cache.
+func (c *FileCache) Load(collection string) (*mcp.Resources, error) {
+	data, err := ioutil.ReadFile(c.path(collection))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	resources := &mcp.Resources{}
+	if err := proto.Unmarshal(data, resources); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// Save implements Cache. The file of the collection is replaced atomically, so that a crash doesn't corrupt it.
+func (c *FileCache) Save(resources *mcp.Resources) error {
+	data, err := proto.Marshal(resources)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(c.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), c.path(resources.Collection)); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	mcp "istio.io/api/mcp/v1alpha1"
+	"istio.io/istio/pkg/mcp/internal/test"
+	"istio.io/istio/pkg/mcp/testing/monitoring"
+)
+
+func newTestFileCache(t *testing.T) (*FileCache, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "mcp-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewFileCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestFileCache(t *testing.T) {
+	c, cleanup := newTestFileCache(t)
+	defer cleanup()
+
+	got, err := c.Load(test.FakeType0Collection)
+	if err != nil || got != nil {
+		t.Fatalf("Load() of a missing collection => got %v, %v want nil, nil", got, err)
+	}
+
+	want := test.MakeResources(false, test.FakeType0Collection, "v1", "", nil, test.Type0A[0], test.Type0B[0])
+	if err := c.Save(want); err != nil {
+		t.Fatalf("Save() => got %v want no error", err)
+	}
+	got, err = c.Load(test.FakeType0Collection)
+	if err != nil {
+		t.Fatalf("Load() => got %v want no error", err)
+	}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("Load() => got %v want %v", got, want)
+	}
+}
+
+func TestSinkCache(t *testing.T) {
+	c, cleanup := newTestFileCache(t)
+	defer cleanup()
+	options := func(u Updater, r *monitoring.InMemoryStatsContext) *Options {
+		return &Options{
+			CollectionOptions: []CollectionOptions{{Name: test.FakeType0Collection, Incremental: true}},
+			Updater:           u,
+			ID:                test.NodeID,
+			Reporter:          r,
+			Cache:             c,
+		}
+	}
+	names := func(objects []*Object) map[string]string {
+		m := make(map[string]string, len(objects))
+		for _, o := range objects {
+			m[o.Metadata.Name] = o.Metadata.Version
+		}
+		return m
+	}
+
+	// the ACK'd resources are saved ...
+	s := New(options(NewInMemoryUpdater(), monitoring.NewInMemoryStatsContext()))
+	s.applyCache()
+	for _, resources := range []*mcp.Resources{
+		test.MakeResources(false, test.FakeType0Collection, "v1", "n1", nil, test.Type0A[0], test.Type0B[0]),
+		test.MakeResources(true, test.FakeType0Collection, "v2", "n2", []string{test.Type0A[0].Metadata.Name}, test.Type0C[0]),
+	} {
+		if req := s.handleResponse(resources); req.ErrorDetail != nil {
+			t.Fatalf("handleResponse() => got NACK %v", req.ErrorDetail)
+		}
+	}
+	s.saveCache()
+
+	// ... applied to the updater of a new sink and marked as stale ...
+	u := NewInMemoryUpdater()
+	r := monitoring.NewInMemoryStatsContext()
+	s = New(options(u, r))
+	if len(u.Get(test.FakeType0Collection)) != 0 {
+		t.Fatal("cache applied before the sink is started")
+	}
+	s.applyCache()
+	want := map[string]string{
+		test.Type0B[0].Metadata.Name: test.Type0B[0].Metadata.Version,
+		test.Type0C[0].Metadata.Name: test.Type0C[0].Metadata.Version,
+	}
+	if diff := cmp.Diff(names(u.Get(test.FakeType0Collection)), want); diff != "" {
+		t.Fatalf("wrong objects applied from the cache: %v", diff)
+	}
+	if !r.CollectionStale[test.FakeType0Collection] {
+		t.Fatal("collection loaded from the cache not marked as stale")
+	}
+	reqs := s.createInitialRequests()
+	if len(reqs) != 1 || !cmp.Equal(reqs[0].InitialResourceVersions, want) {
+		t.Fatalf("wrong initial requests: got %v want initial resource versions %v", reqs, want)
+	}
+
+	// ... until the sink is synchronized with the source.
+	if req := s.handleResponse(test.MakeResources(false, test.FakeType0Collection, "v3", "n1", nil, test.Type0B[1])); req.ErrorDetail != nil {
+		t.Fatalf("handleResponse() => got NACK %v", req.ErrorDetail)
+	}
+	if r.CollectionStale[test.FakeType0Collection] {
+		t.Fatal("collection still marked as stale after synchronization")
+	}
+	s.saveCache()
+	got, err := c.Load(test.FakeType0Collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(got, test.MakeResources(false, test.FakeType0Collection, "v3", "", nil, test.Type0B[1])) {
+		t.Fatalf("wrong resources saved: %v", got)
+	}
+}
+
+type recordingCache struct {
+	mu    sync.Mutex
+	saved []*mcp.Resources
+}
+
+func (c *recordingCache) Load(string) (*mcp.Resources, error) {
+	return nil, nil
+}
+
+func (c *recordingCache) Save(resources *mcp.Resources) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saved = append(c.saved, resources)
+	return nil
+}
+
+func (c *recordingCache) get() []*mcp.Resources {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*mcp.Resources(nil), c.saved...)
+}
+
+func TestSinkCache_SavesChangesAtOnce(t *testing.T) {
+	prevDelay := cacheSaveDelay
+	cacheSaveDelay = 200 * time.Millisecond
+	defer func() { cacheSaveDelay = prevDelay }()
+
+	c := &recordingCache{}
+	s := New(&Options{
+		CollectionOptions: []CollectionOptions{{Name: test.FakeType0Collection, Incremental: true}},
+		Updater:           NewInMemoryUpdater(),
+		ID:                test.NodeID,
+		Reporter:          monitoring.NewInMemoryStatsContext(),
+		Cache:             c,
+	})
+	for _, resources := range []*mcp.Resources{
+		test.MakeResources(false, test.FakeType0Collection, "v1", "n1", nil, test.Type0A[0], test.Type0B[0]),
+		test.MakeResources(true, test.FakeType0Collection, "v2", "n2", nil, test.Type0C[0]),
+		test.MakeResources(true, test.FakeType0Collection, "v3", "n3", []string{test.Type0A[0].Metadata.Name}),
+	} {
+		if req := s.handleResponse(resources); req.ErrorDetail != nil {
+			t.Fatalf("handleResponse() => got NACK %v", req.ErrorDetail)
+		}
+	}
+	if saved := c.get(); len(saved) != 0 {
+		t.Fatalf("collection saved while handling the responses: %v", saved)
+	}
+
+	want := test.MakeResources(false, test.FakeType0Collection, "v3", "", nil, test.Type0B[0], test.Type0C[0])
+	deadline := time.Now().Add(5 * time.Second)
+	for len(c.get()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("collection not saved")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if saved := c.get(); len(saved) != 1 || !cmp.Equal(saved[0], want) {
+		t.Fatalf("wrong resources saved: got %v want %v", saved, want)
+	}
+}
@@ -53,6 +53,9 @@ func NewClient(client mcp.ResourceSourceClient, options *Options) *Client {
 var reconnectTestProbe = func() {}
 
 func (c *Client) Run(ctx context.Context) {
+	// Start from the cached resources while the source is not reachable.
+	c.applyCache()
+
 	// The first attempt is immediate.
 	retryDelay := time.Nanosecond
 
@@ -18,6 +18,7 @@ import (
 	"io"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/gogo/protobuf/types"
@@ -38,6 +39,16 @@ type perCollectionState struct {
 
 	// determines when incremental delivery is enabled for this collection
 	requestIncremental bool
+
+	// the full state of the ACK'd resources by name, only tracked when the sink has a cache
+	resources map[string]*mcp.Resource
+
+	// true when the resources were loaded from the cache and are not synchronized with the source yet
+	stale bool
+
+	// the version of the resources, and whether they changed since they were last saved to the cache
+	cacheVersion string
+	cacheChanged bool
 }
 
 // Sink implements the resource sink message exchange for MCP. It can be instantiated by client and server
@@ -51,6 +62,13 @@ type Sink struct {
 	journal  *RecentRequestsJournal
 	metadata map[string]string
 	reporter monitoring.Reporter
+	cache    Cache
+
+	// applies the cache once, when the sink starts
+	cacheOnce sync.Once
+	// the pending save of the changed collections to the cache, if any
+	cacheTimer  *time.Timer
+	cacheSaveMu sync.Mutex
 }
 
 // New creates a new resource sink.
@@ -68,13 +86,120 @@ func New(options *Options) *Sink { // nolint: lll
 		}
 	}
 
-	return &Sink{
+	sink := &Sink{
 		state:    state,
 		nodeInfo: nodeInfo,
 		updater:  options.Updater,
 		metadata: options.Metadata,
 		reporter: options.Reporter,
 		journal:  NewRequestJournal(),
+		cache:    options.Cache,
+	}
+	return sink
+}
+
+// applyCache applies the resources of the cache to the updater, the first time it's called. They are marked as stale
+// until the sink is synchronized with the source.
+func (sink *Sink) applyCache() {
+	if sink.cache != nil {
+		sink.cacheOnce.Do(sink.loadCache)
+	}
+}
+
+func (sink *Sink) loadCache() {
+	for collection, state := range sink.state {
+		resources, err := sink.cache.Load(collection)
+		if err != nil {
+			scope.Warnf("MCP: failed loading collection %v from the cache: %v", collection, err)
+			continue
+		}
+		if resources == nil {
+			continue
+		}
+		resources.Incremental = false
+
+		change, err := toChange(resources)
+		if err == nil {
+			err = sink.updater.Apply(change)
+		}
+		if err != nil {
+			scope.Warnf("MCP: failed applying collection %v from the cache: %v", collection, err)
+			continue
+		}
+
+		internal.UpdateResourceVersionTracking(state.versions, resources)
+		state.resources = make(map[string]*mcp.Resource, len(resources.Resources))
+		for i := range resources.Resources {
+			r := &resources.Resources[i]
+			state.resources[r.Metadata.Name] = r
+		}
+		state.stale = true
+		sink.reporter.SetCollectionStale(collection, true)
+		scope.Infof("MCP: applied %d resources of collection %v with version %q from the cache",
+			len(resources.Resources), collection, resources.SystemVersionInfo)
+	}
+}
+
+// cacheSaveDelay is the delay after which the changed collections are saved to the cache, so that a burst of changes
+// is saved at once, off the processing of the responses.
+var cacheSaveDelay = time.Second
+
+// updateCache tracks the full state of the collection once the resources are applied, and schedules its save.
+func (sink *Sink) updateCache(state *perCollectionState, resources *mcp.Resources) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	if !resources.Incremental || state.resources == nil {
+		state.resources = make(map[string]*mcp.Resource, len(resources.Resources))
+	}
+	for i := range resources.Resources {
+		r := &resources.Resources[i]
+		state.resources[r.Metadata.Name] = r
+	}
+	for _, name := range resources.RemovedResources {
+		delete(state.resources, name)
+	}
+	state.cacheVersion = resources.SystemVersionInfo
+	state.cacheChanged = true
+
+	if sink.cacheTimer == nil {
+		sink.cacheTimer = time.AfterFunc(cacheSaveDelay, sink.saveCache)
+	}
+}
+
+// saveCache saves the full state of the collections changed since the last save.
+func (sink *Sink) saveCache() {
+	// saves run one at a time, so that an older state never replaces a newer one.
+	sink.cacheSaveMu.Lock()
+	defer sink.cacheSaveMu.Unlock()
+
+	sink.mu.Lock()
+	sink.cacheTimer = nil
+	var changed []*mcp.Resources
+	for collection, state := range sink.state {
+		if !state.cacheChanged {
+			continue
+		}
+		state.cacheChanged = false
+		full := &mcp.Resources{
+			Collection:        collection,
+			SystemVersionInfo: state.cacheVersion,
+			Resources:         make([]mcp.Resource, 0, len(state.resources)),
+		}
+		for _, r := range state.resources {
+			full.Resources = append(full.Resources, *r)
+		}
+		changed = append(changed, full)
+	}
+	sink.mu.Unlock()
+
+	for _, full := range changed {
+		sort.Slice(full.Resources, func(i, j int) bool {
+			return full.Resources[i].Metadata.Name < full.Resources[j].Metadata.Name
+		})
+		if err := sink.cache.Save(full); err != nil {
+			scope.Warnf("MCP: failed saving collection %v to the cache: %v", full.Collection, err)
+		}
 	}
 }
 
@@ -87,6 +212,8 @@ func (sink *Sink) sendNACKRequest(response *mcp.Resources, err error) *mcp.Reque
 	scope.Errorf("MCP: sending NACK for nonce=%v: error=%q", response.Nonce, err)
 	sink.reporter.RecordRequestNack(response.Collection, 0, errorDetails.Code())
 
+	// Incremental is not requested: the source falls back to the full state, whose
+	// application doesn't depend on the (unknown) state of the updater.
 	req := &mcp.RequestResources{
 		SinkNode:      sink.nodeInfo,
 		Collection:    response.Collection,
@@ -107,27 +234,15 @@ func (sink *Sink) handleResponse(resources *mcp.Resources) *mcp.RequestResources
 		return sink.sendNACKRequest(resources, errDetails)
 	}
 
-	change := &Change{
-		Collection:        resources.Collection,
-		Objects:           make([]*Object, 0, len(resources.Resources)),
-		Removed:           resources.RemovedResources,
-		Incremental:       resources.Incremental,
-		SystemVersionInfo: resources.SystemVersionInfo,
+	if resources.Incremental && !state.requestIncremental {
+		errDetails := status.Errorf(codes.InvalidArgument, "incremental update not requested for collection %v",
+			resources.Collection)
+		return sink.sendNACKRequest(resources, errDetails)
 	}
 
-	for _, resource := range resources.Resources {
-		var dynamicAny types.DynamicAny
-		if err := types.UnmarshalAny(resource.Body, &dynamicAny); err != nil {
-			return sink.sendNACKRequest(resources, err)
-		}
-
-		// TODO - use galley metadata to verify collection and type_url match?
-		object := &Object{
-			TypeURL:  resource.Body.TypeUrl,
-			Metadata: resource.Metadata,
-			Body:     dynamicAny.Message,
-		}
-		change.Objects = append(change.Objects, object)
+	change, err := toChange(resources)
+	if err != nil {
+		return sink.sendNACKRequest(resources, err)
 	}
 
 	if err := sink.updater.Apply(change); err != nil {
@@ -139,8 +254,17 @@ func (sink *Sink) handleResponse(resources *mcp.Resources) *mcp.RequestResources
 	sink.mu.Lock()
 	internal.UpdateResourceVersionTracking(state.versions, resources)
 	useIncremental := state.requestIncremental
+	stale := state.stale
+	state.stale = false
 	sink.mu.Unlock()
 
+	if sink.cache != nil {
+		sink.updateCache(state, resources)
+	}
+	if stale {
+		sink.reporter.SetCollectionStale(resources.Collection, false)
+	}
+
 	// ACK
 	sink.reporter.RecordRequestAck(resources.Collection, 0)
 	req := &mcp.RequestResources{
@@ -152,6 +276,33 @@ func (sink *Sink) handleResponse(resources *mcp.Resources) *mcp.RequestResources
 	return req
 }
 
+// toChange decodes the resources of a response into a change for the updater.
+func toChange(resources *mcp.Resources) (*Change, error) {
+	change := &Change{
+		Collection:        resources.Collection,
+		Objects:           make([]*Object, 0, len(resources.Resources)),
+		Removed:           resources.RemovedResources,
+		Incremental:       resources.Incremental,
+		SystemVersionInfo: resources.SystemVersionInfo,
+	}
+
+	for _, resource := range resources.Resources {
+		var dynamicAny types.DynamicAny
+		if err := types.UnmarshalAny(resource.Body, &dynamicAny); err != nil {
+			return nil, err
+		}
+
+		// TODO - use galley metadata to verify collection and type_url match?
+		object := &Object{
+			TypeURL:  resource.Body.TypeUrl,
+			Metadata: resource.Metadata,
+			Body:     dynamicAny.Message,
+		}
+		change.Objects = append(change.Objects, object)
+	}
+	return change, nil
+}
+
 func (sink *Sink) createInitialRequests() []*mcp.RequestResources {
 	sink.mu.Lock()
 
@@ -183,6 +334,8 @@ func (sink *Sink) createInitialRequests() []*mcp.RequestResources {
 // stream interface and returns when a send or receive error occurs. The caller is responsible for
 // handling gRPC client/server specific error handling.
 func (sink *Sink) ProcessStream(stream Stream) error {
+	sink.applyCache()
+
 	// send initial requests for each supported type
 	initialRequests := sink.createInitialRequests()
 	for {
@@ -292,6 +445,7 @@ type Updater interface {
 }
 
 // InMemoryUpdater is an implementation of Updater that keeps a simple in-memory state.
+// Incremental changes are applied to the objects of the previous changes.
 type InMemoryUpdater struct {
 	items      map[string][]*Object
 	itemsMutex sync.Mutex
@@ -310,7 +464,25 @@ func NewInMemoryUpdater() *InMemoryUpdater {
 func (u *InMemoryUpdater) Apply(c *Change) error {
 	u.itemsMutex.Lock()
 	defer u.itemsMutex.Unlock()
-	u.items[c.Collection] = c.Objects
+	if !c.Incremental {
+		u.items[c.Collection] = c.Objects
+		return nil
+	}
+
+	changed := make(map[string]struct{}, len(c.Objects)+len(c.Removed))
+	for _, o := range c.Objects {
+		changed[o.Metadata.Name] = struct{}{}
+	}
+	for _, name := range c.Removed {
+		changed[name] = struct{}{}
+	}
+	objects := make([]*Object, 0, len(u.items[c.Collection])+len(c.Objects))
+	for _, o := range u.items[c.Collection] {
+		if _, ok := changed[o.Metadata.Name]; !ok {
+			objects = append(objects, o)
+		}
+	}
+	u.items[c.Collection] = append(objects, c.Objects...)
 	return nil
 }
 
@@ -351,6 +523,10 @@ type Options struct {
 	ID                string
 	Metadata          map[string]string
 	Reporter          monitoring.Reporter
+
+	// Cache persists the last ACK'd resources of each collection, applied to the Updater on startup until the sink
+	// is synchronized with the source. Nothing is persisted if nil.
+	Cache Cache
 }
 
 // Stream is for sending RequestResources messages and receiving Resource messages.
@@ -453,6 +453,11 @@ func TestSinkNACK(t *testing.T) {
 			resources:   test.MakeResources(false, test.FakeType0Collection, "type0/v2", "type0/n2", nil, test.BadUnmarshal),
 			wantRequest: test.MakeRequest(false, test.FakeType0Collection, "type0/n2", codes.Unknown),
 		},
+		{
+			name:        "NACK incremental not requested",
+			resources:   test.MakeResources(true, test.FakeType0Collection, "type0/v3", "type0/n3", nil, test.Type0A[1]),
+			wantRequest: test.MakeRequest(false, test.FakeType0Collection, "type0/n3", codes.InvalidArgument),
+		},
 		{
 			name:        "NACK updater rejected change",
 			resources:   test.MakeResources(false, test.FakeType0Collection, "type0/v4", "type0/n4", nil, test.Type0A[1]),
@@ -651,6 +656,45 @@ func TestInMemoryUpdater(t *testing.T) {
 	}
 }
 
+func TestInMemoryUpdater_Incremental(t *testing.T) {
+	u := NewInMemoryUpdater()
+
+	object := func(name, version string) *Object {
+		return &Object{
+			TypeURL:  "foo",
+			Metadata: &mcp.Metadata{Name: name, Version: version},
+			Body:     &types.Empty{},
+		}
+	}
+	names := func(objects []*Object) map[string]string {
+		m := make(map[string]string, len(objects))
+		for _, o := range objects {
+			m[o.Metadata.Name] = o.Metadata.Version
+		}
+		return m
+	}
+
+	if err := u.Apply(&Change{
+		Collection: "foo",
+		Objects:    []*Object{object("a", "1"), object("b", "1"), object("c", "1")},
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := u.Apply(&Change{
+		Collection:  "foo",
+		Objects:     []*Object{object("b", "2"), object("d", "1")},
+		Removed:     []string{"c"},
+		Incremental: true,
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := map[string]string{"a": "1", "b": "2", "d": "1"}
+	if diff := cmp.Diff(names(u.Get("foo")), want); diff != "" {
+		t.Fatalf("wrong items in updater: \n got %v \nwant %v \ndiff %v", names(u.Get("foo")), want, diff)
+	}
+}
+
 func TestSink_MetadataID(t *testing.T) {
 	options := &Options{
 		CollectionOptions: CollectionOptionsFromSlice(test.SupportedCollections),
@@ -143,6 +143,12 @@ type watch struct {
 	ackedVersionMap map[string]string // resources that exist at the sink; by name and version
 	pending         *mcp.Resources
 	incremental     bool
+
+	// fullStateRequired is set when the sink connected or NACK'd a response. The
+	// state of the sink is unknown until the next full-state update is ACK'd: the
+	// versions it may already have can name different resources, e.g. after a
+	// restart of the source.
+	fullStateRequired bool
 }
 
 // connection maintains per-stream connection state for a
@@ -206,8 +212,9 @@ func (s *Source) newConnection(stream Stream) *connection {
 	for i := range s.collections {
 		collection := s.collections[i]
 		w := &watch{
-			ackedVersionMap: make(map[string]string),
-			incremental:     collection.Incremental,
+			ackedVersionMap:   make(map[string]string),
+			incremental:       collection.Incremental,
+			fullStateRequired: true,
 		}
 		con.watches[collection.Name] = w
 		collections = append(collections, collection.Name)
@@ -324,8 +331,9 @@ func (con *connection) pushServerResponse(w *watch, resp *WatchResponse) error {
 
 	// send an incremental update if enabled for this collection and the most
 	// recent request from the sink requested it.
+	// Fall back to a full-state update after connecting or a NACK.
 	var incremental bool
-	if w.incremental && resp.Request.incremental {
+	if w.incremental && resp.Request.incremental && !w.fullStateRequired {
 		incremental = true
 	}
 
@@ -355,6 +363,9 @@ func (con *connection) pushServerResponse(w *watch, resp *WatchResponse) error {
 	scope.Debugf("MCP: connection %v: SEND collection=%v version=%v nonce=%v inc=%v",
 		con, resp.Collection, resp.Version, msg.Nonce, msg.Incremental)
 	w.pending = msg
+	if !incremental {
+		w.fullStateRequired = false
+	}
 	return nil
 }
 
@@ -422,6 +433,9 @@ func (con *connection) processClientRequest(req *mcp.RequestResources) error {
 				scope.Warnf("MCP: connection %v: NACK collection=%v version=%q with nonce=%q error=%#v inc=%v", // nolint: lll
 					con, collection, req.ResponseNonce, versionInfo, req.ErrorDetail, req.Incremental)
 				con.reporter.RecordRequestNack(collection, con.id, codes.Code(req.ErrorDetail.Code))
+				w.fullStateRequired = true
+				// watch without version to get the full state right away.
+				versionInfo = ""
 			} else {
 				scope.Infof("MCP: connection %v ACK collection=%v with version=%q nonce=%q inc=%v",
 					con, collection, versionInfo, req.ResponseNonce, req.Incremental)
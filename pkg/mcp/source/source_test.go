@@ -63,6 +63,7 @@ type sourceTestHarness struct {
 	watchResponses    map[string]*WatchResponse
 	pushResponseFuncs map[string][]PushResponseFunc
 	watchCreated      map[string]int
+	watchVersions     map[string]string
 	client            bool
 	closeWatch        bool
 }
@@ -72,6 +73,7 @@ func newSourceTestHarness(t *testing.T) *sourceTestHarness {
 		ctx:                context.Background(),
 		t:                  t,
 		watchCreated:       make(map[string]int),
+		watchVersions:      make(map[string]string),
 		watchesCreatedChan: make(map[string]chan struct{}),
 		pushResponseFuncs:  make(map[string][]PushResponseFunc),
 		watchResponses:     make(map[string]*WatchResponse),
@@ -181,11 +183,18 @@ func (h *sourceTestHarness) watchesCreated(typeURL string) int {
 	return h.watchCreated[typeURL]
 }
 
+func (h *sourceTestHarness) watchVersion(typeURL string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.watchVersions[typeURL]
+}
+
 func (h *sourceTestHarness) Watch(req *Request, pushResponse PushResponseFunc, peerAddr string) CancelWatchFunc {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	h.watchCreated[req.Collection]++
+	h.watchVersions[req.Collection] = req.VersionInfo
 
 	if rsp, ok := h.watchResponses[req.Collection]; ok {
 		delete(h.watchResponses, req.Collection)
@@ -796,9 +805,9 @@ func TestSourceACKAddUpdateDelete_Incremental(t *testing.T) {
 		inject        *WatchResponse
 	}{
 		{
-			name:          "ack add A0",
+			name:          "ack add A0 (full-state on connection)",
 			inject:        makeWatchResponse(test.FakeType0Collection, "1", true, test.Type0A[0]),
-			wantResources: test.MakeResources(true, test.FakeType0Collection, "1", "1", nil, test.Type0A[0]),
+			wantResources: test.MakeResources(false, test.FakeType0Collection, "1", "1", nil, test.Type0A[0]),
 			request:       test.MakeRequest(true, test.FakeType0Collection, "1", codes.OK),
 		},
 		{
@@ -808,9 +817,9 @@ func TestSourceACKAddUpdateDelete_Incremental(t *testing.T) {
 			request:       test.MakeRequest(true, test.FakeType0Collection, "2", codes.InvalidArgument),
 		},
 		{
-			name:          "ack update A0",
+			name:          "ack update A0 (full-state after nack)",
 			inject:        makeWatchResponse(test.FakeType0Collection, "3", true, test.Type0A[1]),
-			wantResources: test.MakeResources(true, test.FakeType0Collection, "3", "3", nil, test.Type0A[1]),
+			wantResources: test.MakeResources(false, test.FakeType0Collection, "3", "3", nil, test.Type0A[1]),
 			request:       test.MakeRequest(true, test.FakeType0Collection, "3", codes.OK),
 		},
 		{
@@ -859,3 +868,107 @@ func TestSourceACKAddUpdateDelete_Incremental(t *testing.T) {
 		}
 	}
 }
+
+func TestSourceIncremental_FullStateOnConnection(t *testing.T) {
+	h := newSourceTestHarness(t)
+	h.setContext(peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.IPAddr{IP: net.IPv4(192, 168, 1, 1)},
+	}))
+
+	fakeLimiter := test.NewFakePerConnLimiter()
+	close(fakeLimiter.ErrCh)
+	options := &Options{
+		Watcher:            h,
+		CollectionsOptions: CollectionOptionsFromSlice(test.SupportedCollections),
+		Reporter:           monitoring.NewInMemoryStatsContext(),
+		ConnRateLimiter:    fakeLimiter,
+	}
+	for i := range options.CollectionsOptions {
+		options.CollectionsOptions[i].Incremental = true
+	}
+	s := New(options)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		if err := s.ProcessStream(h); err != nil {
+			t.Errorf("Stream() => got %v, want no error", err)
+		}
+		wg.Done()
+	}()
+
+	defer func() {
+		h.setRecvError(io.EOF)
+		wg.Wait()
+	}()
+
+	// initial watch of a sink which already has A0 and A2: their versions may name other resources in this source.
+	req := test.MakeRequest(true, test.FakeType0Collection, "", codes.OK)
+	req.InitialResourceVersions = map[string]string{
+		test.Type0A[0].Metadata.Name: test.Type0A[0].Metadata.Version,
+		test.Type0C[0].Metadata.Name: test.Type0C[0].Metadata.Version,
+	}
+	h.requestsChan <- req
+
+	h.injectWatchResponse(makeWatchResponse(test.FakeType0Collection, "1", true, test.Type0A[0], test.Type0B[0]))
+	verifySentResources(t, h, test.MakeResources(false, test.FakeType0Collection, "1", "1", nil,
+		test.Type0A[0], test.Type0B[0]))
+	h.requestsChan <- test.MakeRequest(true, test.FakeType0Collection, "1", codes.OK)
+
+	h.injectWatchResponse(makeWatchResponse(test.FakeType0Collection, "2", true, test.Type0A[0]))
+	verifySentResources(t, h, test.MakeResources(true, test.FakeType0Collection, "2", "2",
+		[]string{test.Type0B[0].Metadata.Name}))
+}
+
+func TestSourceIncremental_WatchWithoutVersionAfterNACK(t *testing.T) {
+	h := newSourceTestHarness(t)
+	h.setContext(peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.IPAddr{IP: net.IPv4(192, 168, 1, 1)},
+	}))
+
+	fakeLimiter := test.NewFakePerConnLimiter()
+	close(fakeLimiter.ErrCh)
+	options := &Options{
+		Watcher:            h,
+		CollectionsOptions: CollectionOptionsFromSlice(test.SupportedCollections),
+		Reporter:           monitoring.NewInMemoryStatsContext(),
+		ConnRateLimiter:    fakeLimiter,
+	}
+	for i := range options.CollectionsOptions {
+		options.CollectionsOptions[i].Incremental = true
+	}
+	s := New(options)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		if err := s.ProcessStream(h); err != nil {
+			t.Errorf("Stream() => got %v, want no error", err)
+		}
+		wg.Done()
+	}()
+
+	defer func() {
+		h.setRecvError(io.EOF)
+		wg.Wait()
+	}()
+
+	h.requestsChan <- test.MakeRequest(true, test.FakeType0Collection, "", codes.OK)
+	<-h.watchesCreatedChan[test.FakeType0Collection]
+
+	h.injectWatchResponse(makeWatchResponse(test.FakeType0Collection, "1", true, test.Type0A[0]))
+	verifySentResources(t, h, test.MakeResources(false, test.FakeType0Collection, "1", "1", nil, test.Type0A[0]))
+	h.requestsChan <- test.MakeRequest(true, test.FakeType0Collection, "1", codes.OK)
+	<-h.watchesCreatedChan[test.FakeType0Collection]
+	if got := h.watchVersion(test.FakeType0Collection); got != "1" {
+		t.Fatalf("watch version after ACK => got %q, want %q", got, "1")
+	}
+
+	h.injectWatchResponse(makeWatchResponse(test.FakeType0Collection, "2", true, test.Type0A[1]))
+	verifySentResources(t, h, test.MakeResources(true, test.FakeType0Collection, "2", "2", nil, test.Type0A[1]))
+	h.requestsChan <- test.MakeRequest(true, test.FakeType0Collection, "2", codes.InvalidArgument)
+	<-h.watchesCreatedChan[test.FakeType0Collection]
+	if got := h.watchVersion(test.FakeType0Collection); got != "" {
+		t.Fatalf("watch version after NACK => got %q, want no version", got)
+	}
+}
@@ -47,6 +47,7 @@ type InMemoryStatsContext struct {
 	SendFailuresTotal        map[errorCodeKey]int64
 	RecvFailuresTotal        map[errorCodeKey]int64
 	StreamCreateSuccessTotal int64
+	CollectionStale          map[string]bool
 }
 
 // SetStreamCount updates the current stream count to the given argument.
@@ -101,6 +102,14 @@ func (s *InMemoryStatsContext) RecordStreamCreateSuccess() {
 	s.mutex.Unlock()
 }
 
+// SetCollectionStale records whether the resources of a collection are loaded from the cache of the sink and not
+// synchronized with the source yet.
+func (s *InMemoryStatsContext) SetCollectionStale(collection string, stale bool) {
+	s.mutex.Lock()
+	s.CollectionStale[collection] = stale
+	s.mutex.Unlock()
+}
+
 // Close implements io.Closer.
 func (s *InMemoryStatsContext) Close() error {
 	return nil
@@ -115,5 +124,6 @@ func NewInMemoryStatsContext() *InMemoryStatsContext {
 		RequestNacksTotal: make(map[nackKey]int64),
 		SendFailuresTotal: make(map[errorCodeKey]int64),
 		RecvFailuresTotal: make(map[errorCodeKey]int64),
+		CollectionStale:   make(map[string]bool),
 	}
 }
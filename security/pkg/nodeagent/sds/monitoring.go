@@ -22,6 +22,8 @@ import (
 // Previously exposed metrics: pending_push_per_connection, stale_conn_count_per_connection,
 // pushes_per_connection, push_errors_per_connection, pushed_root_cert_expiry_timestamp, pushed_server_cert_expiry_timestamp
 var (
+	resourceNameTag = monitoring.MustCreateLabel("resource_name")
+
 	// totalPushCounts records total number of SDS pushes since server starts serving.
 	totalPushCounts = monitoring.NewSum(
 		"total_pushes",
@@ -52,6 +54,15 @@ var (
 		"total_secret_update_failures",
 		"The total number of dynamic secret update failures reported by proxy.",
 	)
+
+	// secretRefreshDuration records the delay between a secret being generated and the proxy
+	// acknowledging it, per SDS resource name.
+	secretRefreshDuration = monitoring.NewDistribution(
+		"secret_refresh_duration",
+		"Delay in seconds between a secret being generated and the proxy acknowledging it.",
+		[]float64{.1, .5, 1, 3, 5, 10, 30, 60, 300},
+		monitoring.WithLabels(resourceNameTag),
+	)
 )
 
 func init() {
@@ -61,5 +72,6 @@ func init() {
 		totalActiveConnCounts,
 		totalStaleConnCounts,
 		totalSecretUpdateFailureCounts,
+		secretRefreshDuration,
 	)
 }
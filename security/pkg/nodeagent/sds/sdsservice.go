@@ -38,6 +38,7 @@ import (
 
 	"istio.io/istio/security/pkg/nodeagent/cache"
 	"istio.io/istio/security/pkg/nodeagent/model"
+	"istio.io/istio/security/pkg/pki/util"
 	"istio.io/pkg/log"
 )
 
@@ -109,6 +110,13 @@ type sdsConnection struct {
 	// Time of the recent SDS push. Will be reset to zero when a new SDS request is received. A
 	// non-zero time indicates that the connection is waiting for SDS request.
 	sdsPushTime time.Time
+
+	// Time of the most recent successful SDS push, for debugging. Unlike sdsPushTime it is not
+	// reset when a new SDS request is received.
+	lastPushTime time.Time
+
+	// Time of the most recent SDS ACK received from the proxy, for debugging.
+	lastAckTime time.Time
 }
 
 type sdsservice struct {
@@ -137,6 +145,14 @@ type ClientDebug struct {
 	RootCert         string `json:"root_cert"`
 	CreatedTime      string `json:"created_time"`
 	ExpireTime       string `json:"expire_time"`
+
+	// fields parsed from the pushed certificate, empty if it can not be parsed
+	CertSerialNumber string `json:"cert_serial_number,omitempty"`
+	NotAfter         string `json:"not_after,omitempty"`
+
+	// push and ACK state of the connection, empty if it has not happened yet
+	LastPushTime string `json:"last_push_time,omitempty"`
+	LastAckTime  string `json:"last_ack_time,omitempty"`
 }
 
 // Debug represents all clients connected to this node agent endpoint and their supplied secrets
@@ -174,12 +190,13 @@ func (s *sdsservice) DebugInfo() (string, error) {
 	defer sdsClientsMutex.RUnlock()
 	clientDebug := make([]ClientDebug, 0)
 	for connKey, conn := range sdsClients {
+		conn.mutex.RLock()
 		// it's possible for the connection to be established without an instantiated secret
 		if conn.secret == nil {
+			conn.mutex.RUnlock()
 			continue
 		}
 
-		conn.mutex.RLock()
 		c := ClientDebug{
 			ConnectionID:     connKey.ConnectionID,
 			ProxyID:          conn.proxyID,
@@ -188,6 +205,16 @@ func (s *sdsservice) DebugInfo() (string, error) {
 			RootCert:         string(conn.secret.RootCert),
 			CreatedTime:      conn.secret.CreatedTime.Format(time.RFC3339),
 			ExpireTime:       conn.secret.ExpireTime.Format(time.RFC3339),
+			LastPushTime:     formatDebugTime(conn.lastPushTime),
+			LastAckTime:      formatDebugTime(conn.lastAckTime),
+		}
+		certPem := conn.secret.CertificateChain
+		if conn.secret.RootCert != nil {
+			certPem = conn.secret.RootCert
+		}
+		if cert, err := util.ParsePemEncodedCertificate(certPem); err == nil {
+			c.CertSerialNumber = cert.SerialNumber.Text(16)
+			c.NotAfter = cert.NotAfter.Format(time.RFC3339)
 		}
 		clientDebug = append(clientDebug, c)
 		conn.mutex.RUnlock()
@@ -298,6 +325,7 @@ func (s *sdsservice) StreamSecrets(stream sds.SecretDiscoveryService_StreamSecre
 			// request's <token, resourceName, Version>, then this request is a confirmation request.
 			// nodeagent stops sending response to envoy in this case.
 			if discReq.VersionInfo != "" && s.st.SecretExist(conID, resourceName, token, discReq.VersionInfo) {
+				recordAck(con)
 				sdsServiceLog.Debugf("%s received SDS ACK from proxy %q, version info %q, "+
 					"error details %s\n", conIDresourceNamePrefix, discReq.Node.Id, discReq.VersionInfo,
 					discReq.ErrorDetail)
@@ -575,6 +603,7 @@ func pushSDS(con *sdsConnection) error {
 	}
 
 	con.sdsPushTime = time.Now()
+	con.lastPushTime = con.sdsPushTime
 
 	// Update metrics after push to avoid adding latency to SDS push.
 	if secret.RootCert != nil {
@@ -642,6 +671,27 @@ func sdsDiscoveryResponse(s *model.SecretItem, conID, resourceName string) (*xds
 	return resp, nil
 }
 
+// recordAck records an SDS ACK from the proxy, and how long it took for the acknowledged secret
+// to reach the proxy since it was generated.
+func recordAck(con *sdsConnection) {
+	con.mutex.Lock()
+	defer con.mutex.Unlock()
+
+	con.lastAckTime = time.Now()
+	if con.secret != nil && !con.secret.CreatedTime.IsZero() {
+		secretRefreshDuration.With(resourceNameTag.Value(con.ResourceName)).
+			Record(con.lastAckTime.Sub(con.secret.CreatedTime).Seconds())
+	}
+}
+
+// formatDebugTime formats t for the debug endpoint, returning an empty string for the zero time.
+func formatDebugTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
 func newSDSConnection(stream discoveryStream) *sdsConnection {
 	return &sdsConnection{
 		pushChannel: make(chan *sdsEvent, 1),
@@ -42,6 +42,7 @@ import (
 	"istio.io/istio/security/pkg/nodeagent/cache"
 	"istio.io/istio/security/pkg/nodeagent/model"
 	"istio.io/istio/security/pkg/nodeagent/util"
+	pkiutil "istio.io/istio/security/pkg/pki/util"
 )
 
 var (
@@ -1015,6 +1016,9 @@ func TestDebugEndpoints(t *testing.T) {
 						t.Errorf("expected cert chain: %s, but got %s",
 							string(fakeCertificateChain), c.CertificateChain)
 					}
+					if c.LastPushTime == "" {
+						t.Errorf("expected last push time to be set for %s", p)
+					}
 					found = true
 					break
 				}
@@ -1029,6 +1033,73 @@ func TestDebugEndpoints(t *testing.T) {
 	}
 }
 
+func TestDebugInfoCertificateFields(t *testing.T) {
+	notBefore := time.Now().Truncate(time.Second)
+	certPem, _, err := pkiutil.GenCertKeyFromOptions(pkiutil.CertOptions{
+		Host:         "spiffe://cluster.local/ns/default/sa/default",
+		NotBefore:    notBefore,
+		TTL:          time.Hour,
+		RSAKeySize:   2048,
+		IsSelfSigned: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate certificate: %v", err)
+	}
+	cert, err := pkiutil.ParsePemEncodedCertificate(certPem)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	sdsClientsMutex.Lock()
+	sdsClients = map[cache.ConnKey]*sdsConnection{}
+	sdsClientsMutex.Unlock()
+
+	con := newSDSConnection(nil)
+	con.conID = "sidecar~127.0.0.1~DebugCertProxy~local-1"
+	con.proxyID = "sidecar~127.0.0.1~DebugCertProxy~local"
+	con.ResourceName = testResourceName
+	con.secret = &model.SecretItem{
+		CertificateChain: certPem,
+		ResourceName:     testResourceName,
+		CreatedTime:      notBefore,
+		ExpireTime:       notBefore.Add(time.Hour),
+	}
+	con.lastPushTime = notBefore
+	addConn(cache.ConnKey{ConnectionID: con.conID, ResourceName: testResourceName}, con)
+	recordAck(con)
+
+	s := &sdsservice{}
+	debugJSON, err := s.DebugInfo()
+	if err != nil {
+		t.Fatalf("failed to get debug info: %v", err)
+	}
+	debug := &Debug{}
+	if err := json.Unmarshal([]byte(debugJSON), debug); err != nil {
+		t.Fatalf("debug JSON unmarshalling failed: %v", err)
+	}
+	if len(debug.Clients) != 1 {
+		t.Fatalf("expected 1 client, found %d", len(debug.Clients))
+	}
+
+	c := debug.Clients[0]
+	if got, want := c.CertSerialNumber, cert.SerialNumber.Text(16); got != want {
+		t.Errorf("expected cert serial number %s, got %s", want, got)
+	}
+	if got, want := c.NotAfter, cert.NotAfter.Format(time.RFC3339); got != want {
+		t.Errorf("expected not after %s, got %s", want, got)
+	}
+	if got, want := c.LastPushTime, notBefore.Format(time.RFC3339); got != want {
+		t.Errorf("expected last push time %s, got %s", want, got)
+	}
+	if c.LastAckTime == "" {
+		t.Error("expected last ack time to be set")
+	}
+
+	sdsClientsMutex.Lock()
+	sdsClients = map[cache.ConnKey]*sdsConnection{}
+	sdsClientsMutex.Unlock()
+}
+
 func checkStaledConnCount(t *testing.T) {
 	// Manually clear staled clients instead of waiting for ticker.
 	clearStaledClients()
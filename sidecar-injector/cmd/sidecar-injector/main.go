@@ -90,6 +90,11 @@ var (
 				HealthCheckFile:     flags.healthCheckFile,
 				MonitoringPort:      flags.monitoringPort,
 			}
+			if client, err := kube.CreateClientset(flags.kubeconfigFile, ""); err != nil {
+				log.Warnf("Injection templates cannot be selected by namespace label: %v", err)
+			} else {
+				parameters.Client = client
+			}
 			wh, err := inject.NewWebhook(parameters)
 			if err != nil {
 				return multierror.Prefix(err, "failed to create injection webhook")
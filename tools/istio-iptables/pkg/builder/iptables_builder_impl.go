@@ -34,6 +34,9 @@ type Rules struct {
 	rulesv6 []*Rule
 }
 
+// tableOrder is the order in which tables are emitted when the output needs to be stable.
+var tableOrder = []string{constants.FILTER, constants.NAT, constants.MANGLE}
+
 // IptablesBuilderImpl is an implementation for IptablesBuilder interface
 type IptablesBuilderImpl struct {
 	rules Rules
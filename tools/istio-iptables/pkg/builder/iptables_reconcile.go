@@ -0,0 +1,404 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
+)
+
+// istioChainPrefix is the prefix of all chains managed by istio-iptables.
+const istioChainPrefix = "ISTIO_"
+
+// IptablesReconciler is an interface for computing the iptables commands needed to bring an existing
+// ruleset, as printed by iptables-save, in line with the rules that have been produced.
+type IptablesReconciler interface {
+	// BuildV4Delta creates the iptables commands reconciling the given iptables-save output
+	BuildV4Delta(current string) [][]string
+	// BuildV6Delta creates the ip6tables commands reconciling the given ip6tables-save output
+	BuildV6Delta(current string) [][]string
+}
+
+// savedRule is a rule as found in iptables-save output, or as desired by the builder.
+type savedRule struct {
+	// spec is the rule specification without the leading `-A <chain>`.
+	spec []string
+	// normalized is the canonical form of spec, used for comparison.
+	normalized string
+	// insert is set for desired rules which are inserted at the head of the chain.
+	insert bool
+}
+
+// savedTable is a table parsed from iptables-save output.
+type savedTable struct {
+	chains []string
+	rules  map[string][]*savedRule
+}
+
+func newSavedTable() *savedTable {
+	return &savedTable{rules: map[string][]*savedRule{}}
+}
+
+func (t *savedTable) addChain(chain string) {
+	if _, present := t.rules[chain]; !present {
+		t.chains = append(t.chains, chain)
+		t.rules[chain] = []*savedRule{}
+	}
+}
+
+// parseIptablesSave parses the output of iptables-save into its tables, chains and rules.
+func parseIptablesSave(save string) map[string]*savedTable {
+	tables := map[string]*savedTable{}
+	var current *savedTable
+	scanner := bufio.NewScanner(strings.NewReader(save))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || line == "COMMIT":
+			continue
+		case strings.HasPrefix(line, "*"):
+			current = newSavedTable()
+			tables[strings.TrimSpace(strings.TrimPrefix(line, "*"))] = current
+		case current == nil:
+			continue
+		case strings.HasPrefix(line, ":"):
+			current.addChain(strings.Fields(strings.TrimPrefix(line, ":"))[0])
+		case strings.HasPrefix(line, "-A "):
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			current.addChain(fields[1])
+			current.rules[fields[1]] = append(current.rules[fields[1]], &savedRule{
+				spec:       fields[2:],
+				normalized: normalizeRuleSpec(fields[2:]),
+			})
+		}
+	}
+	return tables
+}
+
+// desiredTables computes the final state of each table once all rules have been applied in order.
+func desiredTables(rules []*Rule) map[string]*savedTable {
+	tables := map[string]*savedTable{}
+	for _, r := range rules {
+		t, present := tables[r.table]
+		if !present {
+			t = newSavedTable()
+			tables[r.table] = t
+		}
+		t.addChain(r.chain)
+
+		// params are either `-A <chain> spec...` or `-I <chain> <position> spec...`.
+		rule := &savedRule{}
+		position := -1
+		if r.params[0] == "-I" {
+			rule.insert = true
+			position, _ = strconv.Atoi(r.params[2])
+			rule.spec = r.params[3:]
+		} else {
+			rule.spec = r.params[2:]
+		}
+		rule.normalized = normalizeRuleSpec(rule.spec)
+
+		existing := t.rules[r.chain]
+		if !rule.insert {
+			t.rules[r.chain] = append(existing, rule)
+			continue
+		}
+		if position < 1 || position > len(existing)+1 {
+			position = len(existing) + 1
+		}
+		t.rules[r.chain] = append(existing[:position-1], append([]*savedRule{rule}, existing[position-1:]...)...)
+	}
+	return tables
+}
+
+// normalizeRuleSpec returns the canonical form of a rule specification, matching the way iptables-save
+// prints rules: address, interface and protocol options first, implicit protocol matches dropped, and
+// target options in a fixed order.
+func normalizeRuleSpec(spec []string) string {
+	basic := map[string][]string{}
+	var matches, target []string
+	for i := 0; i < len(spec); i++ {
+		var negate []string
+		if spec[i] == "!" && i+1 < len(spec) {
+			negate = []string{"!"}
+			i++
+		}
+		opt := spec[i]
+		value := ""
+		if i+1 < len(spec) {
+			value = spec[i+1]
+		}
+		switch opt {
+		case "-s", "-d":
+			if !strings.Contains(value, "/") {
+				if strings.Contains(value, ":") {
+					value += "/128"
+				} else {
+					value += "/32"
+				}
+			}
+			basic[opt] = append(negate, opt, value)
+			i++
+		case "-i", "-o", "-p":
+			basic[opt] = append(negate, opt, value)
+			i++
+		case "-m":
+			// iptables-save makes the protocol match explicit, it is implied by -p in the desired rules.
+			if value != constants.TCP && value != "udp" {
+				matches = append(matches, opt, value)
+			}
+			i++
+		case "-j":
+			var options []string
+			if i+2 < len(spec) {
+				options = spec[i+2:]
+			}
+			target = normalizeTarget(value, options)
+			i = len(spec)
+		default:
+			matches = append(matches, negate...)
+			matches = append(matches, opt)
+			if value != "" && value != "!" && !strings.HasPrefix(value, "-") {
+				matches = append(matches, value)
+				i++
+			}
+		}
+	}
+
+	var normalized []string
+	for _, opt := range []string{"-s", "-d", "-i", "-o", "-p"} {
+		normalized = append(normalized, basic[opt]...)
+	}
+	normalized = append(normalized, matches...)
+	normalized = append(normalized, target...)
+	return strings.Join(normalized, " ")
+}
+
+// normalizeTarget returns the canonical form of a target and its options.
+func normalizeTarget(target string, options []string) []string {
+	values := map[string]string{}
+	for i := 0; i < len(options); i++ {
+		if i+1 < len(options) && !strings.HasPrefix(options[i+1], "--") {
+			values[options[i]] = options[i+1]
+			i++
+		} else {
+			values[options[i]] = ""
+		}
+	}
+
+	switch target {
+	case constants.REDIRECT:
+		if port, present := values["--to-port"]; present {
+			delete(values, "--to-port")
+			values["--to-ports"] = port
+		}
+	case constants.MARK:
+		if mark, present := values["--set-mark"]; present {
+			delete(values, "--set-mark")
+			values["--set-xmark"] = mark
+		}
+		if mark, present := values["--set-xmark"]; present {
+			values["--set-xmark"] = normalizeMark(mark)
+		}
+	case constants.TPROXY:
+		if mark, present := values["--tproxy-mark"]; present {
+			values["--tproxy-mark"] = normalizeMark(mark)
+		}
+		if ip := values["--on-ip"]; ip == "0.0.0.0" || ip == "::" {
+			delete(values, "--on-ip")
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	normalized := []string{"-j", target}
+	for _, k := range keys {
+		normalized = append(normalized, k)
+		if values[k] != "" {
+			normalized = append(normalized, values[k])
+		}
+	}
+	return normalized
+}
+
+// normalizeMark converts a `value[/mask]` mark to the hexadecimal `value/mask` form used by iptables-save.
+func normalizeMark(mark string) string {
+	parts := strings.SplitN(mark, "/", 2)
+	if len(parts) == 1 {
+		parts = append(parts, "0xffffffff")
+	}
+	for i, p := range parts {
+		if v, err := strconv.ParseUint(p, 0, 32); err == nil {
+			parts[i] = fmt.Sprintf("0x%x", v)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// targetOf returns the target of a rule specification.
+func targetOf(spec []string) string {
+	for i := 0; i < len(spec)-1; i++ {
+		if spec[i] == "-j" {
+			return spec[i+1]
+		}
+	}
+	return ""
+}
+
+func (rb *IptablesBuilderImpl) buildDelta(command string, rules []*Rule, current string) [][]string {
+	output := [][]string{}
+	desired := desiredTables(rules)
+	existing := parseIptablesSave(current)
+
+	for _, table := range tableOrder {
+		want, present := desired[table]
+		if !present {
+			want = newSavedTable()
+		}
+		have, present := existing[table]
+		if !present {
+			have = newSavedTable()
+		}
+		cmd := func(params ...string) {
+			output = append(output, append([]string{command, "-t", table}, params...))
+		}
+
+		// Create the missing istio chains.
+		for _, chain := range want.chains {
+			if _, builtIn := constants.BuiltInChainsMap[chain]; builtIn {
+				continue
+			}
+			if _, present := have.rules[chain]; !present {
+				cmd("-N", chain)
+			}
+		}
+
+		// Built-in chains are shared with other users, so only the rules produced here and the jumps
+		// into istio chains are reconciled, one rule at a time.
+		for _, chain := range have.chains {
+			if _, builtIn := constants.BuiltInChainsMap[chain]; !builtIn {
+				continue
+			}
+			// Remove duplicates of the desired rules, and jumps into istio chains which are not desired.
+			wanted := countRules(want.rules[chain])
+			for _, r := range have.rules[chain] {
+				_, desiredRule := wanted[r.normalized]
+				if wanted[r.normalized] > 0 {
+					wanted[r.normalized]--
+					continue
+				}
+				if desiredRule || strings.HasPrefix(targetOf(r.spec), istioChainPrefix) {
+					cmd(append([]string{"-D", chain}, r.spec...)...)
+				}
+			}
+		}
+		for _, chain := range want.chains {
+			if _, builtIn := constants.BuiltInChainsMap[chain]; !builtIn {
+				continue
+			}
+			missing := countRules(want.rules[chain])
+			for _, r := range have.rules[chain] {
+				if missing[r.normalized] > 0 {
+					missing[r.normalized]--
+				}
+			}
+			for _, r := range want.rules[chain] {
+				if missing[r.normalized] == 0 {
+					continue
+				}
+				missing[r.normalized]--
+				if r.insert {
+					cmd(append([]string{"-I", chain, "1"}, r.spec...)...)
+				} else {
+					cmd(append([]string{"-A", chain}, r.spec...)...)
+				}
+			}
+		}
+
+		// Istio chains are owned entirely, so they are either extended or rebuilt.
+		for _, chain := range want.chains {
+			if _, builtIn := constants.BuiltInChainsMap[chain]; builtIn {
+				continue
+			}
+			wantRules, haveRules := want.rules[chain], have.rules[chain]
+			start := len(haveRules)
+			if !isPrefix(haveRules, wantRules) {
+				cmd("-F", chain)
+				start = 0
+			}
+			for _, r := range wantRules[start:] {
+				cmd(append([]string{"-A", chain}, r.spec...)...)
+			}
+		}
+
+		// Remove istio chains which are no longer wanted, flushing all of them before deleting any, as
+		// they may refer to each other.
+		var stale []string
+		for _, chain := range have.chains {
+			if _, wanted := want.rules[chain]; !wanted && strings.HasPrefix(chain, istioChainPrefix) {
+				stale = append(stale, chain)
+			}
+		}
+		for _, chain := range stale {
+			if len(have.rules[chain]) > 0 {
+				cmd("-F", chain)
+			}
+		}
+		for _, chain := range stale {
+			cmd("-X", chain)
+		}
+	}
+	return output
+}
+
+func countRules(rules []*savedRule) map[string]int {
+	counts := map[string]int{}
+	for _, r := range rules {
+		counts[r.normalized]++
+	}
+	return counts
+}
+
+// isPrefix returns true if the rules in have are the first rules in want.
+func isPrefix(have, want []*savedRule) bool {
+	if len(have) > len(want) {
+		return false
+	}
+	for i, r := range have {
+		if r.normalized != want[i].normalized {
+			return false
+		}
+	}
+	return true
+}
+
+func (rb *IptablesBuilderImpl) BuildV4Delta(current string) [][]string {
+	return rb.buildDelta(constants.IPTABLES, rb.rules.rulesv4, current)
+}
+
+func (rb *IptablesBuilderImpl) BuildV6Delta(current string) [][]string {
+	return rb.buildDelta(constants.IP6TABLES, rb.rules.rulesv6, current)
+}
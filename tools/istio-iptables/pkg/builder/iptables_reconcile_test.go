@@ -0,0 +1,166 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
+)
+
+func newReconcileTestBuilder() *IptablesBuilderImpl {
+	iptables := NewIptablesBuilder()
+	iptables.AppendRuleV4(constants.ISTIOREDIRECT, constants.NAT, "-p", constants.TCP, "-j", constants.REDIRECT, "--to-port", "15001")
+	iptables.AppendRuleV4(constants.PREROUTING, constants.NAT, "-p", constants.TCP, "-j", constants.ISTIOINBOUND)
+	iptables.AppendRuleV4(constants.ISTIOINBOUND, constants.NAT, "-p", constants.TCP, "--dport", "22", "-j", constants.RETURN)
+	iptables.AppendRuleV4(constants.ISTIOINBOUND, constants.NAT, "-p", constants.TCP, "-j", constants.ISTIOREDIRECT)
+	iptables.AppendRuleV4(constants.OUTPUT, constants.NAT, "-p", constants.TCP, "-j", constants.ISTIOOUTPUT)
+	iptables.AppendRuleV4(constants.ISTIOOUTPUT, constants.NAT, "-o", "lo", "-s", "127.0.0.6/32", "-j", constants.RETURN)
+	iptables.AppendRuleV4(constants.ISTIOOUTPUT, constants.NAT, "-o", "lo", "!", "-d", "127.0.0.1/32", "-m", "owner",
+		"--uid-owner", "1337", "-j", constants.ISTIOREDIRECT)
+	iptables.InsertRuleV4(constants.PREROUTING, constants.NAT, 1, "-i", "eth1", "-j", constants.RETURN)
+	iptables.AppendRuleV4(constants.ISTIODIVERT, constants.MANGLE, "-j", constants.MARK, "--set-mark", "1337")
+	iptables.AppendRuleV4(constants.ISTIOTPROXY, constants.MANGLE, "!", "-d", "127.0.0.1/32", "-p", constants.TCP, "-j",
+		constants.TPROXY, "--tproxy-mark", "1337/0xffffffff", "--on-port", "15001")
+	return iptables
+}
+
+const reconciledSave = `# Generated by iptables-save v1.6.1
+*mangle
+:PREROUTING ACCEPT [0:0]
+:ISTIO_DIVERT - [0:0]
+:ISTIO_TPROXY - [0:0]
+-A ISTIO_DIVERT -j MARK --set-xmark 0x539/0xffffffff
+-A ISTIO_TPROXY ! -d 127.0.0.1/32 -p tcp -j TPROXY --on-port 15001 --on-ip 0.0.0.0 --tproxy-mark 0x539/0xffffffff
+COMMIT
+*nat
+:PREROUTING ACCEPT [0:0]
+:OUTPUT ACCEPT [0:0]
+:ISTIO_INBOUND - [0:0]
+:ISTIO_OUTPUT - [0:0]
+:ISTIO_REDIRECT - [0:0]
+-A PREROUTING -i eth1 -j RETURN
+-A PREROUTING -p tcp -j ISTIO_INBOUND
+-A OUTPUT -p tcp -j ISTIO_OUTPUT
+-A ISTIO_INBOUND -p tcp -m tcp --dport 22 -j RETURN
+-A ISTIO_INBOUND -p tcp -j ISTIO_REDIRECT
+-A ISTIO_OUTPUT -s 127.0.0.6/32 -o lo -j RETURN
+-A ISTIO_OUTPUT ! -d 127.0.0.1/32 -o lo -m owner --uid-owner 1337 -j ISTIO_REDIRECT
+-A ISTIO_REDIRECT -p tcp -j REDIRECT --to-ports 15001
+COMMIT
+`
+
+func TestBuildV4DeltaFromEmpty(t *testing.T) {
+	iptables := newReconcileTestBuilder()
+	actual := iptables.BuildV4Delta("")
+	expected := [][]string{
+		{"iptables", "-t", "nat", "-N", "ISTIO_REDIRECT"},
+		{"iptables", "-t", "nat", "-N", "ISTIO_INBOUND"},
+		{"iptables", "-t", "nat", "-N", "ISTIO_OUTPUT"},
+		{"iptables", "-t", "nat", "-I", "PREROUTING", "1", "-i", "eth1", "-j", "RETURN"},
+		{"iptables", "-t", "nat", "-A", "PREROUTING", "-p", "tcp", "-j", "ISTIO_INBOUND"},
+		{"iptables", "-t", "nat", "-A", "OUTPUT", "-p", "tcp", "-j", "ISTIO_OUTPUT"},
+		{"iptables", "-t", "nat", "-A", "ISTIO_REDIRECT", "-p", "tcp", "-j", "REDIRECT", "--to-port", "15001"},
+		{"iptables", "-t", "nat", "-A", "ISTIO_INBOUND", "-p", "tcp", "--dport", "22", "-j", "RETURN"},
+		{"iptables", "-t", "nat", "-A", "ISTIO_INBOUND", "-p", "tcp", "-j", "ISTIO_REDIRECT"},
+		{"iptables", "-t", "nat", "-A", "ISTIO_OUTPUT", "-o", "lo", "-s", "127.0.0.6/32", "-j", "RETURN"},
+		{"iptables", "-t", "nat", "-A", "ISTIO_OUTPUT", "-o", "lo", "!", "-d", "127.0.0.1/32", "-m", "owner", "--uid-owner",
+			"1337", "-j", "ISTIO_REDIRECT"},
+		{"iptables", "-t", "mangle", "-N", "ISTIO_DIVERT"},
+		{"iptables", "-t", "mangle", "-N", "ISTIO_TPROXY"},
+		{"iptables", "-t", "mangle", "-A", "ISTIO_DIVERT", "-j", "MARK", "--set-mark", "1337"},
+		{"iptables", "-t", "mangle", "-A", "ISTIO_TPROXY", "!", "-d", "127.0.0.1/32", "-p", "tcp", "-j", "TPROXY",
+			"--tproxy-mark", "1337/0xffffffff", "--on-port", "15001"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Output mismatch.\nExpected: %#v\nActual: %#v", expected, actual)
+	}
+}
+
+func TestBuildV4DeltaUpToDate(t *testing.T) {
+	iptables := newReconcileTestBuilder()
+	if actual := iptables.BuildV4Delta(reconciledSave); len(actual) != 0 {
+		t.Errorf("Expected no changes; but got %#v", actual)
+	}
+}
+
+func TestBuildV4DeltaWithDrift(t *testing.T) {
+	iptables := newReconcileTestBuilder()
+	current := `*mangle
+:PREROUTING ACCEPT [0:0]
+:ISTIO_DIVERT - [0:0]
+:ISTIO_TPROXY - [0:0]
+-A ISTIO_DIVERT -j MARK --set-xmark 0x539/0xffffffff
+-A ISTIO_TPROXY ! -d 127.0.0.1/32 -p tcp -j TPROXY --on-port 15001 --on-ip 0.0.0.0 --tproxy-mark 0x539/0xffffffff
+COMMIT
+*nat
+:PREROUTING ACCEPT [0:0]
+:OUTPUT ACCEPT [0:0]
+:KUBE-SERVICES - [0:0]
+:ISTIO_INBOUND - [0:0]
+:ISTIO_IN_REDIRECT - [0:0]
+:ISTIO_OUTPUT - [0:0]
+:ISTIO_REDIRECT - [0:0]
+-A PREROUTING -j KUBE-SERVICES
+-A PREROUTING -p tcp -j ISTIO_INBOUND
+-A PREROUTING -p tcp -j ISTIO_INBOUND
+-A OUTPUT -p tcp -j ISTIO_OUTPUT
+-A ISTIO_INBOUND -p tcp -m tcp --dport 22 -j RETURN
+-A ISTIO_INBOUND -p tcp -j ISTIO_IN_REDIRECT
+-A ISTIO_IN_REDIRECT -p tcp -j REDIRECT --to-ports 15006
+-A ISTIO_OUTPUT -s 127.0.0.6/32 -o lo -j RETURN
+-A ISTIO_REDIRECT -p tcp -j REDIRECT --to-ports 15001
+COMMIT
+`
+	actual := iptables.BuildV4Delta(current)
+	expected := [][]string{
+		// the duplicated jump is removed, the kubernetes one is left alone
+		{"iptables", "-t", "nat", "-D", "PREROUTING", "-p", "tcp", "-j", "ISTIO_INBOUND"},
+		{"iptables", "-t", "nat", "-I", "PREROUTING", "1", "-i", "eth1", "-j", "RETURN"},
+		// ISTIO_INBOUND diverged and is rebuilt, ISTIO_OUTPUT is only extended
+		{"iptables", "-t", "nat", "-F", "ISTIO_INBOUND"},
+		{"iptables", "-t", "nat", "-A", "ISTIO_INBOUND", "-p", "tcp", "--dport", "22", "-j", "RETURN"},
+		{"iptables", "-t", "nat", "-A", "ISTIO_INBOUND", "-p", "tcp", "-j", "ISTIO_REDIRECT"},
+		{"iptables", "-t", "nat", "-A", "ISTIO_OUTPUT", "-o", "lo", "!", "-d", "127.0.0.1/32", "-m", "owner", "--uid-owner",
+			"1337", "-j", "ISTIO_REDIRECT"},
+		// ISTIO_IN_REDIRECT is no longer used
+		{"iptables", "-t", "nat", "-F", "ISTIO_IN_REDIRECT"},
+		{"iptables", "-t", "nat", "-X", "ISTIO_IN_REDIRECT"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Output mismatch.\nExpected: %#v\nActual: %#v", expected, actual)
+	}
+}
+
+func TestBuildV6DeltaRemovesStaleRules(t *testing.T) {
+	iptables := NewIptablesBuilder()
+	current := `*nat
+:PREROUTING ACCEPT [0:0]
+:ISTIO_INBOUND - [0:0]
+-A PREROUTING -p tcp -j ISTIO_INBOUND
+-A ISTIO_INBOUND -p tcp -m tcp --dport 22 -j RETURN
+COMMIT
+`
+	actual := iptables.BuildV6Delta(current)
+	expected := [][]string{
+		{"ip6tables", "-t", "nat", "-D", "PREROUTING", "-p", "tcp", "-j", "ISTIO_INBOUND"},
+		{"ip6tables", "-t", "nat", "-F", "ISTIO_INBOUND"},
+		{"ip6tables", "-t", "nat", "-X", "ISTIO_INBOUND"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Output mismatch.\nExpected: %#v\nActual: %#v", expected, actual)
+	}
+}
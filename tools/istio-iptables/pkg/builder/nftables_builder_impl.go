@@ -0,0 +1,285 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
+)
+
+// nftRule represents a rule added through the iptables-style producer API. The params are kept in
+// iptables syntax and translated into nftables expressions when the ruleset is built.
+type nftRule struct {
+	chain  string
+	table  string
+	insert bool
+	params []string
+}
+
+// NftablesBuilderImpl is an implementation for IptablesBuilder interface which produces nftables
+// commands and `nft -f` rulesets instead of iptables ones.
+type NftablesBuilderImpl struct {
+	rulesv4 []*nftRule
+	rulesv6 []*nftRule
+}
+
+// NewNftablesBuilder creates a new IptablesBuilder producing nftables rules
+func NewNftablesBuilder() *NftablesBuilderImpl {
+	return &NftablesBuilderImpl{
+		rulesv4: []*nftRule{},
+		rulesv6: []*nftRule{},
+	}
+}
+
+// nftBaseChain describes how an iptables built-in chain is hooked into netfilter for a given table.
+type nftBaseChain struct {
+	chainType string
+	hook      string
+	priority  int
+}
+
+// nftBaseChains maps the iptables built-in chains of each table to the equivalent nftables base chains,
+// using the priorities iptables itself registers with.
+var nftBaseChains = map[string]map[string]nftBaseChain{
+	constants.NAT: {
+		constants.PREROUTING:  {"nat", "prerouting", -100},
+		constants.INPUT:       {"nat", "input", 100},
+		constants.OUTPUT:      {"nat", "output", -100},
+		constants.POSTROUTING: {"nat", "postrouting", 100},
+	},
+	constants.MANGLE: {
+		constants.PREROUTING:  {"filter", "prerouting", -150},
+		constants.INPUT:       {"filter", "input", -150},
+		constants.FORWARD:     {"filter", "forward", -150},
+		constants.OUTPUT:      {"route", "output", -150},
+		constants.POSTROUTING: {"filter", "postrouting", -150},
+	},
+	constants.FILTER: {
+		constants.INPUT:   {"filter", "input", 0},
+		constants.FORWARD: {"filter", "forward", 0},
+		constants.OUTPUT:  {"filter", "output", 0},
+	},
+}
+
+func (rb *NftablesBuilderImpl) InsertRuleV4(chain string, table string, position int, params ...string) IptablesProducer {
+	// nftables can only insert relative to a rule handle, which is unknown when building from scratch,
+	// so all inserted rules are placed at the head of the chain.
+	rb.rulesv4 = append(rb.rulesv4, &nftRule{chain: chain, table: table, insert: true, params: params})
+	return rb
+}
+
+func (rb *NftablesBuilderImpl) InsertRuleV6(chain string, table string, position int, params ...string) IptablesProducer {
+	rb.rulesv6 = append(rb.rulesv6, &nftRule{chain: chain, table: table, insert: true, params: params})
+	return rb
+}
+
+func (rb *NftablesBuilderImpl) AppendRuleV4(chain string, table string, params ...string) IptablesProducer {
+	rb.rulesv4 = append(rb.rulesv4, &nftRule{chain: chain, table: table, params: params})
+	return rb
+}
+
+func (rb *NftablesBuilderImpl) AppendRuleV6(chain string, table string, params ...string) IptablesProducer {
+	rb.rulesv6 = append(rb.rulesv6, &nftRule{chain: chain, table: table, params: params})
+	return rb
+}
+
+// NftTableName returns the name of the nftables table holding the rules of the given iptables table.
+func NftTableName(table string) string {
+	return "istio_" + table
+}
+
+// buildStatements returns the nftables statements, without the leading `nft`, for the given rules.
+func (rb *NftablesBuilderImpl) buildStatements(family string, rules []*nftRule) [][]string {
+	output := [][]string{}
+	if len(rules) == 0 {
+		return output
+	}
+	for _, table := range tableOrder {
+		var tableRules []*nftRule
+		for _, r := range rules {
+			if r.table == table {
+				tableRules = append(tableRules, r)
+			}
+		}
+		if len(tableRules) == 0 {
+			continue
+		}
+		tableName := NftTableName(table)
+		output = append(output, []string{"add", "table", family, tableName})
+
+		chainLookupMap := make(map[string]struct{})
+		for _, r := range tableRules {
+			if _, present := chainLookupMap[r.chain]; present {
+				continue
+			}
+			chainLookupMap[r.chain] = struct{}{}
+			cmd := []string{"add", "chain", family, tableName, r.chain}
+			if base, present := nftBaseChains[table][r.chain]; present {
+				cmd = append(cmd, "{", "type", base.chainType, "hook", base.hook, "priority", fmt.Sprint(base.priority), ";", "}")
+			}
+			output = append(output, cmd)
+		}
+
+		for _, r := range tableRules {
+			verb := "add"
+			if r.insert {
+				verb = "insert"
+			}
+			cmd := append([]string{verb, "rule", family, tableName, r.chain}, translateToNft(family, r.params)...)
+			output = append(output, cmd)
+		}
+	}
+	return output
+}
+
+func (rb *NftablesBuilderImpl) buildCommands(family string, rules []*nftRule) [][]string {
+	output := [][]string{}
+	for _, s := range rb.buildStatements(family, rules) {
+		// `--` ends the options of nft, so that negative chain priorities aren't parsed as options.
+		output = append(output, append([]string{constants.NFT, "--"}, s...))
+	}
+	return output
+}
+
+func (rb *NftablesBuilderImpl) buildRestore(family string, rules []*nftRule) string {
+	var b strings.Builder
+	for _, s := range rb.buildStatements(family, rules) {
+		fmt.Fprintln(&b, strings.Join(s, " "))
+	}
+	return b.String()
+}
+
+// BuildV4 creates nft commands for the IPv4 rules
+func (rb *NftablesBuilderImpl) BuildV4() [][]string {
+	return rb.buildCommands("ip", rb.rulesv4)
+}
+
+// BuildV6 creates nft commands for the IPv6 rules
+func (rb *NftablesBuilderImpl) BuildV6() [][]string {
+	return rb.buildCommands("ip6", rb.rulesv6)
+}
+
+// BuildV4Restore creates `nft -f` input for the IPv4 rules
+func (rb *NftablesBuilderImpl) BuildV4Restore() string {
+	return rb.buildRestore("ip", rb.rulesv4)
+}
+
+// BuildV6Restore creates `nft -f` input for the IPv6 rules
+func (rb *NftablesBuilderImpl) BuildV6Restore() string {
+	return rb.buildRestore("ip6", rb.rulesv6)
+}
+
+// translateToNft converts iptables match and target parameters into nftables expressions.
+// Parameters it does not know about are passed through unchanged.
+func translateToNft(family string, params []string) []string {
+	output := []string{}
+	negate := false
+	protocol := ""
+	hasPort := false
+	for _, p := range params {
+		if p == "--dport" || p == "--sport" {
+			hasPort = true
+		}
+	}
+	op := func() []string {
+		if negate {
+			negate = false
+			return []string{"!="}
+		}
+		return nil
+	}
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		next := func() string {
+			i++
+			if i < len(params) {
+				return params[i]
+			}
+			return ""
+		}
+		switch p {
+		case "!":
+			negate = true
+		case "-p":
+			protocol = next()
+			if !hasPort {
+				output = append(output, append(append([]string{"meta", "l4proto"}, op()...), protocol)...)
+			}
+		case "--dport", "--sport":
+			output = append(output, append(append([]string{protocol, strings.TrimPrefix(p, "--")}, op()...), next())...)
+		case "-d", "-s":
+			field := "daddr"
+			if p == "-s" {
+				field = "saddr"
+			}
+			output = append(output, append(append([]string{family, field}, op()...), next())...)
+		case "-i":
+			output = append(output, append(append([]string{"iifname"}, op()...), quoteNft(next()))...)
+		case "-o":
+			output = append(output, append(append([]string{"oifname"}, op()...), quoteNft(next()))...)
+		case "-m":
+			// Match extensions are implied by the options that follow, except for the socket match.
+			if next() == "socket" {
+				output = append(output, "socket", "transparent", "1")
+			}
+		case "--uid-owner":
+			output = append(output, append(append([]string{"meta", "skuid"}, op()...), next())...)
+		case "--gid-owner":
+			output = append(output, append(append([]string{"meta", "skgid"}, op()...), next())...)
+		case "-j":
+			output = append(output, translateTargetToNft(next(), params[i+1:])...)
+			return output
+		default:
+			output = append(output, p)
+		}
+	}
+	return output
+}
+
+// translateTargetToNft converts an iptables target and its options into an nftables verdict or statement.
+func translateTargetToNft(target string, options []string) []string {
+	option := func(name string) string {
+		for i := 0; i < len(options)-1; i++ {
+			if options[i] == name {
+				return options[i+1]
+			}
+		}
+		return ""
+	}
+	switch target {
+	case constants.RETURN:
+		return []string{"return"}
+	case constants.ACCEPT:
+		return []string{"accept"}
+	case constants.REJECT:
+		return []string{"reject"}
+	case constants.REDIRECT:
+		return []string{"redirect", "to", ":" + option("--to-port")}
+	case constants.MARK:
+		return []string{"meta", "mark", "set", option("--set-mark")}
+	case constants.TPROXY:
+		// The mask is always 0xffffffff in the rules we generate, so only the mark value is kept.
+		mark := strings.Split(option("--tproxy-mark"), "/")[0]
+		return []string{"meta", "mark", "set", mark, "tproxy", "to", ":" + option("--on-port")}
+	default:
+		return []string{"jump", target}
+	}
+}
+
+func quoteNft(s string) string {
+	return "\"" + s + "\""
+}
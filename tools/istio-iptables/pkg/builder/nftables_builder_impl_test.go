@@ -0,0 +1,121 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
+)
+
+func TestNftBuildEmpty(t *testing.T) {
+	nft := NewNftablesBuilder()
+	if actual := nft.BuildV4Restore(); actual != "" {
+		t.Errorf("Expected empty V4 ruleset; but got %q", actual)
+	}
+	if actual := nft.BuildV6(); !reflect.DeepEqual(actual, [][]string{}) {
+		t.Errorf("Expected empty V6 commands; but got %#v", actual)
+	}
+}
+
+func TestNftBuildV4Redirect(t *testing.T) {
+	nft := NewNftablesBuilder()
+	nft.AppendRuleV4(constants.ISTIOREDIRECT, constants.NAT, "-p", constants.TCP, "-j", constants.REDIRECT, "--to-port", "15001")
+	nft.AppendRuleV4(constants.OUTPUT, constants.NAT, "-p", constants.TCP, "-j", constants.ISTIOOUTPUT)
+	nft.AppendRuleV4(constants.ISTIOOUTPUT, constants.NAT, "-o", "lo", "!", "-d", "127.0.0.1/32", "-m", "owner",
+		"--uid-owner", "1337", "-j", constants.ISTIOINREDIRECT)
+	nft.AppendRuleV4(constants.ISTIOOUTPUT, constants.NAT, "-o", "lo", "-m", "owner", "!", "--gid-owner", "1337", "-j", constants.RETURN)
+	nft.InsertRuleV4(constants.PREROUTING, constants.NAT, 1, "-i", "eth1", "-j", constants.RETURN)
+	nft.AppendRuleV4(constants.ISTIOINBOUND, constants.NAT, "-p", constants.TCP, "--dport", "22", "-j", constants.RETURN)
+
+	expected := "add table ip istio_nat\n" +
+		"add chain ip istio_nat ISTIO_REDIRECT\n" +
+		"add chain ip istio_nat OUTPUT { type nat hook output priority -100 ; }\n" +
+		"add chain ip istio_nat ISTIO_OUTPUT\n" +
+		"add chain ip istio_nat PREROUTING { type nat hook prerouting priority -100 ; }\n" +
+		"add chain ip istio_nat ISTIO_INBOUND\n" +
+		"add rule ip istio_nat ISTIO_REDIRECT meta l4proto tcp redirect to :15001\n" +
+		"add rule ip istio_nat OUTPUT meta l4proto tcp jump ISTIO_OUTPUT\n" +
+		"add rule ip istio_nat ISTIO_OUTPUT oifname \"lo\" ip daddr != 127.0.0.1/32 meta skuid 1337 jump ISTIO_IN_REDIRECT\n" +
+		"add rule ip istio_nat ISTIO_OUTPUT oifname \"lo\" meta skgid != 1337 return\n" +
+		"insert rule ip istio_nat PREROUTING iifname \"eth1\" return\n" +
+		"add rule ip istio_nat ISTIO_INBOUND tcp dport 22 return\n"
+	if actual := nft.BuildV4Restore(); actual != expected {
+		t.Errorf("Output mismatch.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+	if actual := nft.BuildV6Restore(); actual != "" {
+		t.Errorf("Expected empty V6 ruleset; but got %q", actual)
+	}
+}
+
+func TestNftBuildV4TProxy(t *testing.T) {
+	nft := NewNftablesBuilder()
+	nft.AppendRuleV4(constants.ISTIODIVERT, constants.MANGLE, "-j", constants.MARK, "--set-mark", "1337")
+	nft.AppendRuleV4(constants.ISTIODIVERT, constants.MANGLE, "-j", constants.ACCEPT)
+	nft.AppendRuleV4(constants.ISTIOTPROXY, constants.MANGLE, "!", "-d", "127.0.0.1/32", "-p", constants.TCP, "-j", constants.TPROXY,
+		"--tproxy-mark", "1337/0xffffffff", "--on-port", "15001")
+	nft.AppendRuleV4(constants.PREROUTING, constants.MANGLE, "-p", constants.TCP, "-j", constants.ISTIOINBOUND)
+	nft.AppendRuleV4(constants.ISTIOINBOUND, constants.MANGLE, "-p", constants.TCP, "-m", "socket", "-j", constants.ISTIODIVERT)
+	nft.AppendRuleV4(constants.ISTIOOUTPUT, constants.NAT, "-j", constants.RETURN)
+
+	actual := nft.BuildV4()
+	expected := [][]string{
+		{"nft", "--", "add", "table", "ip", "istio_nat"},
+		{"nft", "--", "add", "chain", "ip", "istio_nat", "ISTIO_OUTPUT"},
+		{"nft", "--", "add", "rule", "ip", "istio_nat", "ISTIO_OUTPUT", "return"},
+		{"nft", "--", "add", "table", "ip", "istio_mangle"},
+		{"nft", "--", "add", "chain", "ip", "istio_mangle", "ISTIO_DIVERT"},
+		{"nft", "--", "add", "chain", "ip", "istio_mangle", "ISTIO_TPROXY"},
+		{"nft", "--", "add", "chain", "ip", "istio_mangle", "PREROUTING", "{", "type", "filter", "hook", "prerouting", "priority", "-150", ";", "}"},
+		{"nft", "--", "add", "chain", "ip", "istio_mangle", "ISTIO_INBOUND"},
+		{"nft", "--", "add", "rule", "ip", "istio_mangle", "ISTIO_DIVERT", "meta", "mark", "set", "1337"},
+		{"nft", "--", "add", "rule", "ip", "istio_mangle", "ISTIO_DIVERT", "accept"},
+		{"nft", "--", "add", "rule", "ip", "istio_mangle", "ISTIO_TPROXY", "ip", "daddr", "!=", "127.0.0.1/32", "meta", "l4proto", "tcp",
+			"meta", "mark", "set", "1337", "tproxy", "to", ":15001"},
+		{"nft", "--", "add", "rule", "ip", "istio_mangle", "PREROUTING", "meta", "l4proto", "tcp", "jump", "ISTIO_INBOUND"},
+		{"nft", "--", "add", "rule", "ip", "istio_mangle", "ISTIO_INBOUND", "meta", "l4proto", "tcp", "socket", "transparent", "1",
+			"jump", "ISTIO_DIVERT"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Output mismatch.\nExpected: %#v\nActual: %#v", expected, actual)
+	}
+}
+
+func TestNftBuildV4NegativePriority(t *testing.T) {
+	nft := NewNftablesBuilder()
+	nft.AppendRuleV4(constants.OUTPUT, constants.NAT, "-p", constants.TCP, "-j", constants.ISTIOOUTPUT)
+
+	expected := [][]string{
+		{"nft", "--", "add", "table", "ip", "istio_nat"},
+		{"nft", "--", "add", "chain", "ip", "istio_nat", "OUTPUT", "{", "type", "nat", "hook", "output", "priority", "-100", ";", "}"},
+		{"nft", "--", "add", "rule", "ip", "istio_nat", "OUTPUT", "meta", "l4proto", "tcp", "jump", "ISTIO_OUTPUT"},
+	}
+	if actual := nft.BuildV4(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Output mismatch.\nExpected: %#v\nActual: %#v", expected, actual)
+	}
+}
+
+func TestNftBuildV6(t *testing.T) {
+	nft := NewNftablesBuilder()
+	nft.AppendRuleV6(constants.ISTIOOUTPUT, constants.NAT, "-o", "lo", "-s", "::6/128", "-j", constants.RETURN)
+
+	expected := "add table ip6 istio_nat\n" +
+		"add chain ip6 istio_nat ISTIO_OUTPUT\n" +
+		"add rule ip6 istio_nat ISTIO_OUTPUT oifname \"lo\" ip6 saddr ::6/128 return\n"
+	if actual := nft.BuildV6Restore(); actual != expected {
+		t.Errorf("Output mismatch.\nExpected:\n%s\nActual:\n%s", expected, actual)
+	}
+}
@@ -91,6 +91,13 @@ func constructConfig() *config.Config {
 		ProbeTimeout:            viper.GetDuration(constants.ProbeTimeout),
 		SkipRuleApply:           viper.GetBool(constants.SkipRuleApply),
 		RunValidation:           viper.GetBool(constants.RunValidation),
+		Nftables:                viper.GetBool(constants.Nftables),
+		Reconcile:               viper.GetBool(constants.Reconcile),
+		Check:                   viper.GetBool(constants.Check),
+	}
+	// Checking for drift is done by computing the changes a reconcile would make.
+	if cfg.Check {
+		cfg.Reconcile = true
 	}
 
 	// TODO: Make this more configurable, maybe with a whitelist of users to be captured for output instead of a blacklist.
@@ -278,6 +285,27 @@ func init() {
 		handleError(err)
 	}
 	viper.SetDefault(constants.RunValidation, false)
+
+	rootCmd.Flags().Bool(constants.Nftables, false, "Generate nftables rules applied with nft instead of iptables rules")
+	if err := viper.BindPFlag(constants.Nftables, rootCmd.Flags().Lookup(constants.Nftables)); err != nil {
+		handleError(err)
+	}
+	viper.SetDefault(constants.Nftables, false)
+
+	rootCmd.Flags().Bool(constants.Reconcile, false,
+		"Compare the rules with the current iptables-save output and only apply the changes needed")
+	if err := viper.BindPFlag(constants.Reconcile, rootCmd.Flags().Lookup(constants.Reconcile)); err != nil {
+		handleError(err)
+	}
+	viper.SetDefault(constants.Reconcile, false)
+
+	rootCmd.Flags().Bool(constants.Check, false,
+		fmt.Sprintf("Do not apply any rules, exit with code %d if the current rules differ from the expected ones",
+			constants.DriftErrorCode))
+	if err := viper.BindPFlag(constants.Check, rootCmd.Flags().Lookup(constants.Check)); err != nil {
+		handleError(err)
+	}
+	viper.SetDefault(constants.Check, false)
 }
 
 func Execute() {
@@ -29,15 +29,19 @@ import (
 )
 
 type IptablesConfigurator struct {
-	iptables *builder.IptablesBuilderImpl
+	iptables builder.IptablesBuilder
 	//TODO(abhide): Fix dep.Dependencies with better interface
 	ext dep.Dependencies
 	cfg *config.Config
 }
 
 func NewIptablesConfigurator(cfg *config.Config, ext dep.Dependencies) *IptablesConfigurator {
+	var rules builder.IptablesBuilder = builder.NewIptablesBuilder()
+	if cfg.Nftables {
+		rules = builder.NewNftablesBuilder()
+	}
 	return &IptablesConfigurator{
-		iptables: builder.NewIptablesBuilder(),
+		iptables: rules,
 		ext:      ext,
 		cfg:      cfg,
 	}
@@ -113,18 +117,9 @@ func (iptConfigurator *IptablesConfigurator) handleInboundPortsInclude() {
 				iptConfigurator.cfg.InboundTProxyMark)
 			iptConfigurator.iptables.AppendRuleV4(constants.ISTIODIVERT, constants.MANGLE, "-j", constants.ACCEPT)
 			// Route all packets marked in chain ISTIODIVERT using routing table ${INBOUND_TPROXY_ROUTE_TABLE}.
-			//TODO: (abhide): Move this out of this method
-			iptConfigurator.ext.RunOrFail(
-				constants.IP, "-f", "inet", "rule", "add", "fwmark", iptConfigurator.cfg.InboundTProxyMark, "lookup",
-				iptConfigurator.cfg.InboundTProxyRouteTable)
-			// In routing table ${INBOUND_TPROXY_ROUTE_TABLE}, create a single default rule to route all traffic to
-			// the loopback interface.
-			//TODO: (abhide): Move this out of this method
-			err := iptConfigurator.ext.Run(constants.IP, "-f", "inet", "route", "add", "local", "default", "dev", "lo", "table",
-				iptConfigurator.cfg.InboundTProxyRouteTable)
-			if err != nil {
-				//TODO: (abhide): Move this out of this method
-				iptConfigurator.ext.RunOrFail(constants.IP, "route", "show", "table", "all")
+			// Routing is not checked for drift, only iptables rules are.
+			if !iptConfigurator.cfg.Check {
+				iptConfigurator.addTProxyRoutes()
 			}
 			// Create a new chain for redirecting inbound traffic to the common Envoy
 			// port.
@@ -176,6 +171,32 @@ func (iptConfigurator *IptablesConfigurator) handleInboundPortsInclude() {
 	}
 }
 
+func (iptConfigurator *IptablesConfigurator) addTProxyRoutes() {
+	if iptConfigurator.cfg.Reconcile {
+		// Remove the rule added by a previous run, so that it is not duplicated.
+		iptConfigurator.ext.RunQuietlyAndIgnore(
+			constants.IP, "-f", "inet", "rule", "del", "fwmark", iptConfigurator.cfg.InboundTProxyMark, "lookup",
+			iptConfigurator.cfg.InboundTProxyRouteTable)
+	}
+	//TODO: (abhide): Move this out of this method
+	iptConfigurator.ext.RunOrFail(
+		constants.IP, "-f", "inet", "rule", "add", "fwmark", iptConfigurator.cfg.InboundTProxyMark, "lookup",
+		iptConfigurator.cfg.InboundTProxyRouteTable)
+	// In routing table ${INBOUND_TPROXY_ROUTE_TABLE}, create a single default rule to route all traffic to
+	// the loopback interface.
+	//TODO: (abhide): Move this out of this method
+	routeCmd := "add"
+	if iptConfigurator.cfg.Reconcile {
+		routeCmd = "replace"
+	}
+	err := iptConfigurator.ext.Run(constants.IP, "-f", "inet", "route", routeCmd, "local", "default", "dev", "lo", "table",
+		iptConfigurator.cfg.InboundTProxyRouteTable)
+	if err != nil {
+		//TODO: (abhide): Move this out of this method
+		iptConfigurator.ext.RunOrFail(constants.IP, "route", "show", "table", "all")
+	}
+}
+
 func (iptConfigurator *IptablesConfigurator) handleInboundIpv6Rules(ipv6RangesExclude NetworkRange, ipv6RangesInclude NetworkRange) {
 	var table string
 	// Create a new chain for redirecting outbound traffic to the common Envoy port.
@@ -314,6 +335,10 @@ func (iptConfigurator *IptablesConfigurator) handleInboundIpv4Rules(ipv4RangesIn
 func (iptConfigurator *IptablesConfigurator) run() {
 	defer func() {
 		// Best effort since we don't know if the commands exist
+		if iptConfigurator.cfg.Nftables {
+			_ = iptConfigurator.ext.Run(constants.NFT, "list", "ruleset")
+			return
+		}
 		_ = iptConfigurator.ext.Run(constants.IPTABLESSAVE)
 		if iptConfigurator.cfg.EnableInboundIPv6 {
 			_ = iptConfigurator.ext.Run(constants.IP6TABLESSAVE)
@@ -340,9 +365,13 @@ func (iptConfigurator *IptablesConfigurator) run() {
 
 	iptConfigurator.logConfig()
 
-	if iptConfigurator.cfg.EnableInboundIPv6 {
+	if iptConfigurator.cfg.EnableInboundIPv6 && !iptConfigurator.cfg.Check {
 		//TODO: (abhide): Move this out of this method
-		iptConfigurator.ext.RunOrFail(constants.IP, "-6", "addr", "add", "::6/128", "dev", "lo")
+		if iptConfigurator.cfg.Reconcile {
+			iptConfigurator.ext.RunOrFail(constants.IP, "-6", "addr", "replace", "::6/128", "dev", "lo")
+		} else {
+			iptConfigurator.ext.RunOrFail(constants.IP, "-6", "addr", "add", "::6/128", "dev", "lo")
+		}
 	}
 
 	// Create a new chain for redirecting outbound traffic to the common Envoy port.
@@ -475,8 +504,93 @@ func (iptConfigurator *IptablesConfigurator) executeIptablesRestoreCommand(isIpv
 	return nil
 }
 
+func (iptConfigurator *IptablesConfigurator) executeNftCommand(isIpv4 bool) error {
+	var data, filename string
+	if isIpv4 {
+		data = iptConfigurator.iptables.BuildV4Restore()
+		filename = fmt.Sprintf("nftables-rules-%d.txt", time.Now().UnixNano())
+	} else {
+		data = iptConfigurator.iptables.BuildV6Restore()
+		filename = fmt.Sprintf("nftables6-rules-%d.txt", time.Now().UnixNano())
+	}
+	// An empty ruleset file is valid input for iptables-restore, but there is nothing for nft to do.
+	if data == "" {
+		return nil
+	}
+	rulesFile, err := ioutil.TempFile("", filename)
+	if err != nil {
+		return fmt.Errorf("unable to create nft rules file: %v", err)
+	}
+	defer os.Remove(rulesFile.Name())
+	if err := iptConfigurator.createRulesFile(rulesFile, data); err != nil {
+		return err
+	}
+	iptConfigurator.ext.RunOrFail(constants.NFT, "-f", rulesFile.Name())
+	return nil
+}
+
+// reconcile applies only the changes needed to bring the current rules in line with the built ones, or
+// just reports them in check mode. It returns true if the current rules had drifted.
+func (iptConfigurator *IptablesConfigurator) reconcile(isIpv4 bool) (bool, error) {
+	reconciler, ok := iptConfigurator.iptables.(builder.IptablesReconciler)
+	if !ok {
+		return false, fmt.Errorf("reconciling rules is not supported with the selected backend")
+	}
+	var saveCmd string
+	if isIpv4 {
+		saveCmd = constants.IPTABLESSAVE
+	} else {
+		saveCmd = constants.IP6TABLESSAVE
+	}
+	current, err := iptConfigurator.ext.RunAndGetOutput(saveCmd)
+	if err != nil {
+		return false, fmt.Errorf("unable to read current rules with %s: %v", saveCmd, err)
+	}
+	var delta [][]string
+	if isIpv4 {
+		delta = reconciler.BuildV4Delta(current)
+	} else {
+		delta = reconciler.BuildV6Delta(current)
+	}
+	if iptConfigurator.cfg.Check {
+		for _, cmd := range FormatIptablesCommands(delta) {
+			fmt.Println("Drift detected, missing change: ", cmd)
+		}
+	} else {
+		iptConfigurator.executeIptablesCommands(delta)
+	}
+	return len(delta) > 0, nil
+}
+
 func (iptConfigurator *IptablesConfigurator) executeCommands() {
-	if iptConfigurator.cfg.RestoreFormat {
+	if iptConfigurator.cfg.Reconcile {
+		drifted, err := iptConfigurator.reconcile(true)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if iptConfigurator.cfg.EnableInboundIPv6 {
+			drifted6, err := iptConfigurator.reconcile(false)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			drifted = drifted || drifted6
+		}
+		if drifted && iptConfigurator.cfg.Check {
+			os.Exit(constants.DriftErrorCode)
+		}
+	} else if iptConfigurator.cfg.Nftables && iptConfigurator.cfg.RestoreFormat {
+		// Execute nft -f for the ip and ip6 rulesets
+		if err := iptConfigurator.executeNftCommand(true); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := iptConfigurator.executeNftCommand(false); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	} else if iptConfigurator.cfg.RestoreFormat {
 		// Execute iptables-restore
 		err := iptConfigurator.executeIptablesRestoreCommand(true)
 		if err != nil {
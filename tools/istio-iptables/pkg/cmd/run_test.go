@@ -557,3 +557,29 @@ func TestHandleInboundIpv4RulesWithUidGid(t *testing.T) {
 		t.Errorf("Output mismatch.\nExpected: %#v\nActual: %#v", expected, actual)
 	}
 }
+
+func TestHandleInboundPortsIncludeWithInboundPortsAndNftables(t *testing.T) {
+	cfg := constructTestConfig()
+	cfg.InboundPortsInclude = "32000,31000"
+	cfg.Nftables = true
+
+	iptConfigurator := NewIptablesConfigurator(cfg, &dep.StdoutStubDependencies{})
+	iptConfigurator.handleInboundPortsInclude()
+
+	ip4Rules := FormatIptablesCommands(iptConfigurator.iptables.BuildV4())
+	ip6Rules := FormatIptablesCommands(iptConfigurator.iptables.BuildV6())
+	if !reflect.DeepEqual([]string{}, ip6Rules) {
+		t.Errorf("Expected ip6Rules to be empty; instead got %#v", ip6Rules)
+	}
+	expectedIpv4Rules := []string{
+		"nft -- add table ip istio_nat",
+		"nft -- add chain ip istio_nat PREROUTING { type nat hook prerouting priority -100 ; }",
+		"nft -- add chain ip istio_nat ISTIO_INBOUND",
+		"nft -- add rule ip istio_nat PREROUTING meta l4proto tcp jump ISTIO_INBOUND",
+		"nft -- add rule ip istio_nat ISTIO_INBOUND tcp dport 32000 jump ISTIO_IN_REDIRECT",
+		"nft -- add rule ip istio_nat ISTIO_INBOUND tcp dport 31000 jump ISTIO_IN_REDIRECT",
+	}
+	if !reflect.DeepEqual(ip4Rules, expectedIpv4Rules) {
+		t.Errorf("Output mismatch\nExpected: %#v\nActual: %#v", expectedIpv4Rules, ip4Rules)
+	}
+}
@@ -38,12 +38,15 @@ type Config struct {
 	OutboundIPRangesExclude string        `json:"OUTBOUND_IPRANGES_EXCLUDE"`
 	KubevirtInterfaces      string        `json:"KUBEVIRT_INTERFACES"`
 	IptablesProbePort       uint16        `json:"IPTABLES_PROBE_PORT"`
-	ProbeTimeout            time.Duration `json: "PROBE_TIMEOUT"`
+	ProbeTimeout            time.Duration `json:"PROBE_TIMEOUT"`
 	DryRun                  bool          `json:"DRY_RUN"`
 	RestoreFormat           bool          `json:"RESTORE_FORMAT"`
 	SkipRuleApply           bool          `json:"SKIP_RULE_APPLY"`
 	RunValidation           bool          `json:"RUN_VALIDATION"`
 	EnableInboundIPv6       bool          `json:"ENABLE_INBOUND_IPV6"`
+	Nftables                bool          `json:"NFTABLES"`
+	Reconcile               bool          `json:"RECONCILE"`
+	Check                   bool          `json:"CHECK"`
 }
 
 func (c *Config) String() string {
@@ -71,5 +74,8 @@ func (c *Config) Print() {
 	fmt.Println(fmt.Sprintf("OUTBOUND_PORTS_EXCLUDE=%s", c.OutboundPortsExclude))
 	fmt.Println(fmt.Sprintf("KUBEVIRT_INTERFACES=%s", c.KubevirtInterfaces))
 	fmt.Println(fmt.Sprintf("ENABLE_INBOUND_IPV6=%t", c.EnableInboundIPv6))
+	fmt.Println(fmt.Sprintf("NFTABLES=%t", c.Nftables))
+	fmt.Println(fmt.Sprintf("RECONCILE=%t", c.Reconcile))
+	fmt.Println(fmt.Sprintf("CHECK=%t", c.Check))
 	fmt.Println("")
 }
@@ -84,6 +84,9 @@ const (
 	RunValidation             = "run-validation"
 	IptablesProbePort         = "iptables-probe-port"
 	ProbeTimeout              = "probe-timeout"
+	Nftables                  = "nftables"
+	Reconcile                 = "reconcile"
+	Check                     = "check"
 )
 
 const (
@@ -105,6 +108,7 @@ const (
 	IP6TABLESRESTORE = "ip6tables-restore"
 	IP6TABLESSAVE    = "ip6tables-save"
 	IP               = "ip"
+	NFT              = "nft"
 )
 
 // Constants for syscall
@@ -120,4 +124,5 @@ const (
 
 const (
 	ValidationErrorCode = 126
+	DriftErrorCode      = 3
 )
@@ -15,6 +15,7 @@
 package dependencies
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
@@ -54,3 +55,14 @@ func (r *RealDependencies) Run(cmd string, args ...string) error {
 func (r *RealDependencies) RunQuietlyAndIgnore(cmd string, args ...string) {
 	_ = r.execute(cmd, true, args...)
 }
+
+// RunAndGetOutput runs a command and returns its standard output
+func (r *RealDependencies) RunAndGetOutput(cmd string, args ...string) (string, error) {
+	fmt.Printf("%s %s\n", cmd, strings.Join(args, " "))
+	var stdout bytes.Buffer
+	externalCommand := exec.Command(cmd, args...)
+	externalCommand.Stdout = &stdout
+	externalCommand.Stderr = os.Stderr
+	err := externalCommand.Run()
+	return stdout.String(), err
+}
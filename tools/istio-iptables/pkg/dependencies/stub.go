@@ -38,3 +38,9 @@ func (s *StdoutStubDependencies) Run(cmd string, args ...string) error {
 func (s *StdoutStubDependencies) RunQuietlyAndIgnore(cmd string, args ...string) {
 	fmt.Println(fmt.Sprintf("%s %s", cmd, strings.Join(args, " ")))
 }
+
+// RunAndGetOutput runs a command and returns its standard output, which is always empty
+func (s *StdoutStubDependencies) RunAndGetOutput(cmd string, args ...string) (string, error) {
+	fmt.Println(fmt.Sprintf("%s %s", cmd, strings.Join(args, " ")))
+	return "", nil
+}